@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// lint compiles the CEL expressions of the Pipelines, PipelineRuns and CustomRuns using the CEL custom task
+// in YAML files, like the webhook does when they are created, e.g.
+//
+//	lint -enable-pipelinerun-context pipeline.yaml pipelinerun.yaml
+//
+// The files are read from the standard input if there are none.  The flags mirror the keys of the config-cel
+// ConfigMap declaring the variables exposing the state of the cluster.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
+	celwebhook "github.com/tektoncd/experimental/cel/pkg/webhook"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/webhook/resourcesemantics"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// types are the Tekton resources whose CEL expressions are compiled, like in the webhook.
+var types = map[string]func() resourcesemantics.GenericCRD{
+	"Pipeline":    func() resourcesemantics.GenericCRD { return &celwebhook.Pipeline{} },
+	"PipelineRun": func() resourcesemantics.GenericCRD { return &celwebhook.PipelineRun{} },
+	"CustomRun":   func() resourcesemantics.GenericCRD { return &celwebhook.CustomRun{} },
+}
+
+var (
+	allowedConfigMapNamespaces = flag.String("allowed-configmap-namespaces", "", "Comma-separated list of the namespaces whose ConfigMaps can be read through the configmaps variable")
+	enablePipelineRunContext   = flag.Bool("enable-pipelinerun-context", false, "Declare the context variable holding the PipelineRun owning the CustomRun")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg := &config.Config{AllowedConfigMapNamespaces: sets.NewString(), EnablePipelineRunContext: *enablePipelineRunContext}
+	for _, ns := range strings.Split(*allowedConfigMapNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			cfg.AllowedConfigMapNamespaces.Insert(ns)
+		}
+	}
+	ctx := apis.WithinCreate(config.ToContext(context.Background(), cfg))
+
+	failed := false
+	if flag.NArg() == 0 {
+		failed = lint(ctx, "<stdin>", os.Stdin)
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if lint(ctx, name, f) {
+			failed = true
+		}
+		f.Close()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// lint prints the errors of the resources of a YAML file and returns true if there are any.
+func lint(ctx context.Context, name string, r io.Reader) bool {
+	failed := false
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return failed
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return true
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		var meta metav1.PartialObjectMetadata
+		if err := sigsyaml.Unmarshal(doc, &meta); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		newType, ok := types[meta.Kind]
+		if !ok || meta.APIVersion != v1beta1.SchemeGroupVersion.String() {
+			continue
+		}
+		obj := newType()
+		if err := sigsyaml.Unmarshal(doc, obj); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %v\n", name, meta.Kind, err)
+			failed = true
+			continue
+		}
+		if errs := obj.Validate(ctx); errs != nil {
+			objName := meta.Name
+			if objName == "" {
+				objName = meta.GenerateName
+			}
+			fmt.Fprintf(os.Stderr, "%s: %s %s: %v\n", name, meta.Kind, objName, errs)
+			failed = true
+		}
+	}
+}
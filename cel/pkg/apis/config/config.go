@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/configmap"
+)
+
+type cfgKey struct{}
+
+const (
+	// CelConfigMapName is the name of the ConfigMap holding the configuration of the CEL controller
+	CelConfigMapName = "config-cel"
+
+	allowedConfigMapNamespacesKey = "allowed-configmap-namespaces"
+	enablePipelineRunContextKey   = "enable-pipelinerun-context"
+)
+
+// Config holds the collection of configurations that we attach to contexts.
+// +k8s:deepcopy-gen=false
+type Config struct {
+	// AllowedConfigMapNamespaces are the namespaces whose ConfigMaps can be read by CEL expressions
+	// through the `configmaps` variable.  The variable isn't declared if there are none.
+	AllowedConfigMapNamespaces sets.String
+	// EnablePipelineRunContext declares the `context` variable holding the metadata and params
+	// of the PipelineRun owning the CustomRun.
+	EnablePipelineRunContext bool
+}
+
+// Store is a typed wrapper around configmap.Untyped store to handle our configmaps.
+// +k8s:deepcopy-gen=false
+type Store struct {
+	*configmap.UntypedStore
+}
+
+// ToContext attaches the current Config state to the provided context.
+func (s *Store) ToContext(ctx context.Context) context.Context {
+	return ToContext(ctx, s.Load())
+}
+
+// ToContext attaches the provided Config to the provided context, returning the
+// new context with the Config attached.
+func ToContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, cfgKey{}, c)
+}
+
+// FromContext extracts a Config from the provided context.
+func FromContext(ctx context.Context) *Config {
+	x, ok := ctx.Value(cfgKey{}).(*Config)
+	if ok {
+		return x
+	}
+	return nil
+}
+
+// FromContextOrDefaults is like FromContext, but when no Config is attached it
+// returns a Config which doesn't expose any cluster state.
+func FromContextOrDefaults(ctx context.Context) *Config {
+	if cfg := FromContext(ctx); cfg != nil {
+		return cfg
+	}
+	return &Config{AllowedConfigMapNamespaces: sets.NewString()}
+}
+
+// NewConfigFromConfigMap parses a Config struct from a ConfigMap
+func NewConfigFromConfigMap(config *corev1.ConfigMap) (*Config, error) {
+	c := Config{AllowedConfigMapNamespaces: sets.NewString()}
+	if config == nil {
+		return &c, nil
+	}
+	if v, ok := config.Data[allowedConfigMapNamespacesKey]; ok {
+		for _, ns := range strings.Split(v, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				c.AllowedConfigMapNamespaces.Insert(ns)
+			}
+		}
+	}
+	if v, ok := config.Data[enablePipelineRunContextKey]; ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s %q: %v", enablePipelineRunContextKey, v, err)
+		}
+		c.EnablePipelineRunContext = enabled
+	}
+	return &c, nil
+}
+
+// NewStore creates a new store of Configs and optionally calls functions when ConfigMaps are updated.
+func NewStore(logger configmap.Logger) *Store {
+	store := &Store{
+		UntypedStore: configmap.NewUntypedStore(
+			"cel-config",
+			logger,
+			configmap.Constructors{
+				CelConfigMapName: NewConfigFromConfigMap,
+			},
+		),
+	}
+	return store
+}
+
+// Load creates a Config from the current config state of the Store.
+func (s *Store) Load() *Config {
+	c := s.UntypedLoad(CelConfigMapName)
+	return c.(*Config)
+}
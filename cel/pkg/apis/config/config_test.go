@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
+	test "github.com/tektoncd/pipeline/pkg/reconciler/testing"
+	"k8s.io/apimachinery/pkg/util/sets"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestStoreLoadWithContext(t *testing.T) {
+	cfgMap := test.ConfigMapFromTestFile(t, "config-cel-namespaces")
+	expectedCfg, err := config.NewConfigFromConfigMap(cfgMap)
+	if err != nil {
+		t.Fatalf("error loading configmap: %s", err)
+	}
+
+	store := config.NewStore(logtesting.TestLogger(t))
+	store.OnConfigChanged(cfgMap)
+
+	cfg := config.FromContext(store.ToContext(context.Background()))
+
+	if d := cmp.Diff(cfg, expectedCfg); d != "" {
+		t.Errorf("Unexpected config %s", d)
+	}
+}
+
+func TestNewConfigFromConfigMap(t *testing.T) {
+	tcs := []struct {
+		filename       string
+		expectedConfig *config.Config
+	}{{
+		filename:       "config-cel-empty",
+		expectedConfig: &config.Config{AllowedConfigMapNamespaces: sets.NewString()},
+	}, {
+		filename:       "config-cel-namespaces",
+		expectedConfig: &config.Config{AllowedConfigMapNamespaces: sets.NewString("default", "ops")},
+	}, {
+		filename: "config-cel-pipelinerun-context",
+		expectedConfig: &config.Config{
+			AllowedConfigMapNamespaces: sets.NewString(),
+			EnablePipelineRunContext:   true,
+		},
+	}}
+	for _, tc := range tcs {
+		t.Run(tc.filename, func(t *testing.T) {
+			cm := test.ConfigMapFromTestFile(t, tc.filename)
+			cfg, err := config.NewConfigFromConfigMap(cm)
+			if err != nil {
+				t.Fatalf("error loading configmap: %s", err)
+			}
+			if d := cmp.Diff(tc.expectedConfig, cfg); d != "" {
+				t.Errorf("wrong config: %s", d)
+			}
+		})
+	}
+}
+
+func TestNewConfigFromConfigMapInvalid(t *testing.T) {
+	cm := test.ConfigMapFromTestFile(t, "config-cel-invalid")
+	if _, err := config.NewConfigFromConfigMap(cm); err == nil {
+		t.Error("expected an error parsing enable-pipelinerun-context")
+	}
+}
+
+func TestFromContextOrDefaults(t *testing.T) {
+	expectedCfg := &config.Config{AllowedConfigMapNamespaces: sets.NewString()}
+	if d := cmp.Diff(expectedCfg, config.FromContextOrDefaults(context.Background())); d != "" {
+		t.Errorf("wrong default config: %s", d)
+	}
+}
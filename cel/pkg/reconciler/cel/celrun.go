@@ -21,12 +21,12 @@ import (
 	"fmt"
 
 	"github.com/google/cel-go/cel"
-	"github.com/google/cel-go/common/types"
 	"github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
 
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	reconciler "knative.dev/pkg/reconciler"
 )
@@ -54,6 +54,8 @@ func newReconciledNormal(namespace, name string) reconciler.Event {
 
 // Reconciler implements controller.Reconciler for Run resources.
 type Reconciler struct {
+	configMapListers  *configMapListers
+	pipelineRunLister listers.PipelineRunLister
 }
 
 // Check that our Reconciler implements Interface
@@ -87,8 +89,15 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, customRun *v1beta1.Custo
 		return nil
 	}
 
-	// Create a program environment configured with the standard library of CEL functions and macros
-	env, err := cel.NewEnv(cel.Declarations())
+	// Create a program environment configured with the standard library of CEL functions and macros,
+	// the Tekton function library, the variables declared by the CustomRun and the variables exposing the
+	// state of the cluster enabled in the config
+	decls, activation := getVariables(customRun.Spec.Params)
+	clusterDecls, clusterActivation := r.getClusterVariables(ctx, customRun)
+	for name, value := range clusterActivation {
+		activation[name] = value
+	}
+	env, err := cel.NewEnv(append(append(library(), decls...), clusterDecls...)...)
 	if err != nil {
 		logger.Errorf("Couldn't create a program env with standard library of CEL functions & macros when reconciling CustomRun %s/%s: %v", customRun.Namespace, customRun.Name, err)
 		return err
@@ -96,12 +105,16 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, customRun *v1beta1.Custo
 
 	var runResults []v1beta1.CustomRunResult
 	for _, param := range customRun.Spec.Params {
+		if isVariable(param) {
+			continue
+		}
+
 		// Combine the Parse and Check phases CEL program compilation to produce an Ast and associated issues
 		ast, iss := env.Compile(param.Value.StringVal)
 		if iss.Err() != nil {
 			logger.Errorf("CEL expression %s could not be parsed when reconciling CustomRun %s/%s: %v", param.Name, customRun.Namespace, customRun.Name, iss.Err())
 			customRun.Status.MarkCustomRunFailed(ReasonSyntaxError,
-				"CEL expression %s could not be parsed", param.Name, iss.Err())
+				"CEL expression %s could not be parsed: %v", param.Name, iss.Err())
 			return nil
 		}
 
@@ -110,16 +123,24 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, customRun *v1beta1.Custo
 		if err != nil {
 			logger.Errorf("CEL expression %s could not be evaluated when reconciling Run %s/%s: %v", param.Name, customRun.Namespace, customRun.Name, err)
 			customRun.Status.MarkCustomRunFailed(ReasonEvaluationError,
-				"CEL expression %s could not be evaluated", param.Name, err)
+				"CEL expression %s could not be evaluated: %v", param.Name, err)
 			return nil
 		}
 
 		// Evaluate the CEL expression (Ast)
-		out, _, err := prg.Eval(map[string]interface{}{})
+		out, _, err := prg.Eval(activation)
 		if err != nil {
 			logger.Errorf("CEL expression %s could not be evaluated when reconciling Run %s/%s: %v", param.Name, customRun.Namespace, customRun.Name, err)
 			customRun.Status.MarkCustomRunFailed(ReasonEvaluationError,
-				"CEL expression %s could not be evaluated", param.Name, err)
+				"CEL expression %s could not be evaluated: %v", param.Name, err)
+			return nil
+		}
+
+		value, err := resultValue(out)
+		if err != nil {
+			logger.Errorf("CEL expression %s could not be converted to a result when reconciling Run %s/%s: %v", param.Name, customRun.Namespace, customRun.Name, err)
+			customRun.Status.MarkCustomRunFailed(ReasonEvaluationError,
+				"CEL expression %s could not be converted to a result: %v", param.Name, err)
 			return nil
 		}
 
@@ -127,7 +148,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, customRun *v1beta1.Custo
 		logger.Infof("CEL expression %s evaluated successfully when reconciling Run %s/%s", param.Name, customRun.Namespace, customRun.Name)
 		runResults = append(runResults, v1beta1.CustomRunResult{
 			Name:  param.Name,
-			Value: fmt.Sprintf("%s", out.ConvertToType(types.StringType).Value()),
+			Value: value,
 		})
 	}
 
@@ -140,20 +161,31 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, customRun *v1beta1.Custo
 }
 
 func validate(customRun *v1beta1.CustomRun) (errs *apis.FieldError) {
-	errs = errs.Also(validateExpressionsProvided(customRun))
-	errs = errs.Also(validateExpressionsType(customRun))
+	return validateParams(customRun.Spec.Params)
+}
+
+func validateParams(params []v1beta1.Param) (errs *apis.FieldError) {
+	errs = errs.Also(validateExpressionsProvided(params))
+	errs = errs.Also(validateExpressionsType(params))
+	errs = errs.Also(validateVariables(params))
+	errs = errs.Also(validateUniqueNames(params))
 	return errs
 }
 
-func validateExpressionsProvided(customRun *v1beta1.CustomRun) (errs *apis.FieldError) {
-	if len(customRun.Spec.Params) == 0 {
-		errs = errs.Also(apis.ErrMissingField("params"))
+func validateExpressionsProvided(params []v1beta1.Param) (errs *apis.FieldError) {
+	for _, param := range params {
+		if !isVariable(param) {
+			return nil
+		}
 	}
-	return errs
+	return errs.Also(apis.ErrMissingField("params"))
 }
 
-func validateExpressionsType(customRun *v1beta1.CustomRun) (errs *apis.FieldError) {
-	for _, param := range customRun.Spec.Params {
+func validateExpressionsType(params []v1beta1.Param) (errs *apis.FieldError) {
+	for _, param := range params {
+		if isVariable(param) {
+			continue
+		}
 		if param.Value.StringVal == "" {
 			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("CEL expression parameter %s must be a string", param.Name),
 				"value").ViaFieldKey("params", param.Name))
@@ -161,3 +193,36 @@ func validateExpressionsType(customRun *v1beta1.CustomRun) (errs *apis.FieldErro
 	}
 	return errs
 }
+
+func validateVariables(params []v1beta1.Param) (errs *apis.FieldError) {
+	for _, param := range params {
+		if !isVariable(param) {
+			continue
+		}
+		if name := variableName(param); !variableNameRegex.MatchString(name) {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("variable %s must be a valid CEL identifier", name),
+				"name").ViaFieldKey("params", param.Name))
+		} else if reservedVariableNames.Has(name) {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("variable %s is reserved", name),
+				"name").ViaFieldKey("params", param.Name))
+		}
+	}
+	return errs
+}
+
+// validateUniqueNames checks that every expression produces a result with a unique name and that every
+// variable is declared once.
+func validateUniqueNames(params []v1beta1.Param) (errs *apis.FieldError) {
+	seen := map[string]bool{}
+	for _, param := range params {
+		if seen[param.Name] {
+			msg := fmt.Sprintf("result %s is produced by more than one CEL expression", param.Name)
+			if isVariable(param) {
+				msg = fmt.Sprintf("variable %s is declared more than once", variableName(param))
+			}
+			errs = errs.Also(apis.ErrInvalidValue(msg, "name").ViaFieldKey("params", param.Name))
+		}
+		seen[param.Name] = true
+	}
+	return errs
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
 	"github.com/tektoncd/experimental/cel/test"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
 	"github.com/tektoncd/pipeline/test/diff"
@@ -40,6 +42,9 @@ import (
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+
+	_ "knative.dev/pkg/system/testing" // Setup system.Namespace()
 )
 
 func TestReconcileCelRun(t *testing.T) {
@@ -216,13 +221,154 @@ func TestReconcileCelRun(t *testing.T) {
 	}
 }
 
+func celRun(params ...v1beta1.Param) *v1beta1.CustomRun {
+	return &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cel-run",
+			Namespace: "foo",
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: params,
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: apiVersion,
+				Kind:       kind,
+				Name:       "a-celrun",
+			},
+		},
+	}
+}
+
+func stringParam(name, value string) v1beta1.Param {
+	return v1beta1.Param{Name: name, Value: *v1beta1.NewArrayOrString(value)}
+}
+
+func TestReconcileCelRunWithVariables(t *testing.T) {
+	branch := stringParam("var.branch", "main")
+	tags := v1beta1.Param{Name: "var.tags", Value: *v1beta1.NewArrayOrString("v1", "latest")}
+	labels := v1beta1.Param{Name: "var.labels", Value: *v1beta1.NewObject(map[string]string{"env": "prod"})}
+
+	testcases := []struct {
+		name            string
+		customRun       *v1beta1.CustomRun
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  string
+		expectedResults []v1beta1.CustomRunResult
+		expectedMessage string
+	}{{
+		name: "expressions reference string, array and object variables",
+		customRun: celRun(branch, tags, labels,
+			stringParam("is-main", "branch == 'main'"),
+			stringParam("tag-count", "size(tags)"),
+			stringParam("env", "labels['env']"),
+			stringParam("type", "type(tags)")),
+		expectedStatus:  corev1.ConditionTrue,
+		expectedReason:  ReasonEvaluationSuccess,
+		expectedMessage: "CEL expressions were evaluated successfully",
+		expectedResults: []v1beta1.CustomRunResult{{
+			Name:  "is-main",
+			Value: "true",
+		}, {
+			Name:  "tag-count",
+			Value: "2",
+		}, {
+			Name:  "env",
+			Value: "prod",
+		}, {
+			Name:  "type",
+			Value: "list",
+		}},
+	}, {
+		name: "lists and maps are emitted as JSON",
+		customRun: celRun(tags,
+			stringParam("images", "tags.map(t, 'app:' + t)"),
+			stringParam("numbers", "[1, 2, 3]"),
+			stringParam("config", "{'replicas': 3, 'tags': tags, 'debug': false}")),
+		expectedStatus:  corev1.ConditionTrue,
+		expectedReason:  ReasonEvaluationSuccess,
+		expectedMessage: "CEL expressions were evaluated successfully",
+		expectedResults: []v1beta1.CustomRunResult{{
+			Name:  "images",
+			Value: `["app:v1","app:latest"]`,
+		}, {
+			Name:  "numbers",
+			Value: "[1,2,3]",
+		}, {
+			Name:  "config",
+			Value: `{"debug":false,"replicas":3,"tags":["v1","latest"]}`,
+		}},
+	}, {
+		name:            "variables without expressions",
+		customRun:       celRun(branch),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonFailedValidation,
+		expectedMessage: "CustomRun can't be run because it has an invalid spec - missing field(s): params",
+	}, {
+		name:            "variable name that is not a CEL identifier",
+		customRun:       celRun(stringParam("var.my-branch", "main"), stringParam("expr1", "true")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonFailedValidation,
+		expectedMessage: "CustomRun can't be run because it has an invalid spec - invalid value: variable my-branch must be a valid CEL identifier: params.[var.my-branch].name",
+	}, {
+		name:            "variable name that is reserved",
+		customRun:       celRun(stringParam("var.configmaps", "main"), stringParam("expr1", "true")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonFailedValidation,
+		expectedMessage: "CustomRun can't be run because it has an invalid spec - invalid value: variable configmaps is reserved: params.[var.configmaps].name",
+	}, {
+		name:            "expressions producing the same result",
+		customRun:       celRun(stringParam("expr1", "true"), stringParam("expr1", "false")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonFailedValidation,
+		expectedMessage: "CustomRun can't be run because it has an invalid spec - invalid value: result expr1 is produced by more than one CEL expression: params[expr1].name",
+	}, {
+		name:            "expression with a variable of the wrong type",
+		customRun:       celRun(branch, stringParam("expr1", "branch + 1")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonSyntaxError,
+		expectedMessage: "CEL expression expr1 could not be parsed: ERROR: <input>:1:8: found no matching overload for '_+_' applied to '(string, int)'\n | branch + 1\n | .......^",
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			d := test.Data{
+				CustomRuns: []*v1beta1.CustomRun{tc.customRun},
+			}
+
+			testAssets, _ := getCelController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getCustomRunName(tc.customRun)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledCustomRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.customRun.Namespace).Get(ctx, tc.customRun.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+
+			checkCustomRunCondition(t, reconciledCustomRun, tc.expectedStatus, tc.expectedReason, tc.expectedMessage)
+
+			if d := cmp.Diff(tc.expectedResults, reconciledCustomRun.Status.Results); d != "" {
+				t.Errorf("Status Results: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
 func getCelController(t *testing.T, d test.Data) (test.Assets, func()) {
 	ctx, _ := ttesting.SetupFakeContext(t)
 	ctx, cancel := context.WithCancel(ctx)
 	c, informers := test.SeedTestData(t, ctx, d)
 
-	configMapWatcher := configmap.NewStaticWatcher()
-	//configMapWatcher := configmap.NewInformedWatcher(c.Kube, system.GetNamespace())
+	celConfig := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: config.CelConfigMapName, Namespace: system.Namespace()}}
+	for _, cm := range d.ConfigMaps {
+		if cm.Name == config.CelConfigMapName {
+			celConfig = cm
+		}
+	}
+	configMapWatcher := configmap.NewStaticWatcher(celConfig)
 	ctl := NewController(ctx, configMapWatcher)
 
 	if la, ok := ctl.Reconciler.(reconciler.LeaderAware); ok {
@@ -301,3 +447,252 @@ func checkEvents(fr *record.FakeRecorder, testName string, wantEvents []string)
 	}
 	return nil
 }
+
+func TestReconcileCelRunWithLibrary(t *testing.T) {
+	testcases := []struct {
+		name            string
+		expression      string
+		expectedReason  string
+		expectedResult  string
+		expectedMessage string
+	}{{
+		name:           "semver.compare with a lower version",
+		expression:     "semver.compare('v1.2.0', '1.10.0')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "-1",
+	}, {
+		name:           "semver.compare with equal versions",
+		expression:     "semver.compare('1.2.3', 'v1.2.3') == 0",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:            "semver.compare with an invalid version",
+		expression:      "semver.compare('latest', '1.0.0')",
+		expectedReason:  ReasonEvaluationError,
+		expectedMessage: "CEL expression expr could not be evaluated: semver.compare: invalid version \"latest\": No Major.Minor.Patch elements found",
+	}, {
+		name:           "semver.satisfies",
+		expression:     "semver.satisfies('1.2.3', '>=1.2.0 <2.0.0') && !semver.satisfies('2.0.0', '>=1.2.0 <2.0.0')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:           "capture",
+		expression:     `'release-1.2'.capture('release-(\\d+)\\.(\\d+)')`,
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: `["release-1.2","1","2"]`,
+	}, {
+		name:           "capture without a match",
+		expression:     `size('main'.capture('release-(\\d+)')) == 0`,
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:           "json.parse",
+		expression:     `json.parse('{"image": {"tag": "v1"}, "replicas": 3}').image.tag`,
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "v1",
+	}, {
+		name:           "json.parse parses numbers as doubles",
+		expression:     `json.parse('{"replicas": 3}').replicas == 3.0`,
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:            "json.parse with invalid JSON",
+		expression:      `json.parse('{')`,
+		expectedReason:  ReasonEvaluationError,
+		expectedMessage: "CEL expression expr could not be evaluated: json.parse: unexpected end of JSON input",
+	}, {
+		name:           "base64.encode",
+		expression:     "base64.encode('hello')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "aGVsbG8=",
+	}, {
+		name:           "base64.decode",
+		expression:     "base64.decode('aGVsbG8=')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "hello",
+	}, {
+		name:           "split and join",
+		expression:     "'a,b,c'.split(',').join('-')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "a-b-c",
+	}, {
+		name:           "time.parse and duration math",
+		expression:     "time.parse('2021-02-03 10:00', '2006-01-02 15:04') + duration('90m') == timestamp('2021-02-03T11:30:00Z')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:           "difference of parsed times",
+		expression:     "time.parse('2021-02-04', '2006-01-02') - time.parse('2021-02-03', '2006-01-02') > duration('23h')",
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:            "time.parse with a value that does not match the layout",
+		expression:      "time.parse('03/02/2021', '2006-01-02')",
+		expectedReason:  ReasonEvaluationError,
+		expectedMessage: "CEL expression expr could not be evaluated: time.parse: parsing time \"03/02/2021\" as \"2006-01-02\": cannot parse \"03/02/2021\" as \"2006\"",
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			customRun := celRun(stringParam("expr", tc.expression))
+
+			testAssets, _ := getCelController(t, test.Data{CustomRuns: []*v1beta1.CustomRun{customRun}})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getCustomRunName(customRun)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledCustomRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(customRun.Namespace).Get(ctx, customRun.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+
+			var expectedResults []v1beta1.CustomRunResult
+			expectedStatus := corev1.ConditionFalse
+			expectedMessage := tc.expectedMessage
+			if tc.expectedReason == ReasonEvaluationSuccess {
+				expectedResults = []v1beta1.CustomRunResult{{Name: "expr", Value: tc.expectedResult}}
+				expectedStatus = corev1.ConditionTrue
+				expectedMessage = "CEL expressions were evaluated successfully"
+			}
+			checkCustomRunCondition(t, reconciledCustomRun, expectedStatus, tc.expectedReason, expectedMessage)
+
+			if d := cmp.Diff(expectedResults, reconciledCustomRun.Status.Results); d != "" {
+				t.Errorf("Status Results: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestReconcileCelRunWithClusterState(t *testing.T) {
+	celConfig := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: config.CelConfigMapName, Namespace: system.Namespace()},
+			Data:       data,
+		}
+	}
+	deployFreeze := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy-freeze", Namespace: "ops"},
+		Data:       map[string]string{"frozen": "true"},
+	}
+	secretConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret-config", Namespace: "kube-system"},
+		Data:       map[string]string{"token": "abc"},
+	}
+	pipelineRun := &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pr",
+			Namespace: "foo",
+			Labels:    map[string]string{"app": "frontend"},
+		},
+		Spec: v1beta1.PipelineRunSpec{
+			Params: []v1beta1.Param{
+				stringParam("branch", "main"),
+				{Name: "tags", Value: *v1beta1.NewArrayOrString("v1", "latest")},
+			},
+		},
+	}
+	ownedCelRun := func(params ...v1beta1.Param) *v1beta1.CustomRun {
+		run := celRun(params...)
+		run.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(pipelineRun,
+			v1beta1.SchemeGroupVersion.WithKind(pipeline.PipelineRunControllerName))}
+		return run
+	}
+
+	testcases := []struct {
+		name            string
+		customRun       *v1beta1.CustomRun
+		config          map[string]string
+		expectedReason  string
+		expectedResult  string
+		expectedMessage string
+	}{{
+		name:           "configmap of an allowed namespace",
+		customRun:      celRun(stringParam("expr", "configmaps.ops['deploy-freeze'].frozen == 'true'")),
+		config:         map[string]string{"allowed-configmap-namespaces": "ops"},
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "true",
+	}, {
+		name:            "configmap of a namespace that is not allowed",
+		customRun:       celRun(stringParam("expr", "configmaps['kube-system']['secret-config'].token")),
+		config:          map[string]string{"allowed-configmap-namespaces": "ops"},
+		expectedReason:  ReasonEvaluationError,
+		expectedMessage: "CEL expression expr could not be evaluated: no such key: kube-system",
+	}, {
+		name:            "configmaps without allowed namespaces",
+		customRun:       celRun(stringParam("expr", "configmaps.ops['deploy-freeze'].frozen")),
+		expectedReason:  ReasonSyntaxError,
+		expectedMessage: "CEL expression expr could not be parsed: ERROR: <input>:1:1: undeclared reference to 'configmaps' (in container '')\n | configmaps.ops['deploy-freeze'].frozen\n | ^",
+	}, {
+		name: "pipelineRun context",
+		customRun: ownedCelRun(stringParam("expr",
+			"context.pipelineRun.name + ':' + context.pipelineRun.labels.app + ':' + context.pipelineRun.params.branch + ':' + context.pipelineRun.params.tags[0]")),
+		config:         map[string]string{"enable-pipelinerun-context": "true"},
+		expectedReason: ReasonEvaluationSuccess,
+		expectedResult: "pr:frontend:main:v1",
+	}, {
+		name:            "pipelineRun context of a CustomRun without a PipelineRun",
+		customRun:       celRun(stringParam("expr", "context.pipelineRun.name")),
+		config:          map[string]string{"enable-pipelinerun-context": "true"},
+		expectedReason:  ReasonEvaluationError,
+		expectedMessage: "CEL expression expr could not be evaluated: context: CustomRun foo/cel-run is not owned by a PipelineRun",
+	}, {
+		name:            "pipelineRun context that is not enabled",
+		customRun:       ownedCelRun(stringParam("expr", "context.pipelineRun.name")),
+		expectedReason:  ReasonSyntaxError,
+		expectedMessage: "CEL expression expr could not be parsed: ERROR: <input>:1:1: undeclared reference to 'context' (in container '')\n | context.pipelineRun.name\n | ^",
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.customRun},
+				PipelineRuns: []*v1beta1.PipelineRun{pipelineRun},
+				ConfigMaps:   []*corev1.ConfigMap{celConfig(tc.config), deployFreeze, secretConfig},
+			}
+			testAssets, _ := getCelController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getCustomRunName(tc.customRun)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledCustomRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.customRun.Namespace).Get(ctx, tc.customRun.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+
+			// the ConfigMaps of the namespaces which aren't allowed are never listed nor watched
+			cfg, err := config.NewConfigFromConfigMap(celConfig(tc.config))
+			if err != nil {
+				t.Fatalf("Error parsing the config: %s", err)
+			}
+			for _, action := range clients.Kube.Actions() {
+				if action.GetResource().Resource != "configmaps" || (action.GetVerb() != "list" && action.GetVerb() != "watch") {
+					continue
+				}
+				if !cfg.AllowedConfigMapNamespaces.Has(action.GetNamespace()) {
+					t.Errorf("ConfigMaps of namespace %q were read although it isn't allowed", action.GetNamespace())
+				}
+			}
+
+			var expectedResults []v1beta1.CustomRunResult
+			expectedStatus := corev1.ConditionFalse
+			expectedMessage := tc.expectedMessage
+			if tc.expectedReason == ReasonEvaluationSuccess {
+				expectedResults = []v1beta1.CustomRunResult{{Name: "expr", Value: tc.expectedResult}}
+				expectedStatus = corev1.ConditionTrue
+				expectedMessage = "CEL expressions were evaluated successfully"
+			}
+			checkCustomRunCondition(t, reconciledCustomRun, expectedStatus, tc.expectedReason, expectedMessage)
+
+			if d := cmp.Diff(expectedResults, reconciledCustomRun.Status.Results); d != "" {
+				t.Errorf("Status Results: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// referenceRegex matches the Tekton variable references, e.g. $(params.branch) or $(tasks.build.results.tag),
+// which are only substituted when the CustomRun is created.
+var referenceRegex = regexp.MustCompile(`\$\([^()]*\)`)
+
+// IsCELRef returns true if the reference is to the CEL custom task.
+func IsCELRef(ref *v1beta1.TaskRef) bool {
+	return ref != nil && ref.APIVersion == apiVersion && ref.Kind == kind
+}
+
+// ValidateExpressions validates the params of the CEL custom task and compiles its CEL expressions in the
+// environment the reconciler evaluates them in, including the variables exposing the state of the cluster
+// enabled in the config attached to the context, so that errors are reported before the CustomRun runs.
+//
+// The params may contain Tekton variable references, e.g. in the params of a Pipeline task.  Each reference is
+// replaced by an identifier of the same length declared with the `dyn` type, so that expressions like
+// `$(params.count) > 3` are checked and the columns of the errors still match the expressions.  For the same
+// reason, a variable whose value contains a reference is declared with the `dyn` type.
+func ValidateExpressions(ctx context.Context, params []v1beta1.Param) *apis.FieldError {
+	if errs := validateParams(params); errs != nil {
+		return errs
+	}
+
+	var decls []cel.EnvOption
+	placeholders := map[string]bool{}
+	for _, param := range params {
+		if !isVariable(param) {
+			for _, reference := range referenceRegex.FindAllString(param.Value.StringVal, -1) {
+				placeholders[placeholder(reference)] = true
+			}
+			continue
+		}
+		if param.Value.Type == v1beta1.ParamTypeString && referenceRegex.MatchString(param.Value.StringVal) {
+			decls = append(decls, cel.Variable(variableName(param), cel.DynType))
+		} else {
+			decls = append(decls, cel.Variable(variableName(param), variableType(param)))
+		}
+	}
+	for name := range placeholders {
+		decls = append(decls, cel.Variable(name, cel.DynType))
+	}
+	decls = append(decls, clusterDeclarations(config.FromContextOrDefaults(ctx))...)
+	env, err := cel.NewEnv(append(library(), decls...)...)
+	if err != nil {
+		return apis.ErrGeneric(fmt.Sprintf("could not create a CEL environment: %v", err))
+	}
+
+	var errs *apis.FieldError
+	for _, param := range params {
+		if isVariable(param) {
+			continue
+		}
+		expression := referenceRegex.ReplaceAllStringFunc(param.Value.StringVal, placeholder)
+		if _, iss := env.Compile(expression); iss.Err() != nil {
+			var msgs []string
+			for _, e := range iss.Errors() {
+				msgs = append(msgs, fmt.Sprintf("%d:%d: %s", e.Location.Line(), e.Location.Column()+1, e.Message))
+			}
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("CEL expression %s could not be parsed: %s", param.Name,
+				strings.Join(msgs, "; ")), "value").ViaFieldKey("params", param.Name))
+		}
+	}
+	return errs
+}
+
+// placeholder returns the identifier replacing a Tekton variable reference in a CEL expression.
+func placeholder(reference string) string {
+	return strings.Repeat("_", len(reference))
+}
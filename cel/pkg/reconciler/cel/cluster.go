@@ -0,0 +1,283 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/controller"
+)
+
+const (
+	// configMapsVariable is the name of the variable holding the data of the ConfigMaps of the allowed
+	// namespaces, e.g. configmaps['ops']['deploy-freeze']['frozen']
+	configMapsVariable = "configmaps"
+
+	// contextVariable is the name of the variable holding the PipelineRun owning the CustomRun,
+	// e.g. context.pipelineRun.labels['app']
+	contextVariable = "context"
+
+	// configMapSyncTimeout is how long to wait for the ConfigMaps of a namespace to be listed the first time
+	// they are read, e.g. when the controller isn't allowed to list them
+	configMapSyncTimeout = 10 * time.Second
+
+	// configMapMinRetryBackoff and configMapMaxRetryBackoff bound how long the expressions reading the ConfigMaps
+	// of a namespace which couldn't be listed fail right away, before listing them is tried again
+	configMapMinRetryBackoff = 30 * time.Second
+	configMapMaxRetryBackoff = 5 * time.Minute
+)
+
+// reservedVariableNames are the names of the variables that can't be declared by the params of a CustomRun.
+var reservedVariableNames = sets.NewString(configMapsVariable, contextVariable)
+
+// getClusterVariables returns the declarations of the variables exposing the state of the cluster enabled in
+// the config of the controller, and the activation that binds them.  The values are resolved through the
+// listers the first time an expression references them, so that a CustomRun only pays for what it reads.
+func (r *Reconciler) getClusterVariables(ctx context.Context, customRun *v1beta1.CustomRun) ([]cel.EnvOption, map[string]interface{}) {
+	cfg := config.FromContextOrDefaults(ctx)
+	activation := map[string]interface{}{}
+	if cfg.AllowedConfigMapNamespaces.Len() > 0 {
+		activation[configMapsVariable] = func() ref.Val {
+			return r.getConfigMaps(cfg.AllowedConfigMapNamespaces)
+		}
+	}
+	if cfg.EnablePipelineRunContext {
+		activation[contextVariable] = func() ref.Val {
+			return r.getContext(customRun)
+		}
+	}
+	return clusterDeclarations(cfg), activation
+}
+
+// clusterDeclarations returns the declarations of the variables exposing the state of the cluster enabled in the config.
+func clusterDeclarations(cfg *config.Config) []cel.EnvOption {
+	var decls []cel.EnvOption
+	if cfg.AllowedConfigMapNamespaces.Len() > 0 {
+		decls = append(decls, cel.Variable(configMapsVariable,
+			cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.StringType)))))
+	}
+	if cfg.EnablePipelineRunContext {
+		decls = append(decls, cel.Variable(contextVariable, cel.MapType(cel.StringType, cel.DynType)))
+	}
+	return decls
+}
+
+// getConfigMaps returns the data of the ConfigMaps of the allowed namespaces, indexed by namespace and name.
+func (r *Reconciler) getConfigMaps(namespaces sets.String) ref.Val {
+	listers, err := r.configMapListers.get(namespaces)
+	if err != nil {
+		return types.NewErr("%s: %v", configMapsVariable, err)
+	}
+	configMaps := map[string]map[string]map[string]string{}
+	for _, namespace := range namespaces.List() {
+		cms, err := listers[namespace].ConfigMaps(namespace).List(labels.Everything())
+		if err != nil {
+			return types.NewErr("%s: %v", configMapsVariable, err)
+		}
+		configMaps[namespace] = map[string]map[string]string{}
+		for _, cm := range cms {
+			configMaps[namespace][cm.Name] = nonNil(cm.Data)
+		}
+	}
+	return types.DefaultTypeAdapter.NativeToValue(configMaps)
+}
+
+// configMapListers holds an informer for the ConfigMaps of each namespace that CEL expressions are allowed to
+// read, so that the controller doesn't watch, nor need access to, the ConfigMaps of the other namespaces.
+type configMapListers struct {
+	ctx         context.Context
+	kubeClient  kubernetes.Interface
+	clock       clock.PassiveClock
+	syncTimeout time.Duration
+
+	mu         sync.Mutex
+	namespaces map[string]*namespaceConfigMaps
+}
+
+// namespaceConfigMaps is the informer of the ConfigMaps of a namespace.
+type namespaceConfigMaps struct {
+	lister corev1listers.ConfigMapLister
+	stop   context.CancelFunc
+	// synced is closed once the informer synced, or gave up syncing in which case err is set
+	synced chan struct{}
+	err    error
+	// failures counts the attempts to sync in a row which failed, and retryAt is when the next one may start
+	failures int
+	retryAt  time.Time
+}
+
+func newConfigMapListers(ctx context.Context, kubeClient kubernetes.Interface, clock clock.PassiveClock) *configMapListers {
+	return &configMapListers{
+		ctx:         ctx,
+		kubeClient:  kubeClient,
+		clock:       clock,
+		syncTimeout: configMapSyncTimeout,
+		namespaces:  map[string]*namespaceConfigMaps{},
+	}
+}
+
+// get returns the listers of the ConfigMaps of the namespaces.  The informer of a namespace is started the first
+// time it is read, and stopped once the namespace is no longer allowed.  The informers are waited for without
+// holding the lock, so that a namespace which is slow to sync only holds up the expressions reading it.  A
+// namespace which couldn't be synced fails right away until its backoff passes.
+func (l *configMapListers) get(namespaces sets.String) (map[string]corev1listers.ConfigMapLister, error) {
+	l.mu.Lock()
+	for namespace, ns := range l.namespaces {
+		if !namespaces.Has(namespace) {
+			ns.stop()
+			delete(l.namespaces, namespace)
+		}
+	}
+	toWait := make(map[string]*namespaceConfigMaps, namespaces.Len())
+	for _, namespace := range namespaces.List() {
+		ns, ok := l.namespaces[namespace]
+		if !ok || (ns.failed() && !l.clock.Now().Before(ns.retryAt)) {
+			failures := 0
+			if ok {
+				failures = ns.failures
+			}
+			ns = l.start(namespace, failures)
+			l.namespaces[namespace] = ns
+		}
+		toWait[namespace] = ns
+	}
+	l.mu.Unlock()
+
+	listers := make(map[string]corev1listers.ConfigMapLister, len(toWait))
+	for _, namespace := range namespaces.List() {
+		ns := toWait[namespace]
+		<-ns.synced
+		if ns.err != nil {
+			return nil, ns.err
+		}
+		listers[namespace] = ns.lister
+	}
+	return listers, nil
+}
+
+// start starts the informer of the ConfigMaps of a namespace, and waits for it to sync in the background.  If it
+// doesn't sync in time, it is stopped and retried once a backoff which doubles with each failure has passed.
+func (l *configMapListers) start(namespace string, failures int) *namespaceConfigMaps {
+	ctx, stop := context.WithCancel(l.ctx)
+	factory := informers.NewSharedInformerFactoryWithOptions(l.kubeClient, controller.GetResyncPeriod(l.ctx),
+		informers.WithNamespace(namespace))
+	informer := factory.Core().V1().ConfigMaps()
+	ns := &namespaceConfigMaps{
+		lister:   informer.Lister(),
+		stop:     stop,
+		synced:   make(chan struct{}),
+		failures: failures,
+	}
+	factory.Start(ctx.Done())
+
+	go func() {
+		syncCtx, cancel := context.WithTimeout(ctx, l.syncTimeout)
+		defer cancel()
+		synced := cache.WaitForCacheSync(syncCtx.Done(), informer.Informer().HasSynced)
+		l.mu.Lock()
+		if synced {
+			ns.failures = 0
+		} else {
+			stop()
+			ns.failures++
+			ns.retryAt = l.clock.Now().Add(configMapRetryBackoff(ns.failures))
+			ns.err = fmt.Errorf("couldn't list the ConfigMaps of namespace %s, check that the controller is allowed to", namespace)
+		}
+		l.mu.Unlock()
+		close(ns.synced)
+	}()
+	return ns
+}
+
+// failed returns whether the informer gave up syncing.  It must be called with the lock held.
+func (ns *namespaceConfigMaps) failed() bool {
+	select {
+	case <-ns.synced:
+		return ns.err != nil
+	default:
+		return false
+	}
+}
+
+// configMapRetryBackoff returns how long to wait before syncing the ConfigMaps of a namespace again after the given
+// number of failures in a row.
+func configMapRetryBackoff(failures int) time.Duration {
+	backoff := configMapMinRetryBackoff
+	for i := 1; i < failures && backoff < configMapMaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > configMapMaxRetryBackoff {
+		return configMapMaxRetryBackoff
+	}
+	return backoff
+}
+
+// getContext returns the name, namespace, labels, annotations and params of the PipelineRun owning the CustomRun.
+func (r *Reconciler) getContext(customRun *v1beta1.CustomRun) ref.Val {
+	owner := metav1.GetControllerOf(customRun)
+	if owner == nil || owner.Kind != pipeline.PipelineRunControllerName {
+		return types.NewErr("%s: CustomRun %s/%s is not owned by a PipelineRun", contextVariable, customRun.Namespace, customRun.Name)
+	}
+	pr, err := r.pipelineRunLister.PipelineRuns(customRun.Namespace).Get(owner.Name)
+	if err != nil {
+		return types.NewErr("%s: %v", contextVariable, err)
+	}
+	params := map[string]interface{}{}
+	for _, param := range pr.Spec.Params {
+		switch param.Value.Type {
+		case v1beta1.ParamTypeArray:
+			params[param.Name] = param.Value.ArrayVal
+		case v1beta1.ParamTypeObject:
+			params[param.Name] = param.Value.ObjectVal
+		default:
+			params[param.Name] = param.Value.StringVal
+		}
+	}
+	return types.DefaultTypeAdapter.NativeToValue(map[string]interface{}{
+		"pipelineRun": map[string]interface{}{
+			"name":        pr.Name,
+			"namespace":   pr.Namespace,
+			"labels":      nonNil(pr.Labels),
+			"annotations": nonNil(pr.Annotations),
+			"params":      params,
+		},
+	})
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	ktesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// blockingClient is a kube client whose List of the ConfigMaps of a namespace blocks until it is released.  The
+// fake client can't block in a reactor, since it holds its lock while the reactors run.
+type blockingClient struct {
+	kubernetes.Interface
+	namespace string
+	listing   chan struct{}
+	release   chan struct{}
+}
+
+func (c *blockingClient) CoreV1() typedcorev1.CoreV1Interface {
+	return &blockingCoreV1{CoreV1Interface: c.Interface.CoreV1(), client: c}
+}
+
+type blockingCoreV1 struct {
+	typedcorev1.CoreV1Interface
+	client *blockingClient
+}
+
+func (c *blockingCoreV1) ConfigMaps(namespace string) typedcorev1.ConfigMapInterface {
+	configMaps := c.CoreV1Interface.ConfigMaps(namespace)
+	if namespace != c.client.namespace {
+		return configMaps
+	}
+	return &blockingConfigMaps{ConfigMapInterface: configMaps, client: c.client}
+}
+
+type blockingConfigMaps struct {
+	typedcorev1.ConfigMapInterface
+	client *blockingClient
+}
+
+func (c *blockingConfigMaps) List(ctx context.Context, opts metav1.ListOptions) (*corev1.ConfigMapList, error) {
+	select {
+	case c.client.listing <- struct{}{}:
+	default:
+	}
+	<-c.client.release
+	return c.ConfigMapInterface.List(ctx, opts)
+}
+
+func TestConfigMapListersDontWaitForOtherNamespaces(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := &blockingClient{
+		Interface: fakekube.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy-freeze", Namespace: "ops"},
+		}),
+		namespace: "slow",
+		listing:   make(chan struct{}, 1),
+		release:   make(chan struct{}),
+	}
+	defer close(kubeClient.release)
+
+	l := newConfigMapListers(ctx, kubeClient, clocktesting.NewFakePassiveClock(time.Now()))
+	// the slow namespace would hold up the other namespaces for as long as it's waited for if the lock was held
+	l.syncTimeout = time.Hour
+	go l.get(sets.NewString("ops", "slow"))
+	<-kubeClient.listing
+
+	listers, err := l.get(sets.NewString("ops"))
+	if err != nil {
+		t.Fatalf("couldn't get the listers of namespace ops: %v", err)
+	}
+	if _, err := listers["ops"].ConfigMaps("ops").Get("deploy-freeze"); err != nil {
+		t.Errorf("couldn't get the ConfigMap of namespace ops: %v", err)
+	}
+}
+
+func TestConfigMapListersBackOff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fakekube.NewSimpleClientset()
+	lists := 0
+	var mu sync.Mutex
+	kubeClient.PrependReactor("list", "configmaps", func(action ktesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		lists++
+		return true, nil, errors.NewForbidden(corev1.Resource("configmaps"), "", nil)
+	})
+	listCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return lists
+	}
+
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	l := newConfigMapListers(ctx, kubeClient, clock)
+	l.syncTimeout = 100 * time.Millisecond
+	namespaces := sets.NewString("forbidden")
+
+	if _, err := l.get(namespaces); err == nil {
+		t.Fatalf("expected an error listing the ConfigMaps of a forbidden namespace")
+	}
+	failed := listCount()
+	if failed == 0 {
+		t.Fatalf("expected the ConfigMaps of the namespace to be listed")
+	}
+
+	// the failure is returned right away, without listing the ConfigMaps again, until the backoff passes
+	clock.SetTime(clock.Now().Add(configMapMinRetryBackoff - time.Second))
+	if _, err := l.get(namespaces); err == nil {
+		t.Fatalf("expected the failure to be returned during the backoff")
+	}
+	if lists := listCount(); lists != failed {
+		t.Errorf("expected the ConfigMaps not to be listed during the backoff, but they were listed %d more times", lists-failed)
+	}
+
+	clock.SetTime(clock.Now().Add(time.Second))
+	if _, err := l.get(namespaces); err == nil {
+		t.Fatalf("expected an error listing the ConfigMaps of a forbidden namespace again")
+	}
+	if lists := listCount(); lists == failed {
+		t.Errorf("expected the ConfigMaps to be listed again once the backoff passed")
+	}
+	if retryAt := l.namespaces["forbidden"].retryAt; !retryAt.Equal(clock.Now().Add(2 * configMapMinRetryBackoff)) {
+		t.Errorf("expected the backoff to double after the second failure, but the next retry is at %v", retryAt)
+	}
+}
+
+func TestConfigMapRetryBackoff(t *testing.T) {
+	for failures, expected := range map[int]time.Duration{
+		1:  configMapMinRetryBackoff,
+		2:  2 * configMapMinRetryBackoff,
+		3:  4 * configMapMinRetryBackoff,
+		20: configMapMaxRetryBackoff,
+	} {
+		if backoff := configMapRetryBackoff(failures); backoff != expected {
+			t.Errorf("expected a backoff of %s after %d failures but got %s", expected, failures, backoff)
+		}
+	}
+}
@@ -19,10 +19,14 @@ package cel
 import (
 	context "context"
 
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
 	customRunInformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	pipelineRunInformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/pipelinerun"
 	"github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	tkncontroller "github.com/tektoncd/pipeline/pkg/controller"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	configmap "knative.dev/pkg/configmap"
 	controller "knative.dev/pkg/controller"
 	logging "knative.dev/pkg/logging"
@@ -38,11 +42,18 @@ const (
 func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
-	r := &Reconciler{}
+	configStore := config.NewStore(logger.Named("config-store"))
+	configStore.WatchConfigs(cmw)
+
+	r := &Reconciler{
+		configMapListers:  newConfigMapListers(ctx, kubeclient.Get(ctx), clock.RealClock{}),
+		pipelineRunLister: pipelineRunInformer.Get(ctx).Lister(),
+	}
 
 	impl := customrun.NewImpl(ctx, r, func(impl *controller.Impl) controller.Options {
 		return controller.Options{
-			AgentName: ControllerName,
+			AgentName:   ControllerName,
+			ConfigStore: configStore,
 		}
 	})
 
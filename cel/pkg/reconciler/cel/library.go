@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+)
+
+// library returns the CEL functions available to the expressions of a CustomRun in addition to the
+// standard library and the string extensions of cel-go, e.g. `<string>.split(<string>)` and
+// `<list<string>>.join(<string>)`:
+//
+//	semver.compare(<string>, <string>) -> <int>
+//	semver.satisfies(<string>, <string>) -> <bool>
+//	<string>.capture(<string>) -> <list<string>>
+//	json.parse(<string>) -> <dyn>
+//	base64.encode(<string>) -> <string>
+//	base64.decode(<string>) -> <string>
+//	time.parse(<string>, <string>) -> <timestamp>
+func library() []cel.EnvOption {
+	return []cel.EnvOption{ext.Strings(), cel.Lib(tektonLib{})}
+}
+
+type tektonLib struct{}
+
+// CompileOptions implements cel.Library.
+func (tektonLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		// semver.compare returns -1, 0 or 1 if the first version is lower than, equal to or higher
+		// than the second one.  A leading "v" is allowed, e.g. semver.compare('v1.2.0', '1.10.0') == -1
+		cel.Function("semver.compare",
+			cel.Overload("semver_compare_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+				cel.BinaryBinding(semverCompare))),
+		// semver.satisfies returns true if the version is in the range, e.g.
+		// semver.satisfies('1.2.3', '>=1.2.0 <2.0.0') == true
+		cel.Function("semver.satisfies",
+			cel.Overload("semver_satisfies_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(semverSatisfies))),
+		// capture returns the leftmost match of the regular expression in the string followed by the
+		// matches of its capturing groups, or an empty list if it doesn't match, e.g.
+		// 'release-1.2'.capture('release-(\\d+)\\.(\\d+)') == ['release-1.2', '1', '2']
+		cel.Function("capture",
+			cel.MemberOverload("string_capture_string", []*cel.Type{cel.StringType, cel.StringType}, cel.ListType(cel.StringType),
+				cel.BinaryBinding(capture))),
+		// json.parse parses a JSON document, e.g. the result of an earlier task.  Numbers are parsed as doubles,
+		// e.g. json.parse('{"replicas": 3}').replicas == 3.0
+		cel.Function("json.parse",
+			cel.Overload("json_parse_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(jsonParse))),
+		// base64.encode and base64.decode convert strings to and from their standard base64 encoding.
+		cel.Function("base64.encode",
+			cel.Overload("base64_encode_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(base64Encode))),
+		cel.Function("base64.decode",
+			cel.Overload("base64_decode_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(base64Decode))),
+		// time.parse parses a time with a Go layout, e.g. time.parse('2021-02-03', '2006-01-02').
+		// The timestamps can be compared, and subtracted or shifted with durations, using the standard library,
+		// e.g. time.parse('2021-02-03', '2006-01-02') + duration('24h') > timestamp('2021-02-03T12:00:00Z')
+		cel.Function("time.parse",
+			cel.Overload("time_parse_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.TimestampType,
+				cel.BinaryBinding(timeParse))),
+	}
+}
+
+// ProgramOptions implements cel.Library.
+func (tektonLib) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+func semverCompare(lhs, rhs ref.Val) ref.Val {
+	v1, err := parseVersion(lhs)
+	if err != nil {
+		return types.NewErr("semver.compare: %v", err)
+	}
+	v2, err := parseVersion(rhs)
+	if err != nil {
+		return types.NewErr("semver.compare: %v", err)
+	}
+	return types.Int(v1.Compare(v2))
+}
+
+func semverSatisfies(lhs, rhs ref.Val) ref.Val {
+	v, err := parseVersion(lhs)
+	if err != nil {
+		return types.NewErr("semver.satisfies: %v", err)
+	}
+	r, err := semver.ParseRange(string(rhs.(types.String)))
+	if err != nil {
+		return types.NewErr("semver.satisfies: %v", err)
+	}
+	return types.Bool(r(v))
+}
+
+// parseVersion parses a semantic version with an optional leading "v".
+func parseVersion(val ref.Val) (semver.Version, error) {
+	version := string(val.(types.String))
+	v, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return v, fmt.Errorf("invalid version %q: %v", version, err)
+	}
+	return v, nil
+}
+
+func capture(lhs, rhs ref.Val) ref.Val {
+	re, err := regexp.Compile(string(rhs.(types.String)))
+	if err != nil {
+		return types.NewErr("capture: %v", err)
+	}
+	matches := re.FindStringSubmatch(string(lhs.(types.String)))
+	if matches == nil {
+		matches = []string{}
+	}
+	return types.NewStringList(types.DefaultTypeAdapter, matches)
+}
+
+func jsonParse(val ref.Val) ref.Val {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(val.(types.String)), &doc); err != nil {
+		return types.NewErr("json.parse: %v", err)
+	}
+	return types.DefaultTypeAdapter.NativeToValue(doc)
+}
+
+func base64Encode(val ref.Val) ref.Val {
+	return types.String(base64.StdEncoding.EncodeToString([]byte(val.(types.String))))
+}
+
+func base64Decode(val ref.Val) ref.Val {
+	b, err := base64.StdEncoding.DecodeString(string(val.(types.String)))
+	if err != nil {
+		return types.NewErr("base64.decode: %v", err)
+	}
+	return types.String(b)
+}
+
+func timeParse(lhs, rhs ref.Val) ref.Val {
+	t, err := time.Parse(string(rhs.(types.String)), string(lhs.(types.String)))
+	if err != nil {
+		return types.NewErr("time.parse: %v", err)
+	}
+	return types.Timestamp{Time: t}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var jsonValueType = reflect.TypeOf(&structpb.Value{})
+
+// resultValue returns the value of the result of a CEL expression.  The values of CustomRun results are strings,
+// so lists and maps are emitted as JSON arrays and objects to keep their structure and the types of their elements.
+// All other values are converted to CEL strings, e.g. `true` or `int`.
+func resultValue(out ref.Val) (string, error) {
+	switch out.Type() {
+	case types.ListType, types.MapType:
+		native, err := out.ConvertToNative(jsonValueType)
+		if err != nil {
+			return "", fmt.Errorf("cannot convert %s to JSON: %w", out.Type().TypeName(), err)
+		}
+		b, err := json.Marshal(native.(*structpb.Value).AsInterface())
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return fmt.Sprintf("%s", out.ConvertToType(types.StringType).Value()), nil
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// varParamPrefix is the prefix of the params that declare variables instead of CEL expressions.
+// A param named "var.branch" is bound to the variable "branch" in every CEL expression of the CustomRun.
+const varParamPrefix = "var."
+
+// variableNameRegex matches the names that can be used as CEL identifiers.
+var variableNameRegex = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// isVariable returns true if the param declares a variable rather than a CEL expression.
+func isVariable(param v1beta1.Param) bool {
+	return strings.HasPrefix(param.Name, varParamPrefix)
+}
+
+// variableName returns the name of the variable declared by a param.
+func variableName(param v1beta1.Param) string {
+	return strings.TrimPrefix(param.Name, varParamPrefix)
+}
+
+// getVariables returns the declarations of the variables declared by the params and the activation that binds
+// them to their values.
+func getVariables(params []v1beta1.Param) ([]cel.EnvOption, map[string]interface{}) {
+	var decls []cel.EnvOption
+	activation := map[string]interface{}{}
+	for _, param := range params {
+		if !isVariable(param) {
+			continue
+		}
+		name := variableName(param)
+		decls = append(decls, cel.Variable(name, variableType(param)))
+		switch param.Value.Type {
+		case v1beta1.ParamTypeArray:
+			activation[name] = param.Value.ArrayVal
+		case v1beta1.ParamTypeObject:
+			activation[name] = param.Value.ObjectVal
+		default:
+			activation[name] = param.Value.StringVal
+		}
+	}
+	return decls, activation
+}
+
+// variableType returns the type of the variable declared by a param, which follows the type of the param:
+// strings are declared as `string`, arrays as `list(string)` and objects as `map(string, string)`.
+func variableType(param v1beta1.Param) *cel.Type {
+	switch param.Value.Type {
+	case v1beta1.ParamTypeArray:
+		return cel.ListType(cel.StringType)
+	case v1beta1.ParamTypeObject:
+		return cel.MapType(cel.StringType, cel.StringType)
+	default:
+		return cel.StringType
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook validates the CEL expressions of the Tekton resources using the CEL custom task when they are
+// created, instead of when the CustomRun is reconciled.  The resources are owned by Tekton Pipelines, so they are
+// wrapped in types which only replace the validation of Tekton Pipelines with the one of the CEL custom task.
+package webhook
+
+import (
+	"context"
+
+	"github.com/tektoncd/experimental/cel/pkg/reconciler/cel"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/webhook/resourcesemantics"
+)
+
+// Pipeline is a Pipeline whose tasks referencing the CEL custom task are validated by Validate.
+type Pipeline struct {
+	v1beta1.Pipeline
+}
+
+// PipelineRun is a PipelineRun whose embedded Pipeline is validated like a Pipeline.
+type PipelineRun struct {
+	v1beta1.PipelineRun
+}
+
+// CustomRun is a CustomRun which is validated by Validate if it references the CEL custom task.
+type CustomRun struct {
+	v1beta1.CustomRun
+}
+
+var (
+	_ resourcesemantics.GenericCRD = (*Pipeline)(nil)
+	_ resourcesemantics.GenericCRD = (*PipelineRun)(nil)
+	_ resourcesemantics.GenericCRD = (*CustomRun)(nil)
+)
+
+// DeepCopyObject implements runtime.Object.
+func (p *Pipeline) DeepCopyObject() runtime.Object {
+	return &Pipeline{Pipeline: *p.Pipeline.DeepCopy()}
+}
+
+// SetDefaults implements apis.Defaultable.  The defaults are set by Tekton Pipelines.
+func (p *Pipeline) SetDefaults(context.Context) {}
+
+// Validate implements apis.Validatable.
+func (p *Pipeline) Validate(ctx context.Context) *apis.FieldError {
+	if apis.IsInDelete(ctx) || apis.IsInStatusUpdate(ctx) {
+		return nil
+	}
+	return ValidatePipelineSpec(ctx, &p.Spec).ViaField("spec")
+}
+
+// DeepCopyObject implements runtime.Object.
+func (pr *PipelineRun) DeepCopyObject() runtime.Object {
+	return &PipelineRun{PipelineRun: *pr.PipelineRun.DeepCopy()}
+}
+
+// SetDefaults implements apis.Defaultable.  The defaults are set by Tekton Pipelines.
+func (pr *PipelineRun) SetDefaults(context.Context) {}
+
+// Validate implements apis.Validatable.
+func (pr *PipelineRun) Validate(ctx context.Context) *apis.FieldError {
+	if apis.IsInDelete(ctx) || apis.IsInStatusUpdate(ctx) || pr.Spec.PipelineSpec == nil {
+		return nil
+	}
+	return ValidatePipelineSpec(ctx, pr.Spec.PipelineSpec).ViaField("spec", "pipelineSpec")
+}
+
+// DeepCopyObject implements runtime.Object.
+func (r *CustomRun) DeepCopyObject() runtime.Object {
+	return &CustomRun{CustomRun: *r.CustomRun.DeepCopy()}
+}
+
+// SetDefaults implements apis.Defaultable.  The defaults are set by Tekton Pipelines.
+func (r *CustomRun) SetDefaults(context.Context) {}
+
+// Validate implements apis.Validatable.  The params of a CustomRun can't change once it's created, so only
+// its creation is validated.
+func (r *CustomRun) Validate(ctx context.Context) *apis.FieldError {
+	if !apis.IsInCreate(ctx) {
+		return nil
+	}
+	return ValidateCustomRunSpec(ctx, &r.Spec).ViaField("spec")
+}
+
+// ValidatePipelineSpec compiles the CEL expressions of the tasks and finally tasks referencing the CEL custom task.
+func ValidatePipelineSpec(ctx context.Context, ps *v1beta1.PipelineSpec) (errs *apis.FieldError) {
+	for i, pt := range ps.Tasks {
+		if cel.IsCELRef(pt.TaskRef) {
+			errs = errs.Also(cel.ValidateExpressions(ctx, pt.Params).ViaFieldIndex("tasks", i))
+		}
+	}
+	for i, pt := range ps.Finally {
+		if cel.IsCELRef(pt.TaskRef) {
+			errs = errs.Also(cel.ValidateExpressions(ctx, pt.Params).ViaFieldIndex("finally", i))
+		}
+	}
+	return errs
+}
+
+// ValidateCustomRunSpec compiles the CEL expressions of a CustomRun if it references the CEL custom task.
+func ValidateCustomRunSpec(ctx context.Context, spec *v1beta1.CustomRunSpec) *apis.FieldError {
+	if !cel.IsCELRef(spec.CustomRef) {
+		return nil
+	}
+	return cel.ValidateExpressions(ctx, spec.Params)
+}
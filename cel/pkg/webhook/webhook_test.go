@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/experimental/cel/pkg/apis/config"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/test/diff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/apis"
+)
+
+var celRef = &v1beta1.TaskRef{APIVersion: "cel.tekton.dev/v1alpha1", Kind: "CEL"}
+
+func param(name, value string) v1beta1.Param {
+	return v1beta1.Param{Name: name, Value: *v1beta1.NewArrayOrString(value)}
+}
+
+func celTask(params ...v1beta1.Param) v1beta1.PipelineTask {
+	return v1beta1.PipelineTask{Name: "cel", TaskRef: celRef, Params: params}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	testcases := []struct {
+		name          string
+		spec          v1beta1.PipelineSpec
+		config        *config.Config
+		expectedError string
+	}{{
+		name: "valid expressions",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("is-main", "'main' == 'main'"), param("count", "size([1, 2])"))},
+		},
+	}, {
+		name: "expressions and variables with references",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(
+				param("var.tags", "$(params.tags[*])"),
+				param("is-main", "'$(params.branch)' == 'main'"),
+				param("many", "$(tasks.build.results.count) > 3 && size(tags) > 0"))},
+		},
+	}, {
+		name: "syntax error with its column",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("is-main", "'$(params.branch)' == 1"))},
+		},
+		expectedError: "invalid value: CEL expression is-main could not be parsed: 1:20: found no matching overload for '_==_' applied to '(string, int)': spec.tasks[0].params[is-main].value",
+	}, {
+		name: "error in a finally task",
+		spec: v1beta1.PipelineSpec{
+			Tasks:   []v1beta1.PipelineTask{{Name: "build", TaskRef: &v1beta1.TaskRef{Name: "build"}}},
+			Finally: []v1beta1.PipelineTask{celTask(param("done", "1 +"))},
+		},
+		expectedError: "invalid value: CEL expression done could not be parsed: 1:4: Syntax error: mismatched input '<EOF>' expecting {'[', '{', '(', '.', '-', '!', 'true', 'false', 'null', NUM_FLOAT, NUM_INT, NUM_UINT, STRING, BYTES, IDENTIFIER}: spec.finally[0].params[done].value",
+	}, {
+		name: "duplicate results",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("is-main", "true"), param("is-main", "false"))},
+		},
+		expectedError: "invalid value: result is-main is produced by more than one CEL expression: spec.tasks[0].params[is-main].name",
+	}, {
+		name: "tasks which don't reference the CEL custom task",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{{Name: "build", TaskRef: &v1beta1.TaskRef{Name: "build"}, Params: []v1beta1.Param{param("x", "1 +")}}},
+		},
+	}, {
+		name: "configmaps which are not enabled",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("frozen", "configmaps.ops['deploy-freeze'].frozen"))},
+		},
+		expectedError: "invalid value: CEL expression frozen could not be parsed: 1:1: undeclared reference to 'configmaps' (in container ''): spec.tasks[0].params[frozen].value",
+	}, {
+		name: "configmaps which are enabled",
+		spec: v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("frozen", "configmaps.ops['deploy-freeze'].frozen"))},
+		},
+		config: &config.Config{AllowedConfigMapNamespaces: sets.NewString("ops")},
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := apis.WithinCreate(context.Background())
+			if tc.config != nil {
+				ctx = config.ToContext(ctx, tc.config)
+			}
+			p := &Pipeline{Pipeline: v1beta1.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: "p"}, Spec: tc.spec}}
+			checkError(t, tc.expectedError, p.Validate(ctx))
+		})
+	}
+}
+
+func TestValidatePipelineRun(t *testing.T) {
+	pr := &PipelineRun{PipelineRun: v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr"},
+		Spec: v1beta1.PipelineRunSpec{PipelineSpec: &v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{celTask(param("is-main", "branch == 'main'"))},
+		}},
+	}}
+	checkError(t, "invalid value: CEL expression is-main could not be parsed: 1:1: undeclared reference to 'branch' (in container ''): spec.pipelineSpec.tasks[0].params[is-main].value",
+		pr.Validate(apis.WithinCreate(context.Background())))
+
+	prWithRef := &PipelineRun{PipelineRun: v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "pr"},
+		Spec:       v1beta1.PipelineRunSpec{PipelineRef: &v1beta1.PipelineRef{Name: "p"}},
+	}}
+	checkError(t, "", prWithRef.Validate(apis.WithinCreate(context.Background())))
+}
+
+func TestValidateCustomRun(t *testing.T) {
+	run := &CustomRun{CustomRun: v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "run"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: celRef,
+			Params:    []v1beta1.Param{param("var.branch", "main"), param("is-main", "branch = 'main'")},
+		},
+	}}
+	checkError(t, "invalid value: CEL expression is-main could not be parsed: 1:8: Syntax error: token recognition error at: '= '; 1:10: Syntax error: extraneous input ''main'' expecting <EOF>: spec.params[is-main].value",
+		run.Validate(apis.WithinCreate(context.Background())))
+
+	// The params of a CustomRun can't change, so its updates aren't validated.
+	checkError(t, "", run.Validate(apis.WithinUpdate(context.Background(), run)))
+
+	otherRun := &CustomRun{CustomRun: v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "run"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{APIVersion: "example.dev/v0", Kind: "Example"},
+			Params:    []v1beta1.Param{param("is-main", "branch = 'main'")},
+		},
+	}}
+	checkError(t, "", otherRun.Validate(apis.WithinCreate(context.Background())))
+}
+
+// TestDecode checks that the resources sent to the webhook are decoded into the wrapping types.
+func TestDecode(t *testing.T) {
+	want := &Pipeline{Pipeline: v1beta1.Pipeline{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "tekton.dev/v1beta1", Kind: "Pipeline"},
+		ObjectMeta: metav1.ObjectMeta{Name: "p"},
+		Spec:       v1beta1.PipelineSpec{Tasks: []v1beta1.PipelineTask{celTask(param("is-main", "true"))}},
+	}}
+	b, err := json.Marshal(&want.Pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := (&Pipeline{}).DeepCopyObject().(*Pipeline)
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("Decoded Pipeline: %s", diff.PrintWantGot(d))
+	}
+}
+
+func checkError(t *testing.T, expectedError string, err *apis.FieldError) {
+	t.Helper()
+	if expectedError == "" {
+		if err != nil {
+			t.Errorf("Expected no error but got: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("Expected error %q but got none", expectedError)
+	}
+	if d := cmp.Diff(expectedError, err.Error()); d != "" {
+		t.Errorf("Error: %s", diff.PrintWantGot(d))
+	}
+}
@@ -22,8 +22,6 @@ import (
 
 	pipelineloopv1alpha1 "github.com/tektoncd/experimental/pipeline-loops/pkg/apis/pipelineloop/v1alpha1"
 	defaultconfig "github.com/tektoncd/pipeline/pkg/apis/config"
-	"github.com/tektoncd/pipeline/pkg/contexts"
-	"github.com/tektoncd/pipeline/pkg/system"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
@@ -31,6 +29,7 @@ import (
 	"knative.dev/pkg/injection/sharedmain"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/signals"
+	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
 	"knative.dev/pkg/webhook/certificates"
 	"knative.dev/pkg/webhook/resourcesemantics"
@@ -66,7 +65,7 @@ func newDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
 		func(ctx context.Context) context.Context {
-			return contexts.WithUpgradeViaDefaulting(store.ToContext(ctx))
+			return store.ToContext(ctx)
 		},
 
 		// Whether to disallow unknown fields.
@@ -91,7 +90,7 @@ func newValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
 		func(ctx context.Context) context.Context {
-			return contexts.WithUpgradeViaDefaulting(store.ToContext(ctx))
+			return store.ToContext(ctx)
 		},
 
 		// Whether to disallow unknown fields.
@@ -111,7 +110,7 @@ func main() {
 	}
 
 	// Scope informers to the webhook's namespace instead of cluster-wide
-	ctx := injection.WithNamespaceScope(signals.NewContext(), system.GetNamespace())
+	ctx := injection.WithNamespaceScope(signals.NewContext(), system.Namespace())
 
 	// Set up a signal context with our webhook options
 	ctx = webhook.WithOptions(ctx, webhook.Options{
@@ -121,7 +120,7 @@ func main() {
 	})
 
 	sharedmain.WebhookMainWithConfig(ctx, WebhookLogKey,
-		sharedmain.ParseAndGetConfigOrDie(),
+		injection.ParseAndGetRESTConfigOrDie(),
 		certificates.NewController,
 		newDefaultingAdmissionController,
 		newValidationAdmissionController,
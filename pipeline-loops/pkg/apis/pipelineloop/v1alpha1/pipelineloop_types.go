@@ -18,7 +18,9 @@ package v1alpha1
 
 import (
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
 )
 
 // +genclient
@@ -53,15 +55,96 @@ type PipelineLoopSpec struct {
 
 	IterateNumeric string `json:"iterateNumeric"`
 
-	// Time after which the TaskRun times out.
+	// IterateParamType describes how the items of IterateParam are interpreted.
+	// +optional
+	IterateParamType IterateParamType `json:"iterateParamType,omitempty"`
+
+	// IterateSource resolves the value of IterateParam from a ConfigMap or from a result of
+	// another task in the same PipelineRun instead of from the Run params.
+	// +optional
+	IterateSource *IterateSource `json:"iterateSource,omitempty"`
+
+	// Until is a CEL expression that is evaluated after the PipelineRun of each iteration completes
+	// successfully.  The loop stops as soon as it evaluates to true.  The results of the PipelineRun
+	// are available in the `results` map and the iteration number in `iteration`.  The Run fails if
+	// the iterations run out before it evaluates to true.
+	// +optional
+	Until string `json:"until,omitempty"`
+
+	// MaxIterations caps the number of iterations.  It is required when Until is set and there is
+	// no iterate parameter, so that the loop keeps polling until the condition is met.
+	// +optional
+	MaxIterations int `json:"maxIterations,omitempty"`
+
+	// IterationDelay is the time to wait after an iteration completes before starting the next one.
+	// +optional
+	IterationDelay *metav1.Duration `json:"iterationDelay,omitempty"`
+
+	// StatusMode sets how the statuses of the PipelineRuns are stored in the status of the Run.
+	// +optional
+	StatusMode StatusMode `json:"statusMode,omitempty"`
+
+	// Time after which the PipelineRun of each iteration times out.
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	// LoopTimeout is the time after which the whole loop times out.  The PipelineRuns that are still running
+	// are cancelled and the Run fails with reason LoopTimedOut.  The timeout of the Run itself, if set,
+	// bounds the loop too.
+	// +optional
+	LoopTimeout *metav1.Duration `json:"loopTimeout,omitempty"`
+
 	// Retries represents how many times a task should be retried in case of task failure.
 	// +optional
 	Retries int `json:"retries,omitempty"`
 }
 
+// IterateParamType describes how the items of the iterate parameter are interpreted.
+type IterateParamType string
+
+const (
+	// IterateParamTypeObject treats every item of the iterate parameter as an arbitrary JSON value.
+	// Object items are passed in an object param whose keys are the fields of the item, e.g.
+	// $(params.item.name), and any other items are passed in a string param.
+	IterateParamTypeObject IterateParamType = "object"
+)
+
+// StatusMode describes how the statuses of the PipelineRuns of a Run are stored in the status of the Run.
+type StatusMode string
+
+const (
+	// StatusModeFull stores the full status of every PipelineRun.  This is the default.
+	StatusModeFull StatusMode = "full"
+
+	// StatusModeCompact stores only the iteration, condition and timestamps of every PipelineRun, so that
+	// the status of a Run with many iterations fits in a Kubernetes object.  The full statuses can be
+	// looked up with the labels of the PipelineRuns.
+	StatusModeCompact StatusMode = "compact"
+)
+
+// IterateSource describes where the value of the iterate parameter is read from.
+// Exactly one of its fields must be set.
+type IterateSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the Run.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// TaskResult selects a result of a task in the PipelineRun that the Run belongs to.
+	// Task results share the 4KB termination message of the TaskRun pod, so use
+	// ConfigMapKeyRef for large lists.
+	// +optional
+	TaskResult *TaskResultSelector `json:"taskResult,omitempty"`
+}
+
+// TaskResultSelector selects a result of a pipeline task.
+type TaskResultSelector struct {
+	// PipelineTask is the name of the pipeline task that emits the result.
+	PipelineTask string `json:"pipelineTask"`
+
+	// Result is the name of the result.
+	Result string `json:"result"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // PipelineLoopList contains a list of PipelineLoops
@@ -98,9 +181,29 @@ const (
 	// PipelineLoopRunReasonCouldntGetPipelineLoop indicates that the associated PipelineLoop couldn't be retrieved
 	PipelineLoopRunReasonCouldntGetPipelineLoop PipelineLoopRunReason = "CouldntGetPipelineLoop"
 
+	// PipelineLoopRunReasonCouldntResolveIterateSource indicates that the value of the iterate parameter couldn't be
+	// read from the IterateSource of the PipelineLoop
+	PipelineLoopRunReasonCouldntResolveIterateSource PipelineLoopRunReason = "CouldntResolveIterateSource"
+
+	// PipelineLoopRunReasonCouldntEvaluateUntil indicates that the Until expression of the PipelineLoop
+	// couldn't be evaluated against the results of an iteration
+	PipelineLoopRunReasonCouldntEvaluateUntil PipelineLoopRunReason = "CouldntEvaluateUntil"
+
+	// PipelineLoopRunReasonUntilConditionNotMet indicates that the loop ran all of its iterations without
+	// the Until expression of the PipelineLoop evaluating to true
+	PipelineLoopRunReasonUntilConditionNotMet PipelineLoopRunReason = "UntilConditionNotMet"
+
+	// PipelineLoopRunReasonMaxNestingDepthExceeded indicates that the Run is nested in more loops than
+	// the controller allows
+	PipelineLoopRunReasonMaxNestingDepthExceeded PipelineLoopRunReason = "MaxNestingDepthExceeded"
+
 	// PipelineLoopRunReasonFailedValidation indicates that the PipelineLoop failed runtime validation
 	PipelineLoopRunReasonFailedValidation PipelineLoopRunReason = "PipelineLoopValidationFailed"
 
+	// PipelineLoopRunReasonLoopTimedOut indicates that the loop didn't complete before its loop timeout or the
+	// timeout of the Run
+	PipelineLoopRunReasonLoopTimedOut PipelineLoopRunReason = "LoopTimedOut"
+
 	// PipelineLoopRunReasonInternalError indicates that the PipelineLoop failed due to an internal error in the reconciler
 	PipelineLoopRunReasonInternalError PipelineLoopRunReason = "PipelineLoopInternalError"
 )
@@ -113,9 +216,20 @@ func (t PipelineLoopRunReason) String() string {
 type PipelineLoopRunStatus struct {
 	// PipelineLoopSpec contains the exact spec used to instantiate the Run
 	PipelineLoopSpec *PipelineLoopSpec `json:"pipelineLoopSpec,omitempty"`
+	// IterateParamValue is the value of the iterate parameter that was resolved from the IterateSource
+	// of the PipelineLoop.  It is resolved once so every iteration works on the same list.
+	// +optional
+	IterateParamValue *v1beta1.ArrayOrString `json:"iterateParamValue,omitempty"`
 	// map of PipelineLoopPipelineRunStatus with the PipelineRun name as the key
 	// +optional
 	PipelineRuns map[string]*PipelineLoopPipelineRunStatus `json:"pipelineRuns,omitempty"`
+	// map of PipelineLoopPipelineRunReference with the PipelineRun name as the key.  It is used instead of PipelineRuns
+	// when the StatusMode is compact.
+	// +optional
+	PipelineRunReferences map[string]*PipelineLoopPipelineRunReference `json:"pipelineRunReferences,omitempty"`
+	// Summary counts the PipelineRuns of the Run by state
+	// +optional
+	Summary *PipelineLoopRunSummary `json:"summary,omitempty"`
 }
 
 // PipelineLoopPipelineRunStatus contains the iteration number for a PipelineRun and the PipelineRun's Status
@@ -126,3 +240,32 @@ type PipelineLoopPipelineRunStatus struct {
 	// +optional
 	Status *v1beta1.PipelineRunStatus `json:"status,omitempty"`
 }
+
+// PipelineLoopPipelineRunReference contains the iteration number, condition and timestamps of a PipelineRun
+type PipelineLoopPipelineRunReference struct {
+	// iteration number
+	Iteration int `json:"iteration,omitempty"`
+	// Condition is the Succeeded condition of the PipelineRun
+	// +optional
+	Condition *apis.Condition `json:"condition,omitempty"`
+	// StartTime is the time the PipelineRun started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the PipelineRun completed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PipelineLoopRunSummary counts the PipelineRuns of a Run by state
+type PipelineLoopRunSummary struct {
+	// Iterations is the number of iterations of the loop
+	Iterations int `json:"iterations"`
+	// Started is the number of PipelineRuns that have been created
+	Started int `json:"started"`
+	// Running is the number of PipelineRuns that haven't completed yet
+	Running int `json:"running"`
+	// Succeeded is the number of PipelineRuns that have succeeded
+	Succeeded int `json:"succeeded"`
+	// Failed is the number of PipelineRuns that have failed
+	Failed int `json:"failed"`
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// CompileUntil compiles the Until expression of a PipelineLoop.  The expression can refer to the
+// results of the PipelineRun of an iteration as `results` (a map of strings) and to the iteration
+// number as `iteration`, and must evaluate to a bool.
+func CompileUntil(expr string) (cel.Program, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("results", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("iteration", cel.IntType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to a bool but evaluates to %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
@@ -19,6 +19,7 @@ package v1alpha1_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -54,7 +55,7 @@ func TestPipelineLoop_Validate_Success(t *testing.T) {
 						TaskSpec: &v1beta1.EmbeddedTask{
 							TaskSpec: v1beta1.TaskSpec{
 								Steps: []v1beta1.Step{{
-									Container: corev1.Container{Name: "foo", Image: "bar"},
+									Name: "foo", Image: "bar",
 								}},
 							},
 						},
@@ -95,7 +96,7 @@ func TestPipelineLoop_Validate_Success(t *testing.T) {
 									Type: v1beta1.ParamTypeString,
 								}},
 								Steps: []v1beta1.Step{{
-									Container: corev1.Container{Name: "foo", Image: "bar"},
+									Name: "foo", Image: "bar",
 								}},
 							},
 						},
@@ -103,6 +104,27 @@ func TestPipelineLoop_Validate_Success(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "objectIterateParam",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:      &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam:     "item",
+				IterateParamType: pipelineloopv1alpha1.IterateParamTypeObject,
+			},
+		},
+	}, {
+		name: "pollingUntil",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:    &v1beta1.PipelineRef{Name: "mypipeline"},
+				Until:          `results.status == "ready" || iteration >= 3`,
+				MaxIterations:  10,
+				IterationDelay: &metav1.Duration{Duration: time.Minute},
+			},
+		},
 	}}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -141,7 +163,7 @@ func TestPipelineLoop_Validate_Error(t *testing.T) {
 						TaskSpec: &v1beta1.EmbeddedTask{
 							TaskSpec: v1beta1.TaskSpec{
 								Steps: []v1beta1.Step{{
-									Container: corev1.Container{Name: "foo", Image: "bar"},
+									Name: "foo", Image: "bar",
 								}},
 							},
 						},
@@ -178,7 +200,7 @@ func TestPipelineLoop_Validate_Error(t *testing.T) {
 						TaskSpec: &v1beta1.EmbeddedTask{
 							TaskSpec: v1beta1.TaskSpec{
 								Steps: []v1beta1.Step{{
-									Container: corev1.Container{Name: "bad@name!", Image: "bar"},
+									Name: "bad@name!", Image: "bar",
 								}},
 							},
 						},
@@ -191,6 +213,125 @@ func TestPipelineLoop_Validate_Error(t *testing.T) {
 			Details: "Task step name must be a valid DNS Label, For more info refer to https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names",
 			Paths:   []string{"spec.pipelineSpec.tasks[0].taskSpec.steps[0].name"},
 		},
+	}, {
+		name: "unknown iterateParamType",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:      &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam:     "item",
+				IterateParamType: "map",
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: map`,
+			Paths:   []string{"spec.iterateParamType"},
+		},
+	}, {
+		name: "unknown statusMode",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:  &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam: "item",
+				StatusMode:   "tiny",
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: tiny`,
+			Paths:   []string{"spec.statusMode"},
+		},
+	}, {
+		name: "negative loopTimeout",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:  &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam: "item",
+				LoopTimeout:  &metav1.Duration{Duration: -time.Minute},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: -1m0s`,
+			Paths:   []string{"spec.loopTimeout"},
+		},
+	}, {
+		name: "object iterateParamType without iterateParam",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:      &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateNumeric:   "iteration",
+				IterateParamType: pipelineloopv1alpha1.IterateParamTypeObject,
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `missing field(s)`,
+			Paths:   []string{"spec.iterateParam"},
+		},
+	}, {
+		name: "iterateSource with both configMapKeyRef and taskResult",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:  &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam: "item",
+				IterateSource: &pipelineloopv1alpha1.IterateSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "items"},
+						Key:                  "list",
+					},
+					TaskResult: &pipelineloopv1alpha1.TaskResultSelector{PipelineTask: "list", Result: "items"},
+				},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: "expected exactly one, got both",
+			Paths:   []string{"spec.iterateSource.configMapKeyRef", "spec.iterateSource.taskResult"},
+		},
+	}, {
+		name: "iterateSource with an incomplete taskResult",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:  &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam: "item",
+				IterateSource: &pipelineloopv1alpha1.IterateSource{
+					TaskResult: &pipelineloopv1alpha1.TaskResultSelector{PipelineTask: "list"},
+				},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `missing field(s)`,
+			Paths:   []string{"spec.iterateSource.taskResult.result"},
+		},
+	}, {
+		name: "until that does not evaluate to a bool",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef:  &v1beta1.PipelineRef{Name: "mypipeline"},
+				IterateParam: "item",
+				Until:        "results.status",
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: expression must evaluate to a bool but evaluates to string`,
+			Paths:   []string{"spec.until"},
+		},
+	}, {
+		name: "until without iterateParam or maxIterations",
+		tl: &pipelineloopv1alpha1.PipelineLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "pipelineloop"},
+			Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+				PipelineRef: &v1beta1.PipelineRef{Name: "mypipeline"},
+				Until:       `results.status == "ready"`,
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `missing field(s)`,
+			Paths:   []string{"spec.maxIterations"},
+		},
 	}}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
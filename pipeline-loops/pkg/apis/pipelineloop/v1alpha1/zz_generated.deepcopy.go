@@ -23,10 +23,38 @@ package v1alpha1
 
 import (
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	apis "knative.dev/pkg/apis"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IterateSource) DeepCopyInto(out *IterateSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TaskResult != nil {
+		in, out := &in.TaskResult, &out.TaskResult
+		*out = new(TaskResultSelector)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IterateSource.
+func (in *IterateSource) DeepCopy() *IterateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(IterateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineLoop) DeepCopyInto(out *PipelineLoop) {
 	*out = *in
@@ -116,6 +144,11 @@ func (in *PipelineLoopRunStatus) DeepCopyInto(out *PipelineLoopRunStatus) {
 		*out = new(PipelineLoopSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IterateParamValue != nil {
+		in, out := &in.IterateParamValue, &out.IterateParamValue
+		*out = new(v1beta1.ArrayOrString)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PipelineRuns != nil {
 		in, out := &in.PipelineRuns, &out.PipelineRuns
 		*out = make(map[string]*PipelineLoopPipelineRunStatus, len(*in))
@@ -131,6 +164,26 @@ func (in *PipelineLoopRunStatus) DeepCopyInto(out *PipelineLoopRunStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.PipelineRunReferences != nil {
+		in, out := &in.PipelineRunReferences, &out.PipelineRunReferences
+		*out = make(map[string]*PipelineLoopPipelineRunReference, len(*in))
+		for key, val := range *in {
+			var outVal *PipelineLoopPipelineRunReference
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(PipelineLoopPipelineRunReference)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(PipelineLoopRunSummary)
+		**out = **in
+	}
 	return
 }
 
@@ -157,11 +210,26 @@ func (in *PipelineLoopSpec) DeepCopyInto(out *PipelineLoopSpec) {
 		*out = new(v1beta1.PipelineSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IterateSource != nil {
+		in, out := &in.IterateSource, &out.IterateSource
+		*out = new(IterateSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IterationDelay != nil {
+		in, out := &in.IterationDelay, &out.IterationDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.LoopTimeout != nil {
+		in, out := &in.LoopTimeout, &out.LoopTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -174,3 +242,64 @@ func (in *PipelineLoopSpec) DeepCopy() *PipelineLoopSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskResultSelector) DeepCopyInto(out *TaskResultSelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskResultSelector.
+func (in *TaskResultSelector) DeepCopy() *TaskResultSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskResultSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineLoopPipelineRunReference) DeepCopyInto(out *PipelineLoopPipelineRunReference) {
+	*out = *in
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(apis.Condition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineLoopPipelineRunReference.
+func (in *PipelineLoopPipelineRunReference) DeepCopy() *PipelineLoopPipelineRunReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineLoopPipelineRunReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineLoopRunSummary) DeepCopyInto(out *PipelineLoopRunSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineLoopRunSummary.
+func (in *PipelineLoopRunSummary) DeepCopy() *PipelineLoopRunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineLoopRunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinelooprun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pipelineloopv1alpha1 "github.com/tektoncd/experimental/pipeline-loops/pkg/apis/pipelineloop/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// resolveIterateSource returns the value of the iterate parameter read from the given source.
+func (c *Reconciler) resolveIterateSource(ctx context.Context, run *v1beta1.CustomRun, src *pipelineloopv1alpha1.IterateSource) (*v1beta1.ArrayOrString, error) {
+	var value string
+	switch {
+	case src.ConfigMapKeyRef != nil:
+		ref := src.ConfigMapKeyRef
+		optional := ref.Optional != nil && *ref.Optional
+		cm, err := c.kubeClientSet.CoreV1().ConfigMaps(run.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) && optional {
+				return v1beta1.NewArrayOrString("[]"), nil
+			}
+			return nil, fmt.Errorf("error retrieving ConfigMap %s/%s: %w", run.Namespace, ref.Name, err)
+		}
+		v, ok := cm.Data[ref.Key]
+		if !ok {
+			if optional {
+				return v1beta1.NewArrayOrString("[]"), nil
+			}
+			return nil, fmt.Errorf("ConfigMap %s/%s has no key %q", run.Namespace, ref.Name, ref.Key)
+		}
+		value = v
+	case src.TaskResult != nil:
+		v, err := c.getTaskResult(ctx, run, src.TaskResult)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	default:
+		return nil, fmt.Errorf("no iterate source is specified")
+	}
+	return v1beta1.NewArrayOrString(strings.TrimSpace(value)), nil
+}
+
+// getTaskResult returns a result of a task in the PipelineRun that the Run belongs to.  The result is
+// read from the status of the TaskRun rather than passed in the Run params.  Task results are written to
+// the termination message of the TaskRun pod, which holds at most 4KB for all the results of a task, so
+// large lists should be read from a ConfigMap instead.
+func (c *Reconciler) getTaskResult(ctx context.Context, run *v1beta1.CustomRun, sel *pipelineloopv1alpha1.TaskResultSelector) (string, error) {
+	prName := run.Labels[pipeline.PipelineRunLabelKey]
+	if prName == "" {
+		return "", fmt.Errorf("Run %s/%s is not part of a PipelineRun", run.Namespace, run.Name)
+	}
+	selector := labels.SelectorFromSet(labels.Set{
+		pipeline.PipelineRunLabelKey:  prName,
+		pipeline.PipelineTaskLabelKey: sel.PipelineTask,
+	})
+	taskRuns, err := c.pipelineClientSet.TektonV1beta1().TaskRuns(run.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", fmt.Errorf("error listing TaskRuns of PipelineRun %s/%s: %w", run.Namespace, prName, err)
+	}
+	for _, tr := range taskRuns.Items {
+		if !tr.IsSuccessful() {
+			continue
+		}
+		for _, result := range tr.Status.TaskRunResults {
+			if result.Name == sel.Result {
+				return result.Value.StringVal, nil
+			}
+		}
+		return "", fmt.Errorf("TaskRun %s of task %q has no result %q", tr.Name, sel.PipelineTask, sel.Result)
+	}
+	return "", fmt.Errorf("no successful TaskRun of task %q found in PipelineRun %s/%s", sel.PipelineTask, run.Namespace, prName)
+}
+
+// setParam returns a copy of params in which the param called name has the given value.
+// The param is appended if it isn't present.
+func setParam(params []v1beta1.Param, name string, value v1beta1.ArrayOrString) []v1beta1.Param {
+	out := make([]v1beta1.Param, 0, len(params)+1)
+	found := false
+	for _, p := range params {
+		if p.Name == name {
+			p.Value = value
+			found = true
+		}
+		out = append(out, p)
+	}
+	if !found {
+		out = append(out, v1beta1.Param{Name: name, Value: value})
+	}
+	return out
+}
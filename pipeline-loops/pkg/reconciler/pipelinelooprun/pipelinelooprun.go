@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
 
@@ -31,10 +32,9 @@ import (
 	pipelineloopclientset "github.com/tektoncd/experimental/pipeline-loops/pkg/client/clientset/versioned"
 	listerspipelineloop "github.com/tektoncd/experimental/pipeline-loops/pkg/client/listers/pipelineloop/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
-	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	clientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
-	runreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1alpha1/run"
+	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	listersalpha "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1alpha1"
 	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/names"
@@ -44,6 +44,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
@@ -56,36 +58,46 @@ const (
 	// pipelineLoopRunLabelKey is the label identifier for a Run.  This label is added to the Run's PipelineRuns.
 	pipelineLoopRunLabelKey = "/run"
 
+	// pipelineLoopCustomRunLabelKey is the label identifier for a CustomRun.  This label is added to the CustomRun's PipelineRuns.
+	pipelineLoopCustomRunLabelKey = "/customRun"
+
 	// pipelineLoopIterationLabelKey is the label identifier for the iteration number.  This label is added to the Run's PipelineRuns.
 	pipelineLoopIterationLabelKey = "/pipelineLoopIteration"
 )
 
 // Reconciler implements controller.Reconciler for Configuration resources.
 type Reconciler struct {
+	kubeClientSet         kubernetes.Interface
 	pipelineClientSet     clientset.Interface
 	pipelineloopClientSet pipelineloopclientset.Interface
 	runLister             listersalpha.RunLister
+	customRunLister       listers.CustomRunLister
 	pipelineLoopLister    listerspipelineloop.PipelineLoopLister
 	pipelineRunLister     listers.PipelineRunLister
+	enqueueAfter          func(interface{}, time.Duration)
+	maxNestingDepth       int
+	clock                 clock.PassiveClock
 }
 
 var (
-	// Check that our Reconciler implements runreconciler.Interface
-	_ runreconciler.Interface = (*Reconciler)(nil)
+	// Check that our Reconciler implements customrunreconciler.Interface
+	_ customrunreconciler.Interface = (*Reconciler)(nil)
 )
 
 // ReconcileKind compares the actual state with the desired, and attempts to converge the two.
-// It then updates the Status block of the Run resource with the current status of the resource.
-func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgreconciler.Event {
+// It then updates the Status block of the CustomRun resource with the current status of the resource.
+func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun) pkgreconciler.Event {
+	return c.reconcileKind(ctx, run, runOwner{})
+}
+
+func (c *Reconciler) reconcileKind(ctx context.Context, run *v1beta1.CustomRun, owner runOwner) pkgreconciler.Event {
 	var merr error
 	logger := logging.FromContext(ctx)
-	logger.Infof("Reconciling Run %s/%s at %v", run.Namespace, run.Name, time.Now())
+	logger.Infof("Reconciling Run %s/%s at %v", run.Namespace, run.Name, c.clock.Now())
 
 	// Check that the Run references a PipelineLoop CRD.  The logic is controller.go should ensure that only this type of Run
 	// is reconciled this controller but it never hurts to do some bullet-proofing.
-	if run.Spec.Ref == nil ||
-		run.Spec.Ref.APIVersion != pipelineloopv1alpha1.SchemeGroupVersion.String() ||
-		run.Spec.Ref.Kind != pipelineloop.PipelineLoopControllerName {
+	if !isPipelineLoopRun(run) {
 		logger.Errorf("Received control for a Run %s/%s that does not reference a PipelineLoop custom CRD", run.Namespace, run.Name)
 		return nil
 	}
@@ -93,6 +105,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 	// If the Run has not started, initialize the Condition and set the start time.
 	if !run.HasStarted() {
 		logger.Infof("Starting new Run %s/%s", run.Namespace, run.Name)
+		run.Status.StartTime = &metav1.Time{Time: c.clock.Now()}
 		run.Status.InitializeConditions()
 		// In case node time was not synchronized, when controller has been scheduled to other nodes.
 		if run.Status.StartTime.Sub(run.CreationTimestamp.Time) < 0 {
@@ -105,7 +118,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 		// We also want to send the "Started" event as soon as possible for anyone who may be waiting
 		// on the event to perform user facing initialisations, such has reset a CI check status
 		afterCondition := run.Status.GetCondition(apis.ConditionSucceeded)
-		events.Emit(ctx, nil, afterCondition, run)
+		events.Emit(ctx, nil, afterCondition, owner.object(run))
 	}
 
 	if run.IsDone() {
@@ -118,36 +131,36 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 
 	status := &pipelineloopv1alpha1.PipelineLoopRunStatus{}
 	if err := run.Status.DecodeExtraFields(status); err != nil {
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonInternalError.String(),
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonInternalError.String(),
 			"Internal error calling DecodeExtraFields: %v", err)
 		logger.Errorf("DecodeExtraFields error: %v", err.Error())
 	}
 
 	// Reconcile the Run
-	if err := c.reconcile(ctx, run, status); err != nil {
+	if err := c.reconcile(ctx, run, owner, status); err != nil {
 		logger.Errorf("Reconcile error: %v", err.Error())
 		merr = multierror.Append(merr, err)
 	}
 
-	if err := c.updateLabelsAndAnnotations(ctx, run); err != nil {
+	if err := c.updateLabelsAndAnnotations(ctx, run, owner); err != nil {
 		logger.Warn("Failed to update Run labels/annotations", zap.Error(err))
 		merr = multierror.Append(merr, err)
 	}
 
 	if err := run.Status.EncodeExtraFields(status); err != nil {
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonInternalError.String(),
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonInternalError.String(),
 			"Internal error calling EncodeExtraFields: %v", err)
 		logger.Errorf("EncodeExtraFields error: %v", err.Error())
 	}
 
 	afterCondition := run.Status.GetCondition(apis.ConditionSucceeded)
-	events.Emit(ctx, beforeCondition, afterCondition, run)
+	events.Emit(ctx, beforeCondition, afterCondition, owner.object(run))
 
 	// Only transient errors that should retry the reconcile are returned.
 	return merr
 }
 
-func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *pipelineloopv1alpha1.PipelineLoopRunStatus) error {
+func (c *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun, owner runOwner, status *pipelineloopv1alpha1.PipelineLoopRunStatus) error {
 	logger := logging.FromContext(ctx)
 
 	// Get the PipelineLoop referenced by the Run
@@ -164,66 +177,125 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *p
 
 	// Validate PipelineLoop spec
 	if err := pipelineLoopSpec.Validate(ctx); err != nil {
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
 			"PipelineLoop %s/%s can't be Run; it has an invalid spec: %s",
 			pipelineLoopMeta.Namespace, pipelineLoopMeta.Name, err)
 		return nil
 	}
 
+	// Refuse to run loops that are nested too deep, e.g. a loop whose pipeline runs the loop itself.
+	if depth := getParentLoopDepth(run) + 1; depth > c.maxNestingDepth {
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonMaxNestingDepthExceeded.String(),
+			"Run %s/%s is nested %d loops deep, which exceeds the maximum nesting depth of %d",
+			run.Namespace, run.Name, depth, c.maxNestingDepth)
+		return nil
+	}
+
+	// Resolve the iterate parameter from its source only once and store it on the Run, so that every
+	// iteration works on the same list even if the source changes, and the list can be audited later.
+	params := run.Spec.Params
+	if pipelineLoopSpec.IterateSource != nil {
+		if status.IterateParamValue == nil {
+			value, err := c.resolveIterateSource(ctx, run, pipelineLoopSpec.IterateSource)
+			if err != nil {
+				run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntResolveIterateSource.String(),
+					"Cannot resolve the value of the iterate parameter %q: %s", pipelineLoopSpec.IterateParam, err)
+				return nil
+			}
+			status.IterateParamValue = value
+		}
+		params = setParam(params, pipelineLoopSpec.IterateParam, *status.IterateParamValue)
+	}
+
 	// Determine how many iterations of the Task will be done.
-	totalIterations, err := computeIterations(run, pipelineLoopSpec)
+	totalIterations, err := computeIterations(params, pipelineLoopSpec)
 	if err != nil {
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
 			"Cannot determine number of iterations: %s", err)
 		return nil
 	}
 
+	// Check if the loop has run past its deadline.  If it hasn't, requeue the Run at the deadline so that
+	// it times out even if its PipelineRun doesn't change by then.
+	deadline := getLoopDeadline(run, pipelineLoopSpec)
+	timedOut := deadline != nil && !c.clock.Now().Before(*deadline)
+	if deadline != nil && !timedOut {
+		c.enqueueAfter(run, deadline.Sub(c.clock.Now()))
+	}
+
+	// Convert the statuses of PipelineRuns stored by an earlier reconcile in another form.
+	migrateStatus(status, pipelineLoopSpec.StatusMode)
+
 	// Update status of PipelineRuns.  Return the PipelineRun representing the highest loop iteration.
-	highestIteration, highestIterationPr, err := c.updatePipelineRunStatus(logger, run, status)
+	highestIteration, highestIterationPr, err := c.updatePipelineRunStatus(logger, run, owner, status, pipelineLoopSpec.StatusMode)
 	if err != nil {
 		return fmt.Errorf("error updating PipelineRun status for Run %s/%s: %w", run.Namespace, run.Name, err)
 	}
+	updateRunSummary(status, totalIterations)
 
 	// Check the status of the PipelineRun for the highest iteration.
 	if highestIterationPr != nil {
-		// If it's not done, wait for it to finish or cancel it if the run is cancelled.
+		// If it's not done, wait for it to finish or cancel it if the run is cancelled or has timed out.
 		if !highestIterationPr.IsDone() {
-			if run.IsCancelled() {
-				logger.Infof("Run %s/%s is cancelled.  Cancelling PipelineRun %s.", run.Namespace, run.Name, highestIterationPr.Name)
+			if run.IsCancelled() || timedOut {
+				logger.Infof("Run %s/%s is cancelled or has timed out.  Cancelling PipelineRun %s.", run.Namespace, run.Name, highestIterationPr.Name)
 				b, err := getCancelPatch()
 				if err != nil {
 					return fmt.Errorf("Failed to make patch to cancel PipelineRun %s: %v", highestIterationPr.Name, err)
 				}
 				if _, err := c.pipelineClientSet.TektonV1beta1().PipelineRuns(run.Namespace).Patch(ctx, highestIterationPr.Name, types.JSONPatchType, b, metav1.PatchOptions{}); err != nil {
-					run.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntCancel.String(),
+					run.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntCancel.String(),
 						"Failed to patch PipelineRun `%s` with cancellation: %v", highestIterationPr.Name, err)
 					return nil
 				}
 				// Update status. It is still running until the PipelineRun is actually cancelled.
-				run.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
+				run.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
 					"Cancelling PipelineRun %s", highestIterationPr.Name)
 				return nil
 			}
-			run.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
+			run.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
 				"Iterations completed: %d", highestIteration-1)
 			return nil
 		}
 
 		if !highestIterationPr.Status.GetCondition(apis.ConditionSucceeded).IsTrue() {
 			if run.IsCancelled() {
-				run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCancelled.String(),
+				run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCancelled.String(),
 					"Run %s/%s was cancelled",
 					run.Namespace, run.Name)
+			} else if timedOut {
+				markLoopTimedOut(run, *deadline)
 			} else {
-				run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailed.String(),
+				run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailed.String(),
 					"PipelineRun %s has failed", highestIterationPr.Name)
 			}
 			return nil
 		}
 
+		// Stop the loop once the results of an iteration meet the until condition.
+		if pipelineLoopSpec.Until != "" {
+			met, err := evaluateUntil(pipelineLoopSpec.Until, highestIteration, highestIterationPr)
+			if err != nil {
+				run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntEvaluateUntil.String(),
+					"Cannot evaluate the until condition against the results of PipelineRun %s: %s", highestIterationPr.Name, err)
+				return nil
+			}
+			if met {
+				run.Status.MarkCustomRunSucceeded(pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded.String(),
+					"The until condition was met after %d iterations", highestIteration)
+				run.Status.Results = []v1beta1.CustomRunResult{{
+					Name:  "condition",
+					Value: "pass",
+				}}
+				return nil
+			}
+		}
+
 		// Mark run successful if the condition are met.
 		// if the last loop task is skipped, but the highestIterationPr successed. Mark run success.
 		// lastLoopTask := highestIterationPr.ObjectMeta.Annotations["last-loop-task"]
+		//
+		// Deprecated: the last-loop-task label is superseded by spec.until.
 		lastLoopTask := ""
 		for key, val := range run.ObjectMeta.Labels {
 			if key == "last-loop-task" {
@@ -235,9 +307,9 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *p
 			for _, task := range skippedTaskList {
 				if task.Name == lastLoopTask {
 					// Mark run successful and stop the loop pipelinerun
-					run.Status.MarkRunSucceeded(pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded.String(),
+					run.Status.MarkCustomRunSucceeded(pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded.String(),
 						"PipelineRuns completed successfully with the conditions are met")
-					run.Status.Results = []v1beta1.TaskRunResult{{
+					run.Status.Results = []v1beta1.CustomRunResult{{
 						Name:  "condition",
 						Value: "pass",
 					}}
@@ -250,10 +322,19 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *p
 	// Move on to the next iteration (or the first iteration if there was no PipelineRun).
 	// Check if the Run is done.
 	nextIteration := highestIteration + 1
+	if nextIteration > totalIterations && pipelineLoopSpec.Until != "" && highestIteration > 0 {
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonUntilConditionNotMet.String(),
+			"The until condition was not met after %d iterations", highestIteration)
+		run.Status.Results = []v1beta1.CustomRunResult{{
+			Name:  "condition",
+			Value: "fail",
+		}}
+		return nil
+	}
 	if nextIteration > totalIterations {
-		run.Status.MarkRunSucceeded(pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded.String(),
+		run.Status.MarkCustomRunSucceeded(pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded.String(),
 			"All PipelineRuns completed successfully")
-		run.Status.Results = []v1beta1.TaskRunResult{{
+		run.Status.Results = []v1beta1.CustomRunResult{{
 			Name:  "condition",
 			Value: "fail",
 		}}
@@ -261,72 +342,109 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *p
 	}
 	// Before starting up another PipelineRun, check if the run was cancelled.
 	if run.IsCancelled() {
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCancelled.String(),
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCancelled.String(),
 			"Run %s/%s was cancelled",
 			run.Namespace, run.Name)
 		return nil
 	}
+	// Don't start another PipelineRun once the loop has timed out.
+	if timedOut {
+		markLoopTimedOut(run, *deadline)
+		return nil
+	}
+
+	// Wait for the delay between iterations to pass before starting the next one.
+	if highestIterationPr != nil && pipelineLoopSpec.IterationDelay != nil && highestIterationPr.Status.CompletionTime != nil {
+		startTime := highestIterationPr.Status.CompletionTime.Add(pipelineLoopSpec.IterationDelay.Duration)
+		if wait := startTime.Sub(c.clock.Now()); wait > 0 {
+			run.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
+				"Iterations completed: %d; next iteration starts at %s", highestIteration, startTime.Format(time.RFC3339))
+			c.enqueueAfter(run, wait)
+			return nil
+		}
+	}
 
 	// Create a PipelineRun to run this iteration.
-	pr, err := c.createPipelineRun(ctx, logger, pipelineLoopSpec, run, nextIteration)
+	pr, err := c.createPipelineRun(ctx, logger, pipelineLoopSpec, run, owner, params, nextIteration)
 	if err != nil {
 		return fmt.Errorf("error creating PipelineRun from Run %s: %w", run.Name, err)
 	}
 
-	status.PipelineRuns[pr.Name] = &pipelineloopv1alpha1.PipelineLoopPipelineRunStatus{
-		Iteration: nextIteration,
-		Status:    &pr.Status,
-	}
+	setPipelineRunStatus(status, pipelineLoopSpec.StatusMode, pr.Name, nextIteration, &pr.Status)
+	updateRunSummary(status, totalIterations)
 
-	run.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
+	run.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(),
 		"Iterations completed: %d", highestIteration)
 
 	return nil
 }
 
-func (c *Reconciler) getPipelineLoop(ctx context.Context, run *v1alpha1.Run) (*metav1.ObjectMeta, *pipelineloopv1alpha1.PipelineLoopSpec, error) {
+func (c *Reconciler) getPipelineLoop(ctx context.Context, run *v1beta1.CustomRun) (*metav1.ObjectMeta, *pipelineloopv1alpha1.PipelineLoopSpec, error) {
 	pipelineLoopMeta := metav1.ObjectMeta{}
 	pipelineLoopSpec := pipelineloopv1alpha1.PipelineLoopSpec{}
-	if run.Spec.Ref != nil && run.Spec.Ref.Name != "" {
+	if run.Spec.CustomRef != nil && run.Spec.CustomRef.Name != "" {
 		// Use the k8 client to get the PipelineLoop rather than the lister.  This avoids a timing issue where
 		// the PipelineLoop is not yet in the lister cache if it is created at nearly the same time as the Run.
 		// See https://github.com/tektoncd/pipeline/issues/2740 for discussion on this issue.
 		//
-		// tl, err := c.pipelineLoopLister.PipelineLoops(run.Namespace).Get(run.Spec.Ref.Name)
-		tl, err := c.pipelineloopClientSet.CustomV1alpha1().PipelineLoops(run.Namespace).Get(ctx, run.Spec.Ref.Name, metav1.GetOptions{})
+		// tl, err := c.pipelineLoopLister.PipelineLoops(run.Namespace).Get(run.Spec.CustomRef.Name)
+		tl, err := c.pipelineloopClientSet.CustomV1alpha1().PipelineLoops(run.Namespace).Get(ctx, run.Spec.CustomRef.Name, metav1.GetOptions{})
 		if err != nil {
-			run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop.String(),
+			run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop.String(),
 				"Error retrieving PipelineLoop for Run %s/%s: %s",
 				run.Namespace, run.Name, err)
 			return nil, nil, fmt.Errorf("Error retrieving PipelineLoop for Run %s: %w", fmt.Sprintf("%s/%s", run.Namespace, run.Name), err)
 		}
 		pipelineLoopMeta = tl.ObjectMeta
 		pipelineLoopSpec = tl.Spec
+	} else if run.Spec.CustomSpec != nil {
+		// The PipelineLoop is embedded in the Run.  It has no name, only the labels and annotations of its metadata.
+		if err := json.Unmarshal(run.Spec.CustomSpec.Spec.Raw, &pipelineLoopSpec); err != nil {
+			run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop.String(),
+				"Error parsing the embedded PipelineLoop spec of Run %s/%s: %s",
+				run.Namespace, run.Name, err)
+			return nil, nil, fmt.Errorf("Error parsing the embedded PipelineLoop spec of Run %s: %w", fmt.Sprintf("%s/%s", run.Namespace, run.Name), err)
+		}
+		pipelineLoopMeta = metav1.ObjectMeta{
+			Namespace:   run.Namespace,
+			Labels:      run.Spec.CustomSpec.Metadata.Labels,
+			Annotations: run.Spec.CustomSpec.Metadata.Annotations,
+		}
 	} else {
 		// Run does not require name but for PipelineLoop it does.
-		run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop.String(),
-			"Missing spec.ref.name for Run %s/%s",
+		run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop.String(),
+			"Missing spec.customRef.name or spec.customSpec for Run %s/%s",
 			run.Namespace, run.Name)
-		return nil, nil, fmt.Errorf("Missing spec.ref.name for Run %s", fmt.Sprintf("%s/%s", run.Namespace, run.Name))
+		return nil, nil, fmt.Errorf("Missing spec.customRef.name or spec.customSpec for Run %s", fmt.Sprintf("%s/%s", run.Namespace, run.Name))
 	}
 	return &pipelineLoopMeta, &pipelineLoopSpec, nil
 }
 
-func (c *Reconciler) createPipelineRun(ctx context.Context, logger *zap.SugaredLogger, tls *pipelineloopv1alpha1.PipelineLoopSpec, run *v1alpha1.Run, iteration int) (*v1beta1.PipelineRun, error) {
+func (c *Reconciler) createPipelineRun(ctx context.Context, logger *zap.SugaredLogger, tls *pipelineloopv1alpha1.PipelineLoopSpec, run *v1beta1.CustomRun, owner runOwner, runParams []v1beta1.Param, iteration int) (*v1beta1.PipelineRun, error) {
 
 	// Create name for PipelineRun from Run name plus iteration number.
 	prName := names.SimpleNameGenerator.RestrictLengthWithRandomSuffix(fmt.Sprintf("%s-%s", run.Name, fmt.Sprintf("%05d", iteration)))
 
+	params, err := getParameters(runParams, tls, iteration)
+	if err != nil {
+		return nil, err
+	}
+	parentParams, err := c.getParentLoopParams(run)
+	if err != nil {
+		return nil, err
+	}
+	params = append(params, parentParams...)
+
 	pr := &v1beta1.PipelineRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            prName,
 			Namespace:       run.Namespace,
-			OwnerReferences: []metav1.OwnerReference{run.GetOwnerReference()},
-			Labels:          getPipelineRunLabels(run, strconv.Itoa(iteration)),
+			OwnerReferences: []metav1.OwnerReference{owner.reference(run)},
+			Labels:          getPipelineRunLabels(run, owner, strconv.Itoa(iteration)),
 			Annotations:     getPipelineRunAnnotations(run),
 		},
 		Spec: v1beta1.PipelineRunSpec{
-			Params:             getParameters(run, tls, iteration),
+			Params:             params,
 			Timeout:            tls.Timeout,
 			ServiceAccountName: "",  // TODO: Implement service account name
 			PodTemplate:        nil, // TODO: Implement pod template
@@ -338,7 +456,11 @@ func (c *Reconciler) createPipelineRun(ctx context.Context, logger *zap.SugaredL
 			// Kind: tls.PipelineRef.Kind,
 		}
 	} else if tls.PipelineSpec != nil {
-		pr.Spec.PipelineSpec = tls.PipelineSpec
+		pipelineSpec, err := substituteLoopIteration(tls.PipelineSpec, iteration)
+		if err != nil {
+			return nil, err
+		}
+		pr.Spec.PipelineSpec = pipelineSpec
 	}
 
 	logger.Infof("Creating a new PipelineRun object %s", prName)
@@ -360,12 +482,22 @@ func (c *Reconciler) createPipelineRun(ctx context.Context, logger *zap.SugaredL
 // 	return c.pipelineClientSet.TektonV1beta1().PipelineRuns(tr.Namespace).UpdateStatus(ctx, tr, metav1.UpdateOptions{})
 // }
 
-func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1alpha1.Run) error {
-	newRun, err := c.runLister.Runs(run.Namespace).Get(run.Name)
-	if err != nil {
-		return fmt.Errorf("error getting Run %s when updating labels/annotations: %w", run.Name, err)
+func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1beta1.CustomRun, owner runOwner) error {
+	var newMeta metav1.ObjectMeta
+	if owner.run != nil {
+		newRun, err := c.runLister.Runs(run.Namespace).Get(run.Name)
+		if err != nil {
+			return fmt.Errorf("error getting Run %s when updating labels/annotations: %w", run.Name, err)
+		}
+		newMeta = newRun.ObjectMeta
+	} else {
+		newRun, err := c.customRunLister.CustomRuns(run.Namespace).Get(run.Name)
+		if err != nil {
+			return fmt.Errorf("error getting CustomRun %s when updating labels/annotations: %w", run.Name, err)
+		}
+		newMeta = newRun.ObjectMeta
 	}
-	if !reflect.DeepEqual(run.ObjectMeta.Labels, newRun.ObjectMeta.Labels) || !reflect.DeepEqual(run.ObjectMeta.Annotations, newRun.ObjectMeta.Annotations) {
+	if !reflect.DeepEqual(run.ObjectMeta.Labels, newMeta.Labels) || !reflect.DeepEqual(run.ObjectMeta.Annotations, newMeta.Annotations) {
 		mergePatch := map[string]interface{}{
 			"metadata": map[string]interface{}{
 				"labels":      run.ObjectMeta.Labels,
@@ -376,19 +508,20 @@ func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1alph
 		if err != nil {
 			return err
 		}
-		_, err = c.pipelineClientSet.TektonV1alpha1().Runs(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if owner.run != nil {
+			_, err = c.pipelineClientSet.TektonV1alpha1().Runs(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}
+		_, err = c.pipelineClientSet.TektonV1beta1().CustomRuns(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 		return err
 	}
 	return nil
 }
 
-func (c *Reconciler) updatePipelineRunStatus(logger *zap.SugaredLogger, run *v1alpha1.Run, status *pipelineloopv1alpha1.PipelineLoopRunStatus) (int, *v1beta1.PipelineRun, error) {
+func (c *Reconciler) updatePipelineRunStatus(logger *zap.SugaredLogger, run *v1beta1.CustomRun, owner runOwner, status *pipelineloopv1alpha1.PipelineLoopRunStatus, mode pipelineloopv1alpha1.StatusMode) (int, *v1beta1.PipelineRun, error) {
 	highestIteration := 0
 	var highestIterationPr *v1beta1.PipelineRun = nil
-	if status.PipelineRuns == nil {
-		status.PipelineRuns = make(map[string]*pipelineloopv1alpha1.PipelineLoopPipelineRunStatus)
-	}
-	pipelineRunLabels := getPipelineRunLabels(run, "")
+	pipelineRunLabels := getPipelineRunLabels(run, owner, "")
 	pipelineRuns, err := c.pipelineRunLister.PipelineRuns(run.Namespace).List(labels.SelectorFromSet(pipelineRunLabels))
 	if err != nil {
 		return 0, nil, fmt.Errorf("could not list PipelineRuns %#v", err)
@@ -401,15 +534,12 @@ func (c *Reconciler) updatePipelineRunStatus(logger *zap.SugaredLogger, run *v1a
 		iterationStr := lbls[pipelineloop.GroupName+pipelineLoopIterationLabelKey]
 		iteration, err := strconv.Atoi(iterationStr)
 		if err != nil {
-			run.Status.MarkRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
+			run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation.String(),
 				"Error converting iteration number in PipelineRun %s:  %#v", pr.Name, err)
 			logger.Errorf("Error converting iteration number in PipelineRun %s:  %#v", pr.Name, err)
 			return 0, nil, nil
 		}
-		status.PipelineRuns[pr.Name] = &pipelineloopv1alpha1.PipelineLoopPipelineRunStatus{
-			Iteration: iteration,
-			Status:    &pr.Status,
-		}
+		setPipelineRunStatus(status, mode, pr.Name, iteration, &pr.Status)
 		if iteration > highestIteration {
 			highestIteration = iteration
 			highestIterationPr = pr
@@ -431,13 +561,17 @@ func getCancelPatch() ([]byte, error) {
 	return patchBytes, nil
 }
 
-func computeIterations(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoopSpec) (int, error) {
+func computeIterations(params []v1beta1.Param, tls *pipelineloopv1alpha1.PipelineLoopSpec) (int, error) {
+	// Without an iterate parameter the loop polls until the until condition is met.
+	if tls.IterateParam == "" && tls.IterateNumeric == "" && tls.Until != "" {
+		return tls.MaxIterations, nil
+	}
 	// Find the iterate parameter.
 	numberOfIterations := -1
 	from := -1
 	step := -1
 	to := -1
-	for _, p := range run.Spec.Params {
+	for _, p := range params {
 		if tls.IterateNumeric != "" {
 			if p.Name == "from" {
 				from, _ = strconv.Atoi(p.Value.StringVal)
@@ -449,6 +583,20 @@ func computeIterations(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoop
 				to, _ = strconv.Atoi(p.Value.StringVal)
 			}
 		} else if p.Name == tls.IterateParam {
+			if tls.IterateParamType == pipelineloopv1alpha1.IterateParamTypeObject {
+				items, err := getObjectItems(p)
+				if err != nil {
+					return 0, err
+				}
+				// Check up front that every item can be passed to its PipelineRun.
+				for _, item := range items {
+					if _, err := getObjectParam(p.Name, item); err != nil {
+						return 0, err
+					}
+				}
+				numberOfIterations = len(items)
+				break
+			}
 			if p.Value.Type == v1beta1.ParamTypeString {
 				// Transfer p.Value to Array.
 				var strings []string
@@ -495,15 +643,30 @@ func computeIterations(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoop
 	} else if numberOfIterations == -1 {
 		return 0, fmt.Errorf("The iterate parameter %q was not found", tls.IterateParam)
 	}
+	if tls.MaxIterations > 0 && numberOfIterations > tls.MaxIterations {
+		numberOfIterations = tls.MaxIterations
+	}
 	return numberOfIterations, nil
 }
 
-func getParameters(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoopSpec, iteration int) []v1beta1.Param {
+func getParameters(params []v1beta1.Param, tls *pipelineloopv1alpha1.PipelineLoopSpec, iteration int) ([]v1beta1.Param, error) {
 	var out []v1beta1.Param
 	if tls.IterateParam != "" {
 		// IterateParam defined
-		for i, p := range run.Spec.Params {
+		for i, p := range params {
 			if p.Name == tls.IterateParam {
+				if tls.IterateParamType == pipelineloopv1alpha1.IterateParamTypeObject {
+					items, err := getObjectItems(p)
+					if err != nil {
+						return nil, err
+					}
+					objectParam, err := getObjectParam(p.Name, items[iteration-1])
+					if err != nil {
+						return nil, err
+					}
+					out = append(out, objectParam)
+					continue
+				}
 				if p.Value.Type == v1beta1.ParamTypeArray {
 					out = append(out, v1beta1.Param{
 						Name:  p.Name,
@@ -530,14 +693,14 @@ func getParameters(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoopSpec
 							Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: strconv.Itoa(ints[iteration-1])},
 						})
 					} else if errDictString == nil {
-						for dictParam := range dictsString[iteration-1] {
+						for _, dictParam := range sortedKeys(dictsString[iteration-1]) {
 							out = append(out, v1beta1.Param{
 								Name:  p.Name + "-subvar-" + dictParam,
 								Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: dictsString[iteration-1][dictParam]},
 							})
 						}
 					} else if errDictInt == nil {
-						for dictParam := range dictsInt[iteration-1] {
+						for _, dictParam := range sortedKeys(dictsInt[iteration-1]) {
 							out = append(out, v1beta1.Param{
 								Name:  p.Name + "-subvar-" + dictParam,
 								Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: strconv.Itoa(dictsInt[iteration-1][dictParam])},
@@ -546,26 +709,29 @@ func getParameters(run *v1alpha1.Run, tls *pipelineloopv1alpha1.PipelineLoopSpec
 					}
 				}
 			} else {
-				out = append(out, run.Spec.Params[i])
+				out = append(out, params[i])
 			}
 		}
-	} else {
+	} else if tls.IterateNumeric != "" {
 		// IterateNumeric defined
 		IterateStrings := []string{"from", "step", "to"}
-		for i, p := range run.Spec.Params {
+		for i, p := range params {
 			if _, found := Find(IterateStrings, p.Name); !found {
-				out = append(out, run.Spec.Params[i])
+				out = append(out, params[i])
 			}
 		}
 		out = append(out, v1beta1.Param{
 			Name:  tls.IterateNumeric,
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: strconv.Itoa(iteration)},
 		})
+	} else {
+		// Polling until the until condition is met, every iteration gets the same params
+		out = append(out, params...)
 	}
-	return out
+	return out, nil
 }
 
-func getPipelineRunAnnotations(run *v1alpha1.Run) map[string]string {
+func getPipelineRunAnnotations(run *v1beta1.CustomRun) map[string]string {
 	// Propagate annotations from Run to PipelineRun.
 	annotations := make(map[string]string, len(run.ObjectMeta.Annotations)+1)
 	for key, val := range run.ObjectMeta.Annotations {
@@ -585,7 +751,18 @@ func Find(slice []string, val string) (int, bool) {
 	return -1, false
 }
 
-func getPipelineRunLabels(run *v1alpha1.Run, iterationStr string) map[string]string {
+// sortedKeys returns the keys of a dict item in a stable order so that the
+// subvar params of an iteration are always generated the same way.
+func sortedKeys[V any](dict map[string]V) []string {
+	keys := make([]string, 0, len(dict))
+	for k := range dict {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getPipelineRunLabels(run *v1beta1.CustomRun, owner runOwner, iterationStr string) map[string]string {
 	// Propagate labels from Run to PipelineRun.
 	labels := make(map[string]string, len(run.ObjectMeta.Labels)+1)
 	ignnoreLabelsKey := []string{"tekton.dev/pipelineRun", "tekton.dev/pipelineTask", "tekton.dev/pipeline", "custom.tekton.dev/pipelineLoopIteration"}
@@ -595,14 +772,17 @@ func getPipelineRunLabels(run *v1alpha1.Run, iterationStr string) map[string]str
 		}
 	}
 	// Note: The Run label uses the normal Tekton group name.
-	labels[pipeline.GroupName+pipelineLoopRunLabelKey] = run.Name
+	labels[pipeline.GroupName+owner.labelKey()] = run.Name
 	if iterationStr != "" {
 		labels[pipelineloop.GroupName+pipelineLoopIterationLabelKey] = iterationStr
+		// The hierarchy labels aren't used to find the PipelineRuns of the Run, so that PipelineRuns created
+		// before they were introduced are still found.
+		addLoopHierarchyLabels(labels, run, iterationStr)
 	}
 	return labels
 }
 
-func propagatePipelineLoopLabelsAndAnnotations(run *v1alpha1.Run, pipelineLoopMeta *metav1.ObjectMeta) {
+func propagatePipelineLoopLabelsAndAnnotations(run *v1beta1.CustomRun, pipelineLoopMeta *metav1.ObjectMeta) {
 	// Propagate labels from PipelineLoop to Run.
 	if run.ObjectMeta.Labels == nil {
 		run.ObjectMeta.Labels = make(map[string]string, len(pipelineLoopMeta.Labels)+1)
@@ -610,7 +790,9 @@ func propagatePipelineLoopLabelsAndAnnotations(run *v1alpha1.Run, pipelineLoopMe
 	for key, value := range pipelineLoopMeta.Labels {
 		run.ObjectMeta.Labels[key] = value
 	}
-	run.ObjectMeta.Labels[pipelineloop.GroupName+pipelineLoopLabelKey] = pipelineLoopMeta.Name
+	if pipelineLoopMeta.Name != "" {
+		run.ObjectMeta.Labels[pipelineloop.GroupName+pipelineLoopLabelKey] = pipelineLoopMeta.Name
+	}
 
 	// Propagate annotations from PipelineLoop to Run.
 	if run.ObjectMeta.Annotations == nil {
@@ -627,3 +809,16 @@ func storePipelineLoopSpec(status *pipelineloopv1alpha1.PipelineLoopRunStatus, t
 		status.PipelineLoopSpec = tls
 	}
 }
+
+// isPipelineLoopRun returns whether the CustomRun references or embeds a PipelineLoop.
+func isPipelineLoopRun(run *v1beta1.CustomRun) bool {
+	if run.Spec.CustomRef != nil {
+		return run.Spec.CustomRef.APIVersion == pipelineloopv1alpha1.SchemeGroupVersion.String() &&
+			run.Spec.CustomRef.Kind == pipelineloop.PipelineLoopControllerName
+	}
+	if run.Spec.CustomSpec != nil {
+		return run.Spec.CustomSpec.APIVersion == pipelineloopv1alpha1.SchemeGroupVersion.String() &&
+			run.Spec.CustomSpec.Kind == pipelineloop.PipelineLoopControllerName
+	}
+	return false
+}
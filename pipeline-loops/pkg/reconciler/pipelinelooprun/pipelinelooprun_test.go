@@ -18,6 +18,7 @@ package pipelinelooprun
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -33,40 +34,51 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
-	"github.com/tektoncd/pipeline/pkg/system"
 	"github.com/tektoncd/pipeline/test/diff"
 	"github.com/tektoncd/pipeline/test/names"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ktesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/configmap"
+	cminformer "knative.dev/pkg/configmap/informer"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+	_ "knative.dev/pkg/system/testing" // Setup system.Namespace()
 )
 
 var (
 	namespace = ""
 	trueB     = true
+
+	// now is the time of the clock of the controller in the tests, so that the outcome of the timeouts and the
+	// delays between iterations doesn't depend on how long the tests take.
+	now = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
 )
 
-func getRunName(run *v1alpha1.Run) string {
+func getRunName(run *v1beta1.CustomRun) string {
 	return strings.Join([]string{run.Namespace, run.Name}, "/")
 }
 
-func loopRunning(run *v1alpha1.Run) *v1alpha1.Run {
+func loopRunning(run *v1beta1.CustomRun) *v1beta1.CustomRun {
 	runWithStatus := run.DeepCopy()
+	runWithStatus.Status.StartTime = &metav1.Time{Time: now}
 	runWithStatus.Status.InitializeConditions()
-	runWithStatus.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(), "")
+	runWithStatus.Status.MarkCustomRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(), "")
 	return runWithStatus
 }
 
-func requestCancel(run *v1alpha1.Run) *v1alpha1.Run {
+func requestCancel(run *v1beta1.CustomRun) *v1beta1.CustomRun {
 	runWithCancelStatus := run.DeepCopy()
-	runWithCancelStatus.Spec.Status = v1alpha1.RunSpecStatusCancelled
+	runWithCancelStatus.Spec.Status = v1beta1.CustomRunSpecStatusCancelled
 	return runWithCancelStatus
 }
 
@@ -119,6 +131,16 @@ func failed(pr *v1beta1.PipelineRun) *v1beta1.PipelineRun {
 // getPipelineLoopController returns an instance of the PipelineLoop controller/reconciler that has been seeded with
 // d, where d represents the state of the system (existing resources) needed for the test.
 func getPipelineLoopController(t *testing.T, d test.Data, pipelineloops []*pipelineloopv1alpha1.PipelineLoop) (test.Assets, func()) {
+	return getController(t, d, pipelineloops, NewController)
+}
+
+// getPipelineLoopRunController is like getPipelineLoopController but returns the controller that reconciles v1alpha1.Runs.
+func getPipelineLoopRunController(t *testing.T, d test.Data, pipelineloops []*pipelineloopv1alpha1.PipelineLoop) (test.Assets, func()) {
+	return getController(t, d, pipelineloops, NewRunController)
+}
+
+func getController(t *testing.T, d test.Data, pipelineloops []*pipelineloopv1alpha1.PipelineLoop,
+	newController func(string, clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl) (test.Assets, func()) {
 	ctx, _ := ttesting.SetupFakeContext(t)
 	ctx, cancel := context.WithCancel(ctx)
 	c, informers := test.SeedTestData(t, ctx, d)
@@ -132,8 +154,8 @@ func getPipelineLoopController(t *testing.T, d test.Data, pipelineloops []*pipel
 		}
 	}
 
-	configMapWatcher := configmap.NewInformedWatcher(c.Kube, system.GetNamespace())
-	ctl := NewController(namespace)(ctx, configMapWatcher)
+	configMapWatcher := cminformer.NewInformedWatcher(c.Kube, system.Namespace())
+	ctl := newController(namespace, clocktesting.NewFakePassiveClock(now))(ctx, configMapWatcher)
 
 	if la, ok := ctl.Reconciler.(reconciler.LeaderAware); ok {
 		la.Promote(reconciler.UniversalBucket(), func(reconciler.Bucket, types.NamespacedName) {})
@@ -194,7 +216,7 @@ func checkEvents(fr *record.FakeRecorder, testName string, wantEvents []string)
 	return nil
 }
 
-func checkRunCondition(t *testing.T, run *v1alpha1.Run, expectedStatus corev1.ConditionStatus, expectedReason pipelineloopv1alpha1.PipelineLoopRunReason) {
+func checkRunCondition(t *testing.T, run *v1beta1.CustomRun, expectedStatus corev1.ConditionStatus, expectedReason pipelineloopv1alpha1.PipelineLoopRunReason) {
 	condition := run.Status.GetCondition(apis.ConditionSucceeded)
 	if condition == nil {
 		t.Error("Condition missing in Run")
@@ -218,7 +240,7 @@ func checkRunCondition(t *testing.T, run *v1alpha1.Run, expectedStatus corev1.Co
 	}
 }
 
-func checkRunStatus(t *testing.T, run *v1alpha1.Run, expectedStatus map[string]pipelineloopv1alpha1.PipelineLoopPipelineRunStatus) {
+func checkRunStatus(t *testing.T, run *v1beta1.CustomRun, expectedStatus map[string]pipelineloopv1alpha1.PipelineLoopPipelineRunStatus) {
 	status := &pipelineloopv1alpha1.PipelineLoopRunStatus{}
 	if err := run.Status.DecodeExtraFields(status); err != nil {
 		t.Errorf("DecodeExtraFields error: %v", err.Error())
@@ -259,7 +281,7 @@ var aPipeline = &v1beta1.Pipeline{
 			TaskSpec: &v1beta1.EmbeddedTask{
 				TaskSpec: v1beta1.TaskSpec{
 					Steps: []v1beta1.Step{{
-						Container: corev1.Container{Name: "foo", Image: "bar"},
+						Name: "foo", Image: "bar",
 					}},
 				},
 			},
@@ -290,7 +312,7 @@ var nPipeline = &v1beta1.Pipeline{
 			TaskSpec: &v1beta1.EmbeddedTask{
 				TaskSpec: v1beta1.TaskSpec{
 					Steps: []v1beta1.Step{{
-						Container: corev1.Container{Name: "foo", Image: "bar"},
+						Name: "foo", Image: "bar",
 					}},
 				},
 			},
@@ -306,6 +328,15 @@ var nPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
 	},
 }
 
+var oPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "o-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineRef:      &v1beta1.PipelineRef{Name: "a-pipeline"},
+		IterateParam:     "current-item",
+		IterateParamType: pipelineloopv1alpha1.IterateParamTypeObject,
+	},
+}
+
 var aPipelineLoopWithInlineTask = &pipelineloopv1alpha1.PipelineLoop{
 	ObjectMeta: metav1.ObjectMeta{Name: "a-pipelineloop-with-inline-task", Namespace: "foo"},
 	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
@@ -322,7 +353,7 @@ var aPipelineLoopWithInlineTask = &pipelineloopv1alpha1.PipelineLoop{
 							Type: v1beta1.ParamTypeString,
 						}},
 						Steps: []v1beta1.Step{{
-							Container: corev1.Container{Name: "foo", Image: "bar"},
+							Name: "foo", Image: "bar",
 						}},
 					},
 				},
@@ -333,7 +364,7 @@ var aPipelineLoopWithInlineTask = &pipelineloopv1alpha1.PipelineLoop{
 	},
 }
 
-var runPipelineLoop = &v1alpha1.Run{
+var runPipelineLoop = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop",
 		Namespace: "foo",
@@ -348,7 +379,7 @@ var runPipelineLoop = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
@@ -356,7 +387,7 @@ var runPipelineLoop = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -364,7 +395,7 @@ var runPipelineLoop = &v1alpha1.Run{
 	},
 }
 
-var conditionRunPipelineLoop = &v1alpha1.Run{
+var conditionRunPipelineLoop = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop",
 		Namespace: "foo",
@@ -376,7 +407,7 @@ var conditionRunPipelineLoop = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
@@ -384,7 +415,7 @@ var conditionRunPipelineLoop = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -392,7 +423,7 @@ var conditionRunPipelineLoop = &v1alpha1.Run{
 	},
 }
 
-var runPipelineLoopWithInDictParams = &v1alpha1.Run{
+var runPipelineLoopWithInDictParams = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop",
 		Namespace: "foo",
@@ -407,7 +438,7 @@ var runPipelineLoopWithInDictParams = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `[{"a":1,"b":2}, {"a":2,"b":1}]`},
@@ -415,7 +446,7 @@ var runPipelineLoopWithInDictParams = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -423,7 +454,60 @@ var runPipelineLoopWithInDictParams = &v1alpha1.Run{
 	},
 }
 
-var runPipelineLoopWithInStringParams = &v1alpha1.Run{
+var runPipelineLoopWithObjectParams = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"myTestLabel": "myTestLabelValue",
+		},
+	},
+	Spec: v1beta1.CustomRunSpec{
+		Params: []v1beta1.Param{{
+			Name: "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString,
+				StringVal: `[{"name":"web","image":{"repo":"nginx","tag":"1.19"},"ports":[80,443],"replicas":3}, {"name":"db"}]`},
+		}, {
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       pipelineloop.PipelineLoopControllerName,
+			Name:       "o-pipelineloop",
+		},
+	},
+}
+
+// withEmbeddedPipelineLoop returns a copy of a CustomRun in which the spec of a PipelineLoop is embedded rather than referenced.
+func withEmbeddedPipelineLoop(run *v1beta1.CustomRun, pl *pipelineloopv1alpha1.PipelineLoop) *v1beta1.CustomRun {
+	raw, err := json.Marshal(pl.Spec)
+	if err != nil {
+		panic(err)
+	}
+	runWithEmbeddedSpec := run.DeepCopy()
+	runWithEmbeddedSpec.Spec.CustomRef = nil
+	runWithEmbeddedSpec.Spec.CustomSpec = &v1beta1.EmbeddedCustomRunSpec{
+		TypeMeta: runtime.TypeMeta{
+			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       pipelineloop.PipelineLoopControllerName,
+		},
+		Metadata: v1beta1.PipelineTaskMetadata{
+			Labels:      pl.Labels,
+			Annotations: pl.Annotations,
+		},
+		Spec: runtime.RawExtension{Raw: raw},
+	}
+	return runWithEmbeddedSpec
+}
+
+func withIterateParamValue(run *v1beta1.CustomRun, value v1beta1.ArrayOrString) *v1beta1.CustomRun {
+	runWithValue := run.DeepCopy()
+	runWithValue.Spec.Params[0].Value = value
+	return runWithValue
+}
+
+var runPipelineLoopWithInStringParams = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop",
 		Namespace: "foo",
@@ -438,7 +522,7 @@ var runPipelineLoopWithInStringParams = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `["item1", "item2"]`},
@@ -446,7 +530,7 @@ var runPipelineLoopWithInStringParams = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -454,7 +538,7 @@ var runPipelineLoopWithInStringParams = &v1alpha1.Run{
 	},
 }
 
-var runPipelineLoopWithIterateNumeric = &v1alpha1.Run{
+var runPipelineLoopWithIterateNumeric = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop",
 		Namespace: "foo",
@@ -469,7 +553,7 @@ var runPipelineLoopWithIterateNumeric = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "from",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `1`},
@@ -483,7 +567,7 @@ var runPipelineLoopWithIterateNumeric = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "n-pipelineloop",
@@ -491,7 +575,7 @@ var runPipelineLoopWithIterateNumeric = &v1alpha1.Run{
 	},
 }
 
-var runPipelineLoopWithInlineTask = &v1alpha1.Run{
+var runPipelineLoopWithInlineTask = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop-with-inline-task",
 		Namespace: "foo",
@@ -502,7 +586,7 @@ var runPipelineLoopWithInlineTask = &v1alpha1.Run{
 			"myTestAnnotation": "myTestAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
@@ -510,7 +594,7 @@ var runPipelineLoopWithInlineTask = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop-with-inline-task",
@@ -518,13 +602,13 @@ var runPipelineLoopWithInlineTask = &v1alpha1.Run{
 	},
 }
 
-var runWithMissingPipelineLoopName = &v1alpha1.Run{
+var runWithMissingPipelineLoopName = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-pipelineloop-missing",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
-		Ref: &v1alpha1.TaskRef{
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			// missing Name
@@ -532,13 +616,13 @@ var runWithMissingPipelineLoopName = &v1alpha1.Run{
 	},
 }
 
-var runWithNonexistentPipelineLoop = &v1alpha1.Run{
+var runWithNonexistentPipelineLoop = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-pipelineloop-not-found",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
-		Ref: &v1alpha1.TaskRef{
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "no-such-pipelineloop",
@@ -546,18 +630,18 @@ var runWithNonexistentPipelineLoop = &v1alpha1.Run{
 	},
 }
 
-var runWithMissingIterateParam = &v1alpha1.Run{
+var runWithMissingIterateParam = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-missing-iterate-param",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		// current-item, which is the iterate parameter, is missing from parameters
 		Params: []v1beta1.Param{{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -565,12 +649,12 @@ var runWithMissingIterateParam = &v1alpha1.Run{
 	},
 }
 
-var runWithIterateParamNotAnArray = &v1alpha1.Run{
+var runWithIterateParamNotAnArray = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-iterate-param-not-an-array",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			// Value of iteration parameter must be an array so this is an error.
 			Name:  "current-item",
@@ -579,7 +663,7 @@ var runWithIterateParamNotAnArray = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       pipelineloop.PipelineLoopControllerName,
 			Name:       "a-pipelineloop",
@@ -592,16 +676,19 @@ var expectedPipelineRunIterationDict = &v1beta1.PipelineRun{
 		Name:      "run-pipelineloop-00001-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
-			"tekton.dev/run":                          "run-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
 			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
 			"myTestLabel":                             "myTestLabelValue",
 		},
 		Annotations: map[string]string{
@@ -623,21 +710,63 @@ var expectedPipelineRunIterationDict = &v1beta1.PipelineRun{
 	},
 }
 
+var expectedPipelineRunIterationObject = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop-00001-9l9zj",
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-pipelineloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/pipelineLoop":          "o-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
+			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
+			"myTestLabel":                             "myTestLabelValue",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{Name: "a-pipeline"},
+		Params: []v1beta1.Param{{
+			Name: "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeObject, ObjectVal: map[string]string{
+				"image":    `{"repo":"nginx","tag":"1.19"}`,
+				"name":     "web",
+				"ports":    "[80,443]",
+				"replicas": "3",
+			}},
+		}, {
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+	},
+}
+
 var expectedPipelineRunIteration1 = &v1beta1.PipelineRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-pipelineloop-00001-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
-			"tekton.dev/run":                          "run-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
 			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
 			"myTestLabel":                             "myTestLabelValue",
 		},
 		Annotations: map[string]string{
@@ -661,16 +790,19 @@ var expectedConditionPipelineRunIteration1 = &v1beta1.PipelineRun{
 		Name:      "run-pipelineloop-00001-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
-			"tekton.dev/run":                          "run-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
 			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
 			"myTestLabel":                             "myTestLabelValue",
 			"last-loop-task":                          "task-fail",
 		},
@@ -695,16 +827,19 @@ var expectedPipelineRunIterateNumeric1 = &v1beta1.PipelineRun{
 		Name:      "run-pipelineloop-00001-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "n-pipelineloop",
-			"tekton.dev/run":                          "run-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
 			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
 			"myTestLabel":                             "myTestLabelValue",
 		},
 		Annotations: map[string]string{
@@ -729,16 +864,19 @@ var expectedPipelineRunIteration2 = &v1beta1.PipelineRun{
 		Name:      "run-pipelineloop-00002-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
-			"tekton.dev/run":                          "run-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
 			"custom.tekton.dev/pipelineLoopIteration": "2",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "2",
 			"myTestLabel":                             "myTestLabelValue",
 		},
 		Annotations: map[string]string{
@@ -762,16 +900,19 @@ var expectedPipelineRunWithInlineTaskIteration1 = &v1beta1.PipelineRun{
 		Name:      "run-pipelineloop-with-inline-task-00001-9l9zj",
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-pipelineloop-with-inline-task",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop-with-inline-task",
-			"tekton.dev/run":                          "run-pipelineloop-with-inline-task",
+			"tekton.dev/customRun":                    "run-pipelineloop-with-inline-task",
 			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop-with-inline-task",
+			"custom.tekton.dev/loopIteration-1":       "1",
 			"myTestLabel":                             "myTestLabelValue",
 		},
 		Annotations: map[string]string{
@@ -792,7 +933,7 @@ var expectedPipelineRunWithInlineTaskIteration1 = &v1beta1.PipelineRun{
 							Type: v1beta1.ParamTypeString,
 						}},
 						Steps: []v1beta1.Step{{
-							Container: corev1.Container{Name: "foo", Image: "bar"},
+							Name: "foo", Image: "bar",
 						}},
 					},
 				},
@@ -815,7 +956,7 @@ func TestReconcilePipelineLoopRun(t *testing.T) {
 		name                 string
 		pipeline             *v1beta1.Pipeline
 		pipelineloop         *pipelineloopv1alpha1.PipelineLoop
-		run                  *v1alpha1.Run
+		run                  *v1beta1.CustomRun
 		pipelineruns         []*v1beta1.PipelineRun
 		expectedStatus       corev1.ConditionStatus
 		expectedReason       pipelineloopv1alpha1.PipelineLoopRunReason
@@ -831,6 +972,15 @@ func TestReconcilePipelineLoopRun(t *testing.T) {
 		expectedReason:       pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
 		expectedPipelineruns: []*v1beta1.PipelineRun{expectedPipelineRunIteration1},
 		expectedEvents:       []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:                 "Reconcile a new run with an embedded pipelineloop",
+		pipeline:             aPipeline,
+		run:                  withEmbeddedPipelineLoop(runPipelineLoop, aPipelineLoop),
+		pipelineruns:         []*v1beta1.PipelineRun{},
+		expectedStatus:       corev1.ConditionUnknown,
+		expectedReason:       pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelineruns: []*v1beta1.PipelineRun{expectedPipelineRunIteration1},
+		expectedEvents:       []string{"Normal Started", "Normal Running Iterations completed: 0"},
 	}, {
 		name:                 "Reconcile a new run with a pipelineloop and a dict params",
 		pipeline:             aPipeline,
@@ -851,6 +1001,16 @@ func TestReconcilePipelineLoopRun(t *testing.T) {
 		expectedReason:       pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
 		expectedPipelineruns: []*v1beta1.PipelineRun{expectedPipelineRunIteration1},
 		expectedEvents:       []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:                 "Reconcile a new run with a pipelineloop that iterates over objects",
+		pipeline:             aPipeline,
+		pipelineloop:         oPipelineLoop,
+		run:                  runPipelineLoopWithObjectParams,
+		pipelineruns:         []*v1beta1.PipelineRun{},
+		expectedStatus:       corev1.ConditionUnknown,
+		expectedReason:       pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelineruns: []*v1beta1.PipelineRun{expectedPipelineRunIterationObject},
+		expectedEvents:       []string{"Normal Started", "Normal Running Iterations completed: 0"},
 	}, {
 		name:                 "Reconcile a run with condition pipelinerun, and the first PipelineRun condition check failed",
 		pipeline:             aPipeline,
@@ -952,13 +1112,18 @@ func TestReconcilePipelineLoopRun(t *testing.T) {
 				optionalPipeline = nil
 			}
 
+			optionalPipelineLoop := []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop}
+			if tc.pipelineloop == nil {
+				optionalPipelineLoop = nil
+			}
+
 			d := test.Data{
-				Runs:         []*v1alpha1.Run{tc.run},
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
 				Pipelines:    optionalPipeline,
 				PipelineRuns: tc.pipelineruns,
 			}
 
-			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop})
+			testAssets, _ := getPipelineLoopController(t, d, optionalPipelineLoop)
 			c := testAssets.Controller
 			clients := testAssets.Clients
 
@@ -967,7 +1132,7 @@ func TestReconcilePipelineLoopRun(t *testing.T) {
 			}
 
 			// Fetch the updated Run
-			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("Error getting reconciled run from fake client: %s", err)
 			}
@@ -1013,7 +1178,7 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 	testcases := []struct {
 		name         string
 		pipelineloop *pipelineloopv1alpha1.PipelineLoop
-		run          *v1alpha1.Run
+		run          *v1beta1.CustomRun
 		reason       pipelineloopv1alpha1.PipelineLoopRunReason
 		wantEvents   []string
 	}{{
@@ -1022,7 +1187,7 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 		reason: pipelineloopv1alpha1.PipelineLoopRunReasonCouldntGetPipelineLoop,
 		wantEvents: []string{
 			"Normal Started ",
-			"Warning Failed Missing spec.ref.name for Run",
+			"Warning Failed Missing spec.customRef.name or spec.customSpec for Run",
 		},
 	}, {
 		name:   "nonexistent PipelineLoop",
@@ -1050,6 +1215,42 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 			"Normal Started ",
 			`Warning Failed Cannot determine number of iterations: The value of the iterate parameter "current-item" can not transfer to array`,
 		},
+	}, {
+		name:         "object iterate parameter not a JSON array",
+		pipelineloop: oPipelineLoop,
+		run:          withIterateParamValue(runPipelineLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `{"name":"web"}`}),
+		reason:       pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: The value of the iterate parameter "current-item" is not a JSON array`,
+		},
+	}, {
+		name:         "object iterate parameter with an invalid item",
+		pipelineloop: oPipelineLoop,
+		run:          withIterateParamValue(runPipelineLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{`{"name":"web"}`, `{"name":`}}),
+		reason:       pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: Item 2 of the iterate parameter "current-item" is not valid JSON`,
+		},
+	}, {
+		name:         "object iterate parameter with a key that cannot be the key of an object param",
+		pipelineloop: oPipelineLoop,
+		run:          withIterateParamValue(runPipelineLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `[{"repo.name":"nginx"}]`}),
+		reason:       pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: The key "repo.name" in "current-item" cannot be used as the key of an object param`,
+		},
+	}, {
+		name:         "object iterate parameter mixing objects and other items",
+		pipelineloop: oPipelineLoop,
+		run:          withIterateParamValue(runPipelineLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: `[{"name":"web"}, "db"]`}),
+		reason:       pipelineloopv1alpha1.PipelineLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: The items of the iterate parameter "current-item" must either all be JSON objects or none of them`,
+		},
 	}}
 
 	for _, tc := range testcases {
@@ -1057,7 +1258,7 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 			ctx := context.Background()
 
 			d := test.Data{
-				Runs: []*v1alpha1.Run{tc.run},
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
 			}
 
 			optionalPipelineLoop := []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop}
@@ -1074,7 +1275,7 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 			}
 
 			// Fetch the updated Run
-			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("Error getting reconciled run from fake client: %s", err)
 			}
@@ -1094,3 +1295,944 @@ func TestReconcilePipelineLoopRunFailures(t *testing.T) {
 		})
 	}
 }
+
+var cmPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineRef:  &v1beta1.PipelineRef{Name: "a-pipeline"},
+		IterateParam: "current-item",
+		IterateSource: &pipelineloopv1alpha1.IterateSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "items"},
+				Key:                  "list",
+			},
+		},
+	},
+}
+
+var resultPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineRef:  &v1beta1.PipelineRef{Name: "a-pipeline"},
+		IterateParam: "current-item",
+		IterateSource: &pipelineloopv1alpha1.IterateSource{
+			TaskResult: &pipelineloopv1alpha1.TaskResultSelector{PipelineTask: "list-items", Result: "items"},
+		},
+	},
+}
+
+var itemsConfigMap = &corev1.ConfigMap{
+	ObjectMeta: metav1.ObjectMeta{Name: "items", Namespace: "foo"},
+	Data:       map[string]string{"list": `["item1", "item2", "item3"]`},
+}
+
+var listItemsTaskRun = &v1beta1.TaskRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "pr-loop-example-list-items",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/pipelineRun":  "pr-loop-example",
+			"tekton.dev/pipelineTask": "list-items",
+		},
+	},
+	Status: v1beta1.TaskRunStatus{
+		Status: duckv1.Status{
+			Conditions: duckv1.Conditions{{
+				Type:   apis.ConditionSucceeded,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+		TaskRunStatusFields: v1beta1.TaskRunStatusFields{
+			TaskRunResults: []v1beta1.TaskRunResult{{
+				Name:  "items",
+				Value: *v1beta1.NewArrayOrString("[\"item1\", \"item2\"]\n"),
+			}},
+		},
+	},
+}
+
+var runPipelineLoopWithIterateSource = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"myTestLabel":            "myTestLabelValue",
+			"tekton.dev/pipelineRun": "pr-loop-example",
+		},
+	},
+	Spec: v1beta1.CustomRunSpec{
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       pipelineloop.PipelineLoopControllerName,
+			Name:       "a-pipelineloop",
+		},
+	},
+}
+
+var expectedPipelineRunFromIterateSource = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop-00001-9l9zj",
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-pipelineloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
+			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
+			"myTestLabel":                             "myTestLabelValue",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{Name: "a-pipeline"},
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}, {
+			Name:  "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1"},
+		}},
+	},
+}
+
+func withIterateParamSnapshot(run *v1beta1.CustomRun, value string) *v1beta1.CustomRun {
+	runWithSnapshot := run.DeepCopy()
+	runWithSnapshot.Status.StartTime = &metav1.Time{Time: now}
+	runWithSnapshot.Status.InitializeConditions()
+	if err := runWithSnapshot.Status.EncodeExtraFields(&pipelineloopv1alpha1.PipelineLoopRunStatus{
+		IterateParamValue: v1beta1.NewArrayOrString(value),
+	}); err != nil {
+		panic(err)
+	}
+	return runWithSnapshot
+}
+
+func TestReconcilePipelineLoopRunWithIterateSource(t *testing.T) {
+	testcases := []struct {
+		name                string
+		pipelineloop        *pipelineloopv1alpha1.PipelineLoop
+		run                 *v1beta1.CustomRun
+		configMaps          []*corev1.ConfigMap
+		taskruns            []*v1beta1.TaskRun
+		expectedStatus      corev1.ConditionStatus
+		expectedReason      pipelineloopv1alpha1.PipelineLoopRunReason
+		expectedValue       string
+		expectedPipelinerun *v1beta1.PipelineRun
+	}{{
+		name:                "iterate over the items in a ConfigMap",
+		pipelineloop:        cmPipelineLoop,
+		run:                 runPipelineLoopWithIterateSource,
+		configMaps:          []*corev1.ConfigMap{itemsConfigMap},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedValue:       `["item1", "item2", "item3"]`,
+		expectedPipelinerun: expectedPipelineRunFromIterateSource,
+	}, {
+		name:                "iterate over the items in a task result",
+		pipelineloop:        resultPipelineLoop,
+		run:                 runPipelineLoopWithIterateSource,
+		taskruns:            []*v1beta1.TaskRun{listItemsTaskRun},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedValue:       `["item1", "item2"]`,
+		expectedPipelinerun: expectedPipelineRunFromIterateSource,
+	}, {
+		name:                "iterate over the items stored on the Run rather than the current ConfigMap",
+		pipelineloop:        cmPipelineLoop,
+		run:                 withIterateParamSnapshot(runPipelineLoopWithIterateSource, `["item1"]`),
+		configMaps:          []*corev1.ConfigMap{itemsConfigMap},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedValue:       `["item1"]`,
+		expectedPipelinerun: expectedPipelineRunFromIterateSource,
+	}, {
+		name:           "missing ConfigMap",
+		pipelineloop:   cmPipelineLoop,
+		run:            runPipelineLoopWithIterateSource,
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonCouldntResolveIterateSource,
+	}, {
+		name:           "missing task result",
+		pipelineloop:   resultPipelineLoop,
+		run:            runPipelineLoopWithIterateSource,
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonCouldntResolveIterateSource,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
+				Pipelines:  []*v1beta1.Pipeline{aPipeline},
+				TaskRuns:   tc.taskruns,
+				ConfigMaps: tc.configMaps,
+			}
+
+			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdPipelinerun := getCreatedPipelinerun(t, clients)
+			if d := cmp.Diff(tc.expectedPipelinerun, createdPipelinerun); d != "" {
+				t.Errorf("Expected PipelineRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+
+			status := &pipelineloopv1alpha1.PipelineLoopRunStatus{}
+			if err := reconciledRun.Status.DecodeExtraFields(status); err != nil {
+				t.Fatalf("DecodeExtraFields error: %v", err)
+			}
+			if tc.expectedValue == "" {
+				if status.IterateParamValue != nil {
+					t.Errorf("Expected no iterate parameter value on the Run but got %v", status.IterateParamValue)
+				}
+			} else if d := cmp.Diff(v1beta1.NewArrayOrString(tc.expectedValue), status.IterateParamValue); d != "" {
+				t.Errorf("Iterate parameter value stored on the Run is incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+var untilPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineRef:  &v1beta1.PipelineRef{Name: "a-pipeline"},
+		IterateParam: "current-item",
+		Until:        `results.status == "ready"`,
+	},
+}
+
+var pollingPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineRef:   &v1beta1.PipelineRef{Name: "a-pipeline"},
+		Until:         `results.status == "ready"`,
+		MaxIterations: 1,
+	},
+}
+
+var runPollingPipelineLoop = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"myTestLabel": "myTestLabelValue",
+		},
+		Annotations: map[string]string{
+			"myTestAnnotation": "myTestAnnotationValue",
+		},
+	},
+	Spec: v1beta1.CustomRunSpec{
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       pipelineloop.PipelineLoopControllerName,
+			Name:       "a-pipelineloop",
+		},
+	},
+}
+
+var expectedPollingPipelineRunIteration1 = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-pipelineloop-00001-9l9zj",
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-pipelineloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/pipelineLoop":          "a-pipelineloop",
+			"tekton.dev/customRun":                    "run-pipelineloop",
+			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "1",
+			"custom.tekton.dev/loopRun-1":             "run-pipelineloop",
+			"custom.tekton.dev/loopIteration-1":       "1",
+			"myTestLabel":                             "myTestLabelValue",
+		},
+		Annotations: map[string]string{
+			"myTestAnnotation": "myTestAnnotationValue",
+		},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{Name: "a-pipeline"},
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+	},
+}
+
+func withIterationDelay(pl *pipelineloopv1alpha1.PipelineLoop, delay time.Duration) *pipelineloopv1alpha1.PipelineLoop {
+	plWithDelay := pl.DeepCopy()
+	plWithDelay.Spec.IterationDelay = &metav1.Duration{Duration: delay}
+	return plWithDelay
+}
+
+func withStatusResult(pr *v1beta1.PipelineRun, value string) *v1beta1.PipelineRun {
+	prWithResults := pr.DeepCopy()
+	prWithResults.Status.PipelineResults = []v1beta1.PipelineRunResult{{Name: "status", Value: *v1beta1.NewArrayOrString(value)}}
+	return prWithResults
+}
+
+func completedAt(pr *v1beta1.PipelineRun, t time.Time) *v1beta1.PipelineRun {
+	prWithCompletionTime := pr.DeepCopy()
+	prWithCompletionTime.Status.CompletionTime = &metav1.Time{Time: t}
+	return prWithCompletionTime
+}
+
+func TestReconcilePipelineLoopRunUntil(t *testing.T) {
+	testcases := []struct {
+		name                string
+		pipelineloop        *pipelineloopv1alpha1.PipelineLoop
+		run                 *v1beta1.CustomRun
+		pipelineruns        []*v1beta1.PipelineRun
+		expectedStatus      corev1.ConditionStatus
+		expectedReason      pipelineloopv1alpha1.PipelineLoopRunReason
+		expectedResults     []v1beta1.CustomRunResult
+		expectedPipelinerun *v1beta1.PipelineRun
+	}{{
+		name:            "until condition is met",
+		pipelineloop:    untilPipelineLoop,
+		run:             loopRunning(runPipelineLoop),
+		pipelineruns:    []*v1beta1.PipelineRun{withStatusResult(successful(expectedPipelineRunIteration1), "ready")},
+		expectedStatus:  corev1.ConditionTrue,
+		expectedReason:  pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded,
+		expectedResults: []v1beta1.CustomRunResult{{Name: "condition", Value: "pass"}},
+	}, {
+		name:                "until condition is not met",
+		pipelineloop:        untilPipelineLoop,
+		run:                 loopRunning(runPipelineLoop),
+		pipelineruns:        []*v1beta1.PipelineRun{withStatusResult(successful(expectedPipelineRunIteration1), "pending")},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: expectedPipelineRunIteration2,
+	}, {
+		name:           "until condition refers to a missing result",
+		pipelineloop:   untilPipelineLoop,
+		run:            loopRunning(runPipelineLoop),
+		pipelineruns:   []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1)},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonCouldntEvaluateUntil,
+	}, {
+		name:                "polling loop starts the first iteration",
+		pipelineloop:        pollingPipelineLoop,
+		run:                 runPollingPipelineLoop,
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: expectedPollingPipelineRunIteration1,
+	}, {
+		name:            "polling loop reaches maxIterations",
+		pipelineloop:    pollingPipelineLoop,
+		run:             loopRunning(runPollingPipelineLoop),
+		pipelineruns:    []*v1beta1.PipelineRun{withStatusResult(successful(expectedPollingPipelineRunIteration1), "pending")},
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  pipelineloopv1alpha1.PipelineLoopRunReasonUntilConditionNotMet,
+		expectedResults: []v1beta1.CustomRunResult{{Name: "condition", Value: "fail"}},
+	}, {
+		name:         "until condition is not met after the last item",
+		pipelineloop: untilPipelineLoop,
+		run:          loopRunning(runPipelineLoop),
+		pipelineruns: []*v1beta1.PipelineRun{
+			withStatusResult(successful(expectedPipelineRunIteration1), "pending"),
+			withStatusResult(successful(expectedPipelineRunIteration2), "pending")},
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  pipelineloopv1alpha1.PipelineLoopRunReasonUntilConditionNotMet,
+		expectedResults: []v1beta1.CustomRunResult{{Name: "condition", Value: "fail"}},
+	}, {
+		name:         "next iteration waits for the iteration delay",
+		pipelineloop: withIterationDelay(untilPipelineLoop, time.Hour),
+		run:          loopRunning(runPipelineLoop),
+		pipelineruns: []*v1beta1.PipelineRun{
+			completedAt(withStatusResult(successful(expectedPipelineRunIteration1), "pending"), now)},
+		expectedStatus: corev1.ConditionUnknown,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+	}, {
+		name:         "next iteration starts after the iteration delay",
+		pipelineloop: withIterationDelay(untilPipelineLoop, time.Minute),
+		run:          loopRunning(runPipelineLoop),
+		pipelineruns: []*v1beta1.PipelineRun{
+			completedAt(withStatusResult(successful(expectedPipelineRunIteration1), "pending"), now.Add(-time.Hour))},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: expectedPipelineRunIteration2,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{aPipeline},
+				PipelineRuns: tc.pipelineruns,
+			}
+
+			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdPipelinerun := getCreatedPipelinerun(t, clients)
+			if tc.expectedPipelinerun == nil {
+				if createdPipelinerun != nil {
+					t.Errorf("A PipelineRun was created which was not expected")
+				}
+			} else if d := cmp.Diff(tc.expectedPipelinerun, createdPipelinerun); d != "" {
+				t.Errorf("Expected PipelineRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+
+			if d := cmp.Diff(tc.expectedResults, reconciledRun.Status.Results); d != "" {
+				t.Errorf("Run results are incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+var nestedPipelineLoop = &pipelineloopv1alpha1.PipelineLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "nested-pipelineloop", Namespace: "foo"},
+	Spec: pipelineloopv1alpha1.PipelineLoopSpec{
+		PipelineSpec: &v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{{
+				Name: "mytask",
+				TaskSpec: &v1beta1.EmbeddedTask{
+					TaskSpec: v1beta1.TaskSpec{
+						Steps: []v1beta1.Step{{
+							Name: "foo", Image: "bar", Args: []string{"iteration-$(loop.iteration)"},
+						}},
+					},
+				},
+			}},
+		},
+		IterateParam: "current-item",
+	},
+}
+
+var outerPipelineRun = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "outer-run-00002-abcde",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/customRun":              "outer-run",
+			"custom.tekton.dev/loopDepth":       "1",
+			"custom.tekton.dev/loopRun-1":       "outer-run",
+			"custom.tekton.dev/loopIteration-1": "2",
+		},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{Name: "outer-pipeline"},
+		Params: []v1beta1.Param{{
+			Name:  "outer-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "b"},
+		}},
+	},
+}
+
+func nestedRun(depth string) *v1beta1.CustomRun {
+	return &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run-nested-pipelineloop",
+			Namespace: "foo",
+			Labels: map[string]string{
+				"tekton.dev/pipelineRun":            "outer-run-00002-abcde",
+				"custom.tekton.dev/loopDepth":       depth,
+				"custom.tekton.dev/loopRun-1":       "outer-run",
+				"custom.tekton.dev/loopIteration-1": "2",
+			},
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{{
+				Name:  "current-item",
+				Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
+			}},
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: pipelineloopv1alpha1.SchemeGroupVersion.String(),
+				Kind:       pipelineloop.PipelineLoopControllerName,
+				Name:       "nested-pipelineloop",
+			},
+		},
+	}
+}
+
+var expectedNestedPipelineRunIteration1 = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-nested-pipelineloop-00001-9l9zj",
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-nested-pipelineloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/pipelineLoop":          "nested-pipelineloop",
+			"tekton.dev/customRun":                    "run-nested-pipelineloop",
+			"custom.tekton.dev/pipelineLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":             "2",
+			"custom.tekton.dev/loopRun-1":             "outer-run",
+			"custom.tekton.dev/loopIteration-1":       "2",
+			"custom.tekton.dev/loopRun-2":             "run-nested-pipelineloop",
+			"custom.tekton.dev/loopIteration-2":       "1",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineSpec: &v1beta1.PipelineSpec{
+			Tasks: []v1beta1.PipelineTask{{
+				Name: "mytask",
+				TaskSpec: &v1beta1.EmbeddedTask{
+					TaskSpec: v1beta1.TaskSpec{
+						Steps: []v1beta1.Step{{
+							Name: "foo", Image: "bar", Args: []string{"iteration-1"},
+						}},
+					},
+				},
+			}},
+		},
+		Params: []v1beta1.Param{{
+			Name:  "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1"},
+		}, {
+			Name:  "loop.parent.iteration",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "2"},
+		}, {
+			Name:  "loop.parent.outer-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "b"},
+		}},
+	},
+}
+
+func TestReconcilePipelineLoopRunNested(t *testing.T) {
+	testcases := []struct {
+		name                string
+		run                 *v1beta1.CustomRun
+		expectedStatus      corev1.ConditionStatus
+		expectedReason      pipelineloopv1alpha1.PipelineLoopRunReason
+		expectedPipelinerun *v1beta1.PipelineRun
+	}{{
+		name:                "nested loop passes the parent iteration and labels the hierarchy",
+		run:                 nestedRun("1"),
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: expectedNestedPipelineRunIteration1,
+	}, {
+		name:           "nested loop exceeds the maximum nesting depth",
+		run:            nestedRun("5"),
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonMaxNestingDepthExceeded,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				PipelineRuns: []*v1beta1.PipelineRun{outerPipelineRun},
+			}
+
+			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{nestedPipelineLoop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdPipelinerun := getCreatedPipelinerun(t, clients)
+			if tc.expectedPipelinerun == nil {
+				if createdPipelinerun != nil {
+					t.Errorf("A PipelineRun was created which was not expected")
+				}
+			} else if d := cmp.Diff(tc.expectedPipelinerun, createdPipelinerun); d != "" {
+				t.Errorf("Expected PipelineRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func withStatusMode(pl *pipelineloopv1alpha1.PipelineLoop, mode pipelineloopv1alpha1.StatusMode) *pipelineloopv1alpha1.PipelineLoop {
+	pipelineLoopWithStatusMode := pl.DeepCopy()
+	pipelineLoopWithStatusMode.Spec.StatusMode = mode
+	return pipelineLoopWithStatusMode
+}
+
+func withFullPipelineRunStatus(run *v1beta1.CustomRun, prs ...*v1beta1.PipelineRun) *v1beta1.CustomRun {
+	runWithStatus := run.DeepCopy()
+	status := &pipelineloopv1alpha1.PipelineLoopRunStatus{PipelineRuns: map[string]*pipelineloopv1alpha1.PipelineLoopPipelineRunStatus{}}
+	for i, pr := range prs {
+		status.PipelineRuns[pr.Name] = &pipelineloopv1alpha1.PipelineLoopPipelineRunStatus{Iteration: i + 1, Status: &pr.Status}
+	}
+	if err := runWithStatus.Status.EncodeExtraFields(status); err != nil {
+		panic(err)
+	}
+	return runWithStatus
+}
+
+func TestReconcilePipelineLoopRunStatusMode(t *testing.T) {
+	testcases := []struct {
+		name         string
+		pipelineloop *pipelineloopv1alpha1.PipelineLoop
+		run          *v1beta1.CustomRun
+		pipelineruns []*v1beta1.PipelineRun
+		// expectedReferences maps the iteration of each PipelineRun to its condition status
+		expectedReferences   map[int]corev1.ConditionStatus
+		expectedPipelineRuns int
+		expectedSummary      *pipelineloopv1alpha1.PipelineLoopRunSummary
+	}{{
+		name:                 "full status",
+		pipelineloop:         aPipelineLoop,
+		run:                  loopRunning(runPipelineLoop),
+		pipelineruns:         []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1)},
+		expectedPipelineRuns: 2,
+		expectedSummary:      &pipelineloopv1alpha1.PipelineLoopRunSummary{Iterations: 2, Started: 2, Running: 1, Succeeded: 1},
+	}, {
+		name:         "compact status",
+		pipelineloop: withStatusMode(aPipelineLoop, pipelineloopv1alpha1.StatusModeCompact),
+		run:          loopRunning(runPipelineLoop),
+		pipelineruns: []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1)},
+		expectedReferences: map[int]corev1.ConditionStatus{
+			1: corev1.ConditionTrue,
+			2: "",
+		},
+		expectedSummary: &pipelineloopv1alpha1.PipelineLoopRunSummary{Iterations: 2, Started: 2, Running: 1, Succeeded: 1},
+	}, {
+		name:         "full status stored by an earlier reconcile is compacted",
+		pipelineloop: withStatusMode(aPipelineLoop, pipelineloopv1alpha1.StatusModeCompact),
+		run:          withFullPipelineRunStatus(loopRunning(runPipelineLoop), running(expectedPipelineRunIteration1)),
+		pipelineruns: []*v1beta1.PipelineRun{running(expectedPipelineRunIteration1)},
+		expectedReferences: map[int]corev1.ConditionStatus{
+			1: corev1.ConditionUnknown,
+		},
+		expectedSummary: &pipelineloopv1alpha1.PipelineLoopRunSummary{Iterations: 2, Started: 1, Running: 1},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{aPipeline},
+				PipelineRuns: tc.pipelineruns,
+			}
+
+			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			status := &pipelineloopv1alpha1.PipelineLoopRunStatus{}
+			if err := reconciledRun.Status.DecodeExtraFields(status); err != nil {
+				t.Fatalf("DecodeExtraFields error: %v", err)
+			}
+
+			if len(status.PipelineRuns) != tc.expectedPipelineRuns {
+				t.Errorf("Expected Run status to include %d full PipelineRun statuses but found %d", tc.expectedPipelineRuns, len(status.PipelineRuns))
+			}
+			references := map[int]corev1.ConditionStatus{}
+			for _, ref := range status.PipelineRunReferences {
+				references[ref.Iteration] = ""
+				if ref.Condition != nil {
+					references[ref.Iteration] = ref.Condition.Status
+				}
+			}
+			if len(tc.expectedReferences) == 0 && len(references) == 0 {
+				references = tc.expectedReferences
+			}
+			if d := cmp.Diff(tc.expectedReferences, references); d != "" {
+				t.Errorf("PipelineRun references in the Run status are incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedSummary, status.Summary); d != "" {
+				t.Errorf("Run summary is incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+var legacyRunPipelineLoop = &v1alpha1.Run{
+	ObjectMeta: *runPipelineLoop.ObjectMeta.DeepCopy(),
+	Spec: v1alpha1.RunSpec{
+		Params: runPipelineLoop.Spec.Params,
+		Ref:    runPipelineLoop.Spec.CustomRef,
+	},
+}
+
+func legacyLoopRunning(run *v1alpha1.Run) *v1alpha1.Run {
+	runWithStatus := run.DeepCopy()
+	runWithStatus.Status.StartTime = &metav1.Time{Time: now}
+	runWithStatus.Status.InitializeConditions()
+	runWithStatus.Status.MarkRunRunning(pipelineloopv1alpha1.PipelineLoopRunReasonRunning.String(), "")
+	return runWithStatus
+}
+
+func legacyRequestCancel(run *v1alpha1.Run) *v1alpha1.Run {
+	runWithCancelStatus := run.DeepCopy()
+	runWithCancelStatus.Spec.Status = v1alpha1.RunSpecStatusCancelled
+	return runWithCancelStatus
+}
+
+// ownedByRun returns a copy of a PipelineRun expected for a CustomRun as it is expected for a v1alpha1.Run of the same name.
+func ownedByRun(pr *v1beta1.PipelineRun) *v1beta1.PipelineRun {
+	prOwnedByRun := pr.DeepCopy()
+	prOwnedByRun.OwnerReferences[0].APIVersion = "tekton.dev/v1alpha1"
+	prOwnedByRun.OwnerReferences[0].Kind = "Run"
+	prOwnedByRun.Labels["tekton.dev/run"] = prOwnedByRun.Labels["tekton.dev/customRun"]
+	delete(prOwnedByRun.Labels, "tekton.dev/customRun")
+	return prOwnedByRun
+}
+
+func TestReconcilePipelineLoopRunBackwardCompatibility(t *testing.T) {
+	testcases := []struct {
+		name                string
+		run                 *v1alpha1.Run
+		pipelineruns        []*v1beta1.PipelineRun
+		expectedStatus      corev1.ConditionStatus
+		expectedReason      pipelineloopv1alpha1.PipelineLoopRunReason
+		expectedPipelinerun *v1beta1.PipelineRun
+		expectedEvents      []string
+	}{{
+		name:                "new Run creates a PipelineRun owned by the Run",
+		run:                 legacyRunPipelineLoop,
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: ownedByRun(expectedPipelineRunIteration1),
+		expectedEvents:      []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:           "Run whose PipelineRuns were created before the move to CustomRuns succeeds",
+		run:            legacyLoopRunning(legacyRunPipelineLoop),
+		pipelineruns:   []*v1beta1.PipelineRun{successful(ownedByRun(expectedPipelineRunIteration1)), successful(ownedByRun(expectedPipelineRunIteration2))},
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded,
+		expectedEvents: []string{"Normal Succeeded All PipelineRuns completed successfully"},
+	}, {
+		name:           "cancelled Run",
+		run:            legacyRequestCancel(legacyLoopRunning(legacyRunPipelineLoop)),
+		pipelineruns:   []*v1beta1.PipelineRun{successful(ownedByRun(expectedPipelineRunIteration1))},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonCancelled,
+		expectedEvents: []string{"Warning Failed Run foo/run-pipelineloop was cancelled"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				Runs:         []*v1alpha1.Run{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{aPipeline},
+				PipelineRuns: tc.pipelineruns,
+			}
+
+			testAssets, _ := getPipelineLoopRunController(t, d, []*pipelineloopv1alpha1.PipelineLoop{aPipelineLoop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, tc.run.Namespace+"/"+tc.run.Name); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, toCustomRun(reconciledRun), tc.expectedStatus, tc.expectedReason)
+
+			createdPipelinerun := getCreatedPipelinerun(t, clients)
+			if tc.expectedPipelinerun == nil {
+				if createdPipelinerun != nil {
+					t.Errorf("A PipelineRun was created which was not expected")
+				}
+			} else if d := cmp.Diff(tc.expectedPipelinerun, createdPipelinerun); d != "" {
+				t.Errorf("Expected PipelineRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+
+			if err := checkEvents(testAssets.Recorder, tc.name, tc.expectedEvents); err != nil {
+				t.Errorf(err.Error())
+			}
+		})
+	}
+}
+
+func withLoopTimeout(pl *pipelineloopv1alpha1.PipelineLoop, timeout time.Duration) *pipelineloopv1alpha1.PipelineLoop {
+	pipelineLoopWithTimeout := pl.DeepCopy()
+	pipelineLoopWithTimeout.Spec.LoopTimeout = &metav1.Duration{Duration: timeout}
+	return pipelineLoopWithTimeout
+}
+
+func withRunTimeout(run *v1beta1.CustomRun, timeout time.Duration) *v1beta1.CustomRun {
+	runWithTimeout := run.DeepCopy()
+	runWithTimeout.Spec.Timeout = &metav1.Duration{Duration: timeout}
+	return runWithTimeout
+}
+
+func startedBefore(run *v1beta1.CustomRun, elapsed time.Duration) *v1beta1.CustomRun {
+	runWithStartTime := run.DeepCopy()
+	runWithStartTime.Status.StartTime = &metav1.Time{Time: now.Add(-elapsed)}
+	return runWithStartTime
+}
+
+func getPatchedPipelineRunNames(clients test.Clients) []string {
+	patched := []string{}
+	for _, a := range clients.Pipeline.Actions() {
+		if a.GetVerb() == "patch" && a.GetResource().Resource == "pipelineruns" {
+			patched = append(patched, a.(ktesting.PatchAction).GetName())
+		}
+	}
+	return patched
+}
+
+func TestReconcilePipelineLoopRunLoopTimeout(t *testing.T) {
+	testcases := []struct {
+		name                string
+		pipelineloop        *pipelineloopv1alpha1.PipelineLoop
+		run                 *v1beta1.CustomRun
+		pipelineruns        []*v1beta1.PipelineRun
+		expectedStatus      corev1.ConditionStatus
+		expectedReason      pipelineloopv1alpha1.PipelineLoopRunReason
+		expectedPipelinerun *v1beta1.PipelineRun
+		expectedCancelled   []string
+		expectedEvents      []string
+	}{{
+		name:                "loop within its loop timeout starts the next iteration",
+		pipelineloop:        withLoopTimeout(aPipelineLoop, time.Hour),
+		run:                 startedBefore(loopRunning(runPipelineLoop), time.Minute),
+		pipelineruns:        []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1)},
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedPipelinerun: expectedPipelineRunIteration2,
+		expectedEvents:      []string{"Normal Running Iterations completed: 1"},
+	}, {
+		name:              "loop past its loop timeout cancels the running PipelineRun",
+		pipelineloop:      withLoopTimeout(aPipelineLoop, time.Hour),
+		run:               startedBefore(loopRunning(runPipelineLoop), 2*time.Hour),
+		pipelineruns:      []*v1beta1.PipelineRun{running(expectedPipelineRunIteration1)},
+		expectedStatus:    corev1.ConditionUnknown,
+		expectedReason:    pipelineloopv1alpha1.PipelineLoopRunReasonRunning,
+		expectedCancelled: []string{expectedPipelineRunIteration1.Name},
+		expectedEvents:    []string{"Normal Running Cancelling PipelineRun " + expectedPipelineRunIteration1.Name},
+	}, {
+		name:           "loop past its loop timeout fails once its PipelineRun is done",
+		pipelineloop:   withLoopTimeout(aPipelineLoop, time.Hour),
+		run:            startedBefore(loopRunning(runPipelineLoop), 2*time.Hour),
+		pipelineruns:   []*v1beta1.PipelineRun{failed(expectedPipelineRunIteration1)},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonLoopTimedOut,
+		expectedEvents: []string{"Warning Failed Run foo/run-pipelineloop timed out after 1h0m0s"},
+	}, {
+		name:           "loop past the timeout of the Run does not start the next iteration",
+		pipelineloop:   withLoopTimeout(aPipelineLoop, 2*time.Hour),
+		run:            withRunTimeout(startedBefore(loopRunning(runPipelineLoop), 90*time.Minute), time.Hour),
+		pipelineruns:   []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1)},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonLoopTimedOut,
+		expectedEvents: []string{"Warning Failed Run foo/run-pipelineloop timed out after 1h0m0s"},
+	}, {
+		name:           "loop whose PipelineRuns all succeeded before it was reconciled past its loop timeout succeeds",
+		pipelineloop:   withLoopTimeout(aPipelineLoop, time.Hour),
+		run:            startedBefore(loopRunning(runPipelineLoop), 2*time.Hour),
+		pipelineruns:   []*v1beta1.PipelineRun{successful(expectedPipelineRunIteration1), successful(expectedPipelineRunIteration2)},
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: pipelineloopv1alpha1.PipelineLoopRunReasonSucceeded,
+		expectedEvents: []string{"Normal Succeeded All PipelineRuns completed successfully"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{aPipeline},
+				PipelineRuns: tc.pipelineruns,
+			}
+
+			testAssets, _ := getPipelineLoopController(t, d, []*pipelineloopv1alpha1.PipelineLoop{tc.pipelineloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdPipelinerun := getCreatedPipelinerun(t, clients)
+			if tc.expectedPipelinerun == nil {
+				if createdPipelinerun != nil {
+					t.Errorf("A PipelineRun was created which was not expected")
+				}
+			} else if d := cmp.Diff(tc.expectedPipelinerun, createdPipelinerun); d != "" {
+				t.Errorf("Expected PipelineRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedCancelled, getPatchedPipelineRunNames(clients), cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("Unexpected PipelineRuns were cancelled. Diff %s", diff.PrintWantGot(d))
+			}
+
+			if err := checkEvents(testAssets.Recorder, tc.name, tc.expectedEvents); err != nil {
+				t.Errorf(err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinelooprun
+
+import (
+	pipelineloopv1alpha1 "github.com/tektoncd/experimental/pipeline-loops/pkg/apis/pipelineloop/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// setPipelineRunStatus records the status of a PipelineRun in the status of the Run, in full or compact form
+// depending on the StatusMode of the PipelineLoop.
+func setPipelineRunStatus(status *pipelineloopv1alpha1.PipelineLoopRunStatus, mode pipelineloopv1alpha1.StatusMode, name string, iteration int, prStatus *v1beta1.PipelineRunStatus) {
+	if mode == pipelineloopv1alpha1.StatusModeCompact {
+		if status.PipelineRunReferences == nil {
+			status.PipelineRunReferences = make(map[string]*pipelineloopv1alpha1.PipelineLoopPipelineRunReference)
+		}
+		ref := &pipelineloopv1alpha1.PipelineLoopPipelineRunReference{Iteration: iteration}
+		if prStatus != nil {
+			ref.Condition = prStatus.GetCondition(apis.ConditionSucceeded)
+			ref.StartTime = prStatus.StartTime
+			ref.CompletionTime = prStatus.CompletionTime
+		}
+		status.PipelineRunReferences[name] = ref
+		return
+	}
+	if status.PipelineRuns == nil {
+		status.PipelineRuns = make(map[string]*pipelineloopv1alpha1.PipelineLoopPipelineRunStatus)
+	}
+	status.PipelineRuns[name] = &pipelineloopv1alpha1.PipelineLoopPipelineRunStatus{
+		Iteration: iteration,
+		Status:    prStatus,
+	}
+}
+
+// migrateStatus converts the statuses of the PipelineRuns that were stored in another form than the StatusMode
+// of the PipelineLoop asks for, e.g. by a controller that didn't support compact statuses.
+func migrateStatus(status *pipelineloopv1alpha1.PipelineLoopRunStatus, mode pipelineloopv1alpha1.StatusMode) {
+	if mode == pipelineloopv1alpha1.StatusModeCompact {
+		for name, prs := range status.PipelineRuns {
+			setPipelineRunStatus(status, mode, name, prs.Iteration, prs.Status)
+		}
+		status.PipelineRuns = nil
+		return
+	}
+	// The full statuses can't be rebuilt from the references; they are read from the PipelineRuns again.
+	status.PipelineRunReferences = nil
+}
+
+// updateRunSummary counts the PipelineRuns recorded in the status of the Run by state.
+func updateRunSummary(status *pipelineloopv1alpha1.PipelineLoopRunStatus, iterations int) {
+	summary := &pipelineloopv1alpha1.PipelineLoopRunSummary{Iterations: iterations}
+	count := func(condition *apis.Condition) {
+		summary.Started++
+		switch {
+		case condition.IsTrue():
+			summary.Succeeded++
+		case condition.IsFalse():
+			summary.Failed++
+		default:
+			summary.Running++
+		}
+	}
+	for _, prs := range status.PipelineRuns {
+		if prs.Status == nil {
+			count(nil)
+			continue
+		}
+		count(prs.Status.GetCondition(apis.ConditionSucceeded))
+	}
+	for _, ref := range status.PipelineRunReferences {
+		count(ref.Condition)
+	}
+	status.Summary = summary
+}
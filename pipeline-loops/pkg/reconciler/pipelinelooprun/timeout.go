@@ -0,0 +1,52 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinelooprun
+
+import (
+	"time"
+
+	pipelineloopv1alpha1 "github.com/tektoncd/experimental/pipeline-loops/pkg/apis/pipelineloop/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// getLoopDeadline returns the time by which the whole loop must complete, or nil if the loop has no deadline.
+// The deadline is the earlier of the loop timeout of the PipelineLoop and the timeout of the Run, both counted from
+// the start of the Run.  A zero timeout means no timeout.  The timeout of the Run only applies if it is set
+// explicitly so that loops which run longer than the default timeout of Tekton keep working.
+func getLoopDeadline(run *v1beta1.CustomRun, tls *pipelineloopv1alpha1.PipelineLoopSpec) *time.Time {
+	if run.Status.StartTime == nil {
+		return nil
+	}
+	var timeout time.Duration
+	if tls.LoopTimeout != nil && tls.LoopTimeout.Duration > 0 {
+		timeout = tls.LoopTimeout.Duration
+	}
+	if run.Spec.Timeout != nil && run.Spec.Timeout.Duration > 0 && (timeout == 0 || run.Spec.Timeout.Duration < timeout) {
+		timeout = run.Spec.Timeout.Duration
+	}
+	if timeout == 0 {
+		return nil
+	}
+	deadline := run.Status.StartTime.Add(timeout)
+	return &deadline
+}
+
+// markLoopTimedOut marks the Run as failed because the loop didn't complete by its deadline.
+func markLoopTimedOut(run *v1beta1.CustomRun, deadline time.Time) {
+	run.Status.MarkCustomRunFailed(pipelineloopv1alpha1.PipelineLoopRunReasonLoopTimedOut.String(),
+		"Run %s/%s timed out after %s", run.Namespace, run.Name, deadline.Sub(run.Status.StartTime.Time))
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinelooprun
+
+import (
+	"fmt"
+
+	pipelineloopv1alpha1 "github.com/tektoncd/experimental/pipeline-loops/pkg/apis/pipelineloop/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// evaluateUntil evaluates the until expression of a PipelineLoop against the results of the
+// PipelineRun of an iteration.
+func evaluateUntil(expr string, iteration int, pr *v1beta1.PipelineRun) (bool, error) {
+	prg, err := pipelineloopv1alpha1.CompileUntil(expr)
+	if err != nil {
+		return false, err
+	}
+	results := make(map[string]string, len(pr.Status.PipelineResults))
+	for _, r := range pr.Status.PipelineResults {
+		results[r.Name] = r.Value.StringVal
+	}
+	out, _, err := prg.Eval(map[string]interface{}{
+		"results":   results,
+		"iteration": iteration,
+	})
+	if err != nil {
+		return false, err
+	}
+	met, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v instead of a bool", out.Value())
+	}
+	return met, nil
+}
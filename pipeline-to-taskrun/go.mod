@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/google/go-cmp v0.5.5
+	github.com/google/go-containerregistry v0.4.1-0.20210128200529-19c2b639fab1
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/tektoncd/pipeline v0.24.0
 	go.uber.org/zap v1.16.0
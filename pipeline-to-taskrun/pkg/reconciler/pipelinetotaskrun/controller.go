@@ -26,6 +26,7 @@ import (
 	pipelinecontroller "github.com/tektoncd/pipeline/pkg/controller"
 	tkncontroller "github.com/tektoncd/pipeline/pkg/controller"
 	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	configmap "knative.dev/pkg/configmap"
 	controller "knative.dev/pkg/controller"
 	logging "knative.dev/pkg/logging"
@@ -40,19 +41,24 @@ const (
 func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
+	kubeClientSet := kubeclient.Get(ctx)
 	pipelineClientSet := pipelineclient.Get(ctx)
 	runInformer := run.Get(ctx)
 	taskRunInformer := taskruninformer.Get(ctx)
 
 	r := &Reconciler{
+		kubeClientSet:     kubeClientSet,
 		pipelineClientSet: pipelineClientSet,
 		runLister:         runInformer.Lister(),
 		taskRunLister:     taskRunInformer.Lister(),
 	}
 
 	impl := v1alpha1run.NewImpl(ctx, r, func(impl *controller.Impl) controller.Options {
+		configStore := newFeatureFlagsStore(logger.Named("config-store"))
+		configStore.WatchConfigs(cmw)
 		return controller.Options{
-			AgentName: ControllerName,
+			AgentName:   ControllerName,
+			ConfigStore: configStore,
 		}
 	})
 
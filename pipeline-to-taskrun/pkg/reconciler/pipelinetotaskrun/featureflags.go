@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"context"
+
+	"github.com/tektoncd/pipeline/pkg/apis/config"
+	"knative.dev/pkg/configmap"
+)
+
+// featureFlagsStore holds the Tekton Pipelines feature flags from the feature-flags ConfigMap in the namespace of the
+// controller. Unlike the store of Tekton Pipelines, it doesn't watch the other ConfigMaps of Tekton Pipelines, which
+// don't apply to the custom task.
+type featureFlagsStore struct {
+	*configmap.UntypedStore
+}
+
+// newFeatureFlagsStore will return a store of the feature flags, which calls the functions when the ConfigMap is updated.
+func newFeatureFlagsStore(logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *featureFlagsStore {
+	return &featureFlagsStore{
+		UntypedStore: configmap.NewUntypedStore(
+			"features",
+			logger,
+			configmap.Constructors{
+				config.GetFeatureFlagsConfigName(): config.NewFeatureFlagsFromConfigMap,
+			},
+			onAfterStore...,
+		),
+	}
+}
+
+// ToContext attaches the current feature flags to the provided context, along with the defaults for the rest of the
+// Config, so that the resolution of Pipelines and Tasks by Tekton Pipelines sees them.
+func (s *featureFlagsStore) ToContext(ctx context.Context) context.Context {
+	featureFlags, ok := s.UntypedLoad(config.GetFeatureFlagsConfigName()).(*config.FeatureFlags)
+	if !ok {
+		featureFlags, _ = config.NewFeatureFlagsFromMap(map[string]string{})
+	}
+	cfg := config.FromContextOrDefaults(ctx)
+	return config.ToContext(ctx, &config.Config{
+		Defaults:       cfg.Defaults,
+		FeatureFlags:   featureFlags.DeepCopy(),
+		ArtifactBucket: cfg.ArtifactBucket,
+		ArtifactPVC:    cfg.ArtifactPVC,
+	})
+}
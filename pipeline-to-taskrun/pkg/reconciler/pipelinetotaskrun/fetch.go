@@ -19,11 +19,17 @@ package pipelinetotaskrun
 import (
 	"context"
 	"fmt"
+
+	"github.com/tektoncd/pipeline/pkg/apis/config"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
-	tektonv1beta1 "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+	clientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
+	pipelinerunresources "github.com/tektoncd/pipeline/pkg/reconciler/pipelinerun/resources"
+	taskrunresources "github.com/tektoncd/pipeline/pkg/reconciler/taskrun/resources"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 func getTaskRunIfExists(lister listers.TaskRunLister, namespace, name string) (*v1beta1.TaskRun, error) {
@@ -37,23 +43,49 @@ func getTaskRunIfExists(lister listers.TaskRunLister, namespace, name string) (*
 	return tr, nil
 }
 
-func getPipelineSpec(ctx context.Context, tv1beta1 tektonv1beta1.TektonV1beta1Interface, namespace, pipeline string) (*v1beta1.PipelineSpec, error) {
-	p, err := tv1beta1.Pipelines(namespace).Get(ctx, pipeline, metav1.GetOptions{})
+// checkBundle will return an error if a Pipeline or Task is referenced from a bundle while the
+// enable-tekton-oci-bundles feature flag is off, in which case Tekton Pipelines would ignore the bundle and fetch the
+// Pipeline or Task with the same name from the cluster instead.
+func checkBundle(ctx context.Context, kind, name, bundle string) error {
+	if bundle != "" && !config.FromContextOrDefaults(ctx).FeatureFlags.EnableTektonOCIBundles {
+		return fmt.Errorf("%s %s is referenced from the bundle %s but the enable-tekton-oci-bundles feature flag is off", kind, name, bundle)
+	}
+	return nil
+}
+
+// getPipelineSpec will return the spec of the Pipeline referenced by the Run, fetched from the cluster or from the
+// bundle the Run references.
+func getPipelineSpec(ctx context.Context, k8s kubernetes.Interface, tekton clientset.Interface, run *v1alpha1.Run) (*v1beta1.PipelineSpec, error) {
+	if err := checkBundle(ctx, "Pipeline", run.Spec.Ref.Name, run.Spec.Ref.Bundle); err != nil {
+		return nil, err
+	}
+	getPipeline, err := pipelinerunresources.GetPipelineFunc(ctx, k8s, tekton, &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: run.Namespace},
+		Spec: v1beta1.PipelineRunSpec{
+			PipelineRef:        &v1beta1.PipelineRef{Name: run.Spec.Ref.Name, Bundle: run.Spec.Ref.Bundle},
+			ServiceAccountName: run.Spec.ServiceAccountName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	p, err := getPipeline(ctx, run.Spec.Ref.Name)
 	if err != nil {
 		return nil, err
 	}
-	return &p.Spec, nil
+	pSpec := p.PipelineSpec()
+	return &pSpec, nil
 }
 
-func getTaskSpecs(ctx context.Context, tv1beta1 tektonv1beta1.TektonV1beta1Interface, pSpec *v1beta1.PipelineSpec, namespace string) (map[string]*v1beta1.TaskSpec, error) {
+func getTaskSpecs(ctx context.Context, k8s kubernetes.Interface, tekton clientset.Interface, pSpec *v1beta1.PipelineSpec, namespace, saName string) (map[string]*v1beta1.TaskSpec, error) {
 	taskSpecs := map[string]*v1beta1.TaskSpec{}
-	for _, ptask := range pSpec.Tasks {
+	for _, ptask := range append(pSpec.Tasks, pSpec.Finally...) {
 		var taskSpec *v1beta1.TaskSpec
 		if ptask.TaskRef == nil {
 			taskSpec = &ptask.TaskSpec.TaskSpec
 		} else {
 			var err error
-			taskSpec, err = getTaskSpec(ctx, tv1beta1, namespace, ptask.TaskRef.Name)
+			taskSpec, err = getTaskSpec(ctx, k8s, tekton, ptask.TaskRef, namespace, saName)
 			if err != nil {
 				return nil, fmt.Errorf("couldn't fetch taskspec for %s: %v", ptask.Name, err)
 			}
@@ -63,10 +95,20 @@ func getTaskSpecs(ctx context.Context, tv1beta1 tektonv1beta1.TektonV1beta1Inter
 	return taskSpecs, nil
 }
 
-func getTaskSpec(ctx context.Context, tv1beta1 tektonv1beta1.TektonV1beta1Interface, namespace, task string) (*v1beta1.TaskSpec, error) {
-	t, err := tv1beta1.Tasks(namespace).Get(ctx, task, metav1.GetOptions{})
+// getTaskSpec will return the spec of the Task or ClusterTask referenced by a pipeline task, fetched from the cluster
+// or from the bundle the pipeline task references.
+func getTaskSpec(ctx context.Context, k8s kubernetes.Interface, tekton clientset.Interface, taskRef *v1beta1.TaskRef, namespace, saName string) (*v1beta1.TaskSpec, error) {
+	if err := checkBundle(ctx, "Task", taskRef.Name, taskRef.Bundle); err != nil {
+		return nil, err
+	}
+	getTask, _, err := taskrunresources.GetTaskFunc(ctx, k8s, tekton, taskRef, namespace, saName)
+	if err != nil {
+		return nil, err
+	}
+	t, err := getTask(ctx, taskRef.Name)
 	if err != nil {
 		return nil, err
 	}
-	return &t.Spec, nil
+	taskSpec := t.TaskSpec()
+	return &taskSpec, nil
 }
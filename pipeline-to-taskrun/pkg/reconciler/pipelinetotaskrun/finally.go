@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// markersDir is where the steps that the custom task adds or updates write the files that tell the following
+	// steps which pipeline tasks were skipped or failed; it's in the home directory which all the steps share
+	markersDir = "/tekton/home/.pipelinetotaskrun"
+
+	// pipelineStatusStepName is the name of the step which fails the TaskRun if any pipeline task failed, once the
+	// finally tasks ran
+	pipelineStatusStepName = "pipeline-status"
+)
+
+// shells are the interpreters of the scripts that the custom task can update
+var shells = map[string]bool{"sh": true, "bash": true, "ash": true, "dash": true, "ksh": true, "zsh": true}
+
+// getFailedMarkerPath returns the path of the file which the steps of a pipeline task write when they fail. The steps
+// of all the pipeline tasks share one file since none of them run after one failed, while each finally task has its
+// own file since they all run.
+func getFailedMarkerPath(pTaskName string, isFinally bool) string {
+	if isFinally {
+		return filepath.Join(markersDir, "failed-"+pTaskName)
+	}
+	return filepath.Join(markersDir, "failed")
+}
+
+// getPrelude will return the lines added at the start of the scripts of the steps of a pipeline task. The steps are
+// skipped if the guard step of the pipeline task skipped it, or if a previous step failed. When the Pipeline has
+// finally tasks, a step failing doesn't fail the TaskRun (like a step which continues on error) but is recorded, so
+// that the finally tasks still run.
+func getPrelude(pTaskName, stepName string, guarded, isFinally, hasFinally bool) string {
+	var skip []string
+	if guarded {
+		skip = append(skip, fmt.Sprintf("[ -f %s ]", getSkipMarkerPath(pTaskName)))
+	}
+	if hasFinally {
+		skip = append(skip, fmt.Sprintf("[ -f %s ]", getFailedMarkerPath(pTaskName, isFinally)))
+	}
+	if len(skip) == 0 {
+		return ""
+	}
+
+	prelude := []string{fmt.Sprintf("if %s; then exit 0; fi", strings.Join(skip, " || "))}
+	if hasFinally {
+		failed := fmt.Sprintf("a step of %s", pTaskName)
+		if stepName != "" {
+			failed = "step " + stepName
+		}
+		prelude = append(prelude, fmt.Sprintf(
+			`trap 'code=$?; if [ $code -ne 0 ]; then mkdir -p %s; echo "%s failed with exit code $code" >> %s; fi; exit 0' EXIT`,
+			markersDir, failed, getFailedMarkerPath(pTaskName, isFinally)))
+	}
+	return strings.Join(prelude, "\n")
+}
+
+// addPrelude will return the script of the step with the prelude added after its shebang, or an error if the script
+// isn't a shell script.
+func addPrelude(step v1beta1.Step, prelude string) (string, error) {
+	if step.Script == "" {
+		return "", fmt.Errorf("step %s has no script", step.Name)
+	}
+	if !strings.HasPrefix(step.Script, "#!") {
+		// scripts without a shebang are run with sh
+		return prelude + "\n" + step.Script, nil
+	}
+
+	shebang, body := step.Script, ""
+	if i := strings.Index(step.Script, "\n"); i >= 0 {
+		shebang, body = step.Script[:i], step.Script[i+1:]
+	}
+	interpreter := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+	if len(interpreter) > 1 && filepath.Base(interpreter[0]) == "env" {
+		interpreter = interpreter[1:]
+	}
+	if len(interpreter) == 0 || !shells[filepath.Base(interpreter[0])] {
+		return "", fmt.Errorf("step %s has a script which isn't a shell script", step.Name)
+	}
+	return shebang + "\n" + prelude + "\n" + body, nil
+}
+
+// getPipelineStatusStep will return the step which runs after the steps of the finally tasks and fails if any
+// pipeline task failed.
+func getPipelineStatusStep() v1beta1.Step {
+	return v1beta1.Step{
+		Container: corev1.Container{
+			Name:  pipelineStatusStepName,
+			Image: shellImage,
+		},
+		Script: strings.Join([]string{
+			"#!/bin/sh",
+			"failed=0",
+			fmt.Sprintf("for marker in %s/failed*; do", markersDir),
+			`  if [ -f "$marker" ]; then`,
+			`    cat "$marker"`,
+			`    failed=1`,
+			`  fi`,
+			`done`,
+			`exit $failed`,
+		}, "\n") + "\n",
+	}
+}
@@ -21,54 +21,92 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 )
 
-type linkedTask struct {
-	task *v1beta1.PipelineTask
-	next *linkedTask
-}
-
+// putTasksInOrder will return the tasks in the order they need to run in, which is the order of their dependencies:
+// both runAfter and the results they use. Since the tasks run one after another, each task must depend (directly or
+// not) on the task right before it.
 func putTasksInOrder(tasks []v1beta1.PipelineTask) ([]v1beta1.PipelineTask, error) {
-	seen := map[string]*linkedTask{}
-	var root *linkedTask
-
-	for i := 0; i < len(tasks); i++ {
-		seen[tasks[i].Name] = &linkedTask{task: &tasks[i]}
+	if err := validateDeps(tasks); err != nil {
+		return nil, err
 	}
 
-	for _, task := range tasks {
-		if len(task.RunAfter) > 1 {
-			return nil, fmt.Errorf("fan in not yet supported but %s has more than one runAfter", task.Name)
-		}
-		l, _ := seen[task.Name]
-		if len(task.RunAfter) == 0 {
-			if root != nil {
-				return nil, fmt.Errorf("parallel tasks not yet supported by %s and %s are trying to run in parallel", task.Name, root.task.Name)
-			} else {
-				root = l
+	done := map[string]bool{}
+	ordered := []v1beta1.PipelineTask{}
+	for len(ordered) < len(tasks) {
+		var ready []v1beta1.PipelineTask
+		for _, task := range tasks {
+			if !done[task.Name] && depsDone(task, done) {
+				ready = append(ready, task)
 			}
-		} else {
-			before, ok := seen[task.RunAfter[0]]
-			if !ok {
-				return nil, fmt.Errorf("task %s trying to run after task %s which is not present", task.Name, task.RunAfter[0])
+		}
+		switch len(ready) {
+		case 0:
+			if len(ordered) == 0 {
+				return nil, fmt.Errorf("invalid sequence, there was no starting task (probably a loop?)")
 			}
-			before.next = l
+			return nil, fmt.Errorf("invalid sequence, no task can run after %s (probably a loop?)", ordered[len(ordered)-1].Name)
+		case 1:
+			done[ready[0].Name] = true
+			ordered = append(ordered, ready[0])
+		default:
+			return nil, fmt.Errorf("parallel tasks are only supported when the Run has the annotation %s: \"true\" but %s and %s are trying to run in parallel", parallelAnnotation, ready[1].Name, ready[0].Name)
 		}
 	}
-	if root == nil {
-		return nil, fmt.Errorf("invalid sequence, there was no starting task (probably a loop?)")
+
+	return ordered, nil
+}
+
+// putTasksInLevels will return the tasks grouped in the levels they can run in: the tasks of a level only depend on
+// tasks of the previous levels, so they can all run in parallel once the tasks of the previous levels are done.
+func putTasksInLevels(tasks []v1beta1.PipelineTask) ([][]v1beta1.PipelineTask, error) {
+	if err := validateDeps(tasks); err != nil {
+		return nil, err
+	}
+
+	done := map[string]bool{}
+	levels := [][]v1beta1.PipelineTask{}
+	for count := 0; count < len(tasks); {
+		var ready []v1beta1.PipelineTask
+		for _, task := range tasks {
+			if !done[task.Name] && depsDone(task, done) {
+				ready = append(ready, task)
+			}
+		}
+		if len(ready) == 0 {
+			if len(levels) == 0 {
+				return nil, fmt.Errorf("invalid sequence, there was no starting task (probably a loop?)")
+			}
+			return nil, fmt.Errorf("invalid sequence, no task can run after the tasks running in parallel in level %d (probably a loop?)", len(levels))
+		}
+		for _, task := range ready {
+			done[task.Name] = true
+		}
+		levels = append(levels, ready)
+		count += len(ready)
 	}
 
-	ordered := []v1beta1.PipelineTask{*root.task}
-	curr := root.next
-	for {
-		if curr == nil {
-			if len(ordered) < len(tasks) {
-				return nil, fmt.Errorf("sequence was not completely connected, gap after %s", ordered[len(ordered)-1].Name)
+	return levels, nil
+}
+
+func validateDeps(tasks []v1beta1.PipelineTask) error {
+	names := map[string]bool{}
+	for _, task := range tasks {
+		names[task.Name] = true
+	}
+	for _, task := range tasks {
+		for _, dep := range task.Deps() {
+			if !names[dep] {
+				return fmt.Errorf("task %s trying to run after task %s which is not present", task.Name, dep)
 			}
-			break
 		}
-		ordered = append(ordered, *curr.task)
-		curr = curr.next
 	}
+	return nil
+}
 
-	return ordered, nil
+func depsDone(task v1beta1.PipelineTask, done map[string]bool) bool {
+	for _, dep := range task.Deps() {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
 }
@@ -1,7 +1,9 @@
 package pipelinetotaskrun
 
 import (
+	"github.com/google/go-cmp/cmp"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/test/diff"
 	"testing"
 )
 
@@ -37,6 +39,20 @@ func TestPutTasksInOrderUnsupported(t *testing.T) {
 			Name:     "out2",
 			RunAfter: []string{"first"},
 		}},
+	}, {
+		name: "result of a parallel task",
+		tasks: []v1beta1.PipelineTask{{
+			Name: "first",
+		}, {
+			Name:     "out1",
+			RunAfter: []string{"first"},
+		}, {
+			Name: "out2",
+			Params: []v1beta1.Param{{
+				Name:  "foo",
+				Value: *v1beta1.NewArrayOrString("$(tasks.first.results.foo)"),
+			}},
+		}},
 	}, {
 		name: "cycle",
 		tasks: []v1beta1.PipelineTask{{
@@ -133,6 +149,34 @@ func TestPutTasksInOrder(t *testing.T) {
 			Name: "first",
 		}},
 		expectedOrder: []string{"first", "second", "third"},
+	}, {
+		name: "resultRef",
+		tasks: []v1beta1.PipelineTask{{
+			Name: "second",
+			Params: []v1beta1.Param{{
+				Name:  "foo",
+				Value: *v1beta1.NewArrayOrString("$(tasks.first.results.foo)"),
+			}},
+		}, {
+			Name: "first",
+		}},
+		expectedOrder: []string{"first", "second"},
+	}, {
+		name: "runAfterAndResultRef",
+		tasks: []v1beta1.PipelineTask{{
+			Name:     "third",
+			RunAfter: []string{"second"},
+			Params: []v1beta1.Param{{
+				Name:  "foo",
+				Value: *v1beta1.NewArrayOrString("$(tasks.first.results.foo)"),
+			}},
+		}, {
+			Name: "first",
+		}, {
+			Name:     "second",
+			RunAfter: []string{"first"},
+		}},
+		expectedOrder: []string{"first", "second", "third"},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			sequence, err := putTasksInOrder(tc.tasks)
@@ -151,3 +195,95 @@ func TestPutTasksInOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestPutTasksInLevels(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		tasks          []v1beta1.PipelineTask
+		expectedLevels [][]string
+	}{{
+		name: "sequential",
+		tasks: []v1beta1.PipelineTask{{
+			Name:     "second",
+			RunAfter: []string{"first"},
+		}, {
+			Name: "first",
+		}},
+		expectedLevels: [][]string{{"first"}, {"second"}},
+	}, {
+		name: "parallel",
+		tasks: []v1beta1.PipelineTask{{
+			Name: "starts",
+		}, {
+			Name: "alsostarts",
+		}},
+		expectedLevels: [][]string{{"starts", "alsostarts"}},
+	}, {
+		name: "fan out and fan in",
+		tasks: []v1beta1.PipelineTask{{
+			Name: "first",
+		}, {
+			Name:     "out1",
+			RunAfter: []string{"first"},
+		}, {
+			Name: "out2",
+			Params: []v1beta1.Param{{
+				Name:  "foo",
+				Value: *v1beta1.NewArrayOrString("$(tasks.first.results.foo)"),
+			}},
+		}, {
+			Name:     "in",
+			RunAfter: []string{"out1", "out2"},
+		}},
+		expectedLevels: [][]string{{"first"}, {"out1", "out2"}, {"in"}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			levels, err := putTasksInLevels(tc.tasks)
+			if err != nil {
+				t.Fatalf("did not expect error but got %v", err)
+			}
+			var names [][]string
+			for _, level := range levels {
+				var levelNames []string
+				for _, task := range level {
+					levelNames = append(levelNames, task.Name)
+				}
+				names = append(names, levelNames)
+			}
+			if d := cmp.Diff(tc.expectedLevels, names); d != "" {
+				t.Errorf("didn't get expected levels. Diff: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestPutTasksInLevelsInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		tasks []v1beta1.PipelineTask
+	}{{
+		name: "cycle",
+		tasks: []v1beta1.PipelineTask{{
+			Name: "first",
+		}, {
+			Name:     "second",
+			RunAfter: []string{"first", "third"},
+		}, {
+			Name:     "third",
+			RunAfter: []string{"second"},
+		}},
+	}, {
+		name: "missing task",
+		tasks: []v1beta1.PipelineTask{{
+			Name:     "first",
+			RunAfter: []string{"zeroth"},
+		}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := putTasksInLevels(tc.tasks)
+			if err == nil {
+				t.Fatalf("expected error for invalid tasks but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// parallelAnnotation is the annotation of the Run which opts in to running the pipeline tasks which don't depend
+	// on each other in parallel
+	parallelAnnotation = "pipelinetotaskrun.tekton.dev/parallel"
+
+	// toolsDir is where the entrypoint of each step writes the file which starts the next step; the entrypoint of the
+	// step at index i waits for the file named i-1 and writes the file named i once the step is done, emptying it if it
+	// already exists, or writes the file named i.err instead if the step failed
+	toolsDir = "/tekton/tools"
+)
+
+// isParallel returns true if the Run opted in to running the pipeline tasks which don't depend on each other in
+// parallel.
+func isParallel(run *v1alpha1.Run) bool {
+	return run.Annotations[parallelAnnotation] == "true"
+}
+
+// getParallelSteps will return the steps which run the steps of each branch in parallel, starting at index start of
+// the steps of the resulting TaskRun. Each branch holds the steps of one pipeline task. The steps of the branches are
+// added one branch after another, between a fork step which writes the files the first step of each branch waits
+// for, so that all the branches start right away, and a join step which waits for the last step of each branch.
+func getParallelSteps(name string, start int, branches [][]v1beta1.Step) []v1beta1.Step {
+	if len(branches) == 1 {
+		return branches[0]
+	}
+
+	// the first branch starts after the fork step and the join step starts after the last branch, but the other
+	// branches would start after the previous branch
+	var waitFiles []string
+	steps := []v1beta1.Step{{}}
+	for i, branch := range branches {
+		if i > 0 {
+			waitFiles = append(waitFiles, filepath.Join(toolsDir, fmt.Sprint(start+len(steps)-1)))
+		}
+		steps = append(steps, branch...)
+	}
+	steps[0] = getForkStep(name, waitFiles)
+	return append(steps, getJoinStep(name, waitFiles))
+}
+
+// getForkStep will return the step which writes the files that the first steps of the branches wait for. The files
+// aren't empty so that the join step can tell when the entrypoint of the step writing them has emptied them.
+func getForkStep(name string, waitFiles []string) v1beta1.Step {
+	script := []string{"#!/bin/sh", "set -e"}
+	for _, f := range waitFiles {
+		script = append(script, fmt.Sprintf("echo started > %s", f))
+	}
+	return v1beta1.Step{
+		Container: corev1.Container{
+			Name:  namespaceName(name, "fork"),
+			Image: shellImage,
+		},
+		Script: strings.Join(script, "\n") + "\n",
+	}
+}
+
+// getJoinStep will return the step which waits for the last steps of the branches, other than the last branch which
+// it runs after, and fails if any of them failed.
+func getJoinStep(name string, waitFiles []string) v1beta1.Step {
+	return v1beta1.Step{
+		Container: corev1.Container{
+			Name:  namespaceName(name, "join"),
+			Image: shellImage,
+		},
+		Script: strings.Join([]string{
+			"#!/bin/sh",
+			"failed=0",
+			fmt.Sprintf("for f in %s; do", strings.Join(waitFiles, " ")),
+			`  while [ -s "$f" ] && [ ! -f "$f.err" ]; do`,
+			`    sleep 1`,
+			`  done`,
+			`  if [ -f "$f.err" ]; then`,
+			`    failed=1`,
+			`  fi`,
+			`done`,
+			`exit $failed`,
+		}, "\n") + "\n",
+	}
+}
@@ -0,0 +1,179 @@
+package pipelinetotaskrun
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/entrypoint"
+	"github.com/tektoncd/pipeline/pkg/pod"
+	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+)
+
+// forkFileRegexp matches the files written by the script of a fork step
+var forkFileRegexp = regexp.MustCompile(`echo started > (\S+)`)
+
+func scriptStep(name string) v1beta1.Step {
+	return v1beta1.Step{Container: corev1.Container{Name: name, Image: "busybox"}, Script: "echo " + name}
+}
+
+// getEntrypointFiles will return the files that the entrypoint of each step of the pod built by Tekton for the
+// steps waits for and writes once the step is done, indexed by step name
+func getEntrypointFiles(t *testing.T, steps []v1beta1.Step) (map[string]string, map[string]string) {
+	t.Helper()
+	tr := &v1beta1.TaskRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "run", Namespace: "foo", Annotations: map[string]string{}},
+		Spec:       v1beta1.TaskRunSpec{ServiceAccountName: "default"},
+	}
+	builder := &pod.Builder{
+		Images: pipeline.Images{EntrypointImage: "entrypoint", ShellImage: "shell"},
+		KubeClient: fakek8s.NewSimpleClientset(&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "foo"},
+		}),
+	}
+	p, err := builder.Build(context.Background(), tr, v1beta1.TaskSpec{Steps: steps})
+	if err != nil {
+		t.Fatalf("couldn't build the pod of the steps: %v", err)
+	}
+
+	waitFiles, postFiles := map[string]string{}, map[string]string{}
+	for _, c := range p.Spec.Containers {
+		name := strings.TrimPrefix(c.Name, "step-")
+		for i := 0; i < len(c.Args)-1; i++ {
+			switch c.Args[i] {
+			case "-wait_file":
+				waitFiles[name] = c.Args[i+1]
+			case "-post_file":
+				postFiles[name] = c.Args[i+1]
+			}
+		}
+	}
+	return waitFiles, postFiles
+}
+
+// TestParallelStepsMatchEntrypoint pins the files the fork and join steps use to those the entrypoint of Tekton
+// v0.24 waits for and writes: the step at index i waits for /tekton/tools/<i-1> and writes /tekton/tools/<i>.
+func TestParallelStepsMatchEntrypoint(t *testing.T) {
+	branches := [][]v1beta1.Step{
+		{scriptStep("a1"), scriptStep("a2")},
+		{scriptStep("b1")},
+		{scriptStep("c1"), scriptStep("c2")},
+	}
+	before := scriptStep("before")
+	steps := append([]v1beta1.Step{before}, getParallelSteps("parallel-1", 1, branches)...)
+	waitFiles, postFiles := getEntrypointFiles(t, steps)
+
+	var forked []string
+	for _, m := range forkFileRegexp.FindAllStringSubmatch(steps[1].Script, -1) {
+		forked = append(forked, m[1])
+	}
+	// the first step of each branch but the first waits for the file written by the last step of the previous branch,
+	// which the fork step writes so that the branch starts right away
+	expectedForked := []string{postFiles["a2"], postFiles["b1"]}
+	if d := cmp.Diff(expectedForked, forked); d != "" {
+		t.Errorf("the fork step doesn't write the files written by the last steps of the branches: %s", diff.PrintWantGot(d))
+	}
+	if d := cmp.Diff(expectedForked, []string{waitFiles["b1"], waitFiles["c1"]}); d != "" {
+		t.Errorf("the branches don't wait for the files written by the fork step: %s", diff.PrintWantGot(d))
+	}
+	if waitFiles["a1"] != postFiles["parallel-1-fork"] {
+		t.Errorf("the first branch waits for %s rather than for the fork step", waitFiles["a1"])
+	}
+	// the join step runs after the last branch and waits for the files of the other branches itself
+	if waitFiles["parallel-1-join"] != postFiles["c2"] {
+		t.Errorf("the join step waits for %s rather than for the last branch", waitFiles["parallel-1-join"])
+	}
+	if !strings.Contains(steps[len(steps)-1].Script, "for f in "+strings.Join(expectedForked, " ")+"; do") {
+		t.Errorf("the join step doesn't wait for the files %v:\n%s", expectedForked, steps[len(steps)-1].Script)
+	}
+	for name, f := range postFiles {
+		if filepath.Dir(f) != toolsDir {
+			t.Errorf("step %s writes %s which isn't in %s", name, f, toolsDir)
+		}
+	}
+}
+
+type recordingPostWriter struct {
+	written []string
+}
+
+func (w *recordingPostWriter) Write(file string) {
+	w.written = append(w.written, file)
+}
+
+// TestEntrypointPostFiles pins that the entrypoint of Tekton v0.24 writes the file of a step which failed with the
+// .err suffix, which the join step relies on to tell that a branch failed.
+func TestEntrypointPostFiles(t *testing.T) {
+	w := &recordingPostWriter{}
+	e := entrypoint.Entrypointer{PostWriter: w}
+	e.WritePostFile(filepath.Join(toolsDir, "2"), nil)
+	e.WritePostFile(filepath.Join(toolsDir, "3"), errors.New("step failed"))
+	expected := []string{filepath.Join(toolsDir, "2"), filepath.Join(toolsDir, "3.err")}
+	if d := cmp.Diff(expected, w.written); d != "" {
+		t.Errorf("the entrypoint didn't write the expected files: %s", diff.PrintWantGot(d))
+	}
+}
+
+// TestJoinStep runs the fork and join scripts against files written like the entrypoint does: the file of a step
+// which is done is created empty, truncating the file written by the fork step, and the file of a step which failed
+// gets the .err suffix.
+func TestJoinStep(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is needed to run the scripts")
+	}
+	for _, tc := range []struct {
+		name       string
+		failed     bool
+		shouldFail bool
+	}{{
+		name: "all branches succeeded",
+	}, {
+		name:       "a branch failed",
+		failed:     true,
+		shouldFail: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := []string{filepath.Join(toolsDir, "2"), filepath.Join(toolsDir, "3")}
+			run := func(step v1beta1.Step) error {
+				script := strings.ReplaceAll(step.Script, toolsDir, dir)
+				return exec.Command(sh, "-c", script).Run()
+			}
+
+			if err := run(getForkStep("parallel-0", files)); err != nil {
+				t.Fatalf("the fork step failed: %v", err)
+			}
+			// the branches are done: the entrypoint creates the file of the last step of each, or its .err file
+			if err := os.WriteFile(filepath.Join(dir, "2"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			last := filepath.Join(dir, "3")
+			if tc.failed {
+				last += ".err"
+			}
+			if err := os.WriteFile(last, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := run(getJoinStep("parallel-0", files))
+			if tc.shouldFail && err == nil {
+				t.Errorf("the join step succeeded although a branch failed")
+			}
+			if !tc.shouldFail && err != nil {
+				t.Errorf("the join step failed: %v", err)
+			}
+		})
+	}
+}
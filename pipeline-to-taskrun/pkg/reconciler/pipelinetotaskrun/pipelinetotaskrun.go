@@ -35,6 +35,7 @@ import (
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
@@ -52,6 +53,7 @@ const (
 
 // Reconciler implements controller.Reconciler for Run resources.
 type Reconciler struct {
+	kubeClientSet     kubernetes.Interface
 	pipelineClientSet clientset.Interface
 	runLister         listersalpha.RunLister
 	taskRunLister     listers.TaskRunLister
@@ -136,12 +138,11 @@ func (r *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run) error {
 	}
 
 	// get the pipeline that we're going to be running in a taskrun
-	pSpec, err := getPipelineSpec(ctx, r.pipelineClientSet.TektonV1beta1(), run.Namespace, run.Spec.Ref.Name)
+	pSpec, err := getPipelineSpec(ctx, r.kubeClientSet, r.pipelineClientSet, run)
 	if err != nil {
 		run.Status.MarkRunFailed(ReasonRunFailedValidation,
 			"Pipeline couldn't be fetched - %v", err)
 		return controller.NewPermanentError(fmt.Errorf("run %s/%s is invalid because of %v", run.Namespace, run.Name, err))
-		return fmt.Errorf("couldn't fetch pipeline spec %s: %v", run.Spec.Ref.Name, err)
 	}
 	if err := validatePipelineSpec(pSpec); err != nil {
 		run.Status.MarkRunFailed(ReasonRunFailedValidation,
@@ -150,7 +151,7 @@ func (r *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run) error {
 	}
 
 	// get all the tasks we need to run this pipeline
-	taskSpecs, err := getTaskSpecs(ctx, r.pipelineClientSet.TektonV1beta1(), pSpec, run.Namespace)
+	taskSpecs, err := getTaskSpecs(ctx, r.kubeClientSet, r.pipelineClientSet, pSpec, run.Namespace, run.Spec.ServiceAccountName)
 	if err != nil {
 		run.Status.MarkRunFailed(ReasonRunFailedValidation,
 			"Not all of the pipeline's tasks could be fetched - %v", err)
@@ -161,6 +162,11 @@ func (r *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run) error {
 			"Not all tasks are valid - %v", err)
 		return fmt.Errorf("pipeline's tasks are invalid: %v", err)
 	}
+	if err := validateResultRefs(pSpec, taskSpecs); err != nil {
+		run.Status.MarkRunFailed(ReasonRunFailedValidation,
+			"Pipeline is invalid - %v", err)
+		return fmt.Errorf("pipeline spec for %s is invalid: %v", run.Spec.Ref.Name, err)
+	}
 
 	// use the tasks, the run and the pipeline to form a merged taskrun
 	tr, err = getMergedTaskRun(run, pSpec, taskSpecs)
@@ -185,9 +191,16 @@ func (r *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run) error {
 func updateRunStatus(ctx context.Context, run *v1alpha1.Run, taskRun *v1beta1.TaskRun) error {
 	logger := logging.FromContext(ctx)
 
+	if skippedTasks := getSkippedTasks(taskRun); len(skippedTasks) > 0 {
+		if err := run.Status.EncodeExtraFields(&PipelineToTaskRunStatus{SkippedTasks: skippedTasks}); err != nil {
+			return fmt.Errorf("couldn't report the skipped tasks - %v", err)
+		}
+	}
+
 	c := taskRun.GetStatusCondition().GetCondition(apis.ConditionSucceeded)
 	if c.IsTrue() {
 		logger.Infof("TaskRun created by Run %s/%s has succeeded", run.Namespace, run.Name)
+		run.Status.Results = getRunResults(taskRun)
 		run.Status.MarkRunSucceeded(c.Reason, c.Message)
 	} else if c.IsFalse() {
 		logger.Infof("TaskRun created by Run %s/%s has failed", run.Namespace, run.Name)
@@ -18,12 +18,19 @@ import (
 	"fmt"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/tektoncd/experimental/pipeline-to-taskrun/test"
+	"github.com/tektoncd/pipeline/pkg/apis/config"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
@@ -47,7 +54,16 @@ func getController(t *testing.T, d test.Data) (test.Assets, func()) {
 	ctx, cancel := context.WithCancel(ctx)
 	c, informers := test.SeedTestData(t, ctx, d)
 
-	configMapWatcher := configmap.NewStaticWatcher()
+	// the feature flags are the defaults unless the test provides the feature-flags ConfigMap
+	featureFlags := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: config.GetFeatureFlagsConfigName(), Namespace: "tekton-pipeline-to-taskrun"},
+	}
+	for _, cm := range d.ConfigMaps {
+		if cm.Name == featureFlags.Name {
+			featureFlags = cm
+		}
+	}
+	configMapWatcher := configmap.NewStaticWatcher(featureFlags)
 	ctl := NewController(ctx, configMapWatcher)
 
 	if la, ok := ctl.Reconciler.(reconciler.LeaderAware); ok {
@@ -462,6 +478,920 @@ func TestReconcileComplexPipeline(t *testing.T) {
 	}
 }
 
+func TestReconcileResults(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: make-result
+    taskSpec:
+      steps:
+      - name: make
+        image: ubuntu
+        script: echo -n 1.0 > $(results.version.path)
+      results:
+      - name: version
+        description: the version that was made
+  - name: use-result
+    params:
+    - name: version
+      value: $(tasks.make-result.results.version)
+    taskSpec:
+      params:
+      - name: version
+      steps:
+      - name: use
+        image: ubuntu
+        script: echo "using $(params.version)"
+  results:
+  - name: release
+    description: the version that was released
+    value: v$(tasks.make-result.results.version) "$HOME"
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRun := test.MustParseTaskRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  labels:
+    tekton.dev/run: run-with-pipeline
+  annotations:
+    pipelinetotaskrun.tekton.dev/pipeline-results: release
+  ownerReferences:
+  - apiVersion: tekton.dev/v1alpha1
+    kind: Run
+    name: run-with-pipeline
+    controller: true
+    blockOwnerDeletion: true
+spec:
+  serviceAccountName: default
+  params:
+  - name: use-result-version
+    value: $(cat /tekton/results/make-result-version)
+  taskSpec:
+    params:
+    - name: use-result-version
+    results:
+    - name: make-result-version
+      description: the version that was made
+    - name: release
+      description: the version that was released
+    steps:
+    - name: make-result-make
+      image: ubuntu
+      script: echo -n 1.0 > $(results.make-result-version.path)
+    - name: use-result-use
+      image: ubuntu
+      script: echo "using $(params.use-result-version)"
+    - name: pipeline-results
+      image: docker.io/library/busybox@sha256:c230832bd3b0be59a6c47ed64294f9ce71e91b327957920b6929a0caa8353140
+      script: |
+        #!/bin/sh
+        set -e
+        if [ -f /tekton/results/make-result-version ]; then
+          printf '%s' "v$(cat /tekton/results/make-result-version) \"\$HOME\"" > /tekton/results/release
+        fi
+`)
+
+	t.Run("create TaskRun", func(t *testing.T) {
+		ctx := context.Background()
+		names.TestingSeed()
+
+		d := test.Data{
+			Runs:      []*v1alpha1.Run{run},
+			Pipelines: []*v1beta1.Pipeline{pipeline},
+		}
+		testAssets, _ := getController(t, d)
+
+		if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+			t.Fatalf("couldn't reconcile run %v", err)
+		}
+
+		createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+		if createdTaskRun == nil {
+			t.Fatalf("A TaskRun should have been created but was not")
+		}
+		// string is the default type for params; the version loaded from yaml won't have this set explicitly
+		ignoreString := cmpopts.IgnoreFields(v1beta1.ParamSpec{}, "Type")
+		if d := cmp.Diff(expectedTaskRun.ObjectMeta, createdTaskRun.ObjectMeta); d != "" {
+			t.Errorf("TaskRun metadata was different from expected: %s", diff.PrintWantGot(d))
+		}
+		if d := cmp.Diff(expectedTaskRun.Spec, createdTaskRun.Spec, ignoreString); d != "" {
+			t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+		}
+	})
+
+	t.Run("propagate results", func(t *testing.T) {
+		ctx := context.Background()
+		names.TestingSeed()
+
+		taskRun := successful(expectedTaskRun)
+		taskRun.Status.TaskRunResults = []v1beta1.TaskRunResult{{
+			Name:  "make-result-version",
+			Value: "1.0",
+		}, {
+			Name:  "release",
+			Value: `v1.0 "$HOME"`,
+		}}
+		d := test.Data{
+			Runs:      []*v1alpha1.Run{run},
+			Pipelines: []*v1beta1.Pipeline{pipeline},
+			TaskRuns:  []*v1beta1.TaskRun{taskRun},
+		}
+		testAssets, _ := getController(t, d)
+
+		if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+			t.Fatalf("couldn't reconcile run %v", err)
+		}
+
+		reconciledRun, err := testAssets.Clients.Pipeline.TektonV1alpha1().Runs(run.Namespace).Get(ctx, run.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Error getting reconciled run from fake client: %s", err)
+		}
+		if err := checkRunCondition(t, reconciledRun, corev1.ConditionTrue, v1beta1.TaskRunReasonSuccessful.String(), ""); err != nil {
+			t.Fatalf("run is invalid")
+		}
+		expectedResults := []v1alpha1.RunResult{{
+			Name:  "release",
+			Value: `v1.0 "$HOME"`,
+		}}
+		if d := cmp.Diff(expectedResults, reconciledRun.Status.Results); d != "" {
+			t.Errorf("Run results were different from expected: %s", diff.PrintWantGot(d))
+		}
+	})
+}
+
+func TestReconcileSidecarsStepTemplatesAndVolumes(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: build
+    taskSpec:
+      stepTemplate:
+        env:
+        - name: DOCKER_HOST
+          value: tcp://localhost:2376
+      steps:
+      - name: build
+        image: docker
+        volumeMounts:
+        - name: dind-certs
+          mountPath: /certs/client
+        - name: cache
+          mountPath: /cache
+      sidecars:
+      - name: server
+        image: docker:dind
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - name: dind-certs
+          mountPath: /certs/client
+      volumes:
+      - name: dind-certs
+        emptyDir: {}
+      - name: cache
+        emptyDir: {}
+  - name: push
+    runAfter: [build]
+    taskSpec:
+      steps:
+      - name: push
+        image: docker
+        env:
+        - name: DOCKER_HOST
+          value: tcp://localhost:2376
+        volumeMounts:
+        - name: dind-certs
+          mountPath: /certs/client
+        - name: cache
+          mountPath: /cache
+      sidecars:
+      - name: server
+        image: docker:dind
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - name: dind-certs
+          mountPath: /certs/client
+      - name: redis
+        image: redis
+      volumes:
+      - name: dind-certs
+        emptyDir: {}
+      - name: cache
+        emptyDir: {}
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRun := test.MustParseTaskRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  labels:
+    tekton.dev/run: run-with-pipeline
+  annotations: {}
+  ownerReferences:
+  - apiVersion: tekton.dev/v1alpha1
+    kind: Run
+    name: run-with-pipeline
+    controller: true
+    blockOwnerDeletion: true
+spec:
+  serviceAccountName: default
+  taskSpec:
+    steps:
+    - name: build-build
+      image: docker
+      env:
+      - name: DOCKER_HOST
+        value: tcp://localhost:2376
+      volumeMounts:
+      - name: build-dind-certs
+        mountPath: /certs/client
+      - name: build-cache
+        mountPath: /cache
+    - name: push-push
+      image: docker
+      env:
+      - name: DOCKER_HOST
+        value: tcp://localhost:2376
+      volumeMounts:
+      - name: build-dind-certs
+        mountPath: /certs/client
+      - name: push-cache
+        mountPath: /cache
+    sidecars:
+    - name: build-server
+      image: docker:dind
+      securityContext:
+        privileged: true
+      volumeMounts:
+      - name: build-dind-certs
+        mountPath: /certs/client
+    - name: push-redis
+      image: redis
+    volumes:
+    - name: build-dind-certs
+      emptyDir: {}
+    - name: build-cache
+      emptyDir: {}
+    - name: push-cache
+      emptyDir: {}
+`)
+
+	ctx := context.Background()
+	names.TestingSeed()
+
+	d := test.Data{
+		Runs:      []*v1alpha1.Run{run},
+		Pipelines: []*v1beta1.Pipeline{pipeline},
+	}
+	testAssets, _ := getController(t, d)
+
+	if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+		t.Fatalf("couldn't reconcile run %v", err)
+	}
+
+	createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+	if createdTaskRun == nil {
+		t.Fatalf("A TaskRun should have been created but was not")
+	}
+	if d := cmp.Diff(expectedTaskRun.ObjectMeta, createdTaskRun.ObjectMeta); d != "" {
+		t.Errorf("TaskRun metadata was different from expected: %s", diff.PrintWantGot(d))
+	}
+	if d := cmp.Diff(expectedTaskRun.Spec, createdTaskRun.Spec); d != "" {
+		t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileParallel(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: clone
+    taskSpec:
+      steps:
+      - name: clone
+        image: alpine/git
+  - name: test
+    runAfter: [clone]
+    taskSpec:
+      steps:
+      - name: unit
+        image: golang
+      - name: integration
+        image: golang
+  - name: lint
+    runAfter: [clone]
+    taskSpec:
+      steps:
+      - name: lint
+        image: golangci/golangci-lint
+  - name: release
+    runAfter: [test, lint]
+    taskSpec:
+      steps:
+      - name: release
+        image: goreleaser/goreleaser
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  annotations:
+    pipelinetotaskrun.tekton.dev/parallel: "true"
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRun := test.MustParseTaskRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  labels:
+    tekton.dev/run: run-with-pipeline
+  annotations:
+    pipelinetotaskrun.tekton.dev/parallel: "true"
+  ownerReferences:
+  - apiVersion: tekton.dev/v1alpha1
+    kind: Run
+    name: run-with-pipeline
+    controller: true
+    blockOwnerDeletion: true
+spec:
+  serviceAccountName: default
+  taskSpec:
+    steps:
+    - name: clone-clone
+      image: alpine/git
+    - name: parallel-1-fork
+      image: docker.io/library/busybox@sha256:c230832bd3b0be59a6c47ed64294f9ce71e91b327957920b6929a0caa8353140
+      script: |
+        #!/bin/sh
+        set -e
+        echo started > /tekton/tools/3
+    - name: test-unit
+      image: golang
+    - name: test-integration
+      image: golang
+    - name: lint-lint
+      image: golangci/golangci-lint
+    - name: parallel-1-join
+      image: docker.io/library/busybox@sha256:c230832bd3b0be59a6c47ed64294f9ce71e91b327957920b6929a0caa8353140
+      script: |
+        #!/bin/sh
+        failed=0
+        for f in /tekton/tools/3; do
+          while [ -s "$f" ] && [ ! -f "$f.err" ]; do
+            sleep 1
+          done
+          if [ -f "$f.err" ]; then
+            failed=1
+          fi
+        done
+        exit $failed
+    - name: release-release
+      image: goreleaser/goreleaser
+`)
+
+	ctx := context.Background()
+	names.TestingSeed()
+
+	d := test.Data{
+		Runs:      []*v1alpha1.Run{run},
+		Pipelines: []*v1beta1.Pipeline{pipeline},
+	}
+	testAssets, _ := getController(t, d)
+
+	if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+		t.Fatalf("couldn't reconcile run %v", err)
+	}
+
+	createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+	if createdTaskRun == nil {
+		t.Fatalf("A TaskRun should have been created but was not")
+	}
+	if d := cmp.Diff(expectedTaskRun.ObjectMeta, createdTaskRun.ObjectMeta); d != "" {
+		t.Errorf("TaskRun metadata was different from expected: %s", diff.PrintWantGot(d))
+	}
+	if d := cmp.Diff(expectedTaskRun.Spec, createdTaskRun.Spec); d != "" {
+		t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileArrayParamsTimeoutsAndRetries(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  params:
+  - name: test-flags
+    type: array
+  tasks:
+  - name: test
+    timeout: "0h10m"
+    retries: 2
+    params:
+    - name: flags
+      value: ["$(params.test-flags)"]
+    taskSpec:
+      params:
+      - name: flags
+        type: array
+      steps:
+      - name: unit
+        image: golang
+        command: [go, test]
+        args: ["$(params.flags)", "./..."]
+      - name: report
+        image: alpine
+        timeout: "0h2m"
+        script: cat report.txt
+  - name: lint
+    runAfter: [test]
+    taskSpec:
+      params:
+      - name: linters
+        type: array
+        default: [gofmt, govet]
+      steps:
+      - name: lint
+        image: golangci/golangci-lint
+        command: [golangci-lint, run, --enable]
+        args: ["$(params.linters)"]
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  params:
+  - name: test-flags
+    value: [-v, -race]
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRun := test.MustParseTaskRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  labels:
+    tekton.dev/run: run-with-pipeline
+  annotations: {}
+  ownerReferences:
+  - apiVersion: tekton.dev/v1alpha1
+    kind: Run
+    name: run-with-pipeline
+    controller: true
+    blockOwnerDeletion: true
+spec:
+  serviceAccountName: default
+  taskSpec:
+    steps:
+    - name: test-unit
+      image: golang
+      args: [-v, -race, ./...]
+      timeout: 8m0s
+      script: |
+        #!/bin/sh
+        mkdir -p /tekton/home/.pipelinetotaskrun
+        while true; do
+          'go' 'test' "$@" && exit 0
+          code=$?
+          retried=$(cat /tekton/home/.pipelinetotaskrun/retries-test 2>/dev/null || echo 0)
+          if [ $retried -ge 2 ]; then exit $code; fi
+          echo $((retried + 1)) > /tekton/home/.pipelinetotaskrun/retries-test
+          echo "step test-unit failed with exit code $code, retrying ($((retried + 1))/2)"
+        done
+    - name: test-report
+      image: alpine
+      timeout: 2m0s
+      script: |
+        #!/bin/sh
+        mkdir -p /tekton/home/.pipelinetotaskrun
+        cat > /tekton/home/.pipelinetotaskrun/script-test-1 <<'PIPELINETOTASKRUN_SCRIPT_EOF'
+        #!/bin/sh
+        set -xe
+        cat report.txt
+        PIPELINETOTASKRUN_SCRIPT_EOF
+        chmod +x /tekton/home/.pipelinetotaskrun/script-test-1
+        while true; do
+          /tekton/home/.pipelinetotaskrun/script-test-1 "$@" && exit 0
+          code=$?
+          retried=$(cat /tekton/home/.pipelinetotaskrun/retries-test 2>/dev/null || echo 0)
+          if [ $retried -ge 2 ]; then exit $code; fi
+          echo $((retried + 1)) > /tekton/home/.pipelinetotaskrun/retries-test
+          echo "step test-report failed with exit code $code, retrying ($((retried + 1))/2)"
+        done
+    - name: lint-lint
+      image: golangci/golangci-lint
+      command: [golangci-lint, run, --enable]
+      args: [gofmt, govet]
+`)
+
+	ctx := context.Background()
+	names.TestingSeed()
+
+	d := test.Data{
+		Runs:      []*v1alpha1.Run{run},
+		Pipelines: []*v1beta1.Pipeline{pipeline},
+	}
+	testAssets, _ := getController(t, d)
+
+	if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+		t.Fatalf("couldn't reconcile run %v", err)
+	}
+
+	createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+	if createdTaskRun == nil {
+		t.Fatalf("A TaskRun should have been created but was not")
+	}
+	if d := cmp.Diff(expectedTaskRun.ObjectMeta, createdTaskRun.ObjectMeta); d != "" {
+		t.Errorf("TaskRun metadata was different from expected: %s", diff.PrintWantGot(d))
+	}
+	if d := cmp.Diff(expectedTaskRun.Spec, createdTaskRun.Spec); d != "" {
+		t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileParallelRetriesOfUnnamedSteps(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: test
+    retries: 1
+    taskSpec:
+      steps:
+      - image: golang
+        script: go test ./...
+  - name: lint
+    retries: 1
+    taskSpec:
+      steps:
+      - image: golangci/golangci-lint
+        script: golangci-lint run
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+  annotations:
+    pipelinetotaskrun.tekton.dev/parallel: "true"
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+
+	ctx := context.Background()
+	names.TestingSeed()
+
+	d := test.Data{
+		Runs:      []*v1alpha1.Run{run},
+		Pipelines: []*v1beta1.Pipeline{pipeline},
+	}
+	testAssets, _ := getController(t, d)
+
+	if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+		t.Fatalf("couldn't reconcile run %v", err)
+	}
+
+	createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+	if createdTaskRun == nil {
+		t.Fatalf("A TaskRun should have been created but was not")
+	}
+	// the unnamed steps of the pipeline tasks run at the same time, so they must not write their scripts to the same file
+	var paths []string
+	for _, step := range createdTaskRun.Spec.TaskSpec.Steps {
+		for _, line := range strings.Split(step.Script, "\n") {
+			if strings.HasPrefix(line, "cat > ") {
+				paths = append(paths, strings.Fields(line)[2])
+			}
+		}
+	}
+	expectedPaths := []string{
+		"/tekton/home/.pipelinetotaskrun/script-test-0",
+		"/tekton/home/.pipelinetotaskrun/script-lint-0",
+	}
+	if d := cmp.Diff(expectedPaths, paths); d != "" {
+		t.Errorf("Retried scripts were written to different files than expected: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileClusterTasks(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: lint
+    taskRef:
+      name: lint
+      kind: ClusterTask
+`)
+	clusterTask := test.MustParseClusterTask(t, `
+metadata:
+  name: lint
+spec:
+  steps:
+  - name: lint
+    image: golangci/golangci-lint
+`)
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRunSpec := test.MustParseTaskRun(t, `
+spec:
+  serviceAccountName: default
+  taskSpec:
+    steps:
+    - name: lint-lint
+      image: golangci/golangci-lint
+`).Spec
+
+	ctx := context.Background()
+	names.TestingSeed()
+
+	d := test.Data{
+		Runs:         []*v1alpha1.Run{run},
+		Pipelines:    []*v1beta1.Pipeline{pipeline},
+		ClusterTasks: []*v1beta1.ClusterTask{clusterTask},
+	}
+	testAssets, _ := getController(t, d)
+
+	if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+		t.Fatalf("couldn't reconcile run %v", err)
+	}
+
+	createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+	if createdTaskRun == nil {
+		t.Fatalf("A TaskRun should have been created but was not")
+	}
+	if d := cmp.Diff(expectedTaskRunSpec, createdTaskRun.Spec); d != "" {
+		t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestReconcileBundles(t *testing.T) {
+	// the bundle is an empty image pushed to a registry which only lives as long as the test, so fetching the Pipeline
+	// or the Task from it fails but tells us that it was looked up in the bundle, unless bundles are disabled
+	r := httptest.NewServer(registry.New())
+	defer r.Close()
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		t.Fatalf("couldn't parse the url of the registry: %v", err)
+	}
+	bundle := u.Host + "/catalog:v1"
+	ref, err := name.ParseReference(bundle)
+	if err != nil {
+		t.Fatalf("couldn't parse the reference of the bundle: %v", err)
+	}
+	if err := remote.Write(ref, empty.Image); err != nil {
+		t.Fatalf("couldn't push the bundle: %v", err)
+	}
+
+	pipelineFromBundle := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+    bundle: `+bundle+`
+`)
+	tasksFromBundle := test.MustParsePipeline(t, `
+metadata:
+  name: pipeline
+  namespace: foo
+spec:
+  tasks:
+  - name: build
+    taskRef:
+      name: build
+      bundle: `+bundle+`
+`)
+	runWithPipeline := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+
+	for _, tc := range []struct {
+		name            string
+		bundlesEnabled  bool
+		expectedErrText []string
+		pipeline        *v1beta1.Pipeline
+		run             *v1alpha1.Run
+	}{{
+		name:           "pipeline from a bundle",
+		bundlesEnabled: true,
+		expectedErrText: []string{
+			"Pipeline couldn't be fetched",
+			"could not find object in image",
+		},
+		run: pipelineFromBundle,
+	}, {
+		name:           "tasks from a bundle",
+		bundlesEnabled: true,
+		expectedErrText: []string{
+			"couldn't fetch taskspec for build",
+			"could not find object in image",
+		},
+		pipeline: tasksFromBundle,
+		run:      runWithPipeline,
+	}, {
+		name: "pipeline from a bundle with bundles disabled",
+		expectedErrText: []string{
+			"Pipeline couldn't be fetched",
+			"Pipeline pipeline is referenced from the bundle " + bundle + " but the enable-tekton-oci-bundles feature flag is off",
+		},
+		run: pipelineFromBundle,
+	}, {
+		name: "tasks from a bundle with bundles disabled",
+		expectedErrText: []string{
+			"couldn't fetch taskspec for build",
+			"Task build is referenced from the bundle " + bundle + " but the enable-tekton-oci-bundles feature flag is off",
+		},
+		pipeline: tasksFromBundle,
+		run:      runWithPipeline,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			d := test.Data{
+				Runs: []*v1alpha1.Run{tc.run},
+				// the credentials for the registry are looked up in the service account of the Run
+				ServiceAccounts: []*corev1.ServiceAccount{{
+					ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "foo"},
+				}},
+				ConfigMaps: []*corev1.ConfigMap{{
+					ObjectMeta: metav1.ObjectMeta{Name: config.GetFeatureFlagsConfigName(), Namespace: "tekton-pipeline-to-taskrun"},
+					Data:       map[string]string{"enable-tekton-oci-bundles": fmt.Sprint(tc.bundlesEnabled)},
+				}},
+			}
+			if tc.pipeline != nil {
+				d.Pipelines = []*v1beta1.Pipeline{tc.pipeline}
+			}
+			testAssets, _ := getController(t, d)
+
+			if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(tc.run)); err == nil {
+				t.Fatalf("expected error fetching from the bundle but got none")
+			}
+
+			reconciledRun, err := testAssets.Clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			condition := reconciledRun.Status.GetCondition(apis.ConditionSucceeded)
+			if condition == nil || !condition.IsFalse() {
+				t.Fatalf("expected run to be marked failed but condition was %v", condition)
+			}
+			for _, text := range tc.expectedErrText {
+				if !strings.Contains(condition.Message, text) {
+					t.Errorf("expected failure message to contain %q but was %q", text, condition.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileWhenExpressionsAndFinally(t *testing.T) {
+	pipeline := test.MustParsePipeline(t, fromFile(t, "testdata/when-finally-pipeline.yaml"))
+	run := test.MustParseRun(t, `
+metadata:
+  name: run-with-pipeline
+  namespace: foo
+spec:
+  params:
+  - name: mode
+    value: lenient
+  ref:
+    apiVersion: tekton.dev/v1alpha1
+    kind: PipelineToTaskRun
+    name: pipeline
+`)
+	expectedTaskRun := test.MustParseTaskRun(t, fromFile(t, "testdata/expected-when-finally-taskrun.yaml"))
+
+	t.Run("create TaskRun", func(t *testing.T) {
+		ctx := context.Background()
+		names.TestingSeed()
+
+		d := test.Data{
+			Runs:      []*v1alpha1.Run{run},
+			Pipelines: []*v1beta1.Pipeline{pipeline},
+		}
+		testAssets, _ := getController(t, d)
+
+		if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+			t.Fatalf("couldn't reconcile run %v", err)
+		}
+
+		createdTaskRun := getCreatedTaskRun(testAssets.Clients)
+		if createdTaskRun == nil {
+			t.Fatalf("A TaskRun should have been created but was not")
+		}
+		if d := cmp.Diff(expectedTaskRun.ObjectMeta, createdTaskRun.ObjectMeta); d != "" {
+			t.Errorf("TaskRun metadata was different from expected: %s", diff.PrintWantGot(d))
+		}
+		// string is the default type for params; the version loaded from yaml won't have this set explicitly
+		ignoreString := cmpopts.IgnoreFields(v1beta1.ParamSpec{}, "Type")
+		if d := cmp.Diff(expectedTaskRun.Spec, createdTaskRun.Spec, ignoreString); d != "" {
+			t.Errorf("TaskRun spec was different from expected: %s", diff.PrintWantGot(d))
+		}
+	})
+
+	t.Run("report skipped tasks", func(t *testing.T) {
+		ctx := context.Background()
+		names.TestingSeed()
+
+		taskRun := failed(expectedTaskRun)
+		taskRun.Status.TaskRunResults = []v1beta1.TaskRunResult{{
+			Name:  "build-status",
+			Value: "broken",
+		}, {
+			Name:  "pipelinetotaskrun-skipped-tasks",
+			Value: "deploy\n",
+		}}
+		d := test.Data{
+			Runs:      []*v1alpha1.Run{run},
+			Pipelines: []*v1beta1.Pipeline{pipeline},
+			TaskRuns:  []*v1beta1.TaskRun{taskRun},
+		}
+		testAssets, _ := getController(t, d)
+
+		if err := testAssets.Controller.Reconciler.Reconcile(ctx, getRunName(run)); err != nil {
+			t.Fatalf("couldn't reconcile run %v", err)
+		}
+
+		reconciledRun, err := testAssets.Clients.Pipeline.TektonV1alpha1().Runs(run.Namespace).Get(ctx, run.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Error getting reconciled run from fake client: %s", err)
+		}
+		if err := checkRunCondition(t, reconciledRun, corev1.ConditionFalse, v1beta1.TaskRunReasonFailed.String(), ""); err != nil {
+			t.Fatalf("run is invalid")
+		}
+		status := PipelineToTaskRunStatus{}
+		if err := reconciledRun.Status.DecodeExtraFields(&status); err != nil {
+			t.Fatalf("couldn't decode the extra fields of the run status: %v", err)
+		}
+		expectedStatus := PipelineToTaskRunStatus{
+			SkippedTasks: []v1beta1.SkippedTask{{Name: "lint"}, {Name: "publish-lint"}, {Name: "deploy"}},
+		}
+		if d := cmp.Diff(expectedStatus, status); d != "" {
+			t.Errorf("Run status was different from expected: %s", diff.PrintWantGot(d))
+		}
+	})
+}
+
 func TestReconcileUnsupported(t *testing.T) {
 	run := `
 metadata:
@@ -479,8 +1409,8 @@ spec:
 		pipeline        *v1beta1.Pipeline
 		run             *v1alpha1.Run
 	}{{
-		name:            "pipeline results - TODO (community#447)",
-		expectedErrText: []string{"results"},
+		name:            "pipeline results using a result that isn't declared",
+		expectedErrText: []string{"pipeline result amazing-result", "result other of make-result"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -495,12 +1425,12 @@ spec:
       - name: amazing
   results:
   - name: amazing-result
-    value: $(tasks.make-result.results.amazing)
+    value: $(tasks.make-result.results.other)
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "array params - TODO (community#447)",
-		expectedErrText: []string{"array"},
+		name:            "pipeline results colliding with task results",
+		expectedErrText: []string{"pipeline result make-result-amazing collides"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -508,43 +1438,19 @@ metadata:
 spec:
   tasks:
   - name: make-result
-    taskSpec:
-      params:
-      - name: foo
-        type: array
-`),
-		run: test.MustParseRun(t, run),
-	}, {
-		name:            "workspaces with subpaths  in run binding - TODO(community#447)",
-		expectedErrText: []string{"spec.workspaces.subpath"},
-		pipeline: test.MustParsePipeline(t, `
-metadata:
-  name: pipeline
-  namespace: foo
-spec:
-  tasks:
-  - name: use-workspace
     taskSpec:
       steps:
       - image: ubuntu
-      workspaces:
-      - name: task-workspace
-    workspaces:
-    - name: task-workspace
-      workspace: pipeline-workspace
-  workspaces:
-  - name: pipeline-workspace
-`),
-		run: test.MustParseRun(t, run+`
-  workspaces:
-    - name: pipeline-workspace
-      persistentVolumeClaim:
-        claimName: pvc
-      subPath: some/subdir
+      results:
+      - name: amazing
+  results:
+  - name: make-result-amazing
+    value: $(tasks.make-result.results.amazing)
 `),
+		run: test.MustParseRun(t, run),
 	}, {
-		name:            "workspaces with subpaths pipelinetasks - TODO(community#447)",
-		expectedErrText: []string{"subpath"},
+		name:            "workspaces with mountPaths colliding with pipeline workspaces",
+		expectedErrText: []string{"task-workspace", "/workspace/pipeline-workspace", "workspace pipeline-workspace of the Pipeline"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -557,10 +1463,10 @@ spec:
       - image: ubuntu
       workspaces:
       - name: task-workspace
+        mountPath: /workspace/pipeline-workspace/
     workspaces:
     - name: task-workspace
       workspace: pipeline-workspace
-      subPath: some/subdir
   workspaces:
   - name: pipeline-workspace
 `),
@@ -571,8 +1477,8 @@ spec:
         claimName: pvc
 `),
 	}, {
-		name:            "workspaces with mountpaths - TODO(community#447)",
-		expectedErrText: []string{"mountPath"},
+		name:            "workspaces with mountPaths colliding with volume mounts",
+		expectedErrText: []string{"task-workspace", "/cache", "volume cache of step build"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -582,10 +1488,17 @@ spec:
   - name: use-workspace
     taskSpec:
       steps:
-      - image: ubuntu
+      - name: build
+        image: ubuntu
+        volumeMounts:
+        - name: cache
+          mountPath: /cache
+      volumes:
+      - name: cache
+        emptyDir: {}
       workspaces:
       - name: task-workspace
-        mountPath: /foo/bar/
+        mountPath: /cache
     workspaces:
     - name: task-workspace
       workspace: pipeline-workspace
@@ -599,8 +1512,8 @@ spec:
         claimName: pvc
 `),
 	}, {
-		name:            "workspaces with readonly - TODO(community#447)",
-		expectedErrText: []string{"readOnly"},
+		name:            "workspaces with mountPaths reserved for Tekton",
+		expectedErrText: []string{"task-workspace", "reserved"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -613,7 +1526,7 @@ spec:
       - image: ubuntu
       workspaces:
       - name: task-workspace
-        readOnly: true
+        mountPath: /tekton/results
     workspaces:
     - name: task-workspace
       workspace: pipeline-workspace
@@ -676,8 +1589,8 @@ spec:
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "results between tasks",
-		expectedErrText: []string{"result"},
+		name:            "results between tasks that aren't declared",
+		expectedErrText: []string{"pipeline task use-result", "result amazing of make-result"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -688,8 +1601,6 @@ spec:
     taskSpec:
       steps:
       - image: ubuntu
-      results:
-      - name: amazing
   - name: use-result
     params:
     - name: foo
@@ -702,40 +1613,27 @@ spec:
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "task with sidecar",
-		expectedErrText: []string{"sidecar"},
+		name:            "pipeline task timeouts shorter than the timeouts of the steps",
+		expectedErrText: []string{"timeout", "some-task", "1m30s"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
   namespace: foo
 spec:
   tasks:
-  - name: use-sidecar
+  - name: some-task
+    timeout: "0h1m30s"
     taskSpec:
       steps:
       - image: ubuntu
-      sidecars:
+        timeout: "0h1m"
       - image: ubuntu
+        timeout: "0h1m"
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "tasks as bundles",
-		expectedErrText: []string{"bundle"},
-		pipeline: test.MustParsePipeline(t, `
-metadata:
-  name: pipeline
-  namespace: foo
-spec:
-  tasks:
-  - name: use-bundle
-    taskRef:
-      name: echo-task
-      bundle: docker.com/myrepo/mycatalog
-`),
-		run: test.MustParseRun(t, run),
-	}, {
-		name:            "pipeline task timeouts",
-		expectedErrText: []string{"timeout"},
+		name:            "retries of steps without a script or a command",
+		expectedErrText: []string{"retry", "some-task", "neither a script nor a command"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -743,31 +1641,42 @@ metadata:
 spec:
   tasks:
   - name: some-task
-    timeout: "0h1m30s"
+    retries: 1
     taskSpec:
       steps:
       - image: ubuntu
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "retries",
-		expectedErrText: []string{"retries"},
+		name:            "when expressions using results with steps which aren't scripts",
+		expectedErrText: []string{"only shell scripts", "use-result"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
   namespace: foo
 spec:
   tasks:
-  - name: some-task
-    retries: 1
+  - name: make-result
+    taskSpec:
+      steps:
+      - image: ubuntu
+        script: echo -n bar > $(results.foo.path)
+      results:
+      - name: foo
+  - name: use-result
+    runAfter: [make-result]
+    when:
+      - input: "$(tasks.make-result.results.foo)"
+        operator: in
+        values: ["bar"]
     taskSpec:
       steps:
       - image: ubuntu
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "when expressions - new approach required to support",
-		expectedErrText: []string{"when"},
+		name:            "finally tasks with steps which aren't scripts",
+		expectedErrText: []string{"only shell scripts", "finally"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -775,18 +1684,23 @@ metadata:
 spec:
   tasks:
   - name: some-task
-    when:
-      - input: "foo"
-        operator: in
-        values: ["bar"]    
     taskSpec:
       steps:
       - image: ubuntu
+        script: echo hello
+  finally:
+  - name: some-finally-task
+    taskSpec:
+      steps:
+      - image: ubuntu
+        script: |
+          #!/usr/bin/env python3
+          print("goodbye")
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "finally tasks - new approach required to support",
-		expectedErrText: []string{"finally"},
+		name:            "finally tasks using the execution status of tasks",
+		expectedErrText: []string{"execution status", "some-finally-task"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
   name: pipeline
@@ -797,15 +1711,22 @@ spec:
     taskSpec:
       steps:
       - image: ubuntu
+        script: echo hello
   finally:
   - name: some-finally-task
+    params:
+    - name: status
+      value: $(tasks.some-task.status)
     taskSpec:
+      params:
+      - name: status
       steps:
       - image: ubuntu
+        script: echo $(params.status)
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "parallel tasks (two, starting immediately) - new approach required to support",
+		name:            "parallel tasks (two, starting immediately) without opting in",
 		expectedErrText: []string{"parallel"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
@@ -824,7 +1745,7 @@ spec:
 `),
 		run: test.MustParseRun(t, run),
 	}, {
-		name:            "parallel tasks (branch after first task) - new approach required to support",
+		name:            "parallel tasks (branch after first task) without opting in",
 		expectedErrText: []string{"parallel"},
 		pipeline: test.MustParsePipeline(t, `
 metadata:
@@ -909,26 +1830,6 @@ spec:
           type: git
       steps:
       - image: ubuntu
-`),
-		run: test.MustParseRun(t, run),
-	}, {
-		name: "volumes and volume mounts - unlikely to support",
-		pipeline: test.MustParsePipeline(t, `
-metadata:
-  name: pipeline
-  namespace: foo
-spec:
-  tasks:
-  - name: use-volume
-    taskSpec:
-      steps:
-      - image: ubuntu
-        volumeMounts:
-        - name: my-volume-mount
-          mountPath: /foo/bar/baz
-      volumes:
-      - name: my-volume-mount
-        empty-dir: {}
 `),
 		run: test.MustParseRun(t, run),
 	}, {
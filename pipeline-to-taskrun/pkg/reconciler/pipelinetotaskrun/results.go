@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// shellImage is the image of the steps that the custom task adds to the resulting TaskRun
+	shellImage = "docker.io/library/busybox@sha256:c230832bd3b0be59a6c47ed64294f9ce71e91b327957920b6929a0caa8353140"
+
+	// pipelineResultsStepName is the name of the step which writes the Pipeline level results
+	pipelineResultsStepName = "pipeline-results"
+
+	// pipelineResultsAnnotation lists the results of the resulting TaskRun which are the Pipeline level results, and
+	// which are propagated to the Run
+	pipelineResultsAnnotation = "pipelinetotaskrun.tekton.dev/pipeline-results"
+)
+
+// resultRefRegex matches the references to the results of pipeline tasks, e.g. $(tasks.grab-source.results.commit)
+var resultRefRegex = regexp.MustCompile(`\$\(tasks\.([^.)]+)\.results\.([^.)]+)\)`)
+
+// getResultPath returns the path of the file that the steps of the pipeline task write its result to, once the
+// result has been namespaced.
+func getResultPath(ptaskName, resultName string) string {
+	return filepath.Join(pipeline.DefaultResultPath, namespaceName(ptaskName, resultName))
+}
+
+// readResultRefs will replace all the result references in s with a command substitution reading the file the
+// result was written to. Since all the steps run in the same pod, the result is available as soon as the step
+// writing it is done, but only to steps that evaluate the value in a shell, e.g. in a script.
+func readResultRefs(s string) string {
+	return resultRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		match := resultRefRegex.FindStringSubmatch(ref)
+		return fmt.Sprintf("$(cat %s)", getResultPath(match[1], match[2]))
+	})
+}
+
+// getPipelineResults will return the declarations of the Pipeline level results in the resulting TaskRun and the
+// step which writes them, if the Pipeline declares any. A Pipeline level result is only written if all the results it
+// references were written, which is consistent with how a PipelineRun omits results it can't resolve.
+func getPipelineResults(results []v1beta1.PipelineResult) ([]v1beta1.TaskResult, *v1beta1.Step) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	var declared []v1beta1.TaskResult
+	script := []string{"#!/bin/sh", "set -e"}
+	for _, r := range results {
+		declared = append(declared, v1beta1.TaskResult{
+			Name:        r.Name,
+			Description: r.Description,
+		})
+
+		value, files := getShellValue(r.Value)
+		write := fmt.Sprintf("printf '%%s' %s > %s", value, filepath.Join(pipeline.DefaultResultPath, r.Name))
+		if len(files) == 0 {
+			script = append(script, write)
+		} else {
+			script = append(script, fmt.Sprintf("if %s; then", getFilesExist(files)), "  "+write, "fi")
+		}
+	}
+
+	return declared, &v1beta1.Step{
+		Container: corev1.Container{
+			Name:  pipelineResultsStepName,
+			Image: shellImage,
+		},
+		Script: strings.Join(script, "\n") + "\n",
+	}
+}
+
+// getShellValue will return s as a double quoted string for a shell script, in which the result references are
+// replaced with reading the files the results were written to, along with these files.
+func getShellValue(s string) (string, []string) {
+	var files []string
+	var value strings.Builder
+	last := 0
+	for _, match := range resultRefRegex.FindAllStringSubmatchIndex(s, -1) {
+		path := getResultPath(s[match[2]:match[3]], s[match[4]:match[5]])
+		files = append(files, path)
+		value.WriteString(escapeDoubleQuoted(s[last:match[0]]))
+		value.WriteString(fmt.Sprintf("$(cat %s)", path))
+		last = match[1]
+	}
+	value.WriteString(escapeDoubleQuoted(s[last:]))
+	return `"` + value.String() + `"`, files
+}
+
+// getFilesExist will return the condition of a shell script checking that all the files exist.
+func getFilesExist(files []string) string {
+	var conditions []string
+	for _, f := range files {
+		conditions = append(conditions, fmt.Sprintf("[ -f %s ]", f))
+	}
+	return strings.Join(conditions, " && ")
+}
+
+// escapeDoubleQuoted escapes s so that it is used as is between double quotes in a shell script.
+func escapeDoubleQuoted(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`).Replace(s)
+}
+
+// getRunResults will return the Pipeline level results from the results of the TaskRun that executed the Pipeline.
+func getRunResults(taskRun *v1beta1.TaskRun) []v1alpha1.RunResult {
+	names := taskRun.Annotations[pipelineResultsAnnotation]
+	if names == "" {
+		return nil
+	}
+	pipelineResults := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		pipelineResults[name] = true
+	}
+
+	var results []v1alpha1.RunResult
+	for _, r := range taskRun.Status.TaskRunResults {
+		if pipelineResults[r.Name] {
+			results = append(results, v1alpha1.RunResult{
+				Name:  r.Name,
+				Value: r.Value,
+			})
+		}
+	}
+	return results
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+const (
+	// defaultScriptPreamble is what Tekton runs the scripts without a shebang with
+	defaultScriptPreamble = "#!/bin/sh\nset -xe\n"
+
+	// scriptDelimiter ends the heredoc which the retry wrapper writes the original script of a step with
+	scriptDelimiter = "PIPELINETOTASKRUN_SCRIPT_EOF"
+)
+
+// ApplyRetries will return a new PipelineTaskInfo in which each step is wrapped in a script that runs it again when it
+// fails, until the step succeeds or it was retried the number of times the pipeline task can be retried. The steps
+// share the retries, like the retries of a TaskRun: once a failing step used them up, the next step to fail won't be
+// retried. Since a step can't rerun the steps before it, only the step which failed is retried.
+func (pti PipelineTaskInfo) ApplyRetries(retries int) (PipelineTaskInfo, error) {
+	if retries <= 0 {
+		return pti, nil
+	}
+
+	updatedPti := pti
+	updatedPti.Steps = make([]v1beta1.Step, len(pti.Steps))
+	for i, step := range pti.Steps {
+		script, err := getRetryScript(pti.Name, i, step, retries)
+		if err != nil {
+			return pti, err
+		}
+		step.Script = script
+		step.Command = nil
+		updatedPti.Steps[i] = step
+	}
+	return updatedPti, nil
+}
+
+// getRetryScript will return the script which writes the script of the i-th step of the pipeline task, or runs the
+// command of the step, and retries it as long as the pipeline task has retries left. The args of the step are passed
+// on to either. The script is written to a file named after the pipeline task and the index of the step rather than
+// the step, since unnamed steps of pipeline tasks which run in parallel would write to the same file.
+func getRetryScript(pTaskName string, i int, step v1beta1.Step, retries int) (string, error) {
+	var run []string
+	switch {
+	case step.Script != "":
+		original := step.Script
+		if !strings.HasPrefix(original, "#!") {
+			original = defaultScriptPreamble + original
+		}
+		if !strings.HasSuffix(original, "\n") {
+			original += "\n"
+		}
+		if strings.Contains(original, scriptDelimiter) {
+			return "", fmt.Errorf("step %s can't be retried since its script contains %s", step.Name, scriptDelimiter)
+		}
+		path := filepath.Join(markersDir, fmt.Sprintf("script-%s-%d", pTaskName, i))
+		run = []string{
+			fmt.Sprintf("cat > %s <<'%s'", path, scriptDelimiter),
+			original + scriptDelimiter,
+			fmt.Sprintf("chmod +x %s", path),
+			path,
+		}
+	case len(step.Command) > 0:
+		var quoted []string
+		for _, c := range step.Command {
+			quoted = append(quoted, "'"+strings.ReplaceAll(c, "'", `'\''`)+"'")
+		}
+		run = []string{strings.Join(quoted, " ")}
+	default:
+		return "", fmt.Errorf("step %s can't be retried since it has neither a script nor a command", step.Name)
+	}
+
+	counter := filepath.Join(markersDir, "retries-"+pTaskName)
+	script := append([]string{"#!/bin/sh", fmt.Sprintf("mkdir -p %s", markersDir)}, run[:len(run)-1]...)
+	return strings.Join(append(script,
+		"while true; do",
+		fmt.Sprintf(`  %s "$@" && exit 0`, run[len(run)-1]),
+		"  code=$?",
+		fmt.Sprintf("  retried=$(cat %s 2>/dev/null || echo 0)", counter),
+		fmt.Sprintf("  if [ $retried -ge %d ]; then exit $code; fi", retries),
+		fmt.Sprintf("  echo $((retried + 1)) > %s", counter),
+		fmt.Sprintf(`  echo "step %s failed with exit code $code, retrying ($((retried + 1))/%d)"`, step.Name, retries),
+		"done",
+	), "\n") + "\n", nil
+}
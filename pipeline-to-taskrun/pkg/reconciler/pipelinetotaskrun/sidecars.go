@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// DedupeSidecars will return a new PipelineTaskInfo without the sidecars which are the same as sidecars that were
+// already merged, since all the sidecars of the resulting TaskRun run for its whole duration anyway. The volumes that
+// the removed sidecars mount are replaced with the volumes that the merged sidecars mount, so that the steps of the
+// pipeline task share these volumes with the merged sidecars (e.g. the certificates of a docker daemon).
+func (pti PipelineTaskInfo) DedupeSidecars(mergedSidecars []v1beta1.Sidecar, mergedVolumes []corev1.Volume) PipelineTaskInfo {
+	updatedPti := PipelineTaskInfo{
+		Name:                pti.Name,
+		TaskDeclaredParams:  pti.TaskDeclaredParams,
+		ProvidedParamValues: pti.ProvidedParamValues,
+		Results:             pti.Results,
+	}
+
+	renamed := map[string]string{}
+	var sidecars []v1beta1.Sidecar
+	for _, sidecar := range pti.Sidecars {
+		duplicate := false
+		for _, merged := range mergedSidecars {
+			if volumes, ok := isSameSidecar(sidecar, pti.Volumes, merged, mergedVolumes); ok {
+				for oldName, newName := range volumes {
+					renamed[oldName] = newName
+				}
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			sidecars = append(sidecars, sidecar)
+		}
+	}
+
+	for _, v := range pti.Volumes {
+		if _, ok := renamed[v.Name]; !ok {
+			updatedPti.Volumes = append(updatedPti.Volumes, v)
+		}
+	}
+	updatedPti.Steps, updatedPti.Sidecars = renameVolumeMounts(pti.Steps, sidecars, renamed)
+	return updatedPti
+}
+
+// isSameSidecar will return true if the sidecars are the same apart from their names and the names of the volumes
+// they mount, along with the mapping from the names of the volumes mounted by sidecar to those mounted by merged.
+func isSameSidecar(sidecar v1beta1.Sidecar, volumes []corev1.Volume, merged v1beta1.Sidecar, mergedVolumes []corev1.Volume) (map[string]string, bool) {
+	if len(sidecar.VolumeMounts) != len(merged.VolumeMounts) {
+		return nil, false
+	}
+
+	renamed := map[string]string{}
+	for i := range sidecar.VolumeMounts {
+		name, mergedName := sidecar.VolumeMounts[i].Name, merged.VolumeMounts[i].Name
+		volume, declared := findVolume(volumes, name)
+		mergedVolume, mergedDeclared := findVolume(mergedVolumes, mergedName)
+		if declared != mergedDeclared {
+			return nil, false
+		}
+		if !declared {
+			// the volumes of workspaces aren't declared by the Tasks and aren't namespaced
+			if name != mergedName {
+				return nil, false
+			}
+			continue
+		}
+		if !equality.Semantic.DeepEqual(volume.VolumeSource, mergedVolume.VolumeSource) {
+			return nil, false
+		}
+		renamed[name] = mergedName
+	}
+
+	unnamed, mergedUnnamed := sidecar.DeepCopy(), merged.DeepCopy()
+	unnamed.Name, mergedUnnamed.Name = "", ""
+	for i := range unnamed.VolumeMounts {
+		unnamed.VolumeMounts[i].Name, mergedUnnamed.VolumeMounts[i].Name = "", ""
+	}
+	return renamed, equality.Semantic.DeepEqual(unnamed, mergedUnnamed)
+}
+
+func findVolume(volumes []corev1.Volume, name string) (corev1.Volume, bool) {
+	for _, v := range volumes {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return corev1.Volume{}, false
+}
@@ -18,11 +18,14 @@ package pipelinetotaskrun
 
 import (
 	"fmt"
+	"sort"
+
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/names"
 	"github.com/tektoncd/pipeline/pkg/reconciler/pipelinerun/resources"
 	resources2 "github.com/tektoncd/pipeline/pkg/reconciler/taskrun/resources"
 	"github.com/tektoncd/pipeline/pkg/substitution"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // PipelineTaskInfo holds all of the info needed to run a pipeline task
@@ -36,11 +39,17 @@ type PipelineTaskInfo struct {
 	// ProvidedParamValues are the parameter values that were provided in the pipeline task
 	ProvidedParamValues []v1beta1.Param
 
-	// Steps are the steps the Task declared
+	// Steps are the steps the Task declared, with the Task's step template applied
 	Steps []v1beta1.Step
 
 	// Results are the results the Task declared
 	Results []v1beta1.TaskResult
+
+	// Sidecars are the sidecars the Task declared
+	Sidecars []v1beta1.Sidecar
+
+	// Volumes are the volumes the Task declared
+	Volumes []corev1.Volume
 }
 
 // NewPipelineTaskInfo will construct an object that will hold all the info needed to run the pipeline task
@@ -49,12 +58,19 @@ func NewPipelineTaskInfo(pTask v1beta1.PipelineTask, taskSpecs map[string]*v1bet
 	if !ok {
 		return PipelineTaskInfo{}, fmt.Errorf("expected taskspec wasn't present in map for %q", pTask.Name)
 	}
+	// the step template of each Task only applies to its own steps, so it's applied before the steps are merged
+	steps, err := v1beta1.MergeStepsWithStepTemplate(taskSpec.StepTemplate, taskSpec.Steps)
+	if err != nil {
+		return PipelineTaskInfo{}, fmt.Errorf("couldn't apply the step template of %q: %v", pTask.Name, err)
+	}
 	return PipelineTaskInfo{
 		Name:                pTask.Name,
 		TaskDeclaredParams:  taskSpec.Params,
 		ProvidedParamValues: pTask.Params,
-		Steps:               taskSpec.Steps,
+		Steps:               steps,
 		Results:             taskSpec.Results,
+		Sidecars:            taskSpec.Sidecars,
+		Volumes:             taskSpec.Volumes,
 	}, nil
 }
 
@@ -73,6 +89,11 @@ func getStepName(ptaskName, stepName string) string {
 	return stepName
 }
 
+func getVolumeName(ptaskName, volumeName string) string {
+	// make sure the newly generated name is still a valid volume name
+	return names.SimpleNameGenerator.RestrictLength(namespaceName(ptaskName, volumeName))
+}
+
 // applyPipelineLevelParams will do variable replacement for all params in pTasks which are using Pipeline level
 // params as their values.
 func applyPipelineLevelParams(pTasks []v1beta1.PipelineTask, runSpecParams []v1beta1.Param) []v1beta1.PipelineTask {
@@ -88,15 +109,41 @@ func applyPipelineLevelParams(pTasks []v1beta1.PipelineTask, runSpecParams []v1b
 
 // NamespaceParams will return a new PipelineTaskInfo in which the names of all the declared params and
 // provided values are updated such that the param name is prefaced by the name of the pipeline task. All uses of the
-// params will be updated in the steps as well.
+// params will be updated in the steps as well. Array params are expanded into the args and commands of the steps
+// instead, using the provided values or else their defaults.
 func (pti PipelineTaskInfo) NamespaceParams() PipelineTaskInfo {
 	updatedPti := PipelineTaskInfo{
 		Name:    pti.Name,
 		Results: pti.Results,
 	}
 
+	// array params can only be used where they can be expanded (i.e. in args and commands), so they are expanded right
+	// away instead of being declared in the resulting task spec
+	provided := map[string]v1beta1.ArrayOrString{}
+	for _, p := range pti.ProvidedParamValues {
+		provided[p.Name] = p.Value
+	}
+	arrayReplacements := map[string][]string{}
+	for _, p := range pti.TaskDeclaredParams {
+		if !isArrayParam(p) {
+			continue
+		}
+		if value, ok := provided[p.Name]; ok {
+			arrayReplacements[fmt.Sprintf("params.%s", p.Name)] = value.ArrayVal
+		} else if p.Default != nil {
+			arrayReplacements[fmt.Sprintf("params.%s", p.Name)] = p.Default.ArrayVal
+		}
+	}
+	expanded := func(name string) bool {
+		_, ok := arrayReplacements[fmt.Sprintf("params.%s", name)]
+		return ok
+	}
+
 	// namespace the params by renaming them
 	for _, p := range pti.TaskDeclaredParams {
+		if expanded(p.Name) {
+			continue
+		}
 		pName := namespaceName(pti.Name, p.Name)
 		updatedPti.TaskDeclaredParams = append(updatedPti.TaskDeclaredParams, v1beta1.ParamSpec{
 			Name:        pName,
@@ -108,6 +155,9 @@ func (pti PipelineTaskInfo) NamespaceParams() PipelineTaskInfo {
 
 	// get the values for each renamed param
 	for _, p := range pti.ProvidedParamValues {
+		if expanded(p.Name) {
+			continue
+		}
 		pName := namespaceName(pti.Name, p.Name)
 		updatedPti.ProvidedParamValues = append(updatedPti.ProvidedParamValues, v1beta1.Param{
 			Name:  pName,
@@ -118,6 +168,9 @@ func (pti PipelineTaskInfo) NamespaceParams() PipelineTaskInfo {
 	// create a mapping of the replacements that can be used to update the steps
 	replacements := map[string]string{}
 	for _, p := range pti.TaskDeclaredParams {
+		if expanded(p.Name) {
+			continue
+		}
 		pName := namespaceName(pti.Name, p.Name)
 		// this is the format that ApplyReplacements expects the replacements to arrive in; it infers the surrounding
 		// dollar sign and brackets
@@ -132,8 +185,60 @@ func (pti PipelineTaskInfo) NamespaceParams() PipelineTaskInfo {
 		updatedPti.ProvidedParamValues[i].Value.StringVal = substitution.ApplyReplacements(updatedPti.ProvidedParamValues[i].Value.StringVal, replacements)
 	}
 
-	updatedTaskSpec := resources2.ApplyReplacements(&v1beta1.TaskSpec{Steps: pti.Steps}, replacements, nil)
+	updatedTaskSpec := resources2.ApplyReplacements(&v1beta1.TaskSpec{Steps: pti.Steps, Sidecars: pti.Sidecars, Volumes: pti.Volumes}, replacements, arrayReplacements)
+	updatedPti.Steps = updatedTaskSpec.Steps
+	updatedPti.Sidecars = updatedTaskSpec.Sidecars
+	updatedPti.Volumes = updatedTaskSpec.Volumes
+	return updatedPti
+}
+
+func isArrayParam(p v1beta1.ParamSpec) bool {
+	// the type is inferred from the default when it isn't declared
+	return p.Type == v1beta1.ParamTypeArray || (p.Type == "" && p.Default != nil && p.Default.Type == v1beta1.ParamTypeArray)
+}
+
+// NamespaceResults will return a new PipelineTaskInfo in which the names of all the declared results are updated such
+// that the result name is prefaced by the name of the pipeline task. All uses of the results' paths will be updated in
+// the steps as well, and all references to the results of other pipeline tasks in the provided values are replaced
+// with reading the files those results were written to.
+func (pti PipelineTaskInfo) NamespaceResults() PipelineTaskInfo {
+	updatedPti := PipelineTaskInfo{
+		Name:               pti.Name,
+		TaskDeclaredParams: pti.TaskDeclaredParams,
+	}
+
+	// namespace the results by renaming them
+	for _, r := range pti.Results {
+		updatedPti.Results = append(updatedPti.Results, v1beta1.TaskResult{
+			Name:        namespaceName(pti.Name, r.Name),
+			Description: r.Description,
+		})
+	}
+
+	// the results of previous pipeline tasks have been written by the time the steps of this one run
+	for _, p := range pti.ProvidedParamValues {
+		updatedParam := p.DeepCopy()
+		// not yet supporting array types
+		updatedParam.Value.StringVal = readResultRefs(p.Value.StringVal)
+		updatedPti.ProvidedParamValues = append(updatedPti.ProvidedParamValues, *updatedParam)
+	}
+
+	// create a mapping of the replacements that can be used to update the steps
+	replacements := map[string]string{}
+	for _, r := range pti.Results {
+		// this is the format that ApplyReplacements expects the replacements to arrive in; it infers the surrounding
+		// dollar sign and brackets
+		existing := fmt.Sprintf("results.%s.path", r.Name)
+		// we'll replace the resulting wrapped existing result reference with the variable replacement syntax for
+		// our renamed version
+		renamed := fmt.Sprintf("$(results.%s.path)", namespaceName(pti.Name, r.Name))
+		replacements[existing] = renamed
+	}
+
+	updatedTaskSpec := resources2.ApplyReplacements(&v1beta1.TaskSpec{Steps: pti.Steps, Sidecars: pti.Sidecars, Volumes: pti.Volumes}, replacements, nil)
 	updatedPti.Steps = updatedTaskSpec.Steps
+	updatedPti.Sidecars = updatedTaskSpec.Sidecars
+	updatedPti.Volumes = updatedTaskSpec.Volumes
 	return updatedPti
 }
 
@@ -145,6 +250,8 @@ func (pti PipelineTaskInfo) NamespaceSteps() PipelineTaskInfo {
 		TaskDeclaredParams:  pti.TaskDeclaredParams,
 		ProvidedParamValues: pti.ProvidedParamValues,
 		Results:             pti.Results,
+		Sidecars:            pti.Sidecars,
+		Volumes:             pti.Volumes,
 	}
 	for _, step := range pti.Steps {
 		updatedStep := step.DeepCopy()
@@ -196,11 +303,135 @@ func (pti PipelineTaskInfo) RenameWorkspaces(newMapping map[string]string) Pipel
 
 	updatedTaskSpec := resources2.ApplyReplacements(
 		&v1beta1.TaskSpec{
-			Params: pti.TaskDeclaredParams,
-			Steps:  pti.Steps,
+			Params:   pti.TaskDeclaredParams,
+			Steps:    pti.Steps,
+			Sidecars: pti.Sidecars,
+			Volumes:  pti.Volumes,
 		}, replacements, nil)
 	updatedPti.TaskDeclaredParams = updatedTaskSpec.Params
 	updatedPti.Steps = updatedTaskSpec.Steps
+	updatedPti.Sidecars = updatedTaskSpec.Sidecars
+	updatedPti.Volumes = updatedTaskSpec.Volumes
 
 	return updatedPti
 }
+
+// MountWorkspaces will return a new PipelineTaskInfo in which the steps and sidecars mount the volumes of the Pipeline
+// level workspaces as described by mounts, which is keyed by the names of the workspaces the Task declared. All the
+// references to the paths of these workspaces are updated to the paths they are mounted to, and all the other
+// references to them are updated to the Pipeline level workspaces.
+func (pti PipelineTaskInfo) MountWorkspaces(mounts map[string]WorkspaceMount) PipelineTaskInfo {
+	updatedPti := PipelineTaskInfo{
+		Name:    pti.Name,
+		Results: pti.Results,
+		Volumes: pti.Volumes,
+	}
+
+	// create a mapping of the replacements that can be used to update the steps
+	replacements := map[string]string{}
+	var names []string
+	for name, mount := range mounts {
+		names = append(names, name)
+		replacements[fmt.Sprintf("workspaces.%s.path", name)] = mount.VolumeMount.MountPath
+		for _, variable := range []string{"bound", "claim", "volume"} {
+			replacements[fmt.Sprintf("workspaces.%s.%s", name, variable)] = fmt.Sprintf("$(workspaces.%s.%s)", mount.Workspace, variable)
+		}
+	}
+	sort.Strings(names)
+
+	for _, p := range pti.ProvidedParamValues {
+		updatedParam := p.DeepCopy()
+		// not yet supporting array types
+		updatedParam.Value.StringVal = substitution.ApplyReplacements(p.Value.StringVal, replacements)
+		updatedPti.ProvidedParamValues = append(updatedPti.ProvidedParamValues, *updatedParam)
+	}
+
+	updatedTaskSpec := resources2.ApplyReplacements(
+		&v1beta1.TaskSpec{
+			Params:   pti.TaskDeclaredParams,
+			Steps:    pti.Steps,
+			Sidecars: pti.Sidecars,
+		}, replacements, nil)
+	updatedPti.TaskDeclaredParams = updatedTaskSpec.Params
+	updatedPti.Steps = updatedTaskSpec.Steps
+	updatedPti.Sidecars = updatedTaskSpec.Sidecars
+
+	// the volume mounts are added once the replacements are done, since they use the Pipeline level workspaces
+	for _, name := range names {
+		for i := range updatedPti.Steps {
+			updatedPti.Steps[i].VolumeMounts = append(updatedPti.Steps[i].VolumeMounts, mounts[name].VolumeMount)
+		}
+		for i := range updatedPti.Sidecars {
+			updatedPti.Sidecars[i].VolumeMounts = append(updatedPti.Sidecars[i].VolumeMounts, mounts[name].VolumeMount)
+		}
+	}
+	return updatedPti
+}
+
+// NamespaceSidecars will return a new PipelineTaskInfo in which the names of all sidecars are updated so that they are
+// prefaced by the name of the pipeline task.
+func (pti PipelineTaskInfo) NamespaceSidecars() PipelineTaskInfo {
+	updatedPti := PipelineTaskInfo{
+		Name:                pti.Name,
+		TaskDeclaredParams:  pti.TaskDeclaredParams,
+		ProvidedParamValues: pti.ProvidedParamValues,
+		Steps:               pti.Steps,
+		Results:             pti.Results,
+		Volumes:             pti.Volumes,
+	}
+	for _, sidecar := range pti.Sidecars {
+		updatedSidecar := sidecar.DeepCopy()
+		updatedSidecar.Name = getStepName(pti.Name, updatedSidecar.Name)
+		updatedPti.Sidecars = append(updatedPti.Sidecars, *updatedSidecar)
+	}
+	return updatedPti
+}
+
+// NamespaceVolumes will return a new PipelineTaskInfo in which the names of all volumes are updated so that they are
+// prefaced by the name of the pipeline task. All the volume mounts of the steps and sidecars will be updated as well.
+func (pti PipelineTaskInfo) NamespaceVolumes() PipelineTaskInfo {
+	updatedPti := PipelineTaskInfo{
+		Name:                pti.Name,
+		TaskDeclaredParams:  pti.TaskDeclaredParams,
+		ProvidedParamValues: pti.ProvidedParamValues,
+		Results:             pti.Results,
+	}
+
+	renamed := map[string]string{}
+	for _, v := range pti.Volumes {
+		updatedVolume := v.DeepCopy()
+		updatedVolume.Name = getVolumeName(pti.Name, v.Name)
+		renamed[v.Name] = updatedVolume.Name
+		updatedPti.Volumes = append(updatedPti.Volumes, *updatedVolume)
+	}
+
+	updatedPti.Steps, updatedPti.Sidecars = renameVolumeMounts(pti.Steps, pti.Sidecars, renamed)
+	return updatedPti
+}
+
+// renameVolumeMounts will return copies of steps and sidecars in which the volume mounts of the keys in renamed are
+// updated to mount the values instead. Volume mounts of volumes which aren't in renamed, e.g. the volumes of workspaces,
+// are left as is.
+func renameVolumeMounts(steps []v1beta1.Step, sidecars []v1beta1.Sidecar, renamed map[string]string) ([]v1beta1.Step, []v1beta1.Sidecar) {
+	var updatedSteps []v1beta1.Step
+	for _, step := range steps {
+		updatedStep := step.DeepCopy()
+		for i, vm := range updatedStep.VolumeMounts {
+			if newName, ok := renamed[vm.Name]; ok {
+				updatedStep.VolumeMounts[i].Name = newName
+			}
+		}
+		updatedSteps = append(updatedSteps, *updatedStep)
+	}
+	var updatedSidecars []v1beta1.Sidecar
+	for _, sidecar := range sidecars {
+		updatedSidecar := sidecar.DeepCopy()
+		for i, vm := range updatedSidecar.VolumeMounts {
+			if newName, ok := renamed[vm.Name]; ok {
+				updatedSidecar.VolumeMounts[i].Name = newName
+			}
+		}
+		updatedSidecars = append(updatedSidecars, *updatedSidecar)
+	}
+	return updatedSteps, updatedSidecars
+}
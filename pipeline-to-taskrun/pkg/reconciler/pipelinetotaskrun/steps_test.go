@@ -5,7 +5,10 @@ import (
 	"github.com/tektoncd/experimental/pipeline-to-taskrun/test"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
+	"time"
 )
 
 func parsePipelineTaskInfo(t *testing.T, name, taskDeclaredParams, providedParamValues, steps, results string) PipelineTaskInfo {
@@ -362,6 +365,89 @@ func TestNamespaceParams(t *testing.T) {
 	}
 }
 
+func TestNamespaceResults(t *testing.T) {
+	for _, tc := range []struct {
+		Name             string
+		PipelineTaskInfo PipelineTaskInfo
+		Expected         PipelineTaskInfo
+	}{{
+		Name: "grab-source writes results",
+		PipelineTaskInfo: parsePipelineTaskInfo(t, "grab-source", `
+  - name: url
+    description: "git url to clone"
+`, `
+    - name: url
+      value: https://github.com/tektoncd/chains
+`, `
+  - name: clone
+    image: some-git-image
+    script: |
+      #!/usr/bin/env bash
+      set -xe
+      echo -n "$RESULT_SHA" > $(results.commit.path)
+      echo -n "$(params.url)" > $(results.url.path)
+`, `
+  - name: commit
+    description: "The precise commit SHA that was fetched by this Task"
+  - name: url
+    description: "The precise URL that was fetched by this Task"
+`),
+		Expected: parsePipelineTaskInfo(t, "grab-source", `
+  - name: url
+    description: "git url to clone"
+`, `
+    - name: url
+      value: https://github.com/tektoncd/chains
+`, `
+  - name: clone
+    image: some-git-image
+    script: |
+      #!/usr/bin/env bash
+      set -xe
+      echo -n "$RESULT_SHA" > $(results.grab-source-commit.path)
+      echo -n "$(params.url)" > $(results.grab-source-url.path)
+`, `
+  - name: grab-source-commit
+    description: "The precise commit SHA that was fetched by this Task"
+  - name: grab-source-url
+    description: "The precise URL that was fetched by this Task"
+`),
+	}, {
+		Name: "run-tests uses results",
+		PipelineTaskInfo: parsePipelineTaskInfo(t, "run-tests", `
+  - name: revision
+    description: "revision under test"
+`, `
+    - name: revision
+      value: $(tasks.grab-source.results.commit) from $(tasks.grab-source.results.url) $(params.url)
+`, `
+  - name: unit-test
+    image: "docker.io/library/golang"
+    script: |
+      echo "testing $(params.revision)"
+`, ""),
+		Expected: parsePipelineTaskInfo(t, "run-tests", `
+  - name: revision
+    description: "revision under test"
+`, `
+    - name: revision
+      value: $(cat /tekton/results/grab-source-commit) from $(cat /tekton/results/grab-source-url) $(params.url)
+`, `
+  - name: unit-test
+    image: "docker.io/library/golang"
+    script: |
+      echo "testing $(params.revision)"
+`, ""),
+	}} {
+		t.Run(tc.Name, func(t *testing.T) {
+			updatedPti := tc.PipelineTaskInfo.NamespaceResults()
+			if d := cmp.Diff(tc.Expected, updatedPti); d != "" {
+				t.Errorf("didn't get expected updated info. Diff: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
 func TestNamespaceSteps(t *testing.T) {
 	pti := parsePipelineTaskInfo(t, "grab-source", `
   - name: grab-source-url
@@ -401,6 +487,67 @@ func TestNamespaceSteps(t *testing.T) {
 	}
 }
 
+func TestNamespaceSidecarsAndVolumes(t *testing.T) {
+	task := test.MustParseTask(t, `
+spec:
+  steps:
+  - name: build
+    image: docker
+    volumeMounts:
+    - name: certs
+      mountPath: /certs/client
+    - name: ws-abcde
+      mountPath: /workspace/source
+  sidecars:
+  - name: server
+    image: docker:dind
+    volumeMounts:
+    - name: certs
+      mountPath: /certs/client
+  - image: redis
+  volumes:
+  - name: certs
+    emptyDir: {}
+`)
+	expectedTask := test.MustParseTask(t, `
+spec:
+  steps:
+  - name: build
+    image: docker
+    volumeMounts:
+    - name: build-image-certs
+      mountPath: /certs/client
+    - name: ws-abcde
+      mountPath: /workspace/source
+  sidecars:
+  - name: build-image-server
+    image: docker:dind
+    volumeMounts:
+    - name: build-image-certs
+      mountPath: /certs/client
+  - image: redis
+  volumes:
+  - name: build-image-certs
+    emptyDir: {}
+`)
+	pti := PipelineTaskInfo{
+		Name:     "build-image",
+		Steps:    task.Spec.Steps,
+		Sidecars: task.Spec.Sidecars,
+		Volumes:  task.Spec.Volumes,
+	}
+	expected := PipelineTaskInfo{
+		Name:     "build-image",
+		Steps:    expectedTask.Spec.Steps,
+		Sidecars: expectedTask.Spec.Sidecars,
+		Volumes:  expectedTask.Spec.Volumes,
+	}
+	updatedPti := pti.NamespaceSidecars().NamespaceVolumes()
+	if d := cmp.Diff(expected, updatedPti); d != "" {
+		t.Errorf("didn't get expected updated info. Diff: %s", diff.PrintWantGot(d))
+	}
+}
+
 func TestRenameWorkspaces(t *testing.T) {
 	pti := parsePipelineTaskInfo(t, "grab-source", `
   - name: grab-source-url
@@ -446,3 +593,105 @@ func TestRenameWorkspaces(t *testing.T) {
 		t.Errorf("didn't get expected updated info. Diff: %s", diff.PrintWantGot(d))
 	}
 }
+
+func TestMountWorkspaces(t *testing.T) {
+	pti := parsePipelineTaskInfo(t, "grab-source", "", `
+    - name: grab-source-dir
+      value: "$(workspaces.foobar.path)/src"
+`, `
+  - name: grab-source-clone
+    image: some-git-image
+    script: |
+      echo $(workspaces.foobar.bound)
+      echo $(workspaces.foobar.claim)
+      echo $(workspaces.foobar.volume)
+      cd $(workspaces.foobar.path)
+      cd $(workspaces.other.path)
+`, "")
+	expected := parsePipelineTaskInfo(t, "grab-source", "", `
+    - name: grab-source-dir
+      value: "/workspace/grab-source-foobar/src"
+`, `
+  - name: grab-source-clone
+    image: some-git-image
+    script: |
+      echo $(workspaces.the-ultimate-volume.bound)
+      echo $(workspaces.the-ultimate-volume.claim)
+      echo $(workspaces.the-ultimate-volume.volume)
+      cd /workspace/grab-source-foobar
+      cd $(workspaces.other.path)
+    volumeMounts:
+    - name: $(workspaces.the-ultimate-volume.volume)
+      mountPath: /workspace/grab-source-foobar
+      subPath: src
+      readOnly: true
+`, "")
+	mounts := map[string]WorkspaceMount{
+		"foobar": {
+			Workspace: "the-ultimate-volume",
+			VolumeMount: corev1.VolumeMount{
+				Name:      "$(workspaces.the-ultimate-volume.volume)",
+				MountPath: "/workspace/grab-source-foobar",
+				SubPath:   "src",
+				ReadOnly:  true,
+			},
+		},
+	}
+	updatedPti := pti.MountWorkspaces(mounts)
+	if d := cmp.Diff(expected, updatedPti); d != "" {
+		t.Errorf("didn't get expected updated info. Diff: %s", diff.PrintWantGot(d))
+	}
+}
+
+func TestApplyTimeout(t *testing.T) {
+	pti := parsePipelineTaskInfo(t, "build", "", "", `
+  - name: build-fetch
+    image: alpine
+    timeout: "0h1m"
+  - name: build-compile
+    image: golang
+  - name: build-package
+    image: alpine
+`, "")
+	for _, tc := range []struct {
+		name     string
+		timeout  *metav1.Duration
+		expected []*metav1.Duration
+	}{{
+		name:     "no timeout",
+		expected: []*metav1.Duration{{Duration: time.Minute}, nil, nil},
+	}, {
+		name:     "split between the steps without a timeout",
+		timeout:  &metav1.Duration{Duration: 5 * time.Minute},
+		expected: []*metav1.Duration{{Duration: time.Minute}, {Duration: 2 * time.Minute}, {Duration: 2 * time.Minute}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			updatedPti, err := pti.ApplyTimeout(tc.timeout)
+			if err != nil {
+				t.Fatalf("didn't expect error applying timeout but got %v", err)
+			}
+			var timeouts []*metav1.Duration
+			for _, step := range updatedPti.Steps {
+				timeouts = append(timeouts, step.Timeout)
+			}
+			if d := cmp.Diff(tc.expected, timeouts); d != "" {
+				t.Errorf("didn't get expected timeouts. Diff: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestApplyTimeoutInvalid(t *testing.T) {
+	pti := parsePipelineTaskInfo(t, "build", "", "", `
+  - name: build-fetch
+    image: alpine
+    timeout: "0h1m"
+  - name: build-compile
+    image: golang
+`, "")
+	for _, timeout := range []time.Duration{30 * time.Second, time.Minute} {
+		if _, err := pti.ApplyTimeout(&metav1.Duration{Duration: timeout}); err == nil {
+			t.Errorf("expected error applying timeout %s but got none", timeout)
+		}
+	}
+}
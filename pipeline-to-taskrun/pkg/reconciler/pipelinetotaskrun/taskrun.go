@@ -2,15 +2,21 @@ package pipelinetotaskrun
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 )
 
 func getMergedTaskRun(run *v1alpha1.Run, pSpec *v1beta1.PipelineSpec, taskSpecs map[string]*v1beta1.TaskSpec) (*v1beta1.TaskRun, error) {
-	sequence, err := putTasksInOrder(pSpec.Tasks)
+	levels, err := getLevels(run, pSpec)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't find valid order for tasks: %v", err)
 	}
+	var sequence []v1beta1.PipelineTask
+	for _, level := range levels {
+		sequence = append(sequence, level...)
+	}
 
 	// we'll be declaring and mapping one workspace per provided workspace and eliminating the indirection added by the
 	// workspaces declared by the Task. This will make sure that is volume claim templates are used, only one volume
@@ -51,23 +57,158 @@ func getMergedTaskRun(run *v1alpha1.Run, pSpec *v1beta1.PipelineSpec, taskSpecs
 		})
 	}
 
-	for _, pTask := range sequenceWithAppliedParams {
+	hasFinally := len(pSpec.Finally) > 0
+	skipped := map[string]bool{}
+	guarded := map[string]bool{}
+	var skippedNames []string
+	taskSteps := map[string][]v1beta1.Step{}
+	for i, pTask := range sequenceWithAppliedParams {
+		isFinally := i >= len(sequence)-len(pSpec.Finally)
+
+		// when expressions which don't use results, and results of pipeline tasks which are skipped, tell us right
+		// away whether the pipeline task is skipped; otherwise this is known only once the previous steps ran
+		wes, allowed := evaluateWhenExpressions(pTask.WhenExpressions)
+		needsGuard := len(wes) > 0
+		for _, ref := range v1beta1.PipelineTaskResultRefs(&pTask) {
+			if skipped[ref.PipelineTask] {
+				allowed = false
+			}
+			if guarded[ref.PipelineTask] || isFinally {
+				needsGuard = true
+			}
+		}
+		if !allowed {
+			skipped[pTask.Name] = true
+			skippedNames = append(skippedNames, pTask.Name)
+			continue
+		}
+		guarded[pTask.Name] = needsGuard
+
 		pti, err := NewPipelineTaskInfo(pTask, taskSpecs)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't construct object to hold pipeline task info for %s: %v", pTask.Name, err)
 		}
 
 		pti = pti.NamespaceParams()
+		pti = pti.NamespaceResults()
 		pti = pti.NamespaceSteps()
-		pti = pti.RenameWorkspaces(newWorkspaceMapping[pTask.Name])
+		pti = pti.NamespaceSidecars()
+		pti = pti.NamespaceVolumes()
+		// the workspaces which the steps mount themselves aren't renamed, since they need more than a new name
+		mounts, err := getWorkspaceMounts(pTask, taskSpecs[pTask.Name], pSpec.Workspaces, run.Spec.Workspaces)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't mount the workspaces of %s: %v", pTask.Name, err)
+		}
+		renamed := map[string]string{}
+		for oldName, newName := range newWorkspaceMapping[pTask.Name] {
+			if _, ok := mounts[oldName]; !ok {
+				renamed[oldName] = newName
+			}
+		}
+		pti = pti.RenameWorkspaces(renamed)
+		pti = pti.MountWorkspaces(mounts)
+		pti = pti.DedupeSidecars(tr.Spec.TaskSpec.Sidecars, tr.Spec.TaskSpec.Volumes)
+		if pti, err = pti.ApplyRetries(pTask.Retries); err != nil {
+			return nil, fmt.Errorf("couldn't retry %s: %v", pTask.Name, err)
+		}
+		if pti, err = pti.ApplyTimeout(pTask.Timeout); err != nil {
+			return nil, fmt.Errorf("couldn't apply the timeout of %s: %v", pTask.Name, err)
+		}
+
+		var steps []v1beta1.Step
+		if needsGuard {
+			steps = append(steps, getGuardStep(pTask, wes, getPrelude(pTask.Name, getStepName(pTask.Name, "when"), false, isFinally, hasFinally)))
+		}
+		for _, step := range pti.Steps {
+			if prelude := getPrelude(pTask.Name, step.Name, needsGuard, isFinally, hasFinally); prelude != "" {
+				if step.Script, err = addPrelude(step, prelude); err != nil {
+					return nil, fmt.Errorf("only shell scripts can be skipped or continue on error, but %s is using when expressions or the Pipeline has finally tasks: %v", pTask.Name, err)
+				}
+			}
+			steps = append(steps, step)
+		}
 
 		tr.Spec.Params = append(tr.Spec.Params, pti.ProvidedParamValues...)
 		tr.Spec.TaskSpec.Params = append(tr.Spec.TaskSpec.Params, pti.TaskDeclaredParams...)
-		tr.Spec.TaskSpec.Steps = append(tr.Spec.TaskSpec.Steps, pti.Steps...)
-		// we don't support mapping results but we need to declare them in order for steps that write
-		// results to be able to write to the dirs they expect
+		taskSteps[pTask.Name] = steps
 		tr.Spec.TaskSpec.Results = append(tr.Spec.TaskSpec.Results, pti.Results...)
+		tr.Spec.TaskSpec.Sidecars = append(tr.Spec.TaskSpec.Sidecars, pti.Sidecars...)
+		tr.Spec.TaskSpec.Volumes = append(tr.Spec.TaskSpec.Volumes, pti.Volumes...)
+	}
+
+	// the pipeline tasks of a level which weren't skipped run one after another, or in parallel if the Run opted in
+	for i, level := range levels {
+		var branches [][]v1beta1.Step
+		for _, pTask := range level {
+			if steps := taskSteps[pTask.Name]; len(steps) > 0 {
+				branches = append(branches, steps)
+			}
+		}
+		if len(branches) > 0 {
+			name := fmt.Sprintf("parallel-%d", i)
+			tr.Spec.TaskSpec.Steps = append(tr.Spec.TaskSpec.Steps, getParallelSteps(name, len(tr.Spec.TaskSpec.Steps), branches)...)
+		}
+	}
+
+	// the pipeline tasks skipped at this point are reported via an annotation, and those skipped at runtime via a result
+	delete(tr.Annotations, skippedTasksAnnotation)
+	if len(skippedNames) > 0 {
+		tr.Annotations[skippedTasksAnnotation] = strings.Join(skippedNames, ",")
+	}
+	for _, isGuarded := range guarded {
+		if isGuarded {
+			tr.Spec.TaskSpec.Results = append(tr.Spec.TaskSpec.Results, v1beta1.TaskResult{
+				Name:        skippedTasksResult,
+				Description: "The pipeline tasks that were skipped",
+			})
+			break
+		}
+	}
+
+	// the pipeline level results are written by an additional step once all the tasks are done, and are then
+	// propagated from the results of the TaskRun to the results of the Run
+	delete(tr.Annotations, pipelineResultsAnnotation)
+	pipelineResults, pipelineResultsStep := getPipelineResults(pSpec.Results)
+	if pipelineResultsStep != nil {
+		var names []string
+		for _, r := range pipelineResults {
+			names = append(names, r.Name)
+		}
+		tr.Annotations[pipelineResultsAnnotation] = strings.Join(names, ",")
+		tr.Spec.TaskSpec.Results = append(tr.Spec.TaskSpec.Results, pipelineResults...)
+		tr.Spec.TaskSpec.Steps = append(tr.Spec.TaskSpec.Steps, *pipelineResultsStep)
+	}
+
+	// a pipeline task failing didn't fail the TaskRun so that the finally tasks would run, so it fails now
+	if hasFinally {
+		tr.Spec.TaskSpec.Steps = append(tr.Spec.TaskSpec.Steps, getPipelineStatusStep())
 	}
 
 	return tr, nil
 }
+
+// getLevels will return the pipeline tasks grouped in the levels they run in. Unless the Run opted in to running the
+// pipeline tasks in parallel, each level holds one pipeline task. The finally tasks run after all the other pipeline
+// tasks, in parallel or in the order they are declared.
+func getLevels(run *v1alpha1.Run, pSpec *v1beta1.PipelineSpec) ([][]v1beta1.PipelineTask, error) {
+	if isParallel(run) {
+		levels, err := putTasksInLevels(pSpec.Tasks)
+		if err != nil {
+			return nil, err
+		}
+		if len(pSpec.Finally) > 0 {
+			levels = append(levels, pSpec.Finally)
+		}
+		return levels, nil
+	}
+
+	sequence, err := putTasksInOrder(pSpec.Tasks)
+	if err != nil {
+		return nil, err
+	}
+	var levels [][]v1beta1.PipelineTask
+	for _, pTask := range append(sequence, pSpec.Finally...) {
+		levels = append(levels, []v1beta1.PipelineTask{pTask})
+	}
+	return levels, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplyTimeout will return a new PipelineTaskInfo in which the timeout of the pipeline task is split between its steps,
+// so that the timeouts of its steps add up to it. The steps which have their own timeout keep it, and what is left of
+// the timeout of the pipeline task is split evenly between the other steps.
+func (pti PipelineTaskInfo) ApplyTimeout(timeout *metav1.Duration) (PipelineTaskInfo, error) {
+	if timeout == nil || timeout.Duration == 0 || len(pti.Steps) == 0 {
+		return pti, nil
+	}
+
+	remaining := timeout.Duration
+	var withoutTimeout int
+	for _, step := range pti.Steps {
+		if step.Timeout != nil && step.Timeout.Duration > 0 {
+			remaining -= step.Timeout.Duration
+		} else {
+			withoutTimeout++
+		}
+	}
+	if remaining < 0 {
+		return pti, fmt.Errorf("the timeouts of the steps add up to more than the timeout %s of the pipeline task", timeout.Duration)
+	}
+	if withoutTimeout == 0 {
+		return pti, nil
+	}
+	split := remaining / time.Duration(withoutTimeout)
+	if split <= 0 {
+		return pti, fmt.Errorf("the timeout %s of the pipeline task is too short to be split between its steps", timeout.Duration)
+	}
+
+	updatedPti := pti
+	updatedPti.Steps = make([]v1beta1.Step, len(pti.Steps))
+	for i, step := range pti.Steps {
+		if step.Timeout == nil || step.Timeout.Duration == 0 {
+			step.Timeout = &metav1.Duration{Duration: split}
+		}
+		updatedPti.Steps[i] = step
+	}
+	return updatedPti, nil
+}
@@ -18,37 +18,28 @@ package pipelinetotaskrun
 
 import (
 	"fmt"
+	"regexp"
+
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"knative.dev/pkg/apis"
 )
 
+// taskStatusRefRegex matches the references to the execution status of pipeline tasks, e.g. $(tasks.grab-source.status)
+var taskStatusRefRegex = regexp.MustCompile(`\$\(tasks\.[^.)]+\.status\)`)
+
 func validateRun(run *v1alpha1.Run) (errs *apis.FieldError) {
 	if run.Spec.Ref.Name == "" {
 		errs = errs.Also(apis.ErrMissingField("name"))
 	}
-	for _, w := range run.Spec.Workspaces {
-		if w.SubPath != "" {
-			errs = errs.Also(apis.ErrDisallowedFields("spec.workspaces.subpath"))
-		}
-	}
 	return errs
 }
 
 func validatePipelineTask(pTask *v1beta1.PipelineTask) error {
-	if pTask.Timeout != nil {
-		return fmt.Errorf("task level timeouts are not yet supported; declared a timeout %v", pTask.Timeout)
-	}
-	if pTask.Retries != 0 {
-		return fmt.Errorf("task level retries are not yet supported; declared a %d retries", pTask.Retries)
-	}
-	if len(pTask.WhenExpressions) > 0 {
-		return fmt.Errorf("when expressions are not supported")
-	}
 	if len(pTask.Conditions) > 0 {
 		return fmt.Errorf("conditions are not supported")
 	}
-	if pTask.TaskRef != nil && pTask.TaskRef.Kind != "" && pTask.TaskRef.Kind != "Task" {
+	if pTask.TaskRef != nil && pTask.TaskRef.Kind != "" && pTask.TaskRef.Kind != v1beta1.NamespacedTaskKind && pTask.TaskRef.Kind != v1beta1.ClusterTaskKind {
 		return fmt.Errorf("custom tasks are not supported")
 	}
 	if pTask.TaskRef == nil {
@@ -56,11 +47,6 @@ func validatePipelineTask(pTask *v1beta1.PipelineTask) error {
 			return fmt.Errorf("embedded task spec for %s is invalid: %v", pTask.Name, err)
 		}
 	}
-	for _, w := range pTask.Workspaces {
-		if w.SubPath != "" {
-			return fmt.Errorf("subpaths for workspaces are not yet supported using subpath %s with workspace %s", w.SubPath, w.Name)
-		}
-	}
 	return nil
 }
 
@@ -72,55 +58,33 @@ func validateEmbeddedTaskSpec(embeddedSpec *v1beta1.EmbeddedTask) error {
 }
 
 func validateTaskSpec(taskSpec *v1beta1.TaskSpec) error {
-	if taskSpec.StepTemplate != nil {
-		return fmt.Errorf("step templates are not supported")
-	}
-	if taskSpec.Sidecars != nil {
-		return fmt.Errorf("sidecars are not supported")
-	}
 	if taskSpec.Resources != nil {
 		return fmt.Errorf("pipelineresources are not supported")
 	}
-	if len(taskSpec.Volumes) > 0 {
-		return fmt.Errorf("volumes are not supported")
-	}
 	for _, step := range taskSpec.Steps {
-		if len(step.VolumeMounts) > 0 {
-			return fmt.Errorf("volume mounts are not supported")
-		}
 		if len(step.Workspaces) > 0 {
 			return fmt.Errorf("isolated workspaces are not supported but %s is trying to use them", step.Name)
 		}
 	}
-	for _, w := range taskSpec.Workspaces {
-		if w.MountPath != "" {
-			return fmt.Errorf("mountPaths are not supported but trying to mount %s to %s", w.Name, w.MountPath)
-		}
-		if w.ReadOnly {
-			return fmt.Errorf("readOnly workspaces are not supported but %s is readOnly", w.Name)
-		}
-	}
-	for _, p := range taskSpec.Params {
-		if p.Type == v1beta1.ParamTypeArray {
-			return fmt.Errorf("array params are not yet supported but %s is a param of type array", p.Name)
-		}
-	}
 	return nil
 }
 
 func validatePipelineSpec(pSpec *v1beta1.PipelineSpec) error {
-	if len(pSpec.Finally) > 0 {
-		return fmt.Errorf("finally tasks are not supported")
-	}
-
-	if len(pSpec.Results) > 0 {
-		return fmt.Errorf("mapping of pipeline level results not supported")
-	}
 	for _, pTask := range pSpec.Tasks {
 		if err := validatePipelineTask(&pTask); err != nil {
 			return fmt.Errorf("pipeline task %s is invalid: %v", pTask.Name, err)
 		}
 	}
+	for _, pTask := range pSpec.Finally {
+		if err := validatePipelineTask(&pTask); err != nil {
+			return fmt.Errorf("finally task %s is invalid: %v", pTask.Name, err)
+		}
+		for _, p := range pTask.Params {
+			if taskStatusRefRegex.MatchString(p.Value.StringVal) {
+				return fmt.Errorf("the execution status of pipeline tasks is not supported but finally task %s is using it in param %s", pTask.Name, p.Name)
+			}
+		}
+	}
 	return nil
 }
 
@@ -132,3 +96,32 @@ func validateTaskSpecs(taskSpecs map[string]*v1beta1.TaskSpec) error {
 	}
 	return nil
 }
+
+func validateResultRefs(pSpec *v1beta1.PipelineSpec, taskSpecs map[string]*v1beta1.TaskSpec) error {
+	declared := map[string]bool{}
+	for pTaskName, taskSpec := range taskSpecs {
+		for _, r := range taskSpec.Results {
+			declared[namespaceName(pTaskName, r.Name)] = true
+		}
+	}
+	for _, pTask := range append(pSpec.Tasks, pSpec.Finally...) {
+		for _, ref := range v1beta1.PipelineTaskResultRefs(&pTask) {
+			if !declared[namespaceName(ref.PipelineTask, ref.Result)] {
+				return fmt.Errorf("pipeline task %s is using result %s of %s which doesn't declare it", pTask.Name, ref.Result, ref.PipelineTask)
+			}
+		}
+	}
+	for _, r := range pSpec.Results {
+		expressions, _ := v1beta1.GetVarSubstitutionExpressionsForPipelineResult(r)
+		for _, ref := range v1beta1.NewResultRefs(expressions) {
+			if !declared[namespaceName(ref.PipelineTask, ref.Result)] {
+				return fmt.Errorf("pipeline result %s is using result %s of %s which doesn't declare it", r.Name, ref.Result, ref.PipelineTask)
+			}
+		}
+		// task results are namespaced when they are added to the resulting task spec, but pipeline results aren't
+		if declared[r.Name] || r.Name == skippedTasksResult {
+			return fmt.Errorf("pipeline result %s collides with the namespaced result of a pipeline task", r.Name)
+		}
+	}
+	return nil
+}
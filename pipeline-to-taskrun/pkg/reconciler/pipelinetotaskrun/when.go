@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinetotaskrun
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// skippedTasksResult is the result of the resulting TaskRun which lists the pipeline tasks that were skipped while
+	// the TaskRun was running, one per line
+	skippedTasksResult = "pipelinetotaskrun-skipped-tasks"
+
+	// skippedTasksAnnotation lists the pipeline tasks that were skipped when the resulting TaskRun was created
+	skippedTasksAnnotation = "pipelinetotaskrun.tekton.dev/skipped-tasks"
+)
+
+// PipelineToTaskRunStatus holds the fields of the status of the Run which are specific to this custom task, and are
+// reported in the extra fields of the status of the Run.
+type PipelineToTaskRunStatus struct {
+	// SkippedTasks are the pipeline tasks which didn't run because of their when expressions, or because the results
+	// they use weren't written
+	SkippedTasks []v1beta1.SkippedTask `json:"skippedTasks,omitempty"`
+}
+
+// evaluateWhenExpressions will evaluate the when expressions of a pipeline task which don't use results, since their
+// values are known when the resulting TaskRun is created. It returns false if they don't allow the pipeline task
+// to run, or else the when expressions using results, which are evaluated by a guard step once the TaskRun is running.
+func evaluateWhenExpressions(wes v1beta1.WhenExpressions) (v1beta1.WhenExpressions, bool) {
+	var static, runtime v1beta1.WhenExpressions
+	for _, we := range wes {
+		if resultRefRegex.MatchString(we.GetInput()) || resultRefRegex.MatchString(strings.Join(we.GetValues(), " ")) {
+			runtime = append(runtime, we)
+		} else {
+			static = append(static, we)
+		}
+	}
+	if !static.AllowsExecution() {
+		return nil, false
+	}
+	return runtime, true
+}
+
+// getSkipMarkerPath returns the path of the file which the guard step of a pipeline task writes when it is skipped.
+func getSkipMarkerPath(pTaskName string) string {
+	return filepath.Join(markersDir, "skip-"+pTaskName)
+}
+
+// getGuardStep will return the step which runs before the steps of a pipeline task and skips the pipeline task if
+// its when expressions don't allow it to run, or if some of the results it uses weren't written, e.g. because the
+// pipeline task writing them was skipped itself.
+func getGuardStep(pTask v1beta1.PipelineTask, wes v1beta1.WhenExpressions, prelude string) v1beta1.Step {
+	var files []string
+	for _, ref := range v1beta1.PipelineTaskResultRefs(&pTask) {
+		files = append(files, getResultPath(ref.PipelineTask, ref.Result))
+	}
+
+	var conditions []string
+	for _, we := range wes {
+		input, _ := getShellValue(we.GetInput())
+		condition := []string{"matches", input}
+		for _, v := range we.GetValues() {
+			value, _ := getShellValue(v)
+			condition = append(condition, value)
+		}
+		if we.GetOperator() == selection.NotIn {
+			condition = append([]string{"!"}, condition...)
+		}
+		conditions = append(conditions, strings.Join(condition, " "))
+	}
+	if len(files) > 0 {
+		conditions = append([]string{getFilesExist(files)}, conditions...)
+	}
+
+	script := []string{"#!/bin/sh", "set -e"}
+	if prelude != "" {
+		script = append(script, prelude)
+	}
+	if len(wes) > 0 {
+		script = append(script,
+			`matches() {`,
+			`  input="$1"`,
+			`  shift`,
+			`  for value in "$@"; do`,
+			`    if [ "$input" = "$value" ]; then`,
+			`      return 0`,
+			`    fi`,
+			`  done`,
+			`  return 1`,
+			`}`,
+		)
+	}
+	script = append(script,
+		fmt.Sprintf("if %s; then", strings.Join(conditions, " && ")),
+		`  exit 0`,
+		`fi`,
+		fmt.Sprintf("mkdir -p %s", markersDir),
+		fmt.Sprintf("touch %s", getSkipMarkerPath(pTask.Name)),
+		fmt.Sprintf("echo %s >> %s", pTask.Name, filepath.Join(pipeline.DefaultResultPath, skippedTasksResult)),
+	)
+
+	return v1beta1.Step{
+		Container: corev1.Container{
+			Name:  getStepName(pTask.Name, "when"),
+			Image: shellImage,
+		},
+		Script: strings.Join(script, "\n") + "\n",
+	}
+}
+
+// getSkippedTasks will return the pipeline tasks that were skipped, either when the TaskRun was created or while it
+// was running.
+func getSkippedTasks(taskRun *v1beta1.TaskRun) []v1beta1.SkippedTask {
+	var names []string
+	if skipped := taskRun.Annotations[skippedTasksAnnotation]; skipped != "" {
+		names = append(names, strings.Split(skipped, ",")...)
+	}
+	for _, r := range taskRun.Status.TaskRunResults {
+		if r.Name == skippedTasksResult {
+			names = append(names, strings.Fields(r.Value)...)
+		}
+	}
+
+	var skippedTasks []v1beta1.SkippedTask
+	for _, name := range names {
+		skippedTasks = append(skippedTasks, v1beta1.SkippedTask{Name: name})
+	}
+	return skippedTasks
+}
@@ -18,7 +18,12 @@ package pipelinetotaskrun
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 )
 
 type PipelineTaskToWorkspaces map[string]map[string]string
@@ -56,3 +61,79 @@ func getUnboundOptionalWorkspaces(taskSpecs map[string]*v1beta1.TaskSpec, newWor
 	}
 	return optionalWS, nil
 }
+
+// WorkspaceMount holds how the steps of a pipeline task mount a workspace when they can't simply use the Pipeline
+// level workspace that is bound to it, i.e. when the pipeline task binds it with a subPath, or the Task declares it
+// as readOnly or with a mountPath.
+type WorkspaceMount struct {
+	// Workspace is the Pipeline level workspace which is bound to the workspace of the Task
+	Workspace string
+
+	// VolumeMount mounts the volume of the Pipeline level workspace in the steps of the pipeline task
+	VolumeMount corev1.VolumeMount
+}
+
+// getWorkspaceMounts will return how the steps of pTask mount the workspaces which the Task declared in taskSpec, for
+// the workspaces which need their own volume mounts, keyed by the names of the workspaces the Task declared. The
+// subPath of the binding of the Pipeline level workspace in the Run, if any, is prepended to the subPath of the
+// pipeline task. It returns an error if a volume mount would collide with the workspaces that all the steps of the
+// resulting TaskRun mount, or with another volume mount of the steps of pTask.
+func getWorkspaceMounts(pTask v1beta1.PipelineTask, taskSpec *v1beta1.TaskSpec, pipelineWorkspaces []v1beta1.PipelineWorkspaceDeclaration, runBindings []v1beta1.WorkspaceBinding) (map[string]WorkspaceMount, error) {
+	// all the steps of the resulting TaskRun mount the Pipeline level workspaces at their default paths
+	mountPaths := map[string]string{}
+	for _, w := range pipelineWorkspaces {
+		mountPaths[filepath.Join(pipeline.WorkspaceDir, w.Name)] = fmt.Sprintf("workspace %s of the Pipeline", w.Name)
+	}
+	for _, step := range taskSpec.Steps {
+		for _, vm := range step.VolumeMounts {
+			mountPaths[filepath.Clean(vm.MountPath)] = fmt.Sprintf("volume %s of step %s", vm.Name, step.Name)
+		}
+	}
+	for _, sidecar := range taskSpec.Sidecars {
+		for _, vm := range sidecar.VolumeMounts {
+			mountPaths[filepath.Clean(vm.MountPath)] = fmt.Sprintf("volume %s of sidecar %s", vm.Name, sidecar.Name)
+		}
+	}
+
+	subPaths := map[string]string{}
+	for _, b := range runBindings {
+		subPaths[b.Name] = b.SubPath
+	}
+
+	mounts := map[string]WorkspaceMount{}
+	for _, binding := range pTask.Workspaces {
+		var declaration *v1beta1.WorkspaceDeclaration
+		for i := range taskSpec.Workspaces {
+			if taskSpec.Workspaces[i].Name == binding.Name {
+				declaration = &taskSpec.Workspaces[i]
+			}
+		}
+		if declaration == nil || (binding.SubPath == "" && !declaration.ReadOnly && declaration.MountPath == "") {
+			continue
+		}
+
+		mountPath := filepath.Join(pipeline.WorkspaceDir, namespaceName(pTask.Name, binding.Name))
+		if declaration.MountPath != "" {
+			mountPath = filepath.Clean(declaration.MountPath)
+		}
+		if strings.HasPrefix(mountPath, "/tekton/") {
+			return nil, fmt.Errorf("workspace %s of %s can't be mounted to %s which is reserved for Tekton", binding.Name, pTask.Name, mountPath)
+		}
+		if collision, ok := mountPaths[mountPath]; ok {
+			return nil, fmt.Errorf("workspace %s of %s can't be mounted to %s which is already used by %s", binding.Name, pTask.Name, mountPath, collision)
+		}
+		mountPaths[mountPath] = fmt.Sprintf("workspace %s of %s", binding.Name, pTask.Name)
+
+		mounts[binding.Name] = WorkspaceMount{
+			Workspace: binding.Workspace,
+			VolumeMount: corev1.VolumeMount{
+				// the volume of the workspace is only named when the pod of the TaskRun is created
+				Name:      fmt.Sprintf("$(workspaces.%s.volume)", binding.Workspace),
+				MountPath: mountPath,
+				SubPath:   filepath.Join(subPaths[binding.Workspace], binding.SubPath),
+				ReadOnly:  declaration.ReadOnly,
+			},
+		}
+	}
+	return mounts, nil
+}
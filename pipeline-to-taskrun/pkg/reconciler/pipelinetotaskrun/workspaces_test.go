@@ -21,6 +21,7 @@ import (
 	"github.com/tektoncd/experimental/pipeline-to-taskrun/test"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/test/diff"
+	corev1 "k8s.io/api/core/v1"
 	"testing"
 )
 
@@ -170,3 +171,157 @@ spec:
 		t.Fatalf("Expected error when required workspace is not bound but got none")
 	}
 }
+
+func TestGetWorkspaceMounts(t *testing.T) {
+	p := test.MustParsePipeline(t, `
+spec:
+  workspaces:
+  - name: where-it-all-happens
+  - name: gcs-creds
+  tasks:
+  - name: run-tests
+    workspaces:
+    - name: source
+      workspace: where-it-all-happens
+      subPath: src
+    - name: cache
+      workspace: where-it-all-happens
+    - name: output
+      workspace: where-it-all-happens
+    - name: secret
+      workspace: gcs-creds
+`)
+	task := test.MustParseTask(t, `
+spec:
+  workspaces:
+  - name: source
+  - name: cache
+    mountPath: /root/.cache/
+  - name: output
+  - name: secret
+    readOnly: true
+`)
+	runBindings := []v1beta1.WorkspaceBinding{{
+		Name:     "where-it-all-happens",
+		SubPath:  "run-1",
+		EmptyDir: &corev1.EmptyDirVolumeSource{},
+	}, {
+		Name:   "gcs-creds",
+		Secret: &corev1.SecretVolumeSource{SecretName: "mikey"},
+	}}
+	expectedMounts := map[string]WorkspaceMount{
+		"source": {
+			Workspace: "where-it-all-happens",
+			VolumeMount: corev1.VolumeMount{
+				Name:      "$(workspaces.where-it-all-happens.volume)",
+				MountPath: "/workspace/run-tests-source",
+				SubPath:   "run-1/src",
+			},
+		},
+		"cache": {
+			Workspace: "where-it-all-happens",
+			VolumeMount: corev1.VolumeMount{
+				Name:      "$(workspaces.where-it-all-happens.volume)",
+				MountPath: "/root/.cache",
+				SubPath:   "run-1",
+			},
+		},
+		"secret": {
+			Workspace: "gcs-creds",
+			VolumeMount: corev1.VolumeMount{
+				Name:      "$(workspaces.gcs-creds.volume)",
+				MountPath: "/workspace/run-tests-secret",
+				ReadOnly:  true,
+			},
+		},
+	}
+
+	mounts, err := getWorkspaceMounts(p.Spec.Tasks[0], &task.Spec, p.Spec.Workspaces, runBindings)
+	if err != nil {
+		t.Fatalf("Did not expect error when getting workspace mounts but got %v", err)
+	}
+
+	if d := cmp.Diff(expectedMounts, mounts); d != "" {
+		t.Errorf("Did not get expected workspace mounts: %v", diff.PrintWantGot(d))
+	}
+}
+
+func TestGetWorkspaceMountsCollisions(t *testing.T) {
+	p := test.MustParsePipeline(t, `
+spec:
+  workspaces:
+  - name: where-it-all-happens
+  tasks:
+  - name: run-tests
+    workspaces:
+    - name: source
+      workspace: where-it-all-happens
+    - name: cache
+      workspace: where-it-all-happens
+`)
+	for _, tc := range []struct {
+		name string
+		task string
+	}{{
+		name: "pipeline workspace",
+		task: `
+spec:
+  workspaces:
+  - name: source
+    mountPath: /workspace/where-it-all-happens
+`,
+	}, {
+		name: "other workspace",
+		task: `
+spec:
+  workspaces:
+  - name: source
+    mountPath: /cache
+  - name: cache
+    mountPath: /cache/
+`,
+	}, {
+		name: "volume mount of a step",
+		task: `
+spec:
+  steps:
+  - name: test
+    volumeMounts:
+    - name: cache
+      mountPath: /cache
+  workspaces:
+  - name: cache
+    mountPath: /cache
+`,
+	}, {
+		name: "volume mount of a sidecar",
+		task: `
+spec:
+  sidecars:
+  - name: server
+    volumeMounts:
+    - name: cache
+      mountPath: /cache
+  workspaces:
+  - name: cache
+    readOnly: true
+    mountPath: /cache
+`,
+	}, {
+		name: "reserved path",
+		task: `
+spec:
+  workspaces:
+  - name: source
+    mountPath: /tekton/home
+`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			task := test.MustParseTask(t, tc.task)
+			_, err := getWorkspaceMounts(p.Spec.Tasks[0], &task.Spec, p.Spec.Workspaces, nil)
+			if err == nil {
+				t.Fatalf("Expected error when workspace mounts collide but got none")
+			}
+		})
+	}
+}
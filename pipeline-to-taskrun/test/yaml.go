@@ -48,6 +48,15 @@ kind: Task
 	return &task
 }
 
+func MustParseClusterTask(t *testing.T, yaml string) *v1beta1.ClusterTask {
+	var task v1beta1.ClusterTask
+	yaml = `apiVersion: tekton.dev/v1beta1
+kind: ClusterTask
+` + yaml
+	mustParseYAML(t, yaml, &task)
+	return &task
+}
+
 func MustParsePipelineRun(t *testing.T, yaml string) *v1beta1.PipelineRun {
 	var pr v1beta1.PipelineRun
 	yaml = `apiVersion: tekton.dev/v1beta1
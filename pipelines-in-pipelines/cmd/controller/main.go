@@ -18,9 +18,10 @@ package main
 
 import (
 	"github.com/tektoncd/experimental/pipelines-in-pipelines/pkg/reconciler/pip"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/injection/sharedmain"
 )
 
 func main() {
-	sharedmain.Main(pip.ControllerName, pip.NewController)
+	sharedmain.Main(pip.ControllerName, pip.NewController(clock.RealClock{}))
 }
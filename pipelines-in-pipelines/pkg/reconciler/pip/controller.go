@@ -22,11 +22,13 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
 	"github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	pipelineinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/pipeline"
 	pipelineruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/pipelinerun"
 	v1beta1customrun "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	pipelinecontroller "github.com/tektoncd/pipeline/pkg/controller"
 	tkncontroller "github.com/tektoncd/pipeline/pkg/controller"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
 	configmap "knative.dev/pkg/configmap"
 	controller "knative.dev/pkg/controller"
 	logging "knative.dev/pkg/logging"
@@ -37,18 +39,28 @@ const (
 	kind           = "Pipeline"
 )
 
-// NewController creates a Reconciler for CustomRun and returns the result of NewImpl.
-func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+// NewController returns a function creating a Reconciler for CustomRun, which reads the time from the clock, and
+// returning the result of NewImpl.
+func NewController(clock clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		return newController(ctx, clock)
+	}
+}
+
+func newController(ctx context.Context, clock clock.PassiveClock) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
 	pipelineClientSet := pipelineclient.Get(ctx)
 	customRunInformer := customrun.Get(ctx)
 	pipelineRunInformer := pipelineruninformer.Get(ctx)
+	pipelineInformer := pipelineinformer.Get(ctx)
 
 	r := &Reconciler{
 		pipelineClientSet: pipelineClientSet,
 		customRunLister:   customRunInformer.Lister(),
 		pipelineRunLister: pipelineRunInformer.Lister(),
+		pipelineLister:    pipelineInformer.Lister(),
+		clock:             clock,
 	}
 
 	impl := v1beta1customrun.NewImpl(ctx, r, func(impl *controller.Impl) controller.Options {
@@ -21,9 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/tektoncd/pipeline/pkg/apis/config"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	clientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
@@ -31,9 +33,11 @@ import (
 	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/reconciler/events"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
@@ -47,6 +51,14 @@ const (
 	// ReasonRunFailedCreatingPipelineRun indicates that the reason for failure status is that Run failed
 	// to create PipelineRun
 	ReasonRunFailedCreatingPipelineRun = "ReasonRunFailedCreatingPipelineRun"
+
+	// ReasonRunFailedRecursivePipeline indicates that the reason for failure status is that the Pipeline of the Run
+	// runs itself or one of the Pipelines running the Run
+	ReasonRunFailedRecursivePipeline = "ReasonRunFailedRecursivePipeline"
+
+	// ReasonRunFailedPropagatingResults indicates that the reason for failure status is that the PipelineRun of the
+	// Run succeeded without some of the results to propagate to the Run
+	ReasonRunFailedPropagatingResults = "ReasonRunFailedPropagatingResults"
 )
 
 // Reconciler implements controller.Reconciler for Run resources.
@@ -54,6 +66,8 @@ type Reconciler struct {
 	pipelineClientSet clientset.Interface
 	customRunLister   listers.CustomRunLister
 	pipelineRunLister listers.PipelineRunLister
+	pipelineLister    listers.PipelineLister
+	clock             clock.PassiveClock
 }
 
 // Check that our Reconciler implements Interface
@@ -63,8 +77,7 @@ var _ customrun.Interface = (*Reconciler)(nil)
 func (r *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun) reconciler.Event {
 	logger := logging.FromContext(ctx)
 
-	if run.Spec.CustomRef == nil ||
-		run.Spec.CustomRef.APIVersion != v1beta1.SchemeGroupVersion.String() || run.Spec.CustomRef.Kind != kind {
+	if !isPipelineRun(run) {
 		logger.Warn("Should not have been notified about Run %s/%s; will do nothing", run.Namespace, run.Name)
 		return nil
 	}
@@ -75,6 +88,8 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun)
 	if !run.HasStarted() {
 		logger.Infof("Starting new Run %s/%s", run.Namespace, run.Name)
 		run.Status.InitializeConditions()
+		startTime := metav1.NewTime(r.clock.Now())
+		run.Status.StartTime = &startTime
 		// In case node time was not synchronized, when controller has been scheduled to other nodes.
 		if run.Status.StartTime.Sub(run.CreationTimestamp.Time) < 0 {
 			logger.Warnf("Run %s/%s createTimestamp %s is after the Run started %s", run.Namespace, run.Name, run.CreationTimestamp, run.Status.StartTime)
@@ -87,7 +102,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun)
 
 	// If the run has been cancelled, cancel the pipelineRun
 	if run.IsCancelled() {
-		if err := r.cancelPipelineRun(ctx, run); err != nil {
+		if err := r.cancelPipelineRun(ctx, run, r.getOwnerPipelineRun(ctx, run)); err != nil {
 			logger.Errorf("Failed to cancel PipelineRun created by CustomRun %s/%s due to %v", run.Namespace, run.Name, err)
 		}
 	}
@@ -115,28 +130,70 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun)
 	events.Emit(ctx, beforeCondition, afterCondition, run)
 
 	// Only transient errors that should retry the reconcile are returned
-	return merr
+	if merr != nil {
+		return merr
+	}
 
+	// Check the timeout of the Run again when it elapses
+	if deadline, ok := getTimeoutDeadline(run); ok && !run.IsDone() {
+		return controller.NewRequeueAfter(deadline.Sub(r.clock.Now()))
+	}
+	return nil
 }
 
 func (r *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun) error {
 	logger := logging.FromContext(ctx)
 
 	// confirm the run spec is valid
-	if err := validate(run); err != nil {
+	if err := validate(ctx, run); err != nil {
 		logger.Errorf("Run %s/%s is invalid because of %v", run.Namespace, run.Name, err)
 		run.Status.MarkCustomRunFailed(ReasonRunFailedValidation,
 			"Run can't be run because it has an invalid spec - %v", err)
 		return controller.NewPermanentError(fmt.Errorf("run %s/%s is invalid because of %v", run.Namespace, run.Name, err))
 	}
 
-	// fetch the pipelinerun and, if present, update the run status
+	// fetch the pipelinerun of the current attempt and, if present, cancel it if it's still running after the run
+	// timed out, stop it if its owner is stopping and update the run status.  A pipelinerun that is done decides the
+	// outcome of the run even if the run is reconciled after its deadline, but a failed one isn't retried then.
 	if pr := r.getPipelineRun(ctx, run); pr != nil {
-		return updateRunStatus(ctx, run, pr)
+		if !pr.IsDone() && r.hasTimedOut(run) {
+			return r.timeOut(ctx, run, pr)
+		}
+		if err := r.stopPipelineRun(ctx, pr, r.getOwnerPipelineRun(ctx, run)); err != nil {
+			logger.Errorf("Failed to stop PipelineRun created by CustomRun %s/%s due to %v", run.Namespace, run.Name, err)
+			return err
+		}
+		if err := updateRunStatus(ctx, run, pr); err != nil {
+			return err
+		}
+		if !canRetry(run) || r.hasTimedOut(run) {
+			return nil
+		}
+		logger.Infof("Retrying Run %s/%s after its PipelineRun %s failed", run.Namespace, run.Name, pr.Name)
+		r.retry(run, pr)
+	} else if r.hasTimedOut(run) {
+		return r.timeOut(ctx, run, nil)
 	}
 
-	// pipelinerun doesn't exist yet, create a new pipelinerun
-	if _, err := r.createPipelineRun(ctx, run); err != nil {
+	// pipelinerun of the current attempt doesn't exist yet, check that its pipeline doesn't run itself and create a new pipelinerun
+	var spec *v1beta1.PipelineSpec
+	if run.Spec.CustomSpec != nil {
+		spec, _ = getPipelineSpec(ctx, run)
+	}
+	ownerPipelineRun := r.getOwnerPipelineRun(ctx, run)
+	ancestors, err := getAncestors(r.pipelineLister, run, spec, ownerPipelineRun)
+	if err != nil {
+		logger.Errorf("Run %s/%s runs a Pipeline recursively - %v", run.Namespace, run.Name, err)
+		run.Status.MarkCustomRunFailed(ReasonRunFailedRecursivePipeline,
+			"Run can't be run because it runs a Pipeline recursively - %v", err)
+		return nil
+	}
+	if ownerPipelineRun == nil {
+		// The ancestors of the PipelineRun are found from its PipelineRef.
+		ancestors = nil
+	}
+
+	if _, err := r.createPipelineRun(ctx, run, spec, ownerPipelineRun, ancestors); err != nil {
 		logger.Errorf("Run %s/%s got an error creating PipelineRun - %v", run.Namespace, run.Name, err)
 		run.Status.MarkCustomRunFailed(ReasonRunFailedCreatingPipelineRun,
 			"Run got an error creating pipelineRun - %v", err)
@@ -151,8 +208,15 @@ func updateRunStatus(ctx context.Context, run *v1beta1.CustomRun, pipelineRun *v
 	c := pipelineRun.GetStatusCondition().GetCondition(apis.ConditionSucceeded)
 	if c.IsTrue() {
 		logger.Infof("PipelineRun created by CustomRun %s/%s has succeeded", run.Namespace, run.Name)
+		results, err := getRunResults(run, pipelineRun)
+		if err != nil {
+			logger.Errorf("Run %s/%s got an error propagating results - %v", run.Namespace, run.Name, err)
+			run.Status.MarkCustomRunFailed(ReasonRunFailedPropagatingResults,
+				"Run got an error propagating results - %v", err)
+			return nil
+		}
 		run.Status.MarkCustomRunSucceeded(c.Reason, c.Message)
-		propagateResults(run, pipelineRun)
+		run.Status.Results = append(run.Status.Results, results...)
 	} else if c.IsFalse() {
 		logger.Infof("PipelineRun created by CustomRun %s/%s has failed", run.Namespace, run.Name)
 		run.Status.MarkCustomRunFailed(c.Reason, c.Message)
@@ -171,27 +235,47 @@ func updateRunStatus(ctx context.Context, run *v1beta1.CustomRun, pipelineRun *v
 	return nil
 }
 
-func propagateResults(run *v1beta1.CustomRun, pipelineRun *v1beta1.PipelineRun) {
-	pipelineResults := pipelineRun.Status.PipelineResults
-	for _, pipelineResult := range pipelineResults {
-		run.Status.Results = append(run.Status.Results, v1beta1.CustomRunResult{
-			Name:  pipelineResult.Name,
-			Value: pipelineResult.Value.StringVal,
-		})
+func validate(ctx context.Context, run *v1beta1.CustomRun) (errs *apis.FieldError) {
+	_, errs = getResultMappings(run)
+	if run.Spec.CustomSpec != nil {
+		if run.Spec.CustomRef != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("customRef", "customSpec"))
+		}
+		_, err := getPipelineSpec(ctx, run)
+		return errs.Also(err)
 	}
-}
-
-func validate(run *v1beta1.CustomRun) (errs *apis.FieldError) {
-	if run.Spec.CustomRef.Name == "" {
+	ref := run.Spec.CustomRef
+	switch {
+	case ref.Resolver != "" && ref.Name != "":
+		errs = errs.Also(apis.ErrMultipleOneOf("name", "resolver"))
+	case ref.Resolver != "" && ref.Bundle != "":
+		errs = errs.Also(apis.ErrMultipleOneOf("bundle", "resolver"))
+	case ref.Resolver == "" && ref.Name == "":
 		errs = errs.Also(apis.ErrMissingField("name"))
 	}
 	return errs
 }
 
+// getPipelineSpec returns the Pipeline embedded in the Run, defaulted and validated.
+func getPipelineSpec(ctx context.Context, run *v1beta1.CustomRun) (*v1beta1.PipelineSpec, *apis.FieldError) {
+	if len(run.Spec.CustomSpec.Spec.Raw) == 0 {
+		return nil, apis.ErrMissingField("spec")
+	}
+	spec := &v1beta1.PipelineSpec{}
+	if err := json.Unmarshal(run.Spec.CustomSpec.Spec.Raw, spec); err != nil {
+		return nil, apis.ErrInvalidValue(err.Error(), "spec")
+	}
+	spec.SetDefaults(ctx)
+	if err := spec.Validate(ctx); err != nil {
+		return nil, err.ViaField("spec")
+	}
+	return spec, nil
+}
+
 func (r *Reconciler) getPipelineRun(ctx context.Context, run *v1beta1.CustomRun) *v1beta1.PipelineRun {
 	logger := logging.FromContext(ctx)
 
-	pr, err := r.pipelineRunLister.PipelineRuns(run.Namespace).Get(run.Name)
+	pr, err := r.pipelineRunLister.PipelineRuns(run.Namespace).Get(getPipelineRunName(run))
 	if err != nil {
 		logger.Errorf("Run %s/%s got an error fetching PipelineRun - %v", run.Namespace, run.Name, err)
 		return nil
@@ -201,31 +285,43 @@ func (r *Reconciler) getPipelineRun(ctx context.Context, run *v1beta1.CustomRun)
 	return pr
 }
 
-func (r *Reconciler) createPipelineRun(ctx context.Context, run *v1beta1.CustomRun) (*v1beta1.PipelineRun, error) {
+func (r *Reconciler) getOwnerPipelineRun(ctx context.Context, run *v1beta1.CustomRun) *v1beta1.PipelineRun {
 	logger := logging.FromContext(ctx)
 
-	var ownerPipelineRun *v1beta1.PipelineRun
-	var err error
 	ownerPipelineRunName := getOwnerPipelineRunName(run)
-	if ownerPipelineRunName != "" {
-		ownerPipelineRun, err = r.pipelineRunLister.PipelineRuns(run.Namespace).Get(ownerPipelineRunName)
-		if err != nil {
-			logger.Errorf("Failed to fetch the owner PipelineRun - %v", run.Namespace, ownerPipelineRunName, err)
-		}
+	if ownerPipelineRunName == "" {
+		return nil
+	}
+	ownerPipelineRun, err := r.pipelineRunLister.PipelineRuns(run.Namespace).Get(ownerPipelineRunName)
+	if err != nil {
+		logger.Errorf("Failed to fetch the owner PipelineRun %s/%s - %v", run.Namespace, ownerPipelineRunName, err)
+		return nil
 	}
+	return ownerPipelineRun
+}
+
+func (r *Reconciler) createPipelineRun(ctx context.Context, run *v1beta1.CustomRun, spec *v1beta1.PipelineSpec, ownerPipelineRun *v1beta1.PipelineRun, ancestors []string) (*v1beta1.PipelineRun, error) {
+	logger := logging.FromContext(ctx)
 
 	pr := &v1beta1.PipelineRun{
-		ObjectMeta: getObjectMeta(run),
-		Spec:       getPipelineRunSpec(run, ownerPipelineRun),
+		ObjectMeta: getObjectMeta(run, ownerPipelineRun),
+		Spec:       r.getPipelineRunSpec(ctx, run, spec, ownerPipelineRun),
+	}
+	if len(ancestors) > 0 {
+		value, err := json.Marshal(ancestors)
+		if err != nil {
+			return nil, err
+		}
+		pr.Annotations[ancestorsAnnotation] = string(value)
 	}
 
 	logger.Infof("Creating a new PipelineRun object %s", pr.Name)
 	return r.pipelineClientSet.TektonV1beta1().PipelineRuns(run.Namespace).Create(ctx, pr, metav1.CreateOptions{})
 }
 
-func getObjectMeta(run *v1beta1.CustomRun) metav1.ObjectMeta {
+func getObjectMeta(run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
-		Name:      run.Name,
+		Name:      getPipelineRunName(run),
 		Namespace: run.Namespace,
 		OwnerReferences: []metav1.OwnerReference{
 			*metav1.NewControllerRef(run, schema.GroupVersionKind{
@@ -234,44 +330,109 @@ func getObjectMeta(run *v1beta1.CustomRun) metav1.ObjectMeta {
 				Kind:    pipeline.CustomRunControllerName,
 			}),
 		},
-		Labels:      getPipelineRunLabels(run),
-		Annotations: getPipelineRunAnnotations(run),
+		Labels:      getPipelineRunLabels(run, ownerPipelineRun),
+		Annotations: getPipelineRunAnnotations(run, ownerPipelineRun),
 	}
 }
 
-func (r *Reconciler) cancelPipelineRun(ctx context.Context, run *v1beta1.CustomRun) error {
-	if pr := r.getPipelineRun(ctx, run); pr != nil {
-		logger := logging.FromContext(ctx)
-		logger.Infof("Cancelling PipelineRun created by CustomRun %s/%s", pr.Namespace, pr.Name)
-		mergePatch := map[string]interface{}{
-			"spec": map[string]interface{}{
-				"status": v1beta1.PipelineRunSpecStatusCancelled,
-			},
-		}
-		patch, err := json.Marshal(mergePatch)
-		if err != nil {
-			return err
-		}
-		_, err = r.pipelineClientSet.TektonV1beta1().PipelineRuns(pr.Namespace).Patch(ctx, pr.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+// cancelPipelineRun cancels the PipelineRun of a cancelled Run.  If the PipelineRun owning the Run is cancelled
+// gracefully, the PipelineRun runs its finally tasks as well, otherwise it is cancelled right away.
+func (r *Reconciler) cancelPipelineRun(ctx context.Context, run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) error {
+	pr := r.getPipelineRun(ctx, run)
+	if pr == nil {
+		return nil
+	}
+	status := v1beta1.PipelineRunSpecStatus(v1beta1.PipelineRunSpecStatusCancelled)
+	if ownerPipelineRun != nil && ownerPipelineRun.IsGracefullyCancelled() {
+		status = v1beta1.PipelineRunSpecStatusCancelledRunFinally
+	}
+	logging.FromContext(ctx).Infof("Cancelling PipelineRun created by CustomRun %s/%s with status %s", pr.Namespace, pr.Name, status)
+	return r.patchPipelineRunStatus(ctx, pr, status)
+}
+
+// stopPipelineRun gracefully stops the PipelineRun of a Run when the PipelineRun owning the Run is gracefully stopped,
+// so that it doesn't start new tasks but runs its finally tasks.  The Run itself isn't cancelled in that case.
+func (r *Reconciler) stopPipelineRun(ctx context.Context, pr *v1beta1.PipelineRun, ownerPipelineRun *v1beta1.PipelineRun) error {
+	if ownerPipelineRun == nil || !ownerPipelineRun.IsGracefullyStopped() || pr.Spec.Status != "" || pr.IsDone() {
+		return nil
+	}
+	logging.FromContext(ctx).Infof("Stopping PipelineRun %s/%s since its owner %s is stopping", pr.Namespace, pr.Name, ownerPipelineRun.Name)
+	return r.patchPipelineRunStatus(ctx, pr, v1beta1.PipelineRunSpecStatusStoppedRunFinally)
+}
+
+func (r *Reconciler) patchPipelineRunStatus(ctx context.Context, pr *v1beta1.PipelineRun, status v1beta1.PipelineRunSpecStatus) error {
+	if pr.Spec.Status == status {
+		return nil
+	}
+	mergePatch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"status": status,
+		},
+	}
+	patch, err := json.Marshal(mergePatch)
+	if err != nil {
 		return err
 	}
-	return nil
+	_, err = r.pipelineClientSet.TektonV1beta1().PipelineRuns(pr.Namespace).Patch(ctx, pr.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
 }
 
-func getPipelineRunSpec(run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) v1beta1.PipelineRunSpec {
+// getPipelineRunSpec returns the spec of the PipelineRun of the Run.  Unless the Run overrides them, its service
+// account, pod template and timeout are inherited from the PipelineRun owning it, if any.  The default service
+// account, which the Run gets when it has none, doesn't override that of the PipelineRun owning it.
+func (r *Reconciler) getPipelineRunSpec(ctx context.Context, run *v1beta1.CustomRun, spec *v1beta1.PipelineSpec, ownerPipelineRun *v1beta1.PipelineRun) v1beta1.PipelineRunSpec {
 	pipelineRunSpec := v1beta1.PipelineRunSpec{
 		PipelineRef:        getPipelineRef(run),
+		PipelineSpec:       spec,
 		Params:             run.Spec.Params,
 		ServiceAccountName: run.Spec.ServiceAccountName,
 		Workspaces:         run.Spec.Workspaces,
-		Timeout:            run.Spec.Timeout,
+		Timeout:            r.getRemainingRunTimeout(run),
 	}
 	if ownerPipelineRun != nil {
+		if sa := pipelineRunSpec.ServiceAccountName; sa == "" || sa == config.FromContextOrDefaults(ctx).Defaults.DefaultServiceAccount {
+			pipelineRunSpec.ServiceAccountName = ownerPipelineRun.Spec.ServiceAccountName
+		}
 		pipelineRunSpec.PodTemplate = ownerPipelineRun.Spec.PodTemplate
+		if taskRunSpec := getOwnerTaskRunSpec(run, ownerPipelineRun); taskRunSpec != nil && taskRunSpec.TaskPodTemplate != nil {
+			pipelineRunSpec.PodTemplate = taskRunSpec.TaskPodTemplate
+		}
+		if pipelineRunSpec.Timeout == nil {
+			pipelineRunSpec.Timeout = r.getRemainingTimeout(ownerPipelineRun)
+		}
 	}
 	return pipelineRunSpec
 }
 
+// getOwnerTaskRunSpec returns the taskRunSpec of the PipelineRun owning the Run for the pipeline task of the Run, if any.
+func getOwnerTaskRunSpec(run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) *v1beta1.PipelineTaskRunSpec {
+	pipelineTaskName := run.Labels[pipeline.PipelineTaskLabelKey]
+	for i := range ownerPipelineRun.Spec.TaskRunSpecs {
+		if ownerPipelineRun.Spec.TaskRunSpecs[i].PipelineTaskName == pipelineTaskName {
+			return &ownerPipelineRun.Spec.TaskRunSpecs[i]
+		}
+	}
+	return nil
+}
+
+// getRemainingTimeout returns what remains of the timeout of the PipelineRun owning a Run, so that the PipelineRun of
+// the Run doesn't outlive it, or nil if it doesn't time out.
+func (r *Reconciler) getRemainingTimeout(ownerPipelineRun *v1beta1.PipelineRun) *metav1.Duration {
+	timeout := ownerPipelineRun.Spec.Timeout
+	if ownerPipelineRun.Spec.Timeouts != nil && ownerPipelineRun.Spec.Timeouts.Pipeline != nil {
+		timeout = ownerPipelineRun.Spec.Timeouts.Pipeline
+	}
+	if timeout == nil || timeout.Duration == 0 || ownerPipelineRun.Status.StartTime == nil {
+		return nil
+	}
+	remaining := ownerPipelineRun.Status.StartTime.Add(timeout.Duration).Sub(r.clock.Now())
+	// A zero timeout would disable the timeout of the PipelineRun, which should rather time out right away.
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return &metav1.Duration{Duration: remaining}
+}
+
 func getOwnerPipelineRunName(run *v1beta1.CustomRun) string {
 	for _, ref := range run.GetOwnerReferences() {
 		if ref.Kind == pipeline.PipelineRunControllerName {
@@ -281,31 +442,95 @@ func getOwnerPipelineRunName(run *v1beta1.CustomRun) string {
 	return ""
 }
 
+// isPipelineRun returns true if the Run references or embeds a Pipeline.
+func isPipelineRun(run *v1beta1.CustomRun) bool {
+	if run.Spec.CustomRef != nil {
+		return isPipelineKind(run.Spec.CustomRef.APIVersion, string(run.Spec.CustomRef.Kind))
+	}
+	return run.Spec.CustomSpec != nil && isPipelineKind(run.Spec.CustomSpec.APIVersion, run.Spec.CustomSpec.Kind)
+}
+
+// isPipelineKind returns true if the apiVersion and kind of a custom task are those of a Pipeline.
+func isPipelineKind(apiVersion, k string) bool {
+	return apiVersion == v1beta1.SchemeGroupVersion.String() && k == kind
+}
+
 func getPipelineRef(run *v1beta1.CustomRun) *v1beta1.PipelineRef {
-	if run.Spec.CustomRef.Name == "" {
+	if run.Spec.CustomRef == nil || (run.Spec.CustomRef.Name == "" && run.Spec.CustomRef.Resolver == "") {
 		return nil
 	}
+	return pipelineRefFromTaskRef(run.Spec.CustomRef)
+}
+
+// pipelineRefFromTaskRef returns the PipelineRef of a Pipeline referenced as a custom task, by name in the namespace,
+// in a Tekton bundle or with a remote resolver.
+func pipelineRefFromTaskRef(ref *v1beta1.TaskRef) *v1beta1.PipelineRef {
+	if ref.Resolver != "" {
+		return &v1beta1.PipelineRef{ResolverRef: ref.ResolverRef}
+	}
 	return &v1beta1.PipelineRef{
-		Name:       run.Spec.CustomRef.Name,
+		Name:       ref.Name,
 		APIVersion: pipeline.GroupName,
+		Bundle:     ref.Bundle,
 	}
 }
 
-func getPipelineRunLabels(run *v1beta1.CustomRun) map[string]string {
+// getPipelineRunLabels returns the labels of the PipelineRun of the Run: those of the PipelineRun owning the Run, e.g.
+// added by triggers, except the labels of Tekton, those of its taskRunSpec, of the Run and of its embedded Pipeline.
+func getPipelineRunLabels(run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) map[string]string {
 	labels := make(map[string]string, len(run.ObjectMeta.Labels)+1)
+	if ownerPipelineRun != nil {
+		for key, val := range ownerPipelineRun.ObjectMeta.Labels {
+			if !strings.HasPrefix(key, pipeline.GroupName+"/") {
+				labels[key] = val
+			}
+		}
+		if taskRunSpec := getOwnerTaskRunSpec(run, ownerPipelineRun); taskRunSpec != nil && taskRunSpec.Metadata != nil {
+			for key, val := range taskRunSpec.Metadata.Labels {
+				labels[key] = val
+			}
+		}
+	}
 	for key, val := range run.ObjectMeta.Labels {
 		labels[key] = val
 	}
+	if run.Spec.CustomSpec != nil {
+		for key, val := range run.Spec.CustomSpec.Metadata.Labels {
+			labels[key] = val
+		}
+	}
 	labels[pipeline.CustomRunKey] = run.Name
-	labels[pipeline.PipelineLabelKey] = run.Spec.CustomRef.Name
+	if run.Spec.CustomRef != nil && run.Spec.CustomRef.Name != "" {
+		labels[pipeline.PipelineLabelKey] = run.Spec.CustomRef.Name
+	}
 	return labels
 }
 
-func getPipelineRunAnnotations(run *v1beta1.CustomRun) map[string]string {
+// getPipelineRunAnnotations returns the annotations of the PipelineRun of the Run: those of the PipelineRun owning the
+// Run, e.g. commit info, those of its taskRunSpec, of the Run and of its embedded Pipeline.
+func getPipelineRunAnnotations(run *v1beta1.CustomRun, ownerPipelineRun *v1beta1.PipelineRun) map[string]string {
 	annotations := make(map[string]string, len(run.ObjectMeta.Annotations)+1)
+	if ownerPipelineRun != nil {
+		for key, val := range ownerPipelineRun.ObjectMeta.Annotations {
+			if key != corev1.LastAppliedConfigAnnotation {
+				annotations[key] = val
+			}
+		}
+		if taskRunSpec := getOwnerTaskRunSpec(run, ownerPipelineRun); taskRunSpec != nil && taskRunSpec.Metadata != nil {
+			for key, val := range taskRunSpec.Metadata.Annotations {
+				annotations[key] = val
+			}
+		}
+	}
 	for key, val := range run.ObjectMeta.Annotations {
 		annotations[key] = val
 	}
+	if run.Spec.CustomSpec != nil {
+		for key, val := range run.Spec.CustomSpec.Metadata.Annotations {
+			annotations[key] = val
+		}
+	}
+	delete(annotations, resultsAnnotation)
 	return annotations
 }
 
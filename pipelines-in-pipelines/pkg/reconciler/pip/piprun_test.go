@@ -18,6 +18,7 @@ package pip
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/tektoncd/experimental/pipelines-in-pipelines/test"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
 	"github.com/tektoncd/pipeline/test/diff"
@@ -32,9 +34,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ktesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	testclock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
@@ -42,13 +46,15 @@ import (
 	"knative.dev/pkg/reconciler"
 )
 
+var now = time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+
 func getPipController(t *testing.T, d test.Data) (test.Assets, func()) {
 	ctx, _ := ttesting.SetupFakeContext(t)
 	ctx, cancel := context.WithCancel(ctx)
 	c, informers := test.SeedTestData(t, ctx, d)
 
 	configMapWatcher := configmap.NewStaticWatcher()
-	ctl := NewController(ctx, configMapWatcher)
+	ctl := NewController(testclock.NewFakeClock(now))(ctx, configMapWatcher)
 
 	if la, ok := ctl.Reconciler.(reconciler.LeaderAware); ok {
 		la.Promote(reconciler.UniversalBucket(), func(reconciler.Bucket, types.NamespacedName) {})
@@ -192,6 +198,36 @@ func withResults(pr *v1beta1.PipelineRun, name string, value string) *v1beta1.Pi
 	return prWithStatus
 }
 
+func withResult(pr *v1beta1.PipelineRun, name string, value *v1beta1.ResultValue) *v1beta1.PipelineRun {
+	prWithStatus := pr.DeepCopy()
+	prWithStatus.Status.PipelineResults = append(prWithStatus.Status.PipelineResults, v1beta1.PipelineRunResult{
+		Name:  name,
+		Value: *value,
+	})
+	return prWithStatus
+}
+
+func withDeclaredResults(pr *v1beta1.PipelineRun, names ...string) *v1beta1.PipelineRun {
+	prWithStatus := pr.DeepCopy()
+	prWithStatus.Status.PipelineSpec = &v1beta1.PipelineSpec{}
+	for _, name := range names {
+		prWithStatus.Status.PipelineSpec.Results = append(prWithStatus.Status.PipelineSpec.Results, v1beta1.PipelineResult{
+			Name:  name,
+			Value: *v1beta1.NewStructuredValues("$(tasks.task.results." + name + ")"),
+		})
+	}
+	return prWithStatus
+}
+
+func withAnnotation(run *v1beta1.CustomRun, key string, value string) *v1beta1.CustomRun {
+	runWithAnnotation := run.DeepCopy()
+	if runWithAnnotation.Annotations == nil {
+		runWithAnnotation.Annotations = map[string]string{}
+	}
+	runWithAnnotation.Annotations[key] = value
+	return runWithAnnotation
+}
+
 var p = &v1beta1.Pipeline{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "pipeline",
@@ -309,6 +345,183 @@ var runWithoutAPIVersion = &v1beta1.CustomRun{
 	},
 }
 
+func rawPipelineSpec(spec v1beta1.PipelineSpec) runtime.RawExtension {
+	raw, _ := json.Marshal(spec)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func defaulted(spec v1beta1.PipelineSpec) *v1beta1.PipelineSpec {
+	s := spec.DeepCopy()
+	s.SetDefaults(context.Background())
+	return s
+}
+
+var runWithPipelineSpec = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		CustomSpec: &v1beta1.EmbeddedCustomRunSpec{
+			TypeMeta: runtime.TypeMeta{
+				APIVersion: "tekton.dev/v1beta1",
+				Kind:       "Pipeline",
+			},
+			Metadata: v1beta1.PipelineTaskMetadata{
+				Labels: map[string]string{"embedded": "label"},
+			},
+			Spec: rawPipelineSpec(p.Spec),
+		},
+	},
+}
+
+var prWithPipelineSpec = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"embedded":             "label",
+			"tekton.dev/customRun": "run-with-pipeline",
+		},
+		OwnerReferences: pr.OwnerReferences,
+		Annotations:     map[string]string{},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineSpec:       defaulted(p.Spec),
+		ServiceAccountName: "default",
+	},
+}
+
+var runWithInvalidPipelineSpec = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		CustomSpec: &v1beta1.EmbeddedCustomRunSpec{
+			TypeMeta: runtime.TypeMeta{
+				APIVersion: "tekton.dev/v1beta1",
+				Kind:       "Pipeline",
+			},
+			Spec: runtime.RawExtension{Raw: []byte(`{"tasks": "not-a-list"}`)},
+		},
+	},
+}
+
+var runWithBundle = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: "tekton.dev/v1beta1",
+			Kind:       "Pipeline",
+			Name:       "pipeline",
+			Bundle:     "registry.example.com/pipelines:v1",
+		},
+	},
+}
+
+var prWithBundle = &v1beta1.PipelineRun{
+	ObjectMeta: pr.ObjectMeta,
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{
+			Name:       "pipeline",
+			APIVersion: "tekton.dev",
+			Bundle:     "registry.example.com/pipelines:v1",
+		},
+		ServiceAccountName: "default",
+	},
+}
+
+var gitResolverRef = v1beta1.ResolverRef{
+	Resolver: "git",
+	Params: v1beta1.Params{{
+		Name:  "url",
+		Value: *v1beta1.NewStructuredValues("https://github.com/tektoncd/catalog.git"),
+	}, {
+		Name:  "pathInRepo",
+		Value: *v1beta1.NewStructuredValues("pipeline/build/0.1/build.yaml"),
+	}},
+}
+
+var runWithResolver = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion:  "tekton.dev/v1beta1",
+			Kind:        "Pipeline",
+			ResolverRef: gitResolverRef,
+		},
+	},
+}
+
+var prWithResolver = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/customRun": "run-with-pipeline",
+		},
+		OwnerReferences: pr.OwnerReferences,
+		Annotations:     map[string]string{},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{
+			ResolverRef: gitResolverRef,
+		},
+		ServiceAccountName: "default",
+	},
+}
+
+var runWithNameAndResolver = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-with-pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion:  "tekton.dev/v1beta1",
+			Kind:        "Pipeline",
+			Name:        "pipeline",
+			ResolverRef: gitResolverRef,
+		},
+	},
+}
+
+// recursivePipeline runs itself through another Pipeline embedded in it.
+var recursivePipeline = &v1beta1.Pipeline{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "pipeline",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.PipelineSpec{
+		Tasks: []v1beta1.PipelineTask{{
+			Name: "embedded",
+			TaskSpec: &v1beta1.EmbeddedTask{
+				TypeMeta: runtime.TypeMeta{
+					APIVersion: "tekton.dev/v1beta1",
+					Kind:       "Pipeline",
+				},
+				Spec: rawPipelineSpec(v1beta1.PipelineSpec{
+					Tasks: []v1beta1.PipelineTask{{
+						Name: "recursive",
+						TaskRef: &v1beta1.TaskRef{
+							APIVersion: "tekton.dev/v1beta1",
+							Kind:       "Pipeline",
+							Name:       "pipeline",
+						},
+					}},
+				}),
+			},
+		}},
+	},
+}
+
 func TestReconcilePipRun(t *testing.T) {
 	testcases := []struct {
 		name                string
@@ -391,6 +604,80 @@ func TestReconcilePipRun(t *testing.T) {
 			"Normal Started ",
 			"Normal Succeeded ",
 		},
+	}, {
+		name:     "Reconcile a run with a successful PipelineRun containing array and object PipelineRunResults",
+		pipeline: p,
+		run:      runWithPipeline,
+		pipelineRun: successful(withResult(withResult(pr,
+			"images", v1beta1.NewStructuredValues("alpine", "ubuntu")),
+			"digests", v1beta1.NewObject(map[string]string{"alpine": "sha256:123"}))),
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: v1beta1.PipelineRunReasonSuccessful,
+		expectedResults: []v1beta1.CustomRunResult{{
+			Name:  "images",
+			Value: `["alpine","ubuntu"]`,
+		}, {
+			Name:  "digests",
+			Value: `{"alpine":"sha256:123"}`,
+		}},
+		expectedEvents: []string{
+			"Normal Started ",
+			"Normal Succeeded ",
+		},
+	}, {
+		name:            "Reconcile a run with a successful PipelineRun missing a declared PipelineRunResult",
+		pipeline:        p,
+		run:             runWithPipeline,
+		pipelineRun:     successful(withResults(withDeclaredResults(pr, "foo", "baz"), "foo", "bar")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedPropagatingResults,
+		expectedMessage: "Run got an error propagating results - PipelineRun foo/run-with-pipeline didn't emit the results baz",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run got an error propagating results",
+		},
+	}, {
+		name:           "Reconcile a run with a successful PipelineRun and renamed PipelineRunResults",
+		pipeline:       p,
+		run:            withAnnotation(runWithPipeline, "pip.tekton.dev/results", "message=foo, baz"),
+		pipelineRun:    successful(withResults(withResults(withResults(pr, "foo", "bar"), "baz", "qux"), "other", "ignored")),
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: v1beta1.PipelineRunReasonSuccessful,
+		expectedResults: []v1beta1.CustomRunResult{{
+			Name:  "message",
+			Value: "bar",
+		}, {
+			Name:  "baz",
+			Value: "qux",
+		}},
+		expectedEvents: []string{
+			"Normal Started ",
+			"Normal Succeeded ",
+		},
+	}, {
+		name:            "Reconcile a run with a successful PipelineRun missing a renamed PipelineRunResult",
+		pipeline:        p,
+		run:             withAnnotation(runWithPipeline, "pip.tekton.dev/results", "message=foo"),
+		pipelineRun:     successful(withResults(pr, "bar", "baz")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedPropagatingResults,
+		expectedMessage: "Run got an error propagating results - PipelineRun foo/run-with-pipeline didn't emit the results foo",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run got an error propagating results",
+		},
+	}, {
+		name:            "Reconcile a new run with invalid renamed results",
+		pipeline:        p,
+		run:             withAnnotation(runWithPipeline, "pip.tekton.dev/results", "message=foo,message=bar"),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedValidation,
+		expectedMessage: "Run can't be run because it has an invalid spec - invalid value: result message is propagated more than once: metadata.annotations.pip.tekton.dev/results",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run can't be run because it has an invalid spec",
+			"Warning InternalError 1 error occurred",
+		},
 	}, {
 		name:            "Reconcile a run with a cancelled status",
 		pipeline:        p,
@@ -403,6 +690,71 @@ func TestReconcilePipRun(t *testing.T) {
 			"Normal Started ",
 			"Warning Failed PipelineRun run-with-pipeline was cancelled",
 		},
+	}, {
+		name:                "Reconcile a new run that embeds a pipeline",
+		pipeline:            p,
+		run:                 runWithPipelineSpec,
+		expectedPipelineRun: prWithPipelineSpec,
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      v1beta1.PipelineRunReasonStarted,
+		expectedEvents: []string{
+			"Normal Started ",
+		},
+	}, {
+		name:            "Reconcile a new run that embeds an invalid pipeline",
+		pipeline:        p,
+		run:             runWithInvalidPipelineSpec,
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedValidation,
+		expectedMessage: "Run can't be run because it has an invalid spec - invalid value: json: cannot unmarshal string into Go struct field PipelineSpec.tasks of type []v1beta1.PipelineTask: spec",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run can't be run because it has an invalid spec",
+			"Warning InternalError 1 error occurred",
+		},
+	}, {
+		name:                "Reconcile a new run that references a pipeline in a bundle",
+		pipeline:            p,
+		run:                 runWithBundle,
+		expectedPipelineRun: prWithBundle,
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      v1beta1.PipelineRunReasonStarted,
+		expectedEvents: []string{
+			"Normal Started ",
+		},
+	}, {
+		name:                "Reconcile a new run that references a pipeline with a resolver",
+		pipeline:            p,
+		run:                 runWithResolver,
+		expectedPipelineRun: prWithResolver,
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      v1beta1.PipelineRunReasonStarted,
+		expectedEvents: []string{
+			"Normal Started ",
+		},
+	}, {
+		name:            "Reconcile a new run that references a pipeline by name and with a resolver",
+		pipeline:        p,
+		run:             runWithNameAndResolver,
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedValidation,
+		expectedMessage: "Run can't be run because it has an invalid spec - expected exactly one, got both: name, resolver",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run can't be run because it has an invalid spec - expected exactly one, got both: name, resolver",
+			"Warning InternalError 1 error occurred",
+		},
+	}, {
+		name:            "Reconcile a new run that references a pipeline which runs itself",
+		pipeline:        recursivePipeline,
+		run:             runWithPipeline,
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedRecursivePipeline,
+		expectedMessage: "Run can't be run because it runs a Pipeline recursively - recursive reference to Pipeline foo/pipeline: foo/pipeline -> foo/pipeline",
+		expectedEvents: []string{
+			"Normal Started ",
+			"Warning Failed Run can't be run because it runs a Pipeline recursively",
+		},
 	}}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -432,6 +784,9 @@ func TestReconcilePipRun(t *testing.T) {
 			}
 
 			createdPipelineRun := getCreatedPipelineRun(clients)
+			if tc.expectedPipelineRun == nil && createdPipelineRun != nil {
+				t.Errorf("A PipelineRun should not have been created but was: %v", createdPipelineRun)
+			}
 			if tc.expectedPipelineRun != nil {
 				if createdPipelineRun == nil {
 					t.Errorf("A PipelineRun should have been created but was not")
@@ -455,3 +810,480 @@ func TestReconcilePipRun(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcilePipRunAncestors(t *testing.T) {
+	ownedBy := func(run *v1beta1.CustomRun, owner *v1beta1.PipelineRun) *v1beta1.CustomRun {
+		r := run.DeepCopy()
+		r.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "tekton.dev/v1beta1",
+			Kind:       "PipelineRun",
+			Name:       owner.Name,
+		}}
+		return r
+	}
+	outer := &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "outer", Namespace: "foo"},
+		Spec:       v1beta1.PipelineRunSpec{PipelineRef: &v1beta1.PipelineRef{Name: "outer"}},
+	}
+	nested := &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nested",
+			Namespace: "foo",
+			Annotations: map[string]string{
+				ancestorsAnnotation: `["foo/outer","pipeline in bundle registry.example.com/pipelines:v1"]`,
+			},
+		},
+	}
+
+	testcases := []struct {
+		name              string
+		run               *v1beta1.CustomRun
+		owner             *v1beta1.PipelineRun
+		expectedAncestors string
+		expectedMessage   string
+	}{{
+		name:              "Run in a PipelineRun of a pipeline",
+		run:               ownedBy(runWithPipeline, outer),
+		owner:             outer,
+		expectedAncestors: `["foo/outer","foo/pipeline"]`,
+	}, {
+		name:              "Run in a nested PipelineRun",
+		run:               ownedBy(runWithPipeline, nested),
+		owner:             nested,
+		expectedAncestors: `["foo/outer","pipeline in bundle registry.example.com/pipelines:v1","foo/pipeline"]`,
+	}, {
+		name:  "Run of one of the pipelines running it",
+		run:   ownedBy(runWithBundle, nested),
+		owner: nested,
+		expectedMessage: "Run can't be run because it runs a Pipeline recursively - recursive reference to Pipeline pipeline in bundle registry.example.com/pipelines:v1: " +
+			"foo/outer -> pipeline in bundle registry.example.com/pipelines:v1 -> pipeline in bundle registry.example.com/pipelines:v1",
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{p},
+				PipelineRuns: []*v1beta1.PipelineRun{tc.owner},
+			}
+
+			testAssets, _ := getPipController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			c.Reconciler.Reconcile(ctx, getRunName(tc.run))
+
+			run, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+
+			createdPipelineRun := getCreatedPipelineRun(clients)
+			if tc.expectedMessage != "" {
+				if createdPipelineRun != nil {
+					t.Errorf("A PipelineRun should not have been created but was: %v", createdPipelineRun)
+				}
+				checkRunCondition(t, run, corev1.ConditionFalse, ReasonRunFailedRecursivePipeline, tc.expectedMessage)
+				return
+			}
+			if createdPipelineRun == nil {
+				t.Fatalf("A PipelineRun should have been created but was not")
+			}
+			if got := createdPipelineRun.Annotations[ancestorsAnnotation]; got != tc.expectedAncestors {
+				t.Errorf("Expected ancestors %s but got %s", tc.expectedAncestors, got)
+			}
+		})
+	}
+}
+
+func TestReconcilePipRunInheritsOwner(t *testing.T) {
+	podTemplate := &pod.PodTemplate{NodeSelector: map[string]string{"disktype": "ssd"}}
+	taskPodTemplate := &pod.PodTemplate{NodeSelector: map[string]string{"disktype": "hdd"}}
+	owner := &v1beta1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "outer",
+			Namespace: "foo",
+			Labels: map[string]string{
+				"tekton.dev/pipeline":               "outer",
+				"triggers.tekton.dev/eventlistener": "listener",
+			},
+			Annotations: map[string]string{
+				"example.dev/commit":               "0123456789",
+				corev1.LastAppliedConfigAnnotation: "{}",
+			},
+		},
+		Spec: v1beta1.PipelineRunSpec{
+			PipelineRef:        &v1beta1.PipelineRef{Name: "outer"},
+			ServiceAccountName: "builder",
+			PodTemplate:        podTemplate,
+			Timeouts:           &v1beta1.TimeoutFields{Pipeline: &metav1.Duration{Duration: time.Hour}},
+		},
+		Status: v1beta1.PipelineRunStatus{PipelineRunStatusFields: v1beta1.PipelineRunStatusFields{
+			StartTime: &metav1.Time{Time: now.Add(-20 * time.Minute)},
+		}},
+	}
+	withTaskRunSpec := owner.DeepCopy()
+	withTaskRunSpec.Spec.TaskRunSpecs = []v1beta1.PipelineTaskRunSpec{{
+		PipelineTaskName: "greeting",
+		TaskPodTemplate:  taskPodTemplate,
+		Metadata: &v1beta1.PipelineTaskMetadata{
+			Labels:      map[string]string{"example.dev/team": "greeters"},
+			Annotations: map[string]string{"example.dev/owner": "someone"},
+		},
+	}}
+	run := runWithPipeline.DeepCopy()
+	run.Labels = map[string]string{
+		"tekton.dev/pipelineRun":  "outer",
+		"tekton.dev/pipelineTask": "greeting",
+	}
+	run.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "tekton.dev/v1beta1",
+		Kind:       "PipelineRun",
+		Name:       "outer",
+	}}
+	runWithOverrides := run.DeepCopy()
+	runWithOverrides.Spec.ServiceAccountName = "deployer"
+	runWithOverrides.Spec.Timeout = &metav1.Duration{Duration: 5 * time.Minute}
+	runWithOverrides.Annotations = map[string]string{"example.dev/commit": "9876543210"}
+
+	testcases := []struct {
+		name                string
+		run                 *v1beta1.CustomRun
+		owner               *v1beta1.PipelineRun
+		expectedSA          string
+		expectedPodTemplate *pod.PodTemplate
+		expectedTimeout     *metav1.Duration
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{{
+		name:                "Run inherits its owner",
+		run:                 run,
+		owner:               owner,
+		expectedSA:          "builder",
+		expectedPodTemplate: podTemplate,
+		expectedTimeout:     &metav1.Duration{Duration: 40 * time.Minute},
+		expectedLabels: map[string]string{
+			"triggers.tekton.dev/eventlistener": "listener",
+			"tekton.dev/pipelineRun":            "outer",
+			"tekton.dev/pipelineTask":           "greeting",
+			"tekton.dev/customRun":              "run-with-pipeline",
+			"tekton.dev/pipeline":               "pipeline",
+		},
+		expectedAnnotations: map[string]string{
+			"example.dev/commit": "0123456789",
+			ancestorsAnnotation:  `["foo/outer","foo/pipeline"]`,
+		},
+	}, {
+		name:                "Run inherits the taskRunSpec of its pipeline task",
+		run:                 run,
+		owner:               withTaskRunSpec,
+		expectedSA:          "builder",
+		expectedPodTemplate: taskPodTemplate,
+		expectedTimeout:     &metav1.Duration{Duration: 40 * time.Minute},
+		expectedLabels: map[string]string{
+			"triggers.tekton.dev/eventlistener": "listener",
+			"example.dev/team":                  "greeters",
+			"tekton.dev/pipelineRun":            "outer",
+			"tekton.dev/pipelineTask":           "greeting",
+			"tekton.dev/customRun":              "run-with-pipeline",
+			"tekton.dev/pipeline":               "pipeline",
+		},
+		expectedAnnotations: map[string]string{
+			"example.dev/commit": "0123456789",
+			"example.dev/owner":  "someone",
+			ancestorsAnnotation:  `["foo/outer","foo/pipeline"]`,
+		},
+	}, {
+		name:                "Run overrides its owner",
+		run:                 runWithOverrides,
+		owner:               owner,
+		expectedSA:          "deployer",
+		expectedPodTemplate: podTemplate,
+		expectedTimeout:     &metav1.Duration{Duration: 5 * time.Minute},
+		expectedLabels: map[string]string{
+			"triggers.tekton.dev/eventlistener": "listener",
+			"tekton.dev/pipelineRun":            "outer",
+			"tekton.dev/pipelineTask":           "greeting",
+			"tekton.dev/customRun":              "run-with-pipeline",
+			"tekton.dev/pipeline":               "pipeline",
+		},
+		expectedAnnotations: map[string]string{
+			"example.dev/commit": "9876543210",
+			ancestorsAnnotation:  `["foo/outer","foo/pipeline"]`,
+		},
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{p},
+				PipelineRuns: []*v1beta1.PipelineRun{tc.owner},
+			}
+
+			testAssets, _ := getPipController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			c.Reconciler.Reconcile(ctx, getRunName(tc.run))
+
+			createdPipelineRun := getCreatedPipelineRun(clients)
+			if createdPipelineRun == nil {
+				t.Fatalf("A PipelineRun should have been created but was not")
+			}
+			if createdPipelineRun.Spec.ServiceAccountName != tc.expectedSA {
+				t.Errorf("Expected service account %s but got %s", tc.expectedSA, createdPipelineRun.Spec.ServiceAccountName)
+			}
+			if d := cmp.Diff(tc.expectedPodTemplate, createdPipelineRun.Spec.PodTemplate); d != "" {
+				t.Errorf("PodTemplate: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedTimeout, createdPipelineRun.Spec.Timeout); d != "" {
+				t.Errorf("Timeout: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedLabels, createdPipelineRun.Labels); d != "" {
+				t.Errorf("Labels: %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedAnnotations, createdPipelineRun.Annotations); d != "" {
+				t.Errorf("Annotations: %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func TestReconcilePipRunOwnerCancellation(t *testing.T) {
+	ownedBy := func(run *v1beta1.CustomRun) *v1beta1.CustomRun {
+		r := run.DeepCopy()
+		r.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "tekton.dev/v1beta1",
+			Kind:       "PipelineRun",
+			Name:       "outer",
+		}}
+		return r
+	}
+	owner := func(status v1beta1.PipelineRunSpecStatus) *v1beta1.PipelineRun {
+		return &v1beta1.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "outer", Namespace: "foo"},
+			Spec: v1beta1.PipelineRunSpec{
+				PipelineRef: &v1beta1.PipelineRef{Name: "outer"},
+				Status:      status,
+			},
+		}
+	}
+
+	testcases := []struct {
+		name           string
+		run            *v1beta1.CustomRun
+		owner          *v1beta1.PipelineRun
+		expectedStatus v1beta1.PipelineRunSpecStatus
+	}{{
+		name:           "Run cancelled by its owner",
+		run:            ownedBy(runWithPipelineCancelled),
+		owner:          owner(v1beta1.PipelineRunSpecStatusCancelled),
+		expectedStatus: v1beta1.PipelineRunSpecStatusCancelled,
+	}, {
+		name:           "Run cancelled by its owner running its finally tasks",
+		run:            ownedBy(runWithPipelineCancelled),
+		owner:          owner(v1beta1.PipelineRunSpecStatusCancelledRunFinally),
+		expectedStatus: v1beta1.PipelineRunSpecStatusCancelledRunFinally,
+	}, {
+		name:           "Run of a gracefully stopped owner",
+		run:            ownedBy(runWithPipeline),
+		owner:          owner(v1beta1.PipelineRunSpecStatusStoppedRunFinally),
+		expectedStatus: v1beta1.PipelineRunSpecStatusStoppedRunFinally,
+	}, {
+		name:  "Run of a running owner",
+		run:   ownedBy(runWithPipeline),
+		owner: owner(""),
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{p},
+				PipelineRuns: []*v1beta1.PipelineRun{tc.owner, running(pr)},
+			}
+
+			testAssets, _ := getPipController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			c.Reconciler.Reconcile(ctx, getRunName(tc.run))
+
+			pipelineRun, err := clients.Pipeline.TektonV1beta1().PipelineRuns(pr.Namespace).Get(ctx, pr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting PipelineRun from fake client: %s", err)
+			}
+			if pipelineRun.Spec.Status != tc.expectedStatus {
+				t.Errorf("Expected PipelineRun status %q but got %q", tc.expectedStatus, pipelineRun.Spec.Status)
+			}
+		})
+	}
+}
+
+func TestReconcilePipRunRetriesAndTimeout(t *testing.T) {
+	started := func(run *v1beta1.CustomRun, startTime time.Time) *v1beta1.CustomRun {
+		r := run.DeepCopy()
+		r.Status.InitializeConditions()
+		r.Status.StartTime = &metav1.Time{Time: startTime}
+		return r
+	}
+	withRetries := func(run *v1beta1.CustomRun, retries int, attempts int) *v1beta1.CustomRun {
+		r := run.DeepCopy()
+		r.Spec.Retries = retries
+		for i := 0; i < attempts; i++ {
+			r.Status.RetriesStatus = append(r.Status.RetriesStatus, v1beta1.CustomRunStatus{})
+		}
+		return r
+	}
+	withTimeout := func(run *v1beta1.CustomRun, timeout time.Duration) *v1beta1.CustomRun {
+		r := run.DeepCopy()
+		r.Spec.Timeout = &metav1.Duration{Duration: timeout}
+		return r
+	}
+	retry1 := pr.DeepCopy()
+	retry1.Name = "run-with-pipeline-retry1"
+
+	testcases := []struct {
+		name                   string
+		run                    *v1beta1.CustomRun
+		pipelineRun            *v1beta1.PipelineRun
+		expectedStatus         corev1.ConditionStatus
+		expectedReason         string
+		expectedMessage        string
+		expectedRetries        int
+		expectedPipelineRun    string
+		expectedTimeout        *metav1.Duration
+		expectedPipelineStatus v1beta1.PipelineRunSpecStatus
+	}{{
+		name:                "Run with retries left and a failed PipelineRun",
+		run:                 withRetries(started(runWithPipeline, now), 2, 0),
+		pipelineRun:         failed(pr),
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      ReasonRunRetrying,
+		expectedMessage:     "PipelineRun run-with-pipeline failed, retrying as run-with-pipeline-retry1 (retry 1 of 2)",
+		expectedRetries:     1,
+		expectedPipelineRun: "run-with-pipeline-retry1",
+	}, {
+		name:            "Run without retries left and a failed PipelineRun",
+		run:             withRetries(started(runWithPipeline, now), 1, 1),
+		pipelineRun:     failed(retry1),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  v1beta1.PipelineRunReasonFailed.String(),
+		expectedRetries: 1,
+	}, {
+		name:            "Run with retries left and a successful PipelineRun",
+		run:             withRetries(started(runWithPipeline, now), 1, 1),
+		pipelineRun:     successful(retry1),
+		expectedStatus:  corev1.ConditionTrue,
+		expectedReason:  v1beta1.PipelineRunReasonSuccessful.String(),
+		expectedRetries: 1,
+	}, {
+		name:            "Run with retries left and results that can't be propagated",
+		run:             withRetries(started(runWithPipeline, now), 1, 0),
+		pipelineRun:     successful(withResults(withDeclaredResults(pr, "foo", "baz"), "foo", "bar")),
+		expectedStatus:  corev1.ConditionFalse,
+		expectedReason:  ReasonRunFailedPropagatingResults,
+		expectedMessage: "Run got an error propagating results - PipelineRun foo/run-with-pipeline didn't emit the results baz",
+	}, {
+		name:                "New run with a timeout",
+		run:                 withTimeout(runWithPipeline, time.Hour),
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      v1beta1.PipelineRunReasonStarted.String(),
+		expectedPipelineRun: "run-with-pipeline",
+		expectedTimeout:     &metav1.Duration{Duration: time.Hour},
+	}, {
+		name:                "Retried run with a timeout",
+		run:                 withTimeout(withRetries(started(runWithPipeline, now.Add(-20*time.Minute)), 1, 0), time.Hour),
+		pipelineRun:         failed(pr),
+		expectedStatus:      corev1.ConditionUnknown,
+		expectedReason:      ReasonRunRetrying,
+		expectedMessage:     "PipelineRun run-with-pipeline failed, retrying as run-with-pipeline-retry1 (retry 1 of 1)",
+		expectedRetries:     1,
+		expectedPipelineRun: "run-with-pipeline-retry1",
+		expectedTimeout:     &metav1.Duration{Duration: 40 * time.Minute},
+	}, {
+		name:                   "Timed out run",
+		run:                    withTimeout(withRetries(started(runWithPipeline, now.Add(-2*time.Hour)), 1, 0), time.Hour),
+		pipelineRun:            running(pr),
+		expectedStatus:         corev1.ConditionFalse,
+		expectedReason:         v1beta1.CustomRunReasonTimedOut.String(),
+		expectedMessage:        "Run foo/run-with-pipeline timed out after 1h0m0s",
+		expectedPipelineStatus: v1beta1.PipelineRunSpecStatusCancelled,
+	}, {
+		name:           "Run with a successful PipelineRun reconciled after its deadline",
+		run:            withTimeout(started(runWithPipeline, now.Add(-2*time.Hour)), time.Hour),
+		pipelineRun:    successful(pr),
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: v1beta1.PipelineRunReasonSuccessful.String(),
+	}, {
+		name:           "Run with retries left and a failed PipelineRun reconciled after its deadline",
+		run:            withTimeout(withRetries(started(runWithPipeline, now.Add(-2*time.Hour)), 1, 0), time.Hour),
+		pipelineRun:    failed(pr),
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: v1beta1.PipelineRunReasonFailed.String(),
+	}}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			optionalPipelineRuns := []*v1beta1.PipelineRun{tc.pipelineRun}
+			if tc.pipelineRun == nil {
+				optionalPipelineRuns = nil
+			}
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				Pipelines:    []*v1beta1.Pipeline{p},
+				PipelineRuns: optionalPipelineRuns,
+			}
+
+			testAssets, _ := getPipController(t, d)
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			c.Reconciler.Reconcile(ctx, getRunName(tc.run))
+
+			run, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, run, tc.expectedStatus, tc.expectedReason, tc.expectedMessage)
+			if len(run.Status.RetriesStatus) != tc.expectedRetries {
+				t.Errorf("Expected %d retries but got %d", tc.expectedRetries, len(run.Status.RetriesStatus))
+			}
+
+			createdPipelineRun := getCreatedPipelineRun(clients)
+			switch {
+			case tc.expectedPipelineRun == "" && createdPipelineRun != nil:
+				t.Errorf("A PipelineRun should not have been created but was: %v", createdPipelineRun)
+			case tc.expectedPipelineRun != "" && createdPipelineRun == nil:
+				t.Errorf("PipelineRun %s should have been created but was not", tc.expectedPipelineRun)
+			case createdPipelineRun != nil:
+				if createdPipelineRun.Name != tc.expectedPipelineRun {
+					t.Errorf("Expected PipelineRun %s to be created but got %s", tc.expectedPipelineRun, createdPipelineRun.Name)
+				}
+				if d := cmp.Diff(tc.expectedTimeout, createdPipelineRun.Spec.Timeout); d != "" {
+					t.Errorf("Timeout: %s", diff.PrintWantGot(d))
+				}
+			}
+
+			if tc.pipelineRun != nil {
+				pipelineRun, err := clients.Pipeline.TektonV1beta1().PipelineRuns(tc.pipelineRun.Namespace).Get(ctx, tc.pipelineRun.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Error getting PipelineRun from fake client: %s", err)
+				}
+				if pipelineRun.Spec.Status != tc.expectedPipelineStatus {
+					t.Errorf("Expected PipelineRun status %q but got %q", tc.expectedPipelineStatus, pipelineRun.Spec.Status)
+				}
+			}
+		})
+	}
+}
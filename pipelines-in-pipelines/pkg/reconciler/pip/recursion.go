@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pip
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
+)
+
+// ancestorsAnnotation is the annotation of the PipelineRuns created for CustomRuns which lists the Pipelines running
+// them, from the outermost one to the Pipeline of the PipelineRun itself.  It is propagated from the PipelineRun
+// owning a CustomRun to the PipelineRun created for it, so that a Pipeline which runs itself is detected even when
+// it is fetched from a bundle or with a resolver.
+const ancestorsAnnotation = "pip.tekton.dev/ancestors"
+
+// pipelineKey identifies the Pipeline referenced by a PipelineRef in a namespace, or returns "" for a nil ref.
+func pipelineKey(ref *v1beta1.PipelineRef, namespace string) string {
+	switch {
+	case ref == nil:
+		return ""
+	case ref.Resolver != "":
+		params := make([]string, 0, len(ref.Params))
+		for _, p := range ref.Params {
+			value, _ := json.Marshal(p.Value)
+			params = append(params, fmt.Sprintf("%s=%s", p.Name, value))
+		}
+		sort.Strings(params)
+		return fmt.Sprintf("%s resolver {%s}", ref.Resolver, strings.Join(params, ", "))
+	case ref.Bundle != "":
+		return fmt.Sprintf("%s in bundle %s", ref.Name, ref.Bundle)
+	default:
+		return fmt.Sprintf("%s/%s", namespace, ref.Name)
+	}
+}
+
+// getAncestors returns the keys of the Pipelines which will run the PipelineRun created for the CustomRun, from the
+// outermost one to its own Pipeline, or an error if its Pipeline runs one of them or itself.
+func getAncestors(lister listers.PipelineLister, run *v1beta1.CustomRun, spec *v1beta1.PipelineSpec, ownerPipelineRun *v1beta1.PipelineRun) ([]string, error) {
+	ancestors, err := getOwnerAncestors(ownerPipelineRun)
+	if err != nil {
+		return nil, err
+	}
+	ref := getPipelineRef(run)
+	if spec == nil && ref != nil && ref.Resolver == "" && ref.Bundle == "" {
+		if p, err := lister.Pipelines(run.Namespace).Get(ref.Name); err == nil {
+			spec = &p.Spec
+		}
+	}
+	key := pipelineKey(ref, run.Namespace)
+	if err := checkRecursion(lister, run.Namespace, key, spec, ancestors); err != nil {
+		return nil, err
+	}
+	if key != "" {
+		ancestors = append(ancestors, key)
+	}
+	return ancestors, nil
+}
+
+// getOwnerAncestors returns the keys of the Pipelines running the PipelineRun owning a CustomRun, from the outermost
+// one to the Pipeline of the PipelineRun.
+func getOwnerAncestors(ownerPipelineRun *v1beta1.PipelineRun) ([]string, error) {
+	if ownerPipelineRun == nil {
+		return nil, nil
+	}
+	if value, ok := ownerPipelineRun.Annotations[ancestorsAnnotation]; ok {
+		var ancestors []string
+		if err := json.Unmarshal([]byte(value), &ancestors); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation of PipelineRun %s: %w", ancestorsAnnotation, ownerPipelineRun.Name, err)
+		}
+		return ancestors, nil
+	}
+	if key := pipelineKey(ownerPipelineRun.Spec.PipelineRef, ownerPipelineRun.Namespace); key != "" {
+		return []string{key}, nil
+	}
+	return nil, nil
+}
+
+// checkRecursion returns an error if the Pipeline identified by key, or the Pipelines it runs, run one of the
+// Pipelines of the path.  The spec of the Pipeline is read from the lister when it isn't given, and the Pipelines it
+// runs are followed as long as they're embedded or in the namespace; those fetched from bundles or with resolvers
+// are checked with their ancestors when they run.
+func checkRecursion(lister listers.PipelineLister, namespace, key string, spec *v1beta1.PipelineSpec, path []string) error {
+	if key != "" {
+		for _, ancestor := range path {
+			if ancestor == key {
+				return fmt.Errorf("recursive reference to Pipeline %s: %s", key, strings.Join(append(path, key), " -> "))
+			}
+		}
+		path = append(path[:len(path):len(path)], key)
+	}
+	if spec == nil {
+		return nil
+	}
+	for _, task := range append(spec.Tasks, spec.Finally...) {
+		switch {
+		case task.TaskRef != nil && isPipelineKind(task.TaskRef.APIVersion, string(task.TaskRef.Kind)):
+			ref := pipelineRefFromTaskRef(task.TaskRef)
+			var childSpec *v1beta1.PipelineSpec
+			if ref.Resolver == "" && ref.Bundle == "" && ref.Name != "" {
+				if p, err := lister.Pipelines(namespace).Get(ref.Name); err == nil {
+					childSpec = &p.Spec
+				}
+			}
+			if err := checkRecursion(lister, namespace, pipelineKey(ref, namespace), childSpec, path); err != nil {
+				return err
+			}
+		case task.TaskSpec != nil && isPipelineKind(task.TaskSpec.APIVersion, task.TaskSpec.Kind):
+			var childSpec v1beta1.PipelineSpec
+			if err := json.Unmarshal(task.TaskSpec.Spec.Raw, &childSpec); err != nil {
+				return fmt.Errorf("invalid spec of Pipeline task %s: %w", task.Name, err)
+			}
+			if err := checkRecursion(lister, namespace, "", &childSpec, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
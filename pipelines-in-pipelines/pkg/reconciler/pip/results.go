@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pip
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// resultsAnnotation is the annotation of a CustomRun, or of the metadata of its embedded Pipeline, which selects the
+// results of the PipelineRun propagated to the CustomRun and optionally renames them, e.g. `digest=image-digest,url`
+// propagates the result image-digest as digest and the result url as url.  It isn't added to the PipelineRun, so
+// that the CustomRuns of its own Pipeline don't inherit it.
+const resultsAnnotation = "pip.tekton.dev/results"
+
+// resultMapping is a result of the CustomRun and the result of the PipelineRun it is propagated from.
+type resultMapping struct {
+	name string
+	from string
+}
+
+// getResultMappings returns the results selected by the results annotation of the CustomRun, or nil if all the
+// results of the PipelineRun are propagated as is.
+func getResultMappings(run *v1beta1.CustomRun) ([]resultMapping, *apis.FieldError) {
+	value, ok := run.Annotations[resultsAnnotation]
+	if run.Spec.CustomSpec != nil {
+		if v, found := run.Spec.CustomSpec.Metadata.Annotations[resultsAnnotation]; found {
+			value, ok = v, true
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	field := "metadata.annotations." + resultsAnnotation
+	mappings := []resultMapping{}
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		name, from, found := strings.Cut(entry, "=")
+		name, from = strings.TrimSpace(name), strings.TrimSpace(from)
+		if !found {
+			from = name
+		}
+		if name == "" || from == "" {
+			return nil, apis.ErrInvalidValue(value, field)
+		}
+		if seen[name] {
+			return nil, apis.ErrInvalidValue(fmt.Sprintf("result %s is propagated more than once", name), field)
+		}
+		seen[name] = true
+		mappings = append(mappings, resultMapping{name: name, from: from})
+	}
+	return mappings, nil
+}
+
+// getRunResults returns the results of the CustomRun from those of its successful PipelineRun.  Strings are
+// propagated as is, while arrays and objects are propagated as JSON since the results of CustomRuns are strings.
+// The results declared by the Pipeline, or selected by the results annotation, must all have been emitted.
+func getRunResults(run *v1beta1.CustomRun, pipelineRun *v1beta1.PipelineRun) ([]v1beta1.CustomRunResult, error) {
+	mappings, err := getResultMappings(run)
+	if err != nil {
+		return nil, err
+	}
+	pipelineResults := make(map[string]v1beta1.ResultValue, len(pipelineRun.Status.PipelineResults))
+	for _, pipelineResult := range pipelineRun.Status.PipelineResults {
+		pipelineResults[pipelineResult.Name] = pipelineResult.Value
+	}
+
+	var missing []string
+	if mappings == nil {
+		if spec := pipelineRun.Status.PipelineSpec; spec != nil {
+			for _, declared := range spec.Results {
+				if _, ok := pipelineResults[declared.Name]; !ok {
+					missing = append(missing, declared.Name)
+				}
+			}
+		}
+		for _, pipelineResult := range pipelineRun.Status.PipelineResults {
+			mappings = append(mappings, resultMapping{name: pipelineResult.Name, from: pipelineResult.Name})
+		}
+	}
+
+	var results []v1beta1.CustomRunResult
+	for _, m := range mappings {
+		value, ok := pipelineResults[m.from]
+		if !ok {
+			missing = append(missing, m.from)
+			continue
+		}
+		s, err := resultString(value)
+		if err != nil {
+			return nil, fmt.Errorf("result %s is invalid: %w", m.from, err)
+		}
+		results = append(results, v1beta1.CustomRunResult{Name: m.name, Value: s})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("PipelineRun %s/%s didn't emit the results %s", pipelineRun.Namespace, pipelineRun.Name, strings.Join(missing, ", "))
+	}
+	return results, nil
+}
+
+// resultString returns the value of a result of a PipelineRun as the value of a result of a CustomRun.
+func resultString(value v1beta1.ResultValue) (string, error) {
+	if value.Type == v1beta1.ParamTypeString || value.Type == "" {
+		return value.StringVal, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pip
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+)
+
+// ReasonRunRetrying indicates that the Run is running again after its PipelineRun failed
+const ReasonRunRetrying = "ReasonRunRetrying"
+
+// getPipelineRunName returns the name of the PipelineRun of the current attempt of the Run.  The PipelineRun of the
+// first attempt is named after the Run, and the ones of its retries are suffixed with the number of the retry.
+func getPipelineRunName(run *v1beta1.CustomRun) string {
+	if retry := len(run.Status.RetriesStatus); retry > 0 {
+		return kmeta.ChildName(run.Name, fmt.Sprintf("-retry%d", retry))
+	}
+	return run.Name
+}
+
+// canRetry returns true if the PipelineRun of the Run failed and the Run has retries left.  A cancelled Run isn't
+// retried, nor is a Run whose PipelineRun succeeded but whose results couldn't be propagated, since another attempt
+// would run the Pipeline again only to fail the same way.
+func canRetry(run *v1beta1.CustomRun) bool {
+	if c := run.Status.GetCondition(apis.ConditionSucceeded); c != nil && c.Reason == ReasonRunFailedPropagatingResults {
+		return false
+	}
+	return run.IsDone() && !run.IsSuccessful() && !run.IsCancelled() && len(run.Status.RetriesStatus) < run.Spec.Retries
+}
+
+// retry records the status of the failed attempt of the Run in its retries status, and resets its status so that the
+// PipelineRun of the next attempt is created.
+func (r *Reconciler) retry(run *v1beta1.CustomRun, pr *v1beta1.PipelineRun) {
+	attempt := run.Status.DeepCopy()
+	attempt.RetriesStatus = nil
+	if pr.Status.StartTime != nil {
+		attempt.StartTime = pr.Status.StartTime.DeepCopy()
+	}
+	if pr.Status.CompletionTime != nil {
+		attempt.CompletionTime = pr.Status.CompletionTime.DeepCopy()
+	}
+	run.Status.RetriesStatus = append(run.Status.RetriesStatus, *attempt)
+	run.Status.CompletionTime = nil
+	run.Status.Results = nil
+	run.Status.MarkCustomRunRunning(ReasonRunRetrying, "PipelineRun %s failed, retrying as %s (retry %d of %d)",
+		pr.Name, getPipelineRunName(run), len(run.Status.RetriesStatus), run.Spec.Retries)
+}
+
+// getTimeoutDeadline returns when the Run times out, including all its attempts, or false if it doesn't time out.
+func getTimeoutDeadline(run *v1beta1.CustomRun) (time.Time, bool) {
+	if run.Spec.Timeout == nil || run.Spec.Timeout.Duration == 0 || run.Status.StartTime == nil {
+		return time.Time{}, false
+	}
+	return run.Status.StartTime.Add(run.Spec.Timeout.Duration), true
+}
+
+// hasTimedOut returns true if the timeout of the Run has elapsed.
+func (r *Reconciler) hasTimedOut(run *v1beta1.CustomRun) bool {
+	deadline, ok := getTimeoutDeadline(run)
+	return ok && !r.clock.Now().Before(deadline)
+}
+
+// getRemainingRunTimeout returns the timeout of the PipelineRun of the current attempt of the Run, which is what
+// remains of the timeout of the Run.
+func (r *Reconciler) getRemainingRunTimeout(run *v1beta1.CustomRun) *metav1.Duration {
+	deadline, ok := getTimeoutDeadline(run)
+	if !ok {
+		return run.Spec.Timeout
+	}
+	remaining := deadline.Sub(r.clock.Now())
+	// A zero timeout would disable the timeout of the PipelineRun, which should rather time out right away.
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return &metav1.Duration{Duration: remaining}
+}
+
+// timeOut cancels the PipelineRun of the current attempt of a Run which timed out, if any, and marks the Run failed.
+func (r *Reconciler) timeOut(ctx context.Context, run *v1beta1.CustomRun, pr *v1beta1.PipelineRun) error {
+	if pr != nil && !pr.IsDone() {
+		logging.FromContext(ctx).Infof("Cancelling PipelineRun %s/%s since CustomRun %s timed out", pr.Namespace, pr.Name, run.Name)
+		if err := r.patchPipelineRunStatus(ctx, pr, v1beta1.PipelineRunSpecStatusCancelled); err != nil {
+			return err
+		}
+	}
+	run.Status.MarkCustomRunFailed(v1beta1.CustomRunReasonTimedOut.String(), "Run %s/%s timed out after %s", run.Namespace, run.Name, run.Spec.Timeout.Duration)
+	completionTime := metav1.NewTime(r.clock.Now())
+	run.Status.CompletionTime = &completionTime
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/tektoncd/experimental/task-loops/pkg/reconciler/tasklooprun"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/injection"
 	"knative.dev/pkg/injection/sharedmain"
 	"knative.dev/pkg/signals"
@@ -38,6 +39,7 @@ var (
 func main() {
 	flag.Parse()
 	sharedmain.MainWithContext(injection.WithNamespaceScope(signals.NewContext(), *namespace), ControllerLogKey,
-		tasklooprun.NewController(*namespace),
+		tasklooprun.NewController(*namespace, clock.RealClock{}),
+		tasklooprun.NewRunController(*namespace, clock.RealClock{}),
 	)
 }
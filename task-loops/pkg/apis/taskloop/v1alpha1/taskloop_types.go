@@ -18,7 +18,9 @@ package v1alpha1
 
 import (
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
 )
 
 // +genclient
@@ -50,10 +52,29 @@ type TaskLoopSpec struct {
 	// IterateParam is the name of the task parameter that is iterated upon.
 	IterateParam string `json:"iterateParam"`
 
-	// Time after which the TaskRun times out.
+	// IterateParamType describes how the items of IterateParam are interpreted.
+	// +optional
+	IterateParamType IterateParamType `json:"iterateParamType,omitempty"`
+
+	// IterateSource resolves the value of IterateParam from a ConfigMap or from a result of
+	// another task in the same PipelineRun instead of from the Run params.
+	// +optional
+	IterateSource *IterateSource `json:"iterateSource,omitempty"`
+
+	// StatusMode sets how the statuses of the TaskRuns are stored in the status of the Run.
+	// +optional
+	StatusMode StatusMode `json:"statusMode,omitempty"`
+
+	// Time after which the TaskRun of each iteration times out.
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	// LoopTimeout is the time after which the whole loop times out.  The TaskRuns that are still running
+	// are cancelled and the Run fails with reason LoopTimedOut.  The timeout of the Run itself, if set,
+	// bounds the loop too.
+	// +optional
+	LoopTimeout *metav1.Duration `json:"loopTimeout,omitempty"`
+
 	// Retries represents how many times a task should be retried in case of task failure.
 	// +optional
 	Retries int `json:"retries,omitempty"`
@@ -63,6 +84,52 @@ type TaskLoopSpec struct {
 	Concurrency *int `json:"concurrency,omitempty"`
 }
 
+// IterateParamType describes how the items of the iterate parameter are interpreted.
+type IterateParamType string
+
+const (
+	// IterateParamTypeObject treats every item of the iterate parameter as an arbitrary JSON value.
+	// Object items are passed in an object param whose keys are the fields of the item, e.g.
+	// $(params.item.name), and any other items are passed in a string param.
+	IterateParamTypeObject IterateParamType = "object"
+)
+
+// StatusMode describes how the statuses of the TaskRuns of a Run are stored in the status of the Run.
+type StatusMode string
+
+const (
+	// StatusModeFull stores the full status of every TaskRun.  This is the default.
+	StatusModeFull StatusMode = "full"
+
+	// StatusModeCompact stores only the iteration, condition and timestamps of every TaskRun, so that
+	// the status of a Run with many iterations fits in a Kubernetes object.  The full statuses can be
+	// looked up with the labels of the TaskRuns.
+	StatusModeCompact StatusMode = "compact"
+)
+
+// IterateSource describes where the value of the iterate parameter is read from.
+// Exactly one of its fields must be set.
+type IterateSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the Run.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// TaskResult selects a result of a task in the PipelineRun that the Run belongs to.
+	// Task results share the 4KB termination message of the TaskRun pod, so use
+	// ConfigMapKeyRef for large lists.
+	// +optional
+	TaskResult *TaskResultSelector `json:"taskResult,omitempty"`
+}
+
+// TaskResultSelector selects a result of a pipeline task.
+type TaskResultSelector struct {
+	// PipelineTask is the name of the pipeline task that emits the result.
+	PipelineTask string `json:"pipelineTask"`
+
+	// Result is the name of the result.
+	Result string `json:"result"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // TaskLoopList contains a list of TaskLoops
@@ -96,9 +163,24 @@ const (
 	// TaskLoopRunReasonCouldntGetTaskLoop indicates that the associated TaskLoop couldn't be retrieved
 	TaskLoopRunReasonCouldntGetTaskLoop TaskLoopRunReason = "CouldntGetTaskLoop"
 
+	// TaskLoopRunReasonCouldntGetTask indicates that the Task referenced by the TaskLoop couldn't be retrieved
+	TaskLoopRunReasonCouldntGetTask TaskLoopRunReason = "CouldntGetTask"
+
+	// TaskLoopRunReasonCouldntResolveIterateSource indicates that the value of the iterate parameter couldn't be
+	// read from the IterateSource of the TaskLoop
+	TaskLoopRunReasonCouldntResolveIterateSource TaskLoopRunReason = "CouldntResolveIterateSource"
+
+	// TaskLoopRunReasonMaxNestingDepthExceeded indicates that the Run is nested in more loops than
+	// the controller allows
+	TaskLoopRunReasonMaxNestingDepthExceeded TaskLoopRunReason = "MaxNestingDepthExceeded"
+
 	// TaskLoopRunReasonFailedValidation indicates that the TaskLoop failed runtime validation
 	TaskLoopRunReasonFailedValidation TaskLoopRunReason = "TaskLoopValidationFailed"
 
+	// TaskLoopRunReasonLoopTimedOut indicates that the loop didn't complete before its loop timeout or the
+	// timeout of the Run
+	TaskLoopRunReasonLoopTimedOut TaskLoopRunReason = "LoopTimedOut"
+
 	// TaskLoopRunReasonInternalError indicates that the TaskLoop failed due to an internal error in the reconciler
 	TaskLoopRunReasonInternalError TaskLoopRunReason = "TaskLoopInternalError"
 )
@@ -111,9 +193,20 @@ func (t TaskLoopRunReason) String() string {
 type TaskLoopRunStatus struct {
 	// TaskLoopSpec contains the exact spec used to instantiate the Run
 	TaskLoopSpec *TaskLoopSpec `json:"taskLoopSpec,omitempty"`
+	// IterateParamValue is the value of the iterate parameter that was resolved from the IterateSource
+	// of the TaskLoop.  It is resolved once so every iteration works on the same list.
+	// +optional
+	IterateParamValue *v1beta1.ArrayOrString `json:"iterateParamValue,omitempty"`
 	// map of TaskLoopTaskRunStatus with the taskRun name as the key
 	// +optional
 	TaskRuns map[string]*TaskLoopTaskRunStatus `json:"taskRuns,omitempty"`
+	// map of TaskLoopTaskRunReference with the TaskRun name as the key.  It is used instead of TaskRuns
+	// when the StatusMode is compact.
+	// +optional
+	TaskRunReferences map[string]*TaskLoopTaskRunReference `json:"taskRunReferences,omitempty"`
+	// Summary counts the TaskRuns of the Run by state
+	// +optional
+	Summary *TaskLoopRunSummary `json:"summary,omitempty"`
 }
 
 // TaskLoopTaskRunStatus contains the iteration number for a TaskRun and the TaskRun's Status
@@ -124,3 +217,32 @@ type TaskLoopTaskRunStatus struct {
 	// +optional
 	Status *v1beta1.TaskRunStatus `json:"status,omitempty"`
 }
+
+// TaskLoopTaskRunReference contains the iteration number, condition and timestamps of a TaskRun
+type TaskLoopTaskRunReference struct {
+	// iteration number
+	Iteration int `json:"iteration,omitempty"`
+	// Condition is the Succeeded condition of the TaskRun
+	// +optional
+	Condition *apis.Condition `json:"condition,omitempty"`
+	// StartTime is the time the TaskRun started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the TaskRun completed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// TaskLoopRunSummary counts the TaskRuns of a Run by state
+type TaskLoopRunSummary struct {
+	// Iterations is the number of iterations of the loop
+	Iterations int `json:"iterations"`
+	// Started is the number of TaskRuns that have been created
+	Started int `json:"started"`
+	// Running is the number of TaskRuns that haven't completed yet
+	Running int `json:"running"`
+	// Succeeded is the number of TaskRuns that have succeeded
+	Succeeded int `json:"succeeded"`
+	// Failed is the number of TaskRuns that have failed
+	Failed int `json:"failed"`
+}
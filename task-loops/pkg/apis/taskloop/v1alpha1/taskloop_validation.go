@@ -41,7 +41,30 @@ func (tls *TaskLoopSpec) Validate(ctx context.Context) *apis.FieldError {
 	if err := validateTask(ctx, tls); err != nil {
 		return err
 	}
-	return nil
+	if err := validateIterateParamType(tls); err != nil {
+		return err
+	}
+	if err := validateIterateSource(tls); err != nil {
+		return err
+	}
+	if err := validateStatusMode(tls); err != nil {
+		return err
+	}
+	return validateLoopTimeout(tls)
+}
+
+func validateIterateParamType(tls *TaskLoopSpec) *apis.FieldError {
+	switch tls.IterateParamType {
+	case "":
+		return nil
+	case IterateParamTypeObject:
+		if tls.IterateParam == "" {
+			return apis.ErrMissingField("spec.iterateParam")
+		}
+		return nil
+	default:
+		return apis.ErrInvalidValue(tls.IterateParamType, "spec.iterateParamType")
+	}
 }
 
 func validateTask(ctx context.Context, tls *TaskLoopSpec) *apis.FieldError {
@@ -67,3 +90,53 @@ func validateTask(ctx context.Context, tls *TaskLoopSpec) *apis.FieldError {
 	}
 	return nil
 }
+
+func validateIterateSource(tls *TaskLoopSpec) *apis.FieldError {
+	src := tls.IterateSource
+	if src == nil {
+		return nil
+	}
+	if tls.IterateParam == "" {
+		return apis.ErrMissingField("spec.iterateParam")
+	}
+	if src.ConfigMapKeyRef != nil && src.TaskResult != nil {
+		return apis.ErrMultipleOneOf("spec.iterateSource.configMapKeyRef", "spec.iterateSource.taskResult")
+	}
+	if src.ConfigMapKeyRef == nil && src.TaskResult == nil {
+		return apis.ErrMissingOneOf("spec.iterateSource.configMapKeyRef", "spec.iterateSource.taskResult")
+	}
+	var errs *apis.FieldError
+	if src.ConfigMapKeyRef != nil {
+		if src.ConfigMapKeyRef.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("spec.iterateSource.configMapKeyRef.name"))
+		}
+		if src.ConfigMapKeyRef.Key == "" {
+			errs = errs.Also(apis.ErrMissingField("spec.iterateSource.configMapKeyRef.key"))
+		}
+	}
+	if src.TaskResult != nil {
+		if src.TaskResult.PipelineTask == "" {
+			errs = errs.Also(apis.ErrMissingField("spec.iterateSource.taskResult.pipelineTask"))
+		}
+		if src.TaskResult.Result == "" {
+			errs = errs.Also(apis.ErrMissingField("spec.iterateSource.taskResult.result"))
+		}
+	}
+	return errs
+}
+
+func validateStatusMode(tls *TaskLoopSpec) *apis.FieldError {
+	switch tls.StatusMode {
+	case "", StatusModeFull, StatusModeCompact:
+		return nil
+	default:
+		return apis.ErrInvalidValue(tls.StatusMode, "spec.statusMode")
+	}
+}
+
+func validateLoopTimeout(tls *TaskLoopSpec) *apis.FieldError {
+	if tls.LoopTimeout != nil && tls.LoopTimeout.Duration < 0 {
+		return apis.ErrInvalidValue(tls.LoopTimeout.Duration.String(), "spec.loopTimeout")
+	}
+	return nil
+}
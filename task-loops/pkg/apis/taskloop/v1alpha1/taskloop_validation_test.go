@@ -19,6 +19,7 @@ package v1alpha1_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -49,11 +50,21 @@ func TestTaskLoop_Validate_Success(t *testing.T) {
 			Spec: taskloopv1alpha1.TaskLoopSpec{
 				TaskSpec: &v1beta1.TaskSpec{
 					Steps: []v1beta1.Step{{
-						Container: corev1.Container{Name: "foo", Image: "bar"},
+						Name: "foo", Image: "bar",
 					}},
 				},
 			},
 		},
+	}, {
+		name: "objectIterateParam",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:          &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam:     "item",
+				IterateParamType: taskloopv1alpha1.IterateParamTypeObject,
+			},
+		},
 	}}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -88,7 +99,7 @@ func TestTaskLoop_Validate_Error(t *testing.T) {
 				TaskRef: &v1beta1.TaskRef{Name: "mytask"},
 				TaskSpec: &v1beta1.TaskSpec{
 					Steps: []v1beta1.Step{{
-						Container: corev1.Container{Name: "foo", Image: "bar"},
+						Name: "foo", Image: "bar",
 					}},
 				},
 			},
@@ -118,7 +129,7 @@ func TestTaskLoop_Validate_Error(t *testing.T) {
 			Spec: taskloopv1alpha1.TaskLoopSpec{
 				TaskSpec: &v1beta1.TaskSpec{
 					Steps: []v1beta1.Step{{
-						Container: corev1.Container{Name: "bad@name!", Image: "bar"},
+						Name: "bad@name!", Image: "bar",
 					}},
 				},
 			},
@@ -128,6 +139,93 @@ func TestTaskLoop_Validate_Error(t *testing.T) {
 			Details: "Task step name must be a valid DNS Label, For more info refer to https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names",
 			Paths:   []string{"spec.taskSpec.steps[0].name"},
 		},
+	}, {
+		name: "unknown iterateParamType",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:          &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam:     "item",
+				IterateParamType: "map",
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: map`,
+			Paths:   []string{"spec.iterateParamType"},
+		},
+	}, {
+		name: "unknown statusMode",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:      &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam: "item",
+				StatusMode:   "tiny",
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: tiny`,
+			Paths:   []string{"spec.statusMode"},
+		},
+	}, {
+		name: "negative loopTimeout",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:      &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam: "item",
+				LoopTimeout:  &metav1.Duration{Duration: -time.Minute},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `invalid value: -1m0s`,
+			Paths:   []string{"spec.loopTimeout"},
+		},
+	}, {
+		name: "object iterateParamType without iterateParam",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:          &v1beta1.TaskRef{Name: "mytask"},
+				IterateParamType: taskloopv1alpha1.IterateParamTypeObject,
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `missing field(s)`,
+			Paths:   []string{"spec.iterateParam"},
+		},
+	}, {
+		name: "iterateSource with neither configMapKeyRef nor taskResult",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:       &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam:  "item",
+				IterateSource: &taskloopv1alpha1.IterateSource{},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: "expected exactly one, got neither",
+			Paths:   []string{"spec.iterateSource.configMapKeyRef", "spec.iterateSource.taskResult"},
+		},
+	}, {
+		name: "iterateSource with an incomplete configMapKeyRef",
+		tl: &taskloopv1alpha1.TaskLoop{
+			ObjectMeta: metav1.ObjectMeta{Name: "taskloop"},
+			Spec: taskloopv1alpha1.TaskLoopSpec{
+				TaskRef:      &v1beta1.TaskRef{Name: "mytask"},
+				IterateParam: "item",
+				IterateSource: &taskloopv1alpha1.IterateSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "items"},
+					},
+				},
+			},
+		},
+		expectedError: apis.FieldError{
+			Message: `missing field(s)`,
+			Paths:   []string{"spec.iterateSource.configMapKeyRef.key"},
+		},
 	}}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
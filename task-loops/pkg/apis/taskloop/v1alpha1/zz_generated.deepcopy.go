@@ -23,10 +23,38 @@ package v1alpha1
 
 import (
 	v1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	apis "knative.dev/pkg/apis"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IterateSource) DeepCopyInto(out *IterateSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TaskResult != nil {
+		in, out := &in.TaskResult, &out.TaskResult
+		*out = new(TaskResultSelector)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IterateSource.
+func (in *IterateSource) DeepCopy() *IterateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(IterateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TaskLoop) DeepCopyInto(out *TaskLoop) {
 	*out = *in
@@ -95,6 +123,11 @@ func (in *TaskLoopRunStatus) DeepCopyInto(out *TaskLoopRunStatus) {
 		*out = new(TaskLoopSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IterateParamValue != nil {
+		in, out := &in.IterateParamValue, &out.IterateParamValue
+		*out = new(v1beta1.ArrayOrString)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.TaskRuns != nil {
 		in, out := &in.TaskRuns, &out.TaskRuns
 		*out = make(map[string]*TaskLoopTaskRunStatus, len(*in))
@@ -110,6 +143,26 @@ func (in *TaskLoopRunStatus) DeepCopyInto(out *TaskLoopRunStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.TaskRunReferences != nil {
+		in, out := &in.TaskRunReferences, &out.TaskRunReferences
+		*out = make(map[string]*TaskLoopTaskRunReference, len(*in))
+		for key, val := range *in {
+			var outVal *TaskLoopTaskRunReference
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(TaskLoopTaskRunReference)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(TaskLoopRunSummary)
+		**out = **in
+	}
 	return
 }
 
@@ -136,11 +189,21 @@ func (in *TaskLoopSpec) DeepCopyInto(out *TaskLoopSpec) {
 		*out = new(v1beta1.TaskSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IterateSource != nil {
+		in, out := &in.IterateSource, &out.IterateSource
+		*out = new(IterateSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.LoopTimeout != nil {
+		in, out := &in.LoopTimeout, &out.LoopTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	if in.Concurrency != nil {
 		in, out := &in.Concurrency, &out.Concurrency
 		*out = new(int)
@@ -179,3 +242,64 @@ func (in *TaskLoopTaskRunStatus) DeepCopy() *TaskLoopTaskRunStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskResultSelector) DeepCopyInto(out *TaskResultSelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskResultSelector.
+func (in *TaskResultSelector) DeepCopy() *TaskResultSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskResultSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskLoopTaskRunReference) DeepCopyInto(out *TaskLoopTaskRunReference) {
+	*out = *in
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(apis.Condition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskLoopTaskRunReference.
+func (in *TaskLoopTaskRunReference) DeepCopy() *TaskLoopTaskRunReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskLoopTaskRunReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskLoopRunSummary) DeepCopyInto(out *TaskLoopRunSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskLoopRunSummary.
+func (in *TaskLoopRunSummary) DeepCopy() *TaskLoopRunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskLoopRunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
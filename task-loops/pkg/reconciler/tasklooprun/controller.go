@@ -25,39 +25,73 @@ import (
 	taskloopinformer "github.com/tektoncd/experimental/task-loops/pkg/client/injection/informers/taskloop/v1alpha1/taskloop"
 	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
 	runinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1alpha1/run"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
 	taskruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/taskrun"
 	runreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1alpha1/run"
+	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	pipelinecontroller "github.com/tektoncd/pipeline/pkg/controller"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 )
 
-// NewController instantiates a new controller.Impl from knative.dev/pkg/controller
-func NewController(namespace string) func(context.Context, configmap.Watcher) *controller.Impl {
+// NewController instantiates a new controller.Impl from knative.dev/pkg/controller that reconciles the
+// CustomRuns of TaskLoops, telling the time with the given clock.
+func NewController(namespace string, clock clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+
+		logger := logging.FromContext(ctx)
+		customRunInformer := customruninformer.Get(ctx)
+		taskRunInformer := taskruninformer.Get(ctx)
+
+		c := newReconciler(ctx, clock)
+
+		impl := customrunreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
+			return controller.Options{
+				AgentName: "customrun-taskloop",
+			}
+		})
+		c.enqueueAfter = impl.EnqueueAfter
+
+		logger.Info("Setting up event handlers")
+
+		// Add event handler for CustomRuns
+		customRunInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: pipelinecontroller.FilterCustomRunRef(taskloopv1alpha1.SchemeGroupVersion.String(), taskloop.TaskLoopControllerName),
+			Handler:    controller.HandleAll(impl.Enqueue),
+		})
+
+		// Add event handler for TaskRuns controlled by CustomRun
+		taskRunInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: pipelinecontroller.FilterOwnerCustomRunRef(customRunInformer.Lister(), taskloopv1alpha1.SchemeGroupVersion.String(), taskloop.TaskLoopControllerName),
+			Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+		})
+
+		return impl
+	}
+}
+
+// NewRunController instantiates a new controller.Impl from knative.dev/pkg/controller that reconciles the
+// v1alpha1.Runs of TaskLoops, telling the time with the given clock.
+func NewRunController(namespace string, clock clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 
 		logger := logging.FromContext(ctx)
-		pipelineclientset := pipelineclient.Get(ctx)
-		taskloopclientset := taskloopclient.Get(ctx)
 		runInformer := runinformer.Get(ctx)
-		taskLoopInformer := taskloopinformer.Get(ctx)
 		taskRunInformer := taskruninformer.Get(ctx)
 
-		c := &Reconciler{
-			pipelineClientSet: pipelineclientset,
-			taskloopClientSet: taskloopclientset,
-			runLister:         runInformer.Lister(),
-			taskLoopLister:    taskLoopInformer.Lister(),
-			taskRunLister:     taskRunInformer.Lister(),
-		}
+		c := &RunReconciler{Reconciler: newReconciler(ctx, clock)}
 
 		impl := runreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
 			return controller.Options{
 				AgentName: "run-taskloop",
 			}
 		})
+		// The CustomRun converted from a Run has the same namespace and name, so it is requeued as the Run.
+		c.enqueueAfter = impl.EnqueueAfter
 
 		logger.Info("Setting up event handlers")
 
@@ -76,3 +110,17 @@ func NewController(namespace string) func(context.Context, configmap.Watcher) *c
 		return impl
 	}
 }
+
+func newReconciler(ctx context.Context, clock clock.PassiveClock) *Reconciler {
+	return &Reconciler{
+		kubeClientSet:     kubeclient.Get(ctx),
+		pipelineClientSet: pipelineclient.Get(ctx),
+		taskloopClientSet: taskloopclient.Get(ctx),
+		runLister:         runinformer.Get(ctx).Lister(),
+		customRunLister:   customruninformer.Get(ctx).Lister(),
+		taskLoopLister:    taskloopinformer.Get(ctx).Lister(),
+		taskRunLister:     taskruninformer.Get(ctx).Lister(),
+		maxNestingDepth:   getMaxNestingDepth(logging.FromContext(ctx)),
+		clock:             clock,
+	}
+}
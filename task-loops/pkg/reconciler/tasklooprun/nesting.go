@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasklooprun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tektoncd/experimental/task-loops/pkg/apis/taskloop"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// loopDepthLabelKey is the label identifier for the nesting depth of the loop that created a run.
+	// The outermost loop has depth 1.  Loops nested in a PipelineLoop inherit the label of the enclosing
+	// loop's PipelineRun.
+	loopDepthLabelKey = "/loopDepth"
+
+	// loopRunLabelKeyPrefix is the prefix of the label identifier for the Run of the loop at a depth.
+	loopRunLabelKeyPrefix = "/loopRun-"
+
+	// loopIterationLabelKeyPrefix is the prefix of the label identifier for the iteration of the loop at a depth.
+	loopIterationLabelKeyPrefix = "/loopIteration-"
+
+	// loopIterationVariable is replaced with the iteration number in an inline taskSpec.
+	loopIterationVariable = "$(loop.iteration)"
+
+	// loopParentParamPrefix is the prefix of the params that pass the iteration values of the enclosing
+	// loop to the TaskRuns of a nested loop.
+	loopParentParamPrefix = "loop.parent."
+
+	// defaultMaxNestingDepth is the maximum nesting depth of loops unless it is set in maxNestingDepthEnvKey.
+	defaultMaxNestingDepth = 5
+
+	// maxNestingDepthEnvKey is the environment variable that sets the maximum nesting depth of loops.
+	maxNestingDepthEnvKey = "MAX_NESTING_DEPTH"
+)
+
+// getMaxNestingDepth returns the maximum nesting depth of loops configured for the controller.
+func getMaxNestingDepth(logger *zap.SugaredLogger) int {
+	value := os.Getenv(maxNestingDepthEnvKey)
+	if value == "" {
+		return defaultMaxNestingDepth
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 1 {
+		logger.Warnf("Ignoring invalid %s %q; using %d", maxNestingDepthEnvKey, value, defaultMaxNestingDepth)
+		return defaultMaxNestingDepth
+	}
+	return depth
+}
+
+// getParentLoopDepth returns the depth of the loop whose PipelineRun created the Run, or 0 if the Run
+// isn't nested in a loop.
+func getParentLoopDepth(run *v1beta1.CustomRun) int {
+	depth, err := strconv.Atoi(run.Labels[taskloop.GroupName+loopDepthLabelKey])
+	if err != nil {
+		return 0
+	}
+	return depth
+}
+
+// addLoopHierarchyLabels adds the labels that identify the loop of the Run and the iteration of the
+// TaskRun to the labels propagated from the Run.  The labels of the outer loops are already propagated
+// from the Run.
+func addLoopHierarchyLabels(labels map[string]string, run *v1beta1.CustomRun, iterationStr string) {
+	depth := strconv.Itoa(getParentLoopDepth(run) + 1)
+	labels[taskloop.GroupName+loopDepthLabelKey] = depth
+	labels[taskloop.GroupName+loopRunLabelKeyPrefix+depth] = run.Name
+	labels[taskloop.GroupName+loopIterationLabelKeyPrefix+depth] = iterationStr
+}
+
+// getParentLoopParams returns the params that pass the iteration of the enclosing loop and the params of
+// its PipelineRun to the TaskRuns of a nested loop.  It returns no params if the Run isn't nested.
+func (c *Reconciler) getParentLoopParams(ctx context.Context, run *v1beta1.CustomRun) ([]v1beta1.Param, error) {
+	parentDepth := getParentLoopDepth(run)
+	if parentDepth == 0 {
+		return nil, nil
+	}
+	prName := run.Labels[pipeline.PipelineRunLabelKey]
+	if prName == "" {
+		return nil, nil
+	}
+	pr, err := c.pipelineClientSet.TektonV1beta1().PipelineRuns(run.Namespace).Get(ctx, prName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving the PipelineRun %s of the enclosing loop: %w", prName, err)
+	}
+	out := []v1beta1.Param{{
+		Name: loopParentParamPrefix + "iteration",
+		Value: v1beta1.ArrayOrString{
+			Type:      v1beta1.ParamTypeString,
+			StringVal: pr.Labels[taskloop.GroupName+loopIterationLabelKeyPrefix+strconv.Itoa(parentDepth)],
+		},
+	}}
+	for _, p := range pr.Spec.Params {
+		// The enclosing loop's own parent params aren't passed on so that names don't keep growing with the depth.
+		if strings.HasPrefix(p.Name, loopParentParamPrefix) {
+			continue
+		}
+		out = append(out, v1beta1.Param{Name: loopParentParamPrefix + p.Name, Value: p.Value})
+	}
+	return out, nil
+}
+
+// substituteLoopIteration returns a copy of the taskSpec in which $(loop.iteration) is replaced
+// with the iteration number.
+func substituteLoopIteration(spec *v1beta1.TaskSpec, iteration int) (*v1beta1.TaskSpec, error) {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(b), loopIterationVariable) {
+		return spec, nil
+	}
+	b = []byte(strings.ReplaceAll(string(b), loopIterationVariable, strconv.Itoa(iteration)))
+	out := &v1beta1.TaskSpec{}
+	if err := json.Unmarshal(b, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasklooprun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// PipelineLoops and TaskLoops pass object items to their iterations the same way, so this file is kept
+// identical to pipeline-loops/pkg/reconciler/pipelinelooprun/objectparams.go
+// apart from the package name and the kind of run its comments mention. A change to one must be made to
+// the other.
+
+// objectKeyRegex matches the keys of an object item. They become the keys of an object param, so they
+// must follow the format that Tekton requires for the keys of object params.
+var objectKeyRegex = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9-]*$`)
+
+// getObjectItems returns the items of an iterate parameter whose items are arbitrary JSON values.
+// A string parameter must hold a JSON array and every element of an array parameter must be valid JSON.
+// Since the iterate parameter has the same type in every iteration, either all the items are JSON objects
+// or none of them are.
+func getObjectItems(p v1beta1.Param) ([]interface{}, error) {
+	var raw []json.RawMessage
+	switch p.Value.Type {
+	case v1beta1.ParamTypeArray:
+		for _, s := range p.Value.ArrayVal {
+			raw = append(raw, json.RawMessage(s))
+		}
+	default:
+		if err := json.Unmarshal([]byte(p.Value.StringVal), &raw); err != nil {
+			return nil, fmt.Errorf("The value of the iterate parameter %q is not a JSON array: %v", p.Name, err)
+		}
+	}
+	items := make([]interface{}, 0, len(raw))
+	objects := 0
+	for i, r := range raw {
+		d := json.NewDecoder(bytes.NewReader(r))
+		d.UseNumber()
+		var item interface{}
+		if err := d.Decode(&item); err != nil {
+			return nil, fmt.Errorf("Item %d of the iterate parameter %q is not valid JSON: %v", i+1, p.Name, err)
+		}
+		if d.More() {
+			return nil, fmt.Errorf("Item %d of the iterate parameter %q is not valid JSON: unexpected data after the value", i+1, p.Name)
+		}
+		if _, ok := item.(map[string]interface{}); ok {
+			objects++
+		}
+		items = append(items, item)
+	}
+	if objects > 0 && objects < len(items) {
+		return nil, fmt.Errorf("The items of the iterate parameter %q must either all be JSON objects or none of them", p.Name)
+	}
+	return items, nil
+}
+
+// getObjectParam returns the param that exposes an item to the TaskRun of an iteration.
+// An object item is passed in an object param whose keys are the fields of the item, so that they
+// can be used as $(params.<name>.<key>). Any other item is passed in a string param. Strings are
+// passed as-is and all other values, including nested objects, are passed as JSON.
+func getObjectParam(name string, item interface{}) (v1beta1.Param, error) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		s, err := objectParamValue(item)
+		if err != nil {
+			return v1beta1.Param{}, fmt.Errorf("Cannot encode the value of %q: %v", name, err)
+		}
+		return v1beta1.Param{
+			Name:  name,
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: s},
+		}, nil
+	}
+	objectVal := make(map[string]string, len(fields))
+	for key, field := range fields {
+		if !objectKeyRegex.MatchString(key) {
+			return v1beta1.Param{}, fmt.Errorf("The key %q in %q cannot be used as the key of an object param; keys must start with a letter or '_' and may only contain alphanumeric characters, '-' and '_'", key, name)
+		}
+		s, err := objectParamValue(field)
+		if err != nil {
+			return v1beta1.Param{}, fmt.Errorf("Cannot encode the value of %q in %q: %v", key, name, err)
+		}
+		objectVal[key] = s
+	}
+	return v1beta1.Param{
+		Name:  name,
+		Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeObject, ObjectVal: objectVal},
+	}, nil
+}
+
+func objectParamValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasklooprun
+
+import (
+	"context"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	runv1alpha1 "github.com/tektoncd/pipeline/pkg/apis/run/v1alpha1"
+	runv1beta1 "github.com/tektoncd/pipeline/pkg/apis/run/v1beta1"
+	runreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1alpha1/run"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	pkgreconciler "knative.dev/pkg/reconciler"
+)
+
+// RunReconciler reconciles the v1alpha1.Runs of TaskLoops, so that Runs created before the controller
+// moved to CustomRuns keep working.  A Run is converted to a CustomRun, which is reconciled like any
+// other, and the status of the CustomRun is copied back to the Run.
+type RunReconciler struct {
+	*Reconciler
+}
+
+// Check that our RunReconciler implements runreconciler.Interface
+var _ runreconciler.Interface = (*RunReconciler)(nil)
+
+// ReconcileKind converts the Run to a CustomRun and reconciles it.
+func (c *RunReconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgreconciler.Event {
+	customRun := toCustomRun(run)
+	event := c.reconcileKind(ctx, customRun, runOwner{run: run})
+	copyCustomRun(customRun, run)
+	return event
+}
+
+// runOwner is the object that owns the TaskRuns of a CustomRun.  That is the CustomRun itself, unless
+// the CustomRun was converted from a Run, in which case run is the Run it was converted from.
+type runOwner struct {
+	run *v1alpha1.Run
+}
+
+// object returns the object that events about the CustomRun are emitted for.
+func (o runOwner) object(customRun *v1beta1.CustomRun) runtime.Object {
+	if o.run == nil {
+		return customRun
+	}
+	copyCustomRun(customRun, o.run)
+	return o.run
+}
+
+// reference returns the owner reference that is set on the TaskRuns of the CustomRun.
+func (o runOwner) reference(customRun *v1beta1.CustomRun) metav1.OwnerReference {
+	if o.run == nil {
+		// CustomRun.GetGroupVersionKind returns the kind of a Run, so the kind is set explicitly.
+		return *metav1.NewControllerRef(customRun, v1beta1.SchemeGroupVersion.WithKind(pipeline.CustomRunControllerName))
+	}
+	return *metav1.NewControllerRef(o.run, o.run.GetGroupVersionKind())
+}
+
+// labelKey returns the key of the label that identifies the owner on its TaskRuns.  Runs keep the key
+// they used before CustomRuns were introduced, so that their existing TaskRuns are still found.
+func (o runOwner) labelKey() string {
+	if o.run == nil {
+		return taskLoopCustomRunLabelKey
+	}
+	return taskLoopRunLabelKey
+}
+
+// podTemplate returns the pod template of a Run.  CustomRuns don't have one.
+func (o runOwner) podTemplate() *pod.PodTemplate {
+	if o.run == nil {
+		return nil
+	}
+	return o.run.Spec.PodTemplate
+}
+
+// cancelledReason returns the reason of the condition of a cancelled CustomRun.
+func (o runOwner) cancelledReason() string {
+	if o.run == nil {
+		return v1beta1.CustomRunReasonCancelled.String()
+	}
+	return v1alpha1.RunReasonCancelled.String()
+}
+
+// toCustomRun returns a CustomRun with the metadata, spec and status of a Run.
+func toCustomRun(run *v1alpha1.Run) *v1beta1.CustomRun {
+	customRun := &v1beta1.CustomRun{
+		ObjectMeta: *run.ObjectMeta.DeepCopy(),
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef:          run.Spec.Ref.DeepCopy(),
+			Params:             run.Spec.Params,
+			Status:             v1beta1.CustomRunSpecStatus(run.Spec.Status),
+			StatusMessage:      v1beta1.CustomRunSpecStatusMessage(run.Spec.StatusMessage),
+			Retries:            run.Spec.Retries,
+			ServiceAccountName: run.Spec.ServiceAccountName,
+			Timeout:            run.Spec.Timeout,
+			Workspaces:         run.Spec.Workspaces,
+		},
+		Status: runv1beta1.FromRunStatus(*run.Status.DeepCopy()),
+	}
+	if run.Spec.Spec != nil {
+		customRun.Spec.CustomSpec = &v1beta1.EmbeddedCustomRunSpec{
+			TypeMeta: run.Spec.Spec.TypeMeta,
+			Metadata: run.Spec.Spec.Metadata,
+			Spec:     run.Spec.Spec.Spec,
+		}
+	}
+	return customRun
+}
+
+// copyCustomRun copies the labels, annotations and status that reconciling a CustomRun changes to the
+// Run it was converted from.
+func copyCustomRun(customRun *v1beta1.CustomRun, run *v1alpha1.Run) {
+	run.Labels = customRun.Labels
+	run.Annotations = customRun.Annotations
+	run.Status = toRunStatus(customRun.Status)
+}
+
+// toRunStatus converts the status of a CustomRun to the status of a Run.
+func toRunStatus(status runv1beta1.CustomRunStatus) runv1alpha1.RunStatus {
+	rs := runv1alpha1.RunStatus{
+		Status: status.Status,
+		RunStatusFields: runv1alpha1.RunStatusFields{
+			StartTime:      status.StartTime,
+			CompletionTime: status.CompletionTime,
+			ExtraFields:    status.ExtraFields,
+		},
+	}
+	for _, r := range status.Results {
+		rs.Results = append(rs.Results, runv1alpha1.RunResult{Name: r.Name, Value: r.Value})
+	}
+	for _, retryStatus := range status.RetriesStatus {
+		rs.RetriesStatus = append(rs.RetriesStatus, toRunStatus(retryStatus))
+	}
+	return rs
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasklooprun
+
+import (
+	taskloopv1alpha1 "github.com/tektoncd/experimental/task-loops/pkg/apis/taskloop/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// setTaskRunStatus records the status of a TaskRun in the status of the Run, in full or compact form
+// depending on the StatusMode of the TaskLoop.
+func setTaskRunStatus(status *taskloopv1alpha1.TaskLoopRunStatus, mode taskloopv1alpha1.StatusMode, name string, iteration int, trStatus *v1beta1.TaskRunStatus) {
+	if mode == taskloopv1alpha1.StatusModeCompact {
+		if status.TaskRunReferences == nil {
+			status.TaskRunReferences = make(map[string]*taskloopv1alpha1.TaskLoopTaskRunReference)
+		}
+		ref := &taskloopv1alpha1.TaskLoopTaskRunReference{Iteration: iteration}
+		if trStatus != nil {
+			ref.Condition = trStatus.GetCondition(apis.ConditionSucceeded)
+			ref.StartTime = trStatus.StartTime
+			ref.CompletionTime = trStatus.CompletionTime
+		}
+		status.TaskRunReferences[name] = ref
+		return
+	}
+	if status.TaskRuns == nil {
+		status.TaskRuns = make(map[string]*taskloopv1alpha1.TaskLoopTaskRunStatus)
+	}
+	status.TaskRuns[name] = &taskloopv1alpha1.TaskLoopTaskRunStatus{
+		Iteration: iteration,
+		Status:    trStatus,
+	}
+}
+
+// migrateStatus converts the statuses of the TaskRuns that were stored in another form than the StatusMode
+// of the TaskLoop asks for, e.g. by a controller that didn't support compact statuses.
+func migrateStatus(status *taskloopv1alpha1.TaskLoopRunStatus, mode taskloopv1alpha1.StatusMode) {
+	if mode == taskloopv1alpha1.StatusModeCompact {
+		for name, trs := range status.TaskRuns {
+			setTaskRunStatus(status, mode, name, trs.Iteration, trs.Status)
+		}
+		status.TaskRuns = nil
+		return
+	}
+	// The full statuses can't be rebuilt from the references; they are read from the TaskRuns again.
+	status.TaskRunReferences = nil
+}
+
+// updateRunSummary counts the TaskRuns recorded in the status of the Run by state.
+func updateRunSummary(status *taskloopv1alpha1.TaskLoopRunStatus, iterations int) {
+	summary := &taskloopv1alpha1.TaskLoopRunSummary{Iterations: iterations}
+	count := func(condition *apis.Condition) {
+		summary.Started++
+		switch {
+		case condition.IsTrue():
+			summary.Succeeded++
+		case condition.IsFalse():
+			summary.Failed++
+		default:
+			summary.Running++
+		}
+	}
+	for _, trs := range status.TaskRuns {
+		if trs.Status == nil {
+			count(nil)
+			continue
+		}
+		count(trs.Status.GetCondition(apis.ConditionSucceeded))
+	}
+	for _, ref := range status.TaskRunReferences {
+		count(ref.Condition)
+	}
+	status.Summary = summary
+}
@@ -32,10 +32,9 @@ import (
 	taskloopclientset "github.com/tektoncd/experimental/task-loops/pkg/client/clientset/versioned"
 	listerstaskloop "github.com/tektoncd/experimental/task-loops/pkg/client/listers/taskloop/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
-	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	clientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
-	runreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1alpha1/run"
+	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	listersalpha "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1alpha1"
 	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/names"
@@ -46,6 +45,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
@@ -58,22 +60,30 @@ const (
 	// taskLoopRunLabelKey is the label identifier for a Run.  This label is added to the Run's TaskRuns.
 	taskLoopRunLabelKey = "/run"
 
+	// taskLoopCustomRunLabelKey is the label identifier for a CustomRun.  This label is added to the CustomRun's TaskRuns.
+	taskLoopCustomRunLabelKey = "/customRun"
+
 	// taskLoopIterationLabelKey is the label identifier for the iteration number.  This label is added to the Run's TaskRuns.
 	taskLoopIterationLabelKey = "/taskLoopIteration"
 )
 
 // Reconciler implements controller.Reconciler for Configuration resources.
 type Reconciler struct {
+	kubeClientSet     kubernetes.Interface
 	pipelineClientSet clientset.Interface
 	taskloopClientSet taskloopclientset.Interface
 	runLister         listersalpha.RunLister
+	customRunLister   listers.CustomRunLister
 	taskLoopLister    listerstaskloop.TaskLoopLister
 	taskRunLister     listers.TaskRunLister
+	enqueueAfter      func(interface{}, time.Duration)
+	maxNestingDepth   int
+	clock             clock.PassiveClock
 }
 
 var (
-	// Check that our Reconciler implements runreconciler.Interface
-	_                runreconciler.Interface = (*Reconciler)(nil)
+	// Check that our Reconciler implements customrunreconciler.Interface
+	_                customrunreconciler.Interface = (*Reconciler)(nil)
 	cancelPatchBytes []byte
 )
 
@@ -91,17 +101,19 @@ func init() {
 }
 
 // ReconcileKind compares the actual state with the desired, and attempts to converge the two.
-// It then updates the Status block of the Run resource with the current status of the resource.
-func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgreconciler.Event {
+// It then updates the Status block of the CustomRun resource with the current status of the resource.
+func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun) pkgreconciler.Event {
+	return c.reconcileKind(ctx, run, runOwner{})
+}
+
+func (c *Reconciler) reconcileKind(ctx context.Context, run *v1beta1.CustomRun, owner runOwner) pkgreconciler.Event {
 	var merr error
 	logger := logging.FromContext(ctx)
-	logger.Infof("Reconciling Run %s/%s at %v", run.Namespace, run.Name, time.Now())
+	logger.Infof("Reconciling Run %s/%s at %v", run.Namespace, run.Name, c.clock.Now())
 
-	// Check that the Run references a TaskLoop CRD.  The logic is controller.go should ensure that only this type of Run
+	// Check that the Run references or embeds a TaskLoop CRD.  The logic is controller.go should ensure that only this type of Run
 	// is reconciled this controller but it never hurts to do some bullet-proofing.
-	if run.Spec.Ref == nil ||
-		run.Spec.Ref.APIVersion != taskloopv1alpha1.SchemeGroupVersion.String() ||
-		run.Spec.Ref.Kind != taskloop.TaskLoopControllerName {
+	if !isTaskLoopRun(run) {
 		logger.Errorf("Received control for a Run %s/%s that does not reference a TaskLoop custom CRD", run.Namespace, run.Name)
 		return nil
 	}
@@ -109,6 +121,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 	// If the Run has not started, initialize the Condition and set the start time.
 	if !run.HasStarted() {
 		logger.Infof("Starting new Run %s/%s", run.Namespace, run.Name)
+		run.Status.StartTime = &metav1.Time{Time: c.clock.Now()}
 		run.Status.InitializeConditions()
 		// In case node time was not synchronized, when controller has been scheduled to other nodes.
 		if run.Status.StartTime.Sub(run.CreationTimestamp.Time) < 0 {
@@ -121,7 +134,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 		// We also want to send the "Started" event as soon as possible for anyone who may be waiting
 		// on the event to perform user facing initialisations, such has reset a CI check status
 		afterCondition := run.Status.GetCondition(apis.ConditionSucceeded)
-		events.Emit(ctx, nil, afterCondition, run)
+		events.Emit(ctx, nil, afterCondition, owner.object(run))
 	}
 
 	if run.IsDone() {
@@ -134,36 +147,36 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1alpha1.Run) pkgre
 
 	status := &taskloopv1alpha1.TaskLoopRunStatus{}
 	if err := run.Status.DecodeExtraFields(status); err != nil {
-		run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonInternalError.String(),
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonInternalError.String(),
 			"Internal error calling DecodeExtraFields: %v", err)
 		logger.Errorf("DecodeExtraFields error: %v", err.Error())
 	}
 
 	// Reconcile the Run
-	if err := c.reconcile(ctx, run, status); err != nil {
+	if err := c.reconcile(ctx, run, owner, status); err != nil {
 		logger.Errorf("Reconcile error: %v", err.Error())
 		merr = multierror.Append(merr, err)
 	}
 
-	if err := c.updateLabelsAndAnnotations(ctx, run); err != nil {
+	if err := c.updateLabelsAndAnnotations(ctx, run, owner); err != nil {
 		logger.Warn("Failed to update Run labels/annotations", zap.Error(err))
 		merr = multierror.Append(merr, err)
 	}
 
 	if err := run.Status.EncodeExtraFields(status); err != nil {
-		run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonInternalError.String(),
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonInternalError.String(),
 			"Internal error calling EncodeExtraFields: %v", err)
 		logger.Errorf("EncodeExtraFields error: %v", err.Error())
 	}
 
 	afterCondition := run.Status.GetCondition(apis.ConditionSucceeded)
-	events.Emit(ctx, beforeCondition, afterCondition, run)
+	events.Emit(ctx, beforeCondition, afterCondition, owner.object(run))
 
 	// Only transient errors that should retry the reconcile are returned.
 	return merr
 }
 
-func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *taskloopv1alpha1.TaskLoopRunStatus) error {
+func (c *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun, owner runOwner, status *taskloopv1alpha1.TaskLoopRunStatus) error {
 	logger := logging.FromContext(ctx)
 
 	// Get the TaskLoop referenced by the Run
@@ -180,44 +193,99 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *t
 
 	// Validate TaskLoop spec
 	if err := taskLoopSpec.Validate(ctx); err != nil {
-		run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
 			"TaskLoop %s/%s can't be Run; it has an invalid spec: %s",
 			taskLoopMeta.Namespace, taskLoopMeta.Name, err)
 		return nil
 	}
 
+	// Refuse to run loops that are nested too deep, e.g. a PipelineLoop whose pipeline runs the loop itself.
+	if depth := getParentLoopDepth(run) + 1; depth > c.maxNestingDepth {
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonMaxNestingDepthExceeded.String(),
+			"Run %s/%s is nested %d loops deep, which exceeds the maximum nesting depth of %d",
+			run.Namespace, run.Name, depth, c.maxNestingDepth)
+		return nil
+	}
+
+	// Resolve the iterate parameter from its source only once and store it on the Run, so that every
+	// iteration works on the same list even if the source changes, and the list can be audited later.
+	params := run.Spec.Params
+	if taskLoopSpec.IterateSource != nil {
+		if status.IterateParamValue == nil {
+			value, err := c.resolveIterateSource(ctx, run, taskLoopSpec)
+			if err != nil {
+				run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntResolveIterateSource.String(),
+					"Cannot resolve the value of the iterate parameter %q: %s", taskLoopSpec.IterateParam, err)
+				return nil
+			}
+			status.IterateParamValue = value
+		}
+		params = setParam(params, taskLoopSpec.IterateParam, *status.IterateParamValue)
+	}
+
 	// Determine how many iterations of the Task will be done.
-	totalIterations, err := computeIterations(run, taskLoopSpec)
+	totalIterations, err := computeIterations(params, taskLoopSpec)
 	if err != nil {
-		run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
 			"Cannot determine number of iterations: %s", err)
 		return nil
 	}
 
+	// Check if the loop has run past its deadline.  If it hasn't, requeue the Run at the deadline so that
+	// it times out even if none of its TaskRuns change by then.
+	deadline := getLoopDeadline(run, taskLoopSpec)
+	timedOut := deadline != nil && !c.clock.Now().Before(*deadline)
+	if deadline != nil && !timedOut {
+		c.enqueueAfter(run, deadline.Sub(c.clock.Now()))
+	}
+
+	// The running TaskRuns are cancelled if the Run is cancelled or has timed out.
+	stopReason := ""
+	if run.IsCancelled() {
+		stopReason = "cancelled"
+	} else if timedOut {
+		stopReason = "timed out"
+	}
+
 	// Update the status of the TaskRuns created from this Run on prior reconciliations.
 	// updateTaskRunStatus() also handles TaskRun cancellation and retry.
 	// It returns the total number of TaskRuns that are running now, the highest
 	// iteration number processed so far, and an indicator whether any TaskRun has failed.
-	totalRunning, highestIteration, taskRunFailed, err := c.updateTaskRunStatus(ctx, logger, run, status, taskLoopSpec)
+	// Convert the statuses of TaskRuns stored by an earlier reconcile in another form first.
+	migrateStatus(status, taskLoopSpec.StatusMode)
+	totalRunning, highestIteration, taskRunFailed, err := c.updateTaskRunStatus(ctx, logger, run, owner, status, taskLoopSpec, stopReason)
 	if err != nil {
 		return fmt.Errorf("error updating TaskRun status for Run %s/%s: %w", run.Namespace, run.Name, err)
 	}
+	updateRunSummary(status, totalIterations)
 
 	// Check if the run was cancelled.  Since updateTaskRunStatus() handled cancelling any running TaskRuns
 	// the only thing to do here is to determine if all running TaskRuns have finished.
 	if run.IsCancelled() {
 		// If no TaskRuns are running, mark the Run as failed.
 		if totalRunning == 0 {
-			run.Status.MarkRunFailed(v1alpha1.RunReasonCancelled,
+			run.Status.MarkCustomRunFailed(owner.cancelledReason(),
 				"Run %s/%s was cancelled", run.Namespace, run.Name)
 		} else {
 			// The Run is still running until the TaskRuns process their cancel requests.
-			run.Status.MarkRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
+			run.Status.MarkCustomRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
 				"Cancelling TaskRuns")
 		}
 		return nil
 	}
 
+	// Check if the loop timed out before all of its TaskRuns completed successfully.  updateTaskRunStatus()
+	// cancelled the running TaskRuns, so wait for them to finish before marking the Run as failed.
+	if timedOut && (highestIteration < totalIterations || totalRunning > 0 || taskRunFailed) {
+		if totalRunning == 0 {
+			markLoopTimedOut(run, *deadline)
+		} else {
+			run.Status.MarkCustomRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
+				"Cancelling TaskRuns as the loop timed out")
+		}
+		return nil
+	}
+
 	// Check if the Run is done.
 	//   1) TaskRuns were created for all iterations OR a TaskRun has failed.
 	//      (TaskRun failure stops submission of any remaining iterations.)
@@ -226,20 +294,39 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *t
 	if highestIteration == totalIterations || taskRunFailed {
 		if totalRunning == 0 {
 			if taskRunFailed {
-				run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailed.String(),
+				run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailed.String(),
 					"One or more TaskRuns have failed")
 			} else {
-				run.Status.MarkRunSucceeded(taskloopv1alpha1.TaskLoopRunReasonSucceeded.String(),
+				run.Status.MarkCustomRunSucceeded(taskloopv1alpha1.TaskLoopRunReasonSucceeded.String(),
 					"All TaskRuns completed successfully")
 			}
 		} else {
 			// Update number of iterations completed.
-			run.Status.MarkRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
+			run.Status.MarkCustomRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
 				"Iterations completed: %d", highestIteration-totalRunning)
 		}
 		return nil
 	}
 
+	// Runs nested in a PipelineLoop are passed the iteration and params of the enclosing loop.
+	parentParams, err := c.getParentLoopParams(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	// Parent loop values are passed in params that the Task may not declare but a TaskRun can't be
+	// given params that its Task doesn't declare, so find out which params the Task declares.
+	var taskParamNames sets.String
+	if len(parentParams) > 0 {
+		taskParamNames, err = c.getTaskParamNames(ctx, run, taskLoopSpec)
+		if err != nil {
+			run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTask.String(),
+				"Error retrieving Task for Run %s/%s: %s",
+				run.Namespace, run.Name, err)
+			return nil
+		}
+	}
+
 	// Create TaskRuns for the next iterations.  Continue creating them until the concurrency
 	// limit is reached.  If the limit is unspecified, it defaults to 1 (sequential execution).
 	// If the limit is 0 or negative, then TaskRuns are created for all iterations at once.
@@ -250,70 +337,116 @@ func (c *Reconciler) reconcile(ctx context.Context, run *v1alpha1.Run, status *t
 	}
 	for nextIteration <= totalIterations && (concurrency <= 0 || totalRunning < concurrency) {
 		// Create a TaskRun to run the next iteration.
-		tr, err := c.createTaskRun(ctx, logger, taskLoopSpec, run, nextIteration)
+		tr, err := c.createTaskRun(ctx, logger, taskLoopSpec, run, owner, params, parentParams, nextIteration, taskParamNames)
 		if err != nil {
 			return fmt.Errorf("error creating TaskRun from Run %s: %w", run.Name, err)
 		}
-		status.TaskRuns[tr.Name] = &taskloopv1alpha1.TaskLoopTaskRunStatus{
-			Iteration: nextIteration,
-			Status:    &tr.Status,
-		}
+		setTaskRunStatus(status, taskLoopSpec.StatusMode, tr.Name, nextIteration, &tr.Status)
 		totalRunning++
 		nextIteration++
 	}
+	updateRunSummary(status, totalIterations)
 
-	run.Status.MarkRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
+	run.Status.MarkCustomRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(),
 		"Iterations completed: %d", nextIteration-totalRunning-1)
 
 	return nil
 }
 
-func (c *Reconciler) getTaskLoop(ctx context.Context, run *v1alpha1.Run) (*metav1.ObjectMeta, *taskloopv1alpha1.TaskLoopSpec, error) {
+func (c *Reconciler) getTaskLoop(ctx context.Context, run *v1beta1.CustomRun) (*metav1.ObjectMeta, *taskloopv1alpha1.TaskLoopSpec, error) {
 	taskLoopMeta := metav1.ObjectMeta{}
 	taskLoopSpec := taskloopv1alpha1.TaskLoopSpec{}
-	if run.Spec.Ref != nil && run.Spec.Ref.Name != "" {
+	if run.Spec.CustomRef != nil && run.Spec.CustomRef.Name != "" {
 		// Use the k8 client to get the TaskLoop rather than the lister.  This avoids a timing issue where
 		// the TaskLoop is not yet in the lister cache if it is created at nearly the same time as the Run.
 		// See https://github.com/tektoncd/pipeline/issues/2740 for discussion on this issue.
 		//
-		// tl, err := c.taskLoopLister.TaskLoops(run.Namespace).Get(run.Spec.Ref.Name)
-		tl, err := c.taskloopClientSet.CustomV1alpha1().TaskLoops(run.Namespace).Get(ctx, run.Spec.Ref.Name, metav1.GetOptions{})
+		// tl, err := c.taskLoopLister.TaskLoops(run.Namespace).Get(run.Spec.CustomRef.Name)
+		tl, err := c.taskloopClientSet.CustomV1alpha1().TaskLoops(run.Namespace).Get(ctx, run.Spec.CustomRef.Name, metav1.GetOptions{})
 		if err != nil {
-			run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop.String(),
+			run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop.String(),
 				"Error retrieving TaskLoop for Run %s/%s: %s",
 				run.Namespace, run.Name, err)
 			return nil, nil, fmt.Errorf("Error retrieving TaskLoop for Run %s: %w", fmt.Sprintf("%s/%s", run.Namespace, run.Name), err)
 		}
 		taskLoopMeta = tl.ObjectMeta
 		taskLoopSpec = tl.Spec
+	} else if run.Spec.CustomSpec != nil {
+		// The TaskLoop spec is embedded in the Run.  It has no name, so only its metadata is propagated.
+		if err := json.Unmarshal(run.Spec.CustomSpec.Spec.Raw, &taskLoopSpec); err != nil {
+			run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop.String(),
+				"Error parsing the embedded TaskLoop spec of Run %s/%s: %s",
+				run.Namespace, run.Name, err)
+			return nil, nil, fmt.Errorf("Error parsing the embedded TaskLoop spec of Run %s: %w", fmt.Sprintf("%s/%s", run.Namespace, run.Name), err)
+		}
+		taskLoopMeta = metav1.ObjectMeta{
+			Namespace:   run.Namespace,
+			Labels:      run.Spec.CustomSpec.Metadata.Labels,
+			Annotations: run.Spec.CustomSpec.Metadata.Annotations,
+		}
 	} else {
 		// Run does not require name but for TaskLoop it does.
-		run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop.String(),
-			"Missing spec.ref.name for Run %s/%s",
+		run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop.String(),
+			"Missing spec.customRef.name or spec.customSpec for Run %s/%s",
 			run.Namespace, run.Name)
-		return nil, nil, fmt.Errorf("Missing spec.ref.name for Run %s", fmt.Sprintf("%s/%s", run.Namespace, run.Name))
+		return nil, nil, fmt.Errorf("Missing spec.customRef.name or spec.customSpec for Run %s", fmt.Sprintf("%s/%s", run.Namespace, run.Name))
 	}
 	return &taskLoopMeta, &taskLoopSpec, nil
 }
 
-func (c *Reconciler) createTaskRun(ctx context.Context, logger *zap.SugaredLogger, tls *taskloopv1alpha1.TaskLoopSpec, run *v1alpha1.Run, iteration int) (*v1beta1.TaskRun, error) {
+func (c *Reconciler) getTaskParamNames(ctx context.Context, run *v1beta1.CustomRun, tls *taskloopv1alpha1.TaskLoopSpec) (sets.String, error) {
+	var params []v1beta1.ParamSpec
+	switch {
+	case tls.TaskSpec != nil:
+		params = tls.TaskSpec.Params
+	case tls.TaskRef.Kind == v1beta1.ClusterTaskKind:
+		ct, err := c.pipelineClientSet.TektonV1beta1().ClusterTasks().Get(ctx, tls.TaskRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		params = ct.Spec.Params
+	default:
+		t, err := c.pipelineClientSet.TektonV1beta1().Tasks(run.Namespace).Get(ctx, tls.TaskRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		params = t.Spec.Params
+	}
+	paramNames := sets.NewString()
+	for _, p := range params {
+		paramNames.Insert(p.Name)
+	}
+	return paramNames, nil
+}
+
+func (c *Reconciler) createTaskRun(ctx context.Context, logger *zap.SugaredLogger, tls *taskloopv1alpha1.TaskLoopSpec, run *v1beta1.CustomRun, owner runOwner, runParams []v1beta1.Param, parentParams []v1beta1.Param, iteration int, taskParamNames sets.String) (*v1beta1.TaskRun, error) {
 
 	// Create name for TaskRun from Run name plus iteration number.
 	trName := names.SimpleNameGenerator.RestrictLengthWithRandomSuffix(fmt.Sprintf("%s-%s", run.Name, fmt.Sprintf("%05d", iteration)))
 
+	params, err := getParameters(runParams, tls, iteration)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parentParams {
+		if taskParamNames.Has(p.Name) {
+			params = append(params, p)
+		}
+	}
+
 	tr := &v1beta1.TaskRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            trName,
 			Namespace:       run.Namespace,
-			OwnerReferences: []metav1.OwnerReference{run.GetOwnerReference()},
-			Labels:          getTaskRunLabels(run, strconv.Itoa(iteration), true),
+			OwnerReferences: []metav1.OwnerReference{owner.reference(run)},
+			Labels:          getTaskRunLabels(run, owner, strconv.Itoa(iteration), true),
 			Annotations:     getTaskRunAnnotations(run),
 		},
 		Spec: v1beta1.TaskRunSpec{
-			Params:             getParameters(run, tls, iteration),
+			Params:             params,
 			Timeout:            tls.Timeout,
 			ServiceAccountName: run.Spec.ServiceAccountName,
-			PodTemplate:        run.Spec.PodTemplate,
+			PodTemplate:        owner.podTemplate(),
 			Workspaces:         run.Spec.Workspaces,
 		}}
 
@@ -323,7 +456,11 @@ func (c *Reconciler) createTaskRun(ctx context.Context, logger *zap.SugaredLogge
 			Kind: tls.TaskRef.Kind,
 		}
 	} else if tls.TaskSpec != nil {
-		tr.Spec.TaskSpec = tls.TaskSpec
+		taskSpec, err := substituteLoopIteration(tls.TaskSpec, iteration)
+		if err != nil {
+			return nil, err
+		}
+		tr.Spec.TaskSpec = taskSpec
 	}
 
 	logger.Infof("Creating a new TaskRun object %s", trName)
@@ -345,12 +482,22 @@ func (c *Reconciler) retryTaskRun(ctx context.Context, tr *v1beta1.TaskRun) (*v1
 	return c.pipelineClientSet.TektonV1beta1().TaskRuns(tr.Namespace).UpdateStatus(ctx, tr, metav1.UpdateOptions{})
 }
 
-func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1alpha1.Run) error {
-	newRun, err := c.runLister.Runs(run.Namespace).Get(run.Name)
-	if err != nil {
-		return fmt.Errorf("error getting Run %s when updating labels/annotations: %w", run.Name, err)
+func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1beta1.CustomRun, owner runOwner) error {
+	var newMeta metav1.ObjectMeta
+	if owner.run != nil {
+		newRun, err := c.runLister.Runs(run.Namespace).Get(run.Name)
+		if err != nil {
+			return fmt.Errorf("error getting Run %s when updating labels/annotations: %w", run.Name, err)
+		}
+		newMeta = newRun.ObjectMeta
+	} else {
+		newRun, err := c.customRunLister.CustomRuns(run.Namespace).Get(run.Name)
+		if err != nil {
+			return fmt.Errorf("error getting CustomRun %s when updating labels/annotations: %w", run.Name, err)
+		}
+		newMeta = newRun.ObjectMeta
 	}
-	if !reflect.DeepEqual(run.ObjectMeta.Labels, newRun.ObjectMeta.Labels) || !reflect.DeepEqual(run.ObjectMeta.Annotations, newRun.ObjectMeta.Annotations) {
+	if !reflect.DeepEqual(run.ObjectMeta.Labels, newMeta.Labels) || !reflect.DeepEqual(run.ObjectMeta.Annotations, newMeta.Annotations) {
 		mergePatch := map[string]interface{}{
 			"metadata": map[string]interface{}{
 				"labels":      run.ObjectMeta.Labels,
@@ -361,23 +508,24 @@ func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1alph
 		if err != nil {
 			return err
 		}
-		_, err = c.pipelineClientSet.TektonV1alpha1().Runs(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if owner.run != nil {
+			_, err = c.pipelineClientSet.TektonV1alpha1().Runs(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		} else {
+			_, err = c.pipelineClientSet.TektonV1beta1().CustomRuns(run.Namespace).Patch(ctx, run.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		}
 		return err
 	}
 	return nil
 }
 
-func (c *Reconciler) updateTaskRunStatus(ctx context.Context, logger *zap.SugaredLogger, run *v1alpha1.Run, status *taskloopv1alpha1.TaskLoopRunStatus,
-	taskLoopSpec *taskloopv1alpha1.TaskLoopSpec) (totalRunning int, highestIteration int, taskRunFailed bool, retryableErr error) {
-	if status.TaskRuns == nil {
-		status.TaskRuns = make(map[string]*taskloopv1alpha1.TaskLoopTaskRunStatus)
-	}
+func (c *Reconciler) updateTaskRunStatus(ctx context.Context, logger *zap.SugaredLogger, run *v1beta1.CustomRun, owner runOwner, status *taskloopv1alpha1.TaskLoopRunStatus,
+	taskLoopSpec *taskloopv1alpha1.TaskLoopSpec, stopReason string) (totalRunning int, highestIteration int, taskRunFailed bool, retryableErr error) {
 	// List TaskRuns associated with this Run.  These TaskRuns should be recorded in the Run status but it's
 	// possible that this reconcile call has been passed stale status which doesn't include a previous update.
 	// Find the TaskRuns by matching labels.  Do not include the propagated labels from the Run.
 	// The user could change them during the lifetime of the Run so the current labels may not be set on the
 	// previously created TaskRuns.
-	taskRunLabels := getTaskRunLabels(run, "", false)
+	taskRunLabels := getTaskRunLabels(run, owner, "", false)
 	taskRuns, err := c.taskRunLister.TaskRuns(run.Namespace).List(labels.SelectorFromSet(taskRunLabels))
 	if err != nil {
 		retryableErr = fmt.Errorf("could not list TaskRuns %#v", err)
@@ -392,14 +540,11 @@ func (c *Reconciler) updateTaskRunStatus(ctx context.Context, logger *zap.Sugare
 		iteration, err := strconv.Atoi(iterationStr)
 		if err != nil {
 			logger.Errorf("Error converting iteration number in TaskRun %s:  %#v", tr.Name, err)
-			run.Status.MarkRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
+			run.Status.MarkCustomRunFailed(taskloopv1alpha1.TaskLoopRunReasonFailedValidation.String(),
 				"Error converting iteration number in TaskRun %s:  %#v", tr.Name, err)
 			return
 		}
-		status.TaskRuns[tr.Name] = &taskloopv1alpha1.TaskLoopTaskRunStatus{
-			Iteration: iteration,
-			Status:    &tr.Status,
-		}
+		setTaskRunStatus(status, taskLoopSpec.StatusMode, tr.Name, iteration, &tr.Status)
 		// If the TaskRun was created before the Run says it was started, then change the Run's
 		// start time.  This happens when this reconcile call has been passed stale status that
 		// doesn't have the start time set.  The reconcile call will set a new start time that
@@ -409,7 +554,7 @@ func (c *Reconciler) updateTaskRunStatus(ctx context.Context, logger *zap.Sugare
 			run.Status.CompletionTime = tr.CreationTimestamp.DeepCopy()
 		}
 		// Handle TaskRun cancellation and retry.
-		if err := c.processTaskRun(ctx, logger, tr, run, status, taskLoopSpec); err != nil {
+		if err := c.processTaskRun(ctx, logger, tr, iteration, run, status, taskLoopSpec, stopReason); err != nil {
 			retryableErr = fmt.Errorf("error processing TaskRun %s: %#v", tr.Name, err)
 			return
 		}
@@ -427,19 +572,19 @@ func (c *Reconciler) updateTaskRunStatus(ctx context.Context, logger *zap.Sugare
 	return
 }
 
-func (c *Reconciler) processTaskRun(ctx context.Context, logger *zap.SugaredLogger, tr *v1beta1.TaskRun,
-	run *v1alpha1.Run, status *taskloopv1alpha1.TaskLoopRunStatus, taskLoopSpec *taskloopv1alpha1.TaskLoopSpec) error {
-	// If the TaskRun is running and the Run is cancelled, cancel the TaskRun.
+func (c *Reconciler) processTaskRun(ctx context.Context, logger *zap.SugaredLogger, tr *v1beta1.TaskRun, iteration int,
+	run *v1beta1.CustomRun, status *taskloopv1alpha1.TaskLoopRunStatus, taskLoopSpec *taskloopv1alpha1.TaskLoopSpec, stopReason string) error {
+	// If the TaskRun is running and the Run is cancelled or has timed out, cancel the TaskRun.
 	if !tr.IsDone() {
-		if run.IsCancelled() && !tr.IsCancelled() {
-			logger.Infof("Run %s/%s is cancelled.  Cancelling TaskRun %s.", run.Namespace, run.Name, tr.Name)
+		if stopReason != "" && !tr.IsCancelled() {
+			logger.Infof("Run %s/%s is %s.  Cancelling TaskRun %s.", run.Namespace, run.Name, stopReason, tr.Name)
 			if _, err := c.pipelineClientSet.TektonV1beta1().TaskRuns(run.Namespace).Patch(ctx, tr.Name, types.JSONPatchType, cancelPatchBytes, metav1.PatchOptions{}); err != nil {
 				return fmt.Errorf("Failed to patch TaskRun `%s` with cancellation: %v", tr.Name, err)
 			}
 		}
 	} else {
 		// If the TaskRun failed, then retry it if possible.
-		if !tr.IsSuccessful() && !run.IsCancelled() {
+		if !tr.IsSuccessful() && stopReason == "" {
 			retriesDone := len(tr.Status.RetriesStatus)
 			retries := taskLoopSpec.Retries
 			if retriesDone < retries {
@@ -447,26 +592,38 @@ func (c *Reconciler) processTaskRun(ctx context.Context, logger *zap.SugaredLogg
 				if err != nil {
 					return fmt.Errorf("error retrying TaskRun %s from Run %s: %w", tr.Name, run.Name, err)
 				}
-				status.TaskRuns[retryTr.Name] = &taskloopv1alpha1.TaskLoopTaskRunStatus{
-					Iteration: status.TaskRuns[retryTr.Name].Iteration,
-					Status:    &retryTr.Status,
-				}
+				setTaskRunStatus(status, taskLoopSpec.StatusMode, retryTr.Name, iteration, &retryTr.Status)
 			}
 		}
 	}
 	return nil
 }
 
-func computeIterations(run *v1alpha1.Run, tls *taskloopv1alpha1.TaskLoopSpec) (int, error) {
+func computeIterations(params []v1beta1.Param, tls *taskloopv1alpha1.TaskLoopSpec) (int, error) {
 	// Find the iterate parameter.
 	numberOfIterations := -1
-	for _, p := range run.Spec.Params {
+	for _, p := range params {
 		if p.Name == tls.IterateParam {
+			if tls.IterateParamType == taskloopv1alpha1.IterateParamTypeObject {
+				items, err := getObjectItems(p)
+				if err != nil {
+					return 0, err
+				}
+				// Check up front that every item can be passed to its TaskRun.
+				for _, item := range items {
+					if _, err := getObjectParam(p.Name, item); err != nil {
+						return 0, err
+					}
+				}
+				numberOfIterations = len(items)
+				break
+			}
 			if p.Value.Type == v1beta1.ParamTypeString {
 				// If we got a string param, split it into an array, one item per line
 				p.Value.ArrayVal = strings.Split(strings.TrimSuffix(p.Value.StringVal, "\n"), "\n")
 			}
 			numberOfIterations = len(p.Value.ArrayVal)
+			break
 		}
 	}
 	if numberOfIterations == -1 {
@@ -475,26 +632,38 @@ func computeIterations(run *v1alpha1.Run, tls *taskloopv1alpha1.TaskLoopSpec) (i
 	return numberOfIterations, nil
 }
 
-func getParameters(run *v1alpha1.Run, tls *taskloopv1alpha1.TaskLoopSpec, iteration int) []v1beta1.Param {
-	out := make([]v1beta1.Param, len(run.Spec.Params))
-	for i, p := range run.Spec.Params {
+func getParameters(params []v1beta1.Param, tls *taskloopv1alpha1.TaskLoopSpec, iteration int) ([]v1beta1.Param, error) {
+	out := make([]v1beta1.Param, 0, len(params))
+	for _, p := range params {
 		if p.Name == tls.IterateParam {
+			if tls.IterateParamType == taskloopv1alpha1.IterateParamTypeObject {
+				items, err := getObjectItems(p)
+				if err != nil {
+					return nil, err
+				}
+				objectParam, err := getObjectParam(p.Name, items[iteration-1])
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, objectParam)
+				continue
+			}
 			if p.Value.Type == v1beta1.ParamTypeString {
 				// If we got a string param, split it into an array, one item per line
 				p.Value.ArrayVal = strings.Split(strings.TrimSuffix(p.Value.StringVal, "\n"), "\n")
 			}
-			out[i] = v1beta1.Param{
+			out = append(out, v1beta1.Param{
 				Name:  p.Name,
 				Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: p.Value.ArrayVal[iteration-1]},
-			}
+			})
 		} else {
-			out[i] = run.Spec.Params[i]
+			out = append(out, p)
 		}
 	}
-	return out
+	return out, nil
 }
 
-func getTaskRunAnnotations(run *v1alpha1.Run) map[string]string {
+func getTaskRunAnnotations(run *v1beta1.CustomRun) map[string]string {
 	// Propagate annotations from Run to TaskRun.
 	annotations := make(map[string]string, len(run.ObjectMeta.Annotations)+1)
 	for key, val := range run.ObjectMeta.Annotations {
@@ -503,7 +672,7 @@ func getTaskRunAnnotations(run *v1alpha1.Run) map[string]string {
 	return annotations
 }
 
-func getTaskRunLabels(run *v1alpha1.Run, iterationStr string, includeRunLabels bool) map[string]string {
+func getTaskRunLabels(run *v1beta1.CustomRun, owner runOwner, iterationStr string, includeRunLabels bool) map[string]string {
 	// Propagate labels from Run to TaskRun.
 	labels := make(map[string]string, len(run.ObjectMeta.Labels)+1)
 	if includeRunLabels {
@@ -512,14 +681,19 @@ func getTaskRunLabels(run *v1alpha1.Run, iterationStr string, includeRunLabels b
 		}
 	}
 	// Note: The Run label uses the normal Tekton group name.
-	labels[pipeline.GroupName+taskLoopRunLabelKey] = run.Name
+	labels[pipeline.GroupName+owner.labelKey()] = run.Name
 	if iterationStr != "" {
 		labels[taskloop.GroupName+taskLoopIterationLabelKey] = iterationStr
 	}
+	// The hierarchy labels aren't used to find the TaskRuns of the Run, so that TaskRuns created
+	// before they were introduced are still found.
+	if includeRunLabels {
+		addLoopHierarchyLabels(labels, run, iterationStr)
+	}
 	return labels
 }
 
-func propagateTaskLoopLabelsAndAnnotations(run *v1alpha1.Run, taskLoopMeta *metav1.ObjectMeta) {
+func propagateTaskLoopLabelsAndAnnotations(run *v1beta1.CustomRun, taskLoopMeta *metav1.ObjectMeta) {
 	// Propagate labels from TaskLoop to Run.
 	if run.ObjectMeta.Labels == nil {
 		run.ObjectMeta.Labels = make(map[string]string, len(taskLoopMeta.Labels)+1)
@@ -527,7 +701,10 @@ func propagateTaskLoopLabelsAndAnnotations(run *v1alpha1.Run, taskLoopMeta *meta
 	for key, value := range taskLoopMeta.Labels {
 		run.ObjectMeta.Labels[key] = value
 	}
-	run.ObjectMeta.Labels[taskloop.GroupName+taskLoopLabelKey] = taskLoopMeta.Name
+	// An embedded TaskLoop has no name.
+	if taskLoopMeta.Name != "" {
+		run.ObjectMeta.Labels[taskloop.GroupName+taskLoopLabelKey] = taskLoopMeta.Name
+	}
 
 	// Propagate annotations from TaskLoop to Run.
 	if run.ObjectMeta.Annotations == nil {
@@ -544,3 +721,16 @@ func storeTaskLoopSpec(status *taskloopv1alpha1.TaskLoopRunStatus, tls *taskloop
 		status.TaskLoopSpec = tls
 	}
 }
+
+// isTaskLoopRun returns whether the Run references or embeds a TaskLoop.
+func isTaskLoopRun(run *v1beta1.CustomRun) bool {
+	switch {
+	case run.Spec.CustomRef != nil:
+		return run.Spec.CustomRef.APIVersion == taskloopv1alpha1.SchemeGroupVersion.String() &&
+			run.Spec.CustomRef.Kind == taskloop.TaskLoopControllerName
+	case run.Spec.CustomSpec != nil:
+		return run.Spec.CustomSpec.APIVersion == taskloopv1alpha1.SchemeGroupVersion.String() &&
+			run.Spec.CustomSpec.Kind == taskloop.TaskLoopControllerName
+	}
+	return false
+}
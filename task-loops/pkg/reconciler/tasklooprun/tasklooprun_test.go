@@ -18,6 +18,7 @@ package tasklooprun
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -34,22 +35,32 @@ import (
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	ttesting "github.com/tektoncd/pipeline/pkg/reconciler/testing"
-	"github.com/tektoncd/pipeline/pkg/system"
 	"github.com/tektoncd/pipeline/test/diff"
 	"github.com/tektoncd/pipeline/test/names"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ktesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/configmap"
 	cminformer "knative.dev/pkg/configmap/informer"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+	_ "knative.dev/pkg/system/testing" // Setup system.Namespace()
 )
 
 var (
+	// now is the time of the clock of the controller in the tests, so that the outcome of the timeouts doesn't
+	// depend on how long the tests take.
+	now = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+
 	concurrencyLimit1  = 1
 	concurrencyLimit2  = 2
 	noConcurrencyLimit = 0
@@ -67,20 +78,21 @@ var (
 	trueB     = true
 )
 
-func getRunName(run *v1alpha1.Run) string {
+func getRunName(run *v1beta1.CustomRun) string {
 	return strings.Join([]string{run.Namespace, run.Name}, "/")
 }
 
-func loopRunning(run *v1alpha1.Run) *v1alpha1.Run {
+func loopRunning(run *v1beta1.CustomRun) *v1beta1.CustomRun {
 	runWithStatus := run.DeepCopy()
+	runWithStatus.Status.StartTime = &metav1.Time{Time: now}
 	runWithStatus.Status.InitializeConditions()
-	runWithStatus.Status.MarkRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(), "")
+	runWithStatus.Status.MarkCustomRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(), "")
 	return runWithStatus
 }
 
-func requestCancel(run *v1alpha1.Run) *v1alpha1.Run {
+func requestCancel(run *v1beta1.CustomRun) *v1beta1.CustomRun {
 	runWithCancelStatus := run.DeepCopy()
-	runWithCancelStatus.Spec.Status = v1alpha1.RunSpecStatusCancelled
+	runWithCancelStatus.Spec.Status = v1beta1.CustomRunSpecStatusCancelled
 	return runWithCancelStatus
 }
 
@@ -139,11 +151,10 @@ func retrying(tr *v1beta1.TaskRun) *v1beta1.TaskRun {
 	return trWithRetryStatus
 }
 
-func withUpdatedRunSpec(run *v1alpha1.Run, serviceAccountName string, workspaces []v1beta1.WorkspaceBinding, podTemplate *pod.Template) *v1alpha1.Run {
+func withUpdatedRunSpec(run *v1beta1.CustomRun, serviceAccountName string, workspaces []v1beta1.WorkspaceBinding) *v1beta1.CustomRun {
 	runWithUpdatedSpec := run.DeepCopy()
 	runWithUpdatedSpec.Spec.ServiceAccountName = serviceAccountName
 	runWithUpdatedSpec.Spec.Workspaces = workspaces
-	runWithUpdatedSpec.Spec.PodTemplate = podTemplate
 	return runWithUpdatedSpec
 }
 
@@ -158,6 +169,16 @@ func withUpdatedTaskRunSpec(tr *v1beta1.TaskRun, serviceAccountName string, work
 // getTaskLoopController returns an instance of the TaskLoop controller/reconciler that has been seeded with
 // d, where d represents the state of the system (existing resources) needed for the test.
 func getTaskLoopController(t *testing.T, d test.Data, taskloops []*taskloopv1alpha1.TaskLoop) (test.Assets, func()) {
+	return getController(t, d, taskloops, NewController)
+}
+
+// getTaskLoopRunController is like getTaskLoopController but returns the controller that reconciles v1alpha1.Runs.
+func getTaskLoopRunController(t *testing.T, d test.Data, taskloops []*taskloopv1alpha1.TaskLoop) (test.Assets, func()) {
+	return getController(t, d, taskloops, NewRunController)
+}
+
+func getController(t *testing.T, d test.Data, taskloops []*taskloopv1alpha1.TaskLoop,
+	newController func(string, clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl) (test.Assets, func()) {
 	ctx, _ := ttesting.SetupFakeContext(t)
 	ctx, cancel := context.WithCancel(ctx)
 	c, informers := test.SeedTestData(t, ctx, d)
@@ -171,8 +192,8 @@ func getTaskLoopController(t *testing.T, d test.Data, taskloops []*taskloopv1alp
 		}
 	}
 
-	configMapWatcher := cminformer.NewInformedWatcher(c.Kube, system.GetNamespace())
-	ctl := NewController(namespace)(ctx, configMapWatcher)
+	configMapWatcher := cminformer.NewInformedWatcher(c.Kube, system.Namespace())
+	ctl := newController(namespace, clocktesting.NewFakePassiveClock(now))(ctx, configMapWatcher)
 
 	if la, ok := ctl.Reconciler.(reconciler.LeaderAware); ok {
 		la.Promote(reconciler.UniversalBucket(), func(reconciler.Bucket, types.NamespacedName) {})
@@ -234,7 +255,7 @@ func checkEvents(fr *record.FakeRecorder, testName string, wantEvents []string)
 	return nil
 }
 
-func checkRunCondition(t *testing.T, run *v1alpha1.Run, expectedStatus corev1.ConditionStatus, expectedReason taskloopv1alpha1.TaskLoopRunReason) {
+func checkRunCondition(t *testing.T, run *v1beta1.CustomRun, expectedStatus corev1.ConditionStatus, expectedReason taskloopv1alpha1.TaskLoopRunReason) {
 	condition := run.Status.GetCondition(apis.ConditionSucceeded)
 	if condition == nil {
 		t.Error("Condition missing in Run")
@@ -258,7 +279,7 @@ func checkRunCondition(t *testing.T, run *v1alpha1.Run, expectedStatus corev1.Co
 	}
 }
 
-func checkRunStatus(t *testing.T, run *v1alpha1.Run, expectedStatus map[string]taskloopv1alpha1.TaskLoopTaskRunStatus) {
+func checkRunStatus(t *testing.T, run *v1beta1.CustomRun, expectedStatus map[string]taskloopv1alpha1.TaskLoopTaskRunStatus) {
 	status := &taskloopv1alpha1.TaskLoopRunStatus{}
 	if err := run.Status.DecodeExtraFields(status); err != nil {
 		t.Errorf("DecodeExtraFields error: %v", err.Error())
@@ -301,7 +322,7 @@ var commonTaskSpec = v1beta1.TaskSpec{
 		Type: v1beta1.ParamTypeString,
 	}},
 	Steps: []v1beta1.Step{{
-		Container: corev1.Container{Name: "foo", Image: "bar"},
+		Name: "foo", Image: "bar",
 	}},
 }
 
@@ -327,6 +348,35 @@ var aTaskLoop = &taskloopv1alpha1.TaskLoop{
 	},
 }
 
+var oTask = &v1beta1.Task{
+	ObjectMeta: metav1.ObjectMeta{Name: "o-task", Namespace: "foo"},
+	Spec: v1beta1.TaskSpec{
+		Params: []v1beta1.ParamSpec{{
+			Name: "current-item",
+			Type: v1beta1.ParamTypeObject,
+			Properties: map[string]v1beta1.PropertySpec{
+				"name":  {Type: v1beta1.ParamTypeString},
+				"image": {Type: v1beta1.ParamTypeString},
+			},
+		}, {
+			Name: "additional-parameter",
+			Type: v1beta1.ParamTypeString,
+		}},
+		Steps: []v1beta1.Step{{
+			Name: "foo", Image: "bar",
+		}},
+	},
+}
+
+var oTaskLoop = &taskloopv1alpha1.TaskLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "o-taskloop", Namespace: "foo"},
+	Spec: taskloopv1alpha1.TaskLoopSpec{
+		TaskRef:          &v1beta1.TaskRef{Name: "o-task"},
+		IterateParam:     "current-item",
+		IterateParamType: taskloopv1alpha1.IterateParamTypeObject,
+	},
+}
+
 var aTaskLoopWithInlineTask = &taskloopv1alpha1.TaskLoop{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "a-taskloop-with-inline-task",
@@ -340,7 +390,7 @@ var aTaskLoopWithInlineTask = &taskloopv1alpha1.TaskLoop{
 	},
 }
 
-var runTaskLoop = &v1alpha1.Run{
+var runTaskLoop = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-taskloop",
 		Namespace: "foo",
@@ -351,7 +401,7 @@ var runTaskLoop = &v1alpha1.Run{
 			"myRunAnnotation": "myRunAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
@@ -359,7 +409,7 @@ var runTaskLoop = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -367,13 +417,13 @@ var runTaskLoop = &v1alpha1.Run{
 	},
 }
 
-var runTaskLoopWithInlineTask = &v1alpha1.Run{
+var runTaskLoopWithInlineTask = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-taskloop-with-inline-task",
 		Namespace: "foo",
 		// No labels or annotations in this one to test that case works
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
@@ -381,7 +431,7 @@ var runTaskLoopWithInlineTask = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop-with-inline-task",
@@ -389,7 +439,59 @@ var runTaskLoopWithInlineTask = &v1alpha1.Run{
 	},
 }
 
-var runWithIterateParamNotAnArray = &v1alpha1.Run{
+// withEmbeddedTaskLoop returns a copy of a CustomRun in which the spec of a TaskLoop is embedded rather than referenced.
+func withEmbeddedTaskLoop(run *v1beta1.CustomRun, tl *taskloopv1alpha1.TaskLoop) *v1beta1.CustomRun {
+	raw, err := json.Marshal(tl.Spec)
+	if err != nil {
+		panic(err)
+	}
+	runWithEmbeddedSpec := run.DeepCopy()
+	runWithEmbeddedSpec.Spec.CustomRef = nil
+	runWithEmbeddedSpec.Spec.CustomSpec = &v1beta1.EmbeddedCustomRunSpec{
+		TypeMeta: runtime.TypeMeta{
+			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       taskloop.TaskLoopControllerName,
+		},
+		Metadata: v1beta1.PipelineTaskMetadata{
+			Labels:      tl.Labels,
+			Annotations: tl.Annotations,
+		},
+		Spec: runtime.RawExtension{Raw: raw},
+	}
+	return runWithEmbeddedSpec
+}
+
+var runTaskLoopWithObjectParams = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-taskloop",
+		Namespace: "foo",
+	},
+	Spec: v1beta1.CustomRunSpec{
+		Params: []v1beta1.Param{{
+			Name: "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{
+				`{"name":"web","image":{"repo":"nginx","tag":"1.19"}}`,
+				`{"name":"db","image":{"repo":"postgres","tag":"13"}}`,
+			}},
+		}, {
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       taskloop.TaskLoopControllerName,
+			Name:       "o-taskloop",
+		},
+	},
+}
+
+func withIterateParamValue(run *v1beta1.CustomRun, value v1beta1.ArrayOrString) *v1beta1.CustomRun {
+	runWithValue := run.DeepCopy()
+	runWithValue.Spec.Params[0].Value = value
+	return runWithValue
+}
+
+var runWithIterateParamNotAnArray = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-taskloop",
 		Namespace: "foo",
@@ -400,7 +502,7 @@ var runWithIterateParamNotAnArray = &v1alpha1.Run{
 			"myRunAnnotation": "myRunAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			// Value of iteration parameter must be an array so this is an error.
 			Name:  "current-item",
@@ -409,7 +511,7 @@ var runWithIterateParamNotAnArray = &v1alpha1.Run{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -417,13 +519,13 @@ var runWithIterateParamNotAnArray = &v1alpha1.Run{
 	},
 }
 
-var runWithMissingTaskLoopName = &v1alpha1.Run{
+var runWithMissingTaskLoopName = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-taskloop-missing",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
-		Ref: &v1alpha1.TaskRef{
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			// missing Name
@@ -431,13 +533,13 @@ var runWithMissingTaskLoopName = &v1alpha1.Run{
 	},
 }
 
-var runWithNonexistentTaskLoop = &v1alpha1.Run{
+var runWithNonexistentTaskLoop = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-taskloop-not-found",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
-		Ref: &v1alpha1.TaskRef{
+	Spec: v1beta1.CustomRunSpec{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "no-such-taskloop",
@@ -445,18 +547,18 @@ var runWithNonexistentTaskLoop = &v1alpha1.Run{
 	},
 }
 
-var runWithMissingIterateParam = &v1alpha1.Run{
+var runWithMissingIterateParam = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "bad-run-missing-iterate-param",
 		Namespace: "foo",
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		// current-item, which is the iterate parameter, is missing from parameters
 		Params: []v1beta1.Param{{
 			Name:  "additional-parameter",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -469,16 +571,19 @@ var expectedTaskRunIteration1 = &v1beta1.TaskRun{
 		Name:      "run-taskloop-00001-", // does not include random suffix
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-taskloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/taskLoop":          "a-taskloop",
-			"tekton.dev/run":                      "run-taskloop",
+			"tekton.dev/customRun":                "run-taskloop",
 			"custom.tekton.dev/taskLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop",
+			"custom.tekton.dev/loopIteration-1":   "1",
 			"myTaskLoopLabel":                     "myTaskLoopLabelValue",
 			"myRunLabel":                          "myRunLabelValue",
 		},
@@ -500,21 +605,61 @@ var expectedTaskRunIteration1 = &v1beta1.TaskRun{
 	},
 }
 
+var expectedTaskRunWithObjectParamsIteration1 = &v1beta1.TaskRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-taskloop-00001-", // does not include random suffix
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-taskloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/taskLoop":          "o-taskloop",
+			"tekton.dev/customRun":                "run-taskloop",
+			"custom.tekton.dev/taskLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop",
+			"custom.tekton.dev/loopIteration-1":   "1",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.TaskRunSpec{
+		TaskRef: &v1beta1.TaskRef{Name: "o-task"},
+		Params: []v1beta1.Param{{
+			Name: "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeObject, ObjectVal: map[string]string{
+				"name":  "web",
+				"image": `{"repo":"nginx","tag":"1.19"}`,
+			}},
+		}, {
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		ServiceAccountName: "default",
+	},
+}
+
 var expectedTaskRunIteration2 = &v1beta1.TaskRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      "run-taskloop-00002-", // does not include random suffix
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-taskloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/taskLoop":          "a-taskloop",
-			"tekton.dev/run":                      "run-taskloop",
+			"tekton.dev/customRun":                "run-taskloop",
 			"custom.tekton.dev/taskLoopIteration": "2",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop",
+			"custom.tekton.dev/loopIteration-1":   "2",
 			"myTaskLoopLabel":                     "myTaskLoopLabelValue",
 			"myRunLabel":                          "myRunLabelValue",
 		},
@@ -541,16 +686,19 @@ var expectedTaskRunIteration3 = &v1beta1.TaskRun{
 		Name:      "run-taskloop-00003-", // does not include random suffix
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-taskloop",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/taskLoop":          "a-taskloop",
-			"tekton.dev/run":                      "run-taskloop",
+			"tekton.dev/customRun":                "run-taskloop",
 			"custom.tekton.dev/taskLoopIteration": "3",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop",
+			"custom.tekton.dev/loopIteration-1":   "3",
 			"myTaskLoopLabel":                     "myTaskLoopLabelValue",
 			"myRunLabel":                          "myRunLabelValue",
 		},
@@ -577,16 +725,19 @@ var expectedTaskRunWithInlineTaskIteration1 = &v1beta1.TaskRun{
 		Name:      "run-taskloop-with-inline-task-00001-", // does not include random suffix
 		Namespace: "foo",
 		OwnerReferences: []metav1.OwnerReference{{
-			APIVersion:         "tekton.dev/v1alpha1",
-			Kind:               "Run",
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
 			Name:               "run-taskloop-with-inline-task",
 			Controller:         &trueB,
 			BlockOwnerDeletion: &trueB,
 		}},
 		Labels: map[string]string{
 			"custom.tekton.dev/taskLoop":          "a-taskloop-with-inline-task",
-			"tekton.dev/run":                      "run-taskloop-with-inline-task",
+			"tekton.dev/customRun":                "run-taskloop-with-inline-task",
 			"custom.tekton.dev/taskLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop-with-inline-task",
+			"custom.tekton.dev/loopIteration-1":   "1",
 		},
 		Annotations: map[string]string{},
 	},
@@ -604,6 +755,13 @@ var expectedTaskRunWithInlineTaskIteration1 = &v1beta1.TaskRun{
 	},
 }
 
+// withoutTaskLoopLabel returns a copy of a TaskRun without the label of the TaskLoop, as the TaskRun of an embedded TaskLoop has.
+func withoutTaskLoopLabel(tr *v1beta1.TaskRun) *v1beta1.TaskRun {
+	trWithoutLabel := tr.DeepCopy()
+	delete(trWithoutLabel.Labels, "custom.tekton.dev/taskLoop")
+	return trWithoutLabel
+}
+
 func TestReconcileTaskLoopRun(t *testing.T) {
 
 	testcases := []struct {
@@ -611,7 +769,7 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 		// The following set of fields describe the resources on entry to reconcile.
 		task     *v1beta1.Task
 		taskloop *taskloopv1alpha1.TaskLoop
-		run      *v1alpha1.Run
+		run      *v1beta1.CustomRun
 		taskruns []*v1beta1.TaskRun
 		// The following set of fields describe the expected state after reconcile.
 		expectedStatus   corev1.ConditionStatus
@@ -637,15 +795,34 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 		expectedReason:   taskloopv1alpha1.TaskLoopRunReasonRunning,
 		expectedTaskruns: []*v1beta1.TaskRun{expectedTaskRunWithInlineTaskIteration1},
 		expectedEvents:   []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:             "Reconcile a new run with an embedded taskloop",
+		task:             aTask,
+		run:              withEmbeddedTaskLoop(runTaskLoop, aTaskLoop),
+		taskruns:         []*v1beta1.TaskRun{},
+		expectedStatus:   corev1.ConditionUnknown,
+		expectedReason:   taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedTaskruns: []*v1beta1.TaskRun{withoutTaskLoopLabel(expectedTaskRunIteration1)},
+		expectedEvents:   []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:             "Reconcile a new run with a taskloop that iterates over objects",
+		task:             oTask,
+		taskloop:         oTaskLoop,
+		run:              runTaskLoopWithObjectParams,
+		taskruns:         []*v1beta1.TaskRun{},
+		expectedStatus:   corev1.ConditionUnknown,
+		expectedReason:   taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedTaskruns: []*v1beta1.TaskRun{expectedTaskRunWithObjectParamsIteration1},
+		expectedEvents:   []string{"Normal Started", "Normal Running Iterations completed: 0"},
 	}, {
 		name:             "Reconcile a new run that uses all the bells and whistles (tests propagation to TaskRun)",
 		task:             aTask,
 		taskloop:         aTaskLoop,
-		run:              withUpdatedRunSpec(runTaskLoop, myServiceAccountName, myWorkspaces, myPodTemplate),
+		run:              withUpdatedRunSpec(runTaskLoop, myServiceAccountName, myWorkspaces),
 		taskruns:         []*v1beta1.TaskRun{},
 		expectedStatus:   corev1.ConditionUnknown,
 		expectedReason:   taskloopv1alpha1.TaskLoopRunReasonRunning,
-		expectedTaskruns: []*v1beta1.TaskRun{withUpdatedTaskRunSpec(expectedTaskRunIteration1, myServiceAccountName, myWorkspaces, myPodTemplate)},
+		expectedTaskruns: []*v1beta1.TaskRun{withUpdatedTaskRunSpec(expectedTaskRunIteration1, myServiceAccountName, myWorkspaces, nil)},
 		expectedEvents:   []string{"Normal Started", "Normal Running Iterations completed: 0"},
 	}, {
 		name:             "Reconcile a run after the first TaskRun has succeeded",
@@ -714,7 +891,7 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 		run:              requestCancel(loopRunning(runTaskLoop)),
 		taskruns:         []*v1beta1.TaskRun{failed(expectedTaskRunIteration1)},
 		expectedStatus:   corev1.ConditionFalse,
-		expectedReason:   v1alpha1.RunReasonCancelled,
+		expectedReason:   taskloopv1alpha1.TaskLoopRunReason(v1beta1.CustomRunReasonCancelled),
 		expectedTaskruns: []*v1beta1.TaskRun{failed(expectedTaskRunIteration1)},
 		expectedEvents:   []string{"Warning Failed Run " + runTaskLoop.Namespace + "/" + runTaskLoop.Name + " was cancelled"},
 	}, {
@@ -724,7 +901,7 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 		run:              requestCancel(loopRunning(runTaskLoop)),
 		taskruns:         []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
 		expectedStatus:   corev1.ConditionFalse,
-		expectedReason:   v1alpha1.RunReasonCancelled,
+		expectedReason:   taskloopv1alpha1.TaskLoopRunReason(v1beta1.CustomRunReasonCancelled),
 		expectedTaskruns: []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
 		expectedEvents:   []string{"Warning Failed Run " + runTaskLoop.Namespace + "/" + runTaskLoop.Name + " was cancelled"},
 	}, {
@@ -807,13 +984,18 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 				optionalTask = nil
 			}
 
+			optionalTaskLoop := []*taskloopv1alpha1.TaskLoop{tc.taskloop}
+			if tc.taskloop == nil {
+				optionalTaskLoop = nil
+			}
+
 			d := test.Data{
-				Runs:     []*v1alpha1.Run{tc.run},
-				Tasks:    optionalTask,
-				TaskRuns: tc.taskruns,
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
+				Tasks:      optionalTask,
+				TaskRuns:   tc.taskruns,
 			}
 
-			testAssets, _ := getTaskLoopController(t, d, []*taskloopv1alpha1.TaskLoop{tc.taskloop})
+			testAssets, _ := getTaskLoopController(t, d, optionalTaskLoop)
 			c := testAssets.Controller
 			clients := testAssets.Clients
 
@@ -822,7 +1004,7 @@ func TestReconcileTaskLoopRun(t *testing.T) {
 			}
 
 			// Fetch the updated Run
-			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("Error getting reconciled run from fake client: %s", err)
 			}
@@ -880,7 +1062,7 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 	testcases := []struct {
 		name       string
 		taskloop   *taskloopv1alpha1.TaskLoop
-		run        *v1alpha1.Run
+		run        *v1beta1.CustomRun
 		reason     taskloopv1alpha1.TaskLoopRunReason
 		wantEvents []string
 	}{{
@@ -889,7 +1071,7 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 		reason: taskloopv1alpha1.TaskLoopRunReasonCouldntGetTaskLoop,
 		wantEvents: []string{
 			"Normal Started ",
-			"Warning Failed Missing spec.ref.name for Run",
+			"Warning Failed Missing spec.customRef.name or spec.customSpec for Run",
 		},
 	}, {
 		name:   "nonexistent TaskLoop",
@@ -908,6 +1090,33 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 			"Normal Started ",
 			`Warning Failed Cannot determine number of iterations: The iterate parameter "current-item" was not found`,
 		},
+	}, {
+		name:     "object iterate parameter not a JSON array",
+		taskloop: oTaskLoop,
+		run:      withIterateParamValue(runTaskLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1\nitem2"}),
+		reason:   taskloopv1alpha1.TaskLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: The value of the iterate parameter "current-item" is not a JSON array`,
+		},
+	}, {
+		name:     "object iterate parameter with an invalid item",
+		taskloop: oTaskLoop,
+		run:      withIterateParamValue(runTaskLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{`{"name":"web"}`, `web`}}),
+		reason:   taskloopv1alpha1.TaskLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: Item 2 of the iterate parameter "current-item" is not valid JSON`,
+		},
+	}, {
+		name:     "object iterate parameter mixing objects and other items",
+		taskloop: oTaskLoop,
+		run:      withIterateParamValue(runTaskLoopWithObjectParams, v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{`{"name":"web"}`, `"db"`}}),
+		reason:   taskloopv1alpha1.TaskLoopRunReasonFailedValidation,
+		wantEvents: []string{
+			"Normal Started ",
+			`Warning Failed Cannot determine number of iterations: The items of the iterate parameter "current-item" must either all be JSON objects or none of them`,
+		},
 	}}
 
 	for _, tc := range testcases {
@@ -915,7 +1124,7 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 			ctx := context.Background()
 
 			d := test.Data{
-				Runs: []*v1alpha1.Run{tc.run},
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
 			}
 
 			optionalTaskLoop := []*taskloopv1alpha1.TaskLoop{tc.taskloop}
@@ -932,7 +1141,7 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 			}
 
 			// Fetch the updated Run
-			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("Error getting reconciled run from fake client: %s", err)
 			}
@@ -952,3 +1161,769 @@ func TestReconcileTaskLoopRunFailures(t *testing.T) {
 		})
 	}
 }
+
+var cmTaskLoop = &taskloopv1alpha1.TaskLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-taskloop", Namespace: "foo"},
+	Spec: taskloopv1alpha1.TaskLoopSpec{
+		TaskRef:      &v1beta1.TaskRef{Name: "a-task"},
+		IterateParam: "current-item",
+		IterateSource: &taskloopv1alpha1.IterateSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "items"},
+				Key:                  "list",
+			},
+		},
+	},
+}
+
+var resultTaskLoop = &taskloopv1alpha1.TaskLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "a-taskloop", Namespace: "foo"},
+	Spec: taskloopv1alpha1.TaskLoopSpec{
+		TaskRef:      &v1beta1.TaskRef{Name: "a-task"},
+		IterateParam: "current-item",
+		IterateSource: &taskloopv1alpha1.IterateSource{
+			TaskResult: &taskloopv1alpha1.TaskResultSelector{PipelineTask: "list-items", Result: "items"},
+		},
+	},
+}
+
+var itemsConfigMap = &corev1.ConfigMap{
+	ObjectMeta: metav1.ObjectMeta{Name: "items", Namespace: "foo"},
+	Data:       map[string]string{"list": `["item1", "item2", "item3"]`},
+}
+
+var itemsConfigMapWithLines = &corev1.ConfigMap{
+	ObjectMeta: metav1.ObjectMeta{Name: "items", Namespace: "foo"},
+	Data:       map[string]string{"list": "item1\nitem2\n"},
+}
+
+var listItemsTaskRun = &v1beta1.TaskRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "pr-loop-example-list-items",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/pipelineRun":  "pr-loop-example",
+			"tekton.dev/pipelineTask": "list-items",
+		},
+	},
+	Status: v1beta1.TaskRunStatus{
+		Status: duckv1.Status{
+			Conditions: duckv1.Conditions{{
+				Type:   apis.ConditionSucceeded,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+		TaskRunStatusFields: v1beta1.TaskRunStatusFields{
+			TaskRunResults: []v1beta1.TaskRunResult{{
+				Name:  "items",
+				Value: *v1beta1.NewArrayOrString("[\"item1\", \"item2\"]\n"),
+			}},
+		},
+	},
+}
+
+var runTaskLoopWithIterateSource = &v1beta1.CustomRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-taskloop",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/pipelineRun": "pr-loop-example",
+		},
+	},
+	Spec: v1beta1.CustomRunSpec{
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		CustomRef: &v1beta1.TaskRef{
+			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       taskloop.TaskLoopControllerName,
+			Name:       "a-taskloop",
+		},
+	},
+}
+
+var expectedTaskRunFromIterateSource = &v1beta1.TaskRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-taskloop-00001-", // does not include random suffix
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-taskloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/taskLoop":          "a-taskloop",
+			"tekton.dev/customRun":                "run-taskloop",
+			"custom.tekton.dev/taskLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":         "1",
+			"custom.tekton.dev/loopRun-1":         "run-taskloop",
+			"custom.tekton.dev/loopIteration-1":   "1",
+			"tekton.dev/pipelineRun":              "pr-loop-example",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.TaskRunSpec{
+		TaskRef: &v1beta1.TaskRef{Name: "a-task"},
+		Params: []v1beta1.Param{{
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}, {
+			Name:  "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1"},
+		}},
+		ServiceAccountName: "default",
+	},
+}
+
+func withIterateParamSnapshot(run *v1beta1.CustomRun, value v1beta1.ArrayOrString) *v1beta1.CustomRun {
+	runWithSnapshot := run.DeepCopy()
+	runWithSnapshot.Status.StartTime = &metav1.Time{Time: now}
+	runWithSnapshot.Status.InitializeConditions()
+	if err := runWithSnapshot.Status.EncodeExtraFields(&taskloopv1alpha1.TaskLoopRunStatus{
+		IterateParamValue: &value,
+	}); err != nil {
+		panic(err)
+	}
+	return runWithSnapshot
+}
+
+func TestReconcileTaskLoopRunWithIterateSource(t *testing.T) {
+	testcases := []struct {
+		name            string
+		taskloop        *taskloopv1alpha1.TaskLoop
+		run             *v1beta1.CustomRun
+		configMaps      []*corev1.ConfigMap
+		taskruns        []*v1beta1.TaskRun
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  taskloopv1alpha1.TaskLoopRunReason
+		expectedValue   *v1beta1.ArrayOrString
+		expectedTaskrun *v1beta1.TaskRun
+	}{{
+		name:            "iterate over a JSON array in a ConfigMap",
+		taskloop:        cmTaskLoop,
+		run:             runTaskLoopWithIterateSource,
+		configMaps:      []*corev1.ConfigMap{itemsConfigMap},
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedValue:   v1beta1.NewArrayOrString("item1", "item2", "item3"),
+		expectedTaskrun: expectedTaskRunFromIterateSource,
+	}, {
+		name:            "iterate over the lines in a ConfigMap",
+		taskloop:        cmTaskLoop,
+		run:             runTaskLoopWithIterateSource,
+		configMaps:      []*corev1.ConfigMap{itemsConfigMapWithLines},
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedValue:   v1beta1.NewArrayOrString("item1\nitem2"),
+		expectedTaskrun: expectedTaskRunFromIterateSource,
+	}, {
+		name:            "iterate over the items in a task result",
+		taskloop:        resultTaskLoop,
+		run:             runTaskLoopWithIterateSource,
+		taskruns:        []*v1beta1.TaskRun{listItemsTaskRun},
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedValue:   v1beta1.NewArrayOrString("item1", "item2"),
+		expectedTaskrun: expectedTaskRunFromIterateSource,
+	}, {
+		name:            "iterate over the items stored on the Run rather than the current ConfigMap",
+		taskloop:        cmTaskLoop,
+		run:             withIterateParamSnapshot(runTaskLoopWithIterateSource, *v1beta1.NewArrayOrString("item1")),
+		configMaps:      []*corev1.ConfigMap{itemsConfigMap},
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedValue:   v1beta1.NewArrayOrString("item1"),
+		expectedTaskrun: expectedTaskRunFromIterateSource,
+	}, {
+		name:           "missing ConfigMap",
+		taskloop:       cmTaskLoop,
+		run:            runTaskLoopWithIterateSource,
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonCouldntResolveIterateSource,
+	}, {
+		name:           "missing task result",
+		taskloop:       resultTaskLoop,
+		run:            runTaskLoopWithIterateSource,
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonCouldntResolveIterateSource,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
+				Tasks:      []*v1beta1.Task{aTask},
+				TaskRuns:   tc.taskruns,
+				ConfigMaps: tc.configMaps,
+			}
+
+			testAssets, _ := getTaskLoopController(t, d, []*taskloopv1alpha1.TaskLoop{tc.taskloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdTaskRuns := getCreatedTaskRuns(t, clients)
+			if tc.expectedTaskrun == nil {
+				if len(createdTaskRuns) != 0 {
+					t.Errorf("Expected no TaskRun to be created but got %d", len(createdTaskRuns))
+				}
+			} else if len(createdTaskRuns) != 1 {
+				t.Errorf("Expected one TaskRun to be created but got %d", len(createdTaskRuns))
+			} else if d := cmp.Diff(tc.expectedTaskrun, createdTaskRuns[0], cmpopts.IgnoreFields(metav1.ObjectMeta{}, "Name")); d != "" {
+				t.Errorf("Expected TaskRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+
+			status := &taskloopv1alpha1.TaskLoopRunStatus{}
+			if err := reconciledRun.Status.DecodeExtraFields(status); err != nil {
+				t.Fatalf("DecodeExtraFields error: %v", err)
+			}
+			if d := cmp.Diff(tc.expectedValue, status.IterateParamValue); d != "" {
+				t.Errorf("Iterate parameter value stored on the Run is incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+var nestedTaskLoop = &taskloopv1alpha1.TaskLoop{
+	ObjectMeta: metav1.ObjectMeta{Name: "nested-taskloop", Namespace: "foo"},
+	Spec: taskloopv1alpha1.TaskLoopSpec{
+		TaskSpec: &v1beta1.TaskSpec{
+			Params: []v1beta1.ParamSpec{{
+				Name: "current-item",
+				Type: v1beta1.ParamTypeString,
+			}, {
+				Name: "loop.parent.iteration",
+				Type: v1beta1.ParamTypeString,
+			}},
+			Steps: []v1beta1.Step{{
+				Name: "foo", Image: "bar", Args: []string{"iteration-$(loop.iteration)"},
+			}},
+		},
+		IterateParam: "current-item",
+	},
+}
+
+var outerPipelineRun = &v1beta1.PipelineRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "outer-run-00002-abcde",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"tekton.dev/run":                    "outer-run",
+			"custom.tekton.dev/loopDepth":       "1",
+			"custom.tekton.dev/loopRun-1":       "outer-run",
+			"custom.tekton.dev/loopIteration-1": "2",
+		},
+	},
+	Spec: v1beta1.PipelineRunSpec{
+		PipelineRef: &v1beta1.PipelineRef{Name: "outer-pipeline"},
+		Params: []v1beta1.Param{{
+			Name:  "outer-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "b"},
+		}},
+	},
+}
+
+func nestedRun(depth string) *v1beta1.CustomRun {
+	return &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run-nested-taskloop",
+			Namespace: "foo",
+			Labels: map[string]string{
+				"tekton.dev/pipelineRun":            "outer-run-00002-abcde",
+				"custom.tekton.dev/loopDepth":       depth,
+				"custom.tekton.dev/loopRun-1":       "outer-run",
+				"custom.tekton.dev/loopIteration-1": "2",
+			},
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{{
+				Name:  "current-item",
+				Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2"}},
+			}},
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
+				Kind:       taskloop.TaskLoopControllerName,
+				Name:       "nested-taskloop",
+			},
+		},
+	}
+}
+
+var expectedNestedTaskRunIteration1 = &v1beta1.TaskRun{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-nested-taskloop-00001-", // does not include random suffix
+		Namespace: "foo",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         "tekton.dev/v1beta1",
+			Kind:               "CustomRun",
+			Name:               "run-nested-taskloop",
+			Controller:         &trueB,
+			BlockOwnerDeletion: &trueB,
+		}},
+		Labels: map[string]string{
+			"custom.tekton.dev/taskLoop":          "nested-taskloop",
+			"tekton.dev/customRun":                "run-nested-taskloop",
+			"tekton.dev/pipelineRun":              "outer-run-00002-abcde",
+			"custom.tekton.dev/taskLoopIteration": "1",
+			"custom.tekton.dev/loopDepth":         "2",
+			"custom.tekton.dev/loopRun-1":         "outer-run",
+			"custom.tekton.dev/loopIteration-1":   "2",
+			"custom.tekton.dev/loopRun-2":         "run-nested-taskloop",
+			"custom.tekton.dev/loopIteration-2":   "1",
+		},
+		Annotations: map[string]string{},
+	},
+	Spec: v1beta1.TaskRunSpec{
+		TaskSpec: &v1beta1.TaskSpec{
+			Params: []v1beta1.ParamSpec{{
+				Name: "current-item",
+				Type: v1beta1.ParamTypeString,
+			}, {
+				Name: "loop.parent.iteration",
+				Type: v1beta1.ParamTypeString,
+			}},
+			Steps: []v1beta1.Step{{
+				Name: "foo", Image: "bar", Args: []string{"iteration-1"},
+			}},
+		},
+		Params: []v1beta1.Param{{
+			Name:  "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1"},
+		}, {
+			Name:  "loop.parent.iteration",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "2"},
+		}},
+		ServiceAccountName: "default",
+	},
+}
+
+func TestReconcileTaskLoopRunNested(t *testing.T) {
+	testcases := []struct {
+		name            string
+		run             *v1beta1.CustomRun
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  taskloopv1alpha1.TaskLoopRunReason
+		expectedTaskrun *v1beta1.TaskRun
+	}{{
+		name:            "nested loop passes the declared parent params and labels the hierarchy",
+		run:             nestedRun("1"),
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedTaskrun: expectedNestedTaskRunIteration1,
+	}, {
+		name:           "nested loop exceeds the maximum nesting depth",
+		run:            nestedRun("5"),
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonMaxNestingDepthExceeded,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns:   []*v1beta1.CustomRun{tc.run},
+				PipelineRuns: []*v1beta1.PipelineRun{outerPipelineRun},
+			}
+
+			testAssets, _ := getTaskLoopController(t, d, []*taskloopv1alpha1.TaskLoop{nestedTaskLoop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			createdTaskRuns := getCreatedTaskRuns(t, clients)
+			if tc.expectedTaskrun == nil {
+				if len(createdTaskRuns) != 0 {
+					t.Errorf("Expected no TaskRun to be created but got %d", len(createdTaskRuns))
+				}
+			} else if len(createdTaskRuns) != 1 {
+				t.Errorf("Expected one TaskRun to be created but got %d", len(createdTaskRuns))
+			} else if d := cmp.Diff(tc.expectedTaskrun, createdTaskRuns[0], cmpopts.IgnoreFields(metav1.ObjectMeta{}, "Name")); d != "" {
+				t.Errorf("Expected TaskRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+func withStatusMode(tl *taskloopv1alpha1.TaskLoop, mode taskloopv1alpha1.StatusMode) *taskloopv1alpha1.TaskLoop {
+	taskLoopWithStatusMode := tl.DeepCopy()
+	taskLoopWithStatusMode.Spec.StatusMode = mode
+	return taskLoopWithStatusMode
+}
+
+func withFullTaskRunStatus(run *v1beta1.CustomRun, trs ...*v1beta1.TaskRun) *v1beta1.CustomRun {
+	runWithStatus := run.DeepCopy()
+	status := &taskloopv1alpha1.TaskLoopRunStatus{TaskRuns: map[string]*taskloopv1alpha1.TaskLoopTaskRunStatus{}}
+	for i, tr := range trs {
+		status.TaskRuns[tr.Name] = &taskloopv1alpha1.TaskLoopTaskRunStatus{Iteration: i + 1, Status: &tr.Status}
+	}
+	if err := runWithStatus.Status.EncodeExtraFields(status); err != nil {
+		panic(err)
+	}
+	return runWithStatus
+}
+
+func TestReconcileTaskLoopRunStatusMode(t *testing.T) {
+	testcases := []struct {
+		name     string
+		taskloop *taskloopv1alpha1.TaskLoop
+		run      *v1beta1.CustomRun
+		taskruns []*v1beta1.TaskRun
+		// expectedReferences maps the iteration of each TaskRun to its condition status
+		expectedReferences map[int]corev1.ConditionStatus
+		expectedTaskRuns   int
+		expectedSummary    *taskloopv1alpha1.TaskLoopRunSummary
+	}{{
+		name:             "full status",
+		taskloop:         withConcurrencyLimit(aTaskLoop, 2),
+		run:              loopRunning(runTaskLoop),
+		taskruns:         []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
+		expectedTaskRuns: 3,
+		expectedSummary:  &taskloopv1alpha1.TaskLoopRunSummary{Iterations: 3, Started: 3, Running: 2, Succeeded: 1},
+	}, {
+		name:     "compact status",
+		taskloop: withStatusMode(withConcurrencyLimit(aTaskLoop, 2), taskloopv1alpha1.StatusModeCompact),
+		run:      loopRunning(runTaskLoop),
+		taskruns: []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
+		expectedReferences: map[int]corev1.ConditionStatus{
+			1: corev1.ConditionTrue,
+			2: "",
+			3: "",
+		},
+		expectedSummary: &taskloopv1alpha1.TaskLoopRunSummary{Iterations: 3, Started: 3, Running: 2, Succeeded: 1},
+	}, {
+		name:     "full status stored by an earlier reconcile is compacted",
+		taskloop: withStatusMode(aTaskLoop, taskloopv1alpha1.StatusModeCompact),
+		run:      withFullTaskRunStatus(loopRunning(runTaskLoop), failed(expectedTaskRunIteration1)),
+		taskruns: []*v1beta1.TaskRun{failed(expectedTaskRunIteration1)},
+		expectedReferences: map[int]corev1.ConditionStatus{
+			1: corev1.ConditionFalse,
+		},
+		expectedSummary: &taskloopv1alpha1.TaskLoopRunSummary{Iterations: 3, Started: 1, Failed: 1},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
+				Tasks:      []*v1beta1.Task{aTask},
+				TaskRuns:   tc.taskruns,
+			}
+
+			testAssets, _ := getTaskLoopController(t, d, []*taskloopv1alpha1.TaskLoop{tc.taskloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			status := &taskloopv1alpha1.TaskLoopRunStatus{}
+			if err := reconciledRun.Status.DecodeExtraFields(status); err != nil {
+				t.Fatalf("DecodeExtraFields error: %v", err)
+			}
+
+			if len(status.TaskRuns) != tc.expectedTaskRuns {
+				t.Errorf("Expected Run status to include %d full TaskRun statuses but found %d", tc.expectedTaskRuns, len(status.TaskRuns))
+			}
+			references := map[int]corev1.ConditionStatus{}
+			for _, ref := range status.TaskRunReferences {
+				references[ref.Iteration] = ""
+				if ref.Condition != nil {
+					references[ref.Iteration] = ref.Condition.Status
+				}
+			}
+			if len(tc.expectedReferences) == 0 && len(references) == 0 {
+				references = tc.expectedReferences
+			}
+			if d := cmp.Diff(tc.expectedReferences, references); d != "" {
+				t.Errorf("TaskRun references in the Run status are incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+			if d := cmp.Diff(tc.expectedSummary, status.Summary); d != "" {
+				t.Errorf("Run summary is incorrect. Diff %s", diff.PrintWantGot(d))
+			}
+		})
+	}
+}
+
+var legacyRunTaskLoop = &v1alpha1.Run{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "run-taskloop",
+		Namespace: "foo",
+		Labels: map[string]string{
+			"myRunLabel": "myRunLabelValue",
+		},
+		Annotations: map[string]string{
+			"myRunAnnotation": "myRunAnnotationValue",
+		},
+	},
+	Spec: v1alpha1.RunSpec{
+		Params: []v1beta1.Param{{
+			Name:  "current-item",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
+		}, {
+			Name:  "additional-parameter",
+			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "stuff"},
+		}},
+		Ref: &v1beta1.TaskRef{
+			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
+			Kind:       taskloop.TaskLoopControllerName,
+			Name:       "a-taskloop",
+		},
+		PodTemplate: myPodTemplate,
+	},
+}
+
+func legacyLoopRunning(run *v1alpha1.Run) *v1alpha1.Run {
+	runWithStatus := run.DeepCopy()
+	runWithStatus.Status.StartTime = &metav1.Time{Time: now}
+	runWithStatus.Status.InitializeConditions()
+	runWithStatus.Status.MarkRunRunning(taskloopv1alpha1.TaskLoopRunReasonRunning.String(), "")
+	return runWithStatus
+}
+
+func legacyRequestCancel(run *v1alpha1.Run) *v1alpha1.Run {
+	runWithCancelStatus := run.DeepCopy()
+	runWithCancelStatus.Spec.Status = v1alpha1.RunSpecStatusCancelled
+	return runWithCancelStatus
+}
+
+// ownedByRun returns a copy of a TaskRun expected for a CustomRun as it is expected for a v1alpha1.Run of the same name.
+func ownedByRun(tr *v1beta1.TaskRun) *v1beta1.TaskRun {
+	trOwnedByRun := tr.DeepCopy()
+	trOwnedByRun.OwnerReferences[0].APIVersion = "tekton.dev/v1alpha1"
+	trOwnedByRun.OwnerReferences[0].Kind = "Run"
+	trOwnedByRun.Labels["tekton.dev/run"] = trOwnedByRun.Labels["tekton.dev/customRun"]
+	delete(trOwnedByRun.Labels, "tekton.dev/customRun")
+	trOwnedByRun.Spec.PodTemplate = myPodTemplate
+	return trOwnedByRun
+}
+
+func TestReconcileTaskLoopRunBackwardCompatibility(t *testing.T) {
+	testcases := []struct {
+		name            string
+		run             *v1alpha1.Run
+		taskruns        []*v1beta1.TaskRun
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  taskloopv1alpha1.TaskLoopRunReason
+		expectedTaskrun *v1beta1.TaskRun
+		expectedEvents  []string
+	}{{
+		name:            "new Run creates a TaskRun owned by the Run",
+		run:             legacyRunTaskLoop,
+		expectedStatus:  corev1.ConditionUnknown,
+		expectedReason:  taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedTaskrun: ownedByRun(expectedTaskRunIteration1),
+		expectedEvents:  []string{"Normal Started", "Normal Running Iterations completed: 0"},
+	}, {
+		name:           "Run whose TaskRuns were created before the move to CustomRuns succeeds",
+		run:            legacyLoopRunning(legacyRunTaskLoop),
+		taskruns:       []*v1beta1.TaskRun{successful(ownedByRun(expectedTaskRunIteration1)), successful(ownedByRun(expectedTaskRunIteration2)), successful(ownedByRun(expectedTaskRunIteration3))},
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonSucceeded,
+		expectedEvents: []string{"Normal Succeeded All TaskRuns completed successfully"},
+	}, {
+		name:           "cancelled Run",
+		run:            legacyRequestCancel(legacyLoopRunning(legacyRunTaskLoop)),
+		taskruns:       []*v1beta1.TaskRun{failed(ownedByRun(expectedTaskRunIteration1))},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReason(v1alpha1.RunReasonCancelled),
+		expectedEvents: []string{"Warning Failed Run foo/run-taskloop was cancelled"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				Runs:     []*v1alpha1.Run{tc.run},
+				Tasks:    []*v1beta1.Task{aTask},
+				TaskRuns: tc.taskruns,
+			}
+
+			testAssets, _ := getTaskLoopRunController(t, d, []*taskloopv1alpha1.TaskLoop{aTaskLoop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, tc.run.Namespace+"/"+tc.run.Name); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1alpha1().Runs(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, toCustomRun(reconciledRun), tc.expectedStatus, tc.expectedReason)
+
+			createdTaskRuns := getCreatedTaskRuns(t, clients)
+			if tc.expectedTaskrun == nil {
+				if len(createdTaskRuns) != 0 {
+					t.Errorf("Expected no TaskRun to be created but got %d", len(createdTaskRuns))
+				}
+			} else if len(createdTaskRuns) != 1 {
+				t.Errorf("Expected one TaskRun to be created but got %d", len(createdTaskRuns))
+			} else if d := cmp.Diff(tc.expectedTaskrun, createdTaskRuns[0], cmpopts.IgnoreFields(metav1.ObjectMeta{}, "Name")); d != "" {
+				t.Errorf("Expected TaskRun was not created. Diff %s", diff.PrintWantGot(d))
+			}
+
+			if err := checkEvents(testAssets.Recorder, tc.name, tc.expectedEvents); err != nil {
+				t.Errorf(err.Error())
+			}
+		})
+	}
+}
+
+func withLoopTimeout(tl *taskloopv1alpha1.TaskLoop, timeout time.Duration) *taskloopv1alpha1.TaskLoop {
+	taskLoopWithTimeout := tl.DeepCopy()
+	taskLoopWithTimeout.Spec.LoopTimeout = &metav1.Duration{Duration: timeout}
+	return taskLoopWithTimeout
+}
+
+func withRunTimeout(run *v1beta1.CustomRun, timeout time.Duration) *v1beta1.CustomRun {
+	runWithTimeout := run.DeepCopy()
+	runWithTimeout.Spec.Timeout = &metav1.Duration{Duration: timeout}
+	return runWithTimeout
+}
+
+func startedBefore(run *v1beta1.CustomRun, elapsed time.Duration) *v1beta1.CustomRun {
+	runWithStartTime := run.DeepCopy()
+	runWithStartTime.Status.StartTime = &metav1.Time{Time: now.Add(-elapsed)}
+	return runWithStartTime
+}
+
+func getPatchedTaskRunNames(clients test.Clients) []string {
+	patched := []string{}
+	for _, a := range clients.Pipeline.Actions() {
+		if a.GetVerb() == "patch" && a.GetResource().Resource == "taskruns" {
+			patched = append(patched, a.(ktesting.PatchAction).GetName())
+		}
+	}
+	return patched
+}
+
+func TestReconcileTaskLoopRunLoopTimeout(t *testing.T) {
+	testcases := []struct {
+		name              string
+		taskloop          *taskloopv1alpha1.TaskLoop
+		run               *v1beta1.CustomRun
+		taskruns          []*v1beta1.TaskRun
+		expectedStatus    corev1.ConditionStatus
+		expectedReason    taskloopv1alpha1.TaskLoopRunReason
+		expectedTaskRuns  int
+		expectedCancelled []string
+		expectedEvents    []string
+	}{{
+		name:             "loop within its loop timeout starts the next iteration",
+		taskloop:         withLoopTimeout(aTaskLoop, time.Hour),
+		run:              startedBefore(loopRunning(runTaskLoop), time.Minute),
+		taskruns:         []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
+		expectedStatus:   corev1.ConditionUnknown,
+		expectedReason:   taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedTaskRuns: 1,
+		expectedEvents:   []string{"Normal Running Iterations completed: 1"},
+	}, {
+		name:              "loop past its loop timeout cancels the running TaskRun",
+		taskloop:          withLoopTimeout(aTaskLoop, time.Hour),
+		run:               startedBefore(loopRunning(runTaskLoop), 2*time.Hour),
+		taskruns:          []*v1beta1.TaskRun{running(expectedTaskRunIteration1)},
+		expectedStatus:    corev1.ConditionUnknown,
+		expectedReason:    taskloopv1alpha1.TaskLoopRunReasonRunning,
+		expectedCancelled: []string{expectedTaskRunIteration1.Name},
+		expectedEvents:    []string{"Normal Running Cancelling TaskRuns as the loop timed out"},
+	}, {
+		name:           "loop past its loop timeout fails once its TaskRuns are done without retrying them",
+		taskloop:       allowRetry(withLoopTimeout(aTaskLoop, time.Hour)),
+		run:            startedBefore(loopRunning(runTaskLoop), 2*time.Hour),
+		taskruns:       []*v1beta1.TaskRun{failed(expectedTaskRunIteration1)},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonLoopTimedOut,
+		expectedEvents: []string{"Warning Failed Run foo/run-taskloop timed out after 1h0m0s"},
+	}, {
+		name:           "loop past the timeout of the Run does not start the next iteration",
+		taskloop:       withLoopTimeout(aTaskLoop, 2*time.Hour),
+		run:            withRunTimeout(startedBefore(loopRunning(runTaskLoop), 90*time.Minute), time.Hour),
+		taskruns:       []*v1beta1.TaskRun{successful(expectedTaskRunIteration1)},
+		expectedStatus: corev1.ConditionFalse,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonLoopTimedOut,
+		expectedEvents: []string{"Warning Failed Run foo/run-taskloop timed out after 1h0m0s"},
+	}, {
+		name:           "loop whose TaskRuns all succeeded before it was reconciled past its loop timeout succeeds",
+		taskloop:       withLoopTimeout(aTaskLoop, time.Hour),
+		run:            startedBefore(loopRunning(runTaskLoop), 2*time.Hour),
+		taskruns:       []*v1beta1.TaskRun{successful(expectedTaskRunIteration1), successful(expectedTaskRunIteration2), successful(expectedTaskRunIteration3)},
+		expectedStatus: corev1.ConditionTrue,
+		expectedReason: taskloopv1alpha1.TaskLoopRunReasonSucceeded,
+		expectedEvents: []string{"Normal Succeeded All TaskRuns completed successfully"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			names.TestingSeed()
+
+			d := test.Data{
+				CustomRuns: []*v1beta1.CustomRun{tc.run},
+				Tasks:      []*v1beta1.Task{aTask},
+				TaskRuns:   tc.taskruns,
+			}
+
+			testAssets, _ := getTaskLoopController(t, d, []*taskloopv1alpha1.TaskLoop{tc.taskloop})
+			c := testAssets.Controller
+			clients := testAssets.Clients
+
+			if err := c.Reconciler.Reconcile(ctx, getRunName(tc.run)); err != nil {
+				t.Fatalf("Error reconciling: %s", err)
+			}
+
+			reconciledRun, err := clients.Pipeline.TektonV1beta1().CustomRuns(tc.run.Namespace).Get(ctx, tc.run.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Error getting reconciled run from fake client: %s", err)
+			}
+			checkRunCondition(t, reconciledRun, tc.expectedStatus, tc.expectedReason)
+
+			if got := len(getCreatedTaskRuns(t, clients)); got != tc.expectedTaskRuns {
+				t.Errorf("Expected %d TaskRuns to be created but got %d", tc.expectedTaskRuns, got)
+			}
+			if d := cmp.Diff(tc.expectedCancelled, getPatchedTaskRunNames(clients), cmpopts.EquateEmpty()); d != "" {
+				t.Errorf("Unexpected TaskRuns were cancelled. Diff %s", diff.PrintWantGot(d))
+			}
+
+			if err := checkEvents(testAssets.Recorder, tc.name, tc.expectedEvents); err != nil {
+				t.Errorf(err.Error())
+			}
+		})
+	}
+}
@@ -45,12 +45,13 @@ import (
 	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
 	resourceversioned "github.com/tektoncd/pipeline/pkg/client/resource/clientset/versioned"
 	resourcev1alpha1 "github.com/tektoncd/pipeline/pkg/client/resource/clientset/versioned/typed/resource/v1alpha1"
+	"k8s.io/client-go/kubernetes"
 	knativetest "knative.dev/pkg/test"
 )
 
 // clients holds instances of interfaces for making requests to the Pipeline controllers.
 type clients struct {
-	KubeClient *knativetest.KubeClient
+	KubeClient kubernetes.Interface
 
 	PipelineClient         v1beta1.PipelineInterface
 	ClusterTaskClient      v1beta1.ClusterTaskInterface
@@ -58,8 +59,8 @@ type clients struct {
 	TaskRunClient          v1beta1.TaskRunInterface
 	PipelineRunClient      v1beta1.PipelineRunInterface
 	PipelineResourceClient resourcev1alpha1.PipelineResourceInterface
-	ConditionClient        v1alpha1.ConditionInterface
 	RunClient              v1alpha1.RunInterface
+	CustomRunClient        v1beta1.CustomRunInterface
 }
 
 // newClients instantiates and returns several clientsets required for making requests to the
@@ -70,14 +71,14 @@ func newClients(t *testing.T, configPath, clusterName, namespace string) *client
 	var err error
 	c := &clients{}
 
-	c.KubeClient, err = knativetest.NewKubeClient(configPath, clusterName)
+	cfg, err := knativetest.BuildClientConfig(configPath, clusterName)
 	if err != nil {
-		t.Fatalf("failed to create kubeclient from config file at %s: %s", configPath, err)
+		t.Fatalf("failed to create configuration obj from %s for cluster %s: %s", configPath, clusterName, err)
 	}
 
-	cfg, err := knativetest.BuildClientConfig(configPath, clusterName)
+	c.KubeClient, err = kubernetes.NewForConfig(cfg)
 	if err != nil {
-		t.Fatalf("failed to create configuration obj from %s for cluster %s: %s", configPath, clusterName, err)
+		t.Fatalf("failed to create kubeclient from config file at %s: %s", configPath, err)
 	}
 
 	cs, err := versioned.NewForConfig(cfg)
@@ -94,7 +95,7 @@ func newClients(t *testing.T, configPath, clusterName, namespace string) *client
 	c.TaskRunClient = cs.TektonV1beta1().TaskRuns(namespace)
 	c.PipelineRunClient = cs.TektonV1beta1().PipelineRuns(namespace)
 	c.PipelineResourceClient = rcs.TektonV1alpha1().PipelineResources(namespace)
-	c.ConditionClient = cs.TektonV1alpha1().Conditions(namespace)
 	c.RunClient = cs.TektonV1alpha1().Runs(namespace)
+	c.CustomRunClient = cs.TektonV1beta1().CustomRuns(namespace)
 	return c
 }
@@ -45,8 +45,8 @@ function uninstall_taskloop_crd() {
 }
 
 function delete_taskloop_resources() {
-  # TODO: Runs should be cleaned up by e2e-common script in pipeline
-  for res in run; do
+  # TODO: CustomRuns and Runs should be cleaned up by e2e-common script in pipeline
+  for res in customrun run; do
     kubectl delete --ignore-not-found=true ${res}.tekton.dev --all
   done
   for res in taskloop; do
@@ -37,20 +37,22 @@ import (
 	taskloopv1alpha1 "github.com/tektoncd/experimental/task-loops/pkg/apis/taskloop/v1alpha1"
 	"github.com/tektoncd/experimental/task-loops/pkg/client/clientset/versioned"
 	resourceversioned "github.com/tektoncd/experimental/task-loops/pkg/client/clientset/versioned/typed/taskloop/v1alpha1"
-	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/names"
-	"github.com/tektoncd/pipeline/pkg/pod"
 	"github.com/tektoncd/pipeline/test/diff"
 	"gomodules.xyz/jsonpatch/v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"knative.dev/pkg/apis"
-	duckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	knativetest "knative.dev/pkg/test"
 )
 
+// releaseAnnotation is the annotation Tekton adds to the TaskRuns with the version of the release.
+const releaseAnnotation = "pipeline.tekton.dev/release"
+
 var (
 	concurrencyLimit2         = 2
 	noConcurrencyLimit        = 0
@@ -61,7 +63,7 @@ var (
 	defaultTaskRunTimeout     = &metav1.Duration{Duration: 1 * time.Hour}
 	shortTaskRunTimeout       = &metav1.Duration{Duration: 10 * time.Second}
 	ignoreReleaseAnnotation   = func(k string, v string) bool {
-		return k == pod.ReleaseAnnotation
+		return k == releaseAnnotation
 	}
 )
 
@@ -88,12 +90,10 @@ var commonTaskSpec = v1beta1.TaskSpec{
 		Default: &v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "0"},
 	}},
 	Steps: []v1beta1.Step{{
-		Container: corev1.Container{
-			Name:    "passfail",
-			Image:   "ubuntu",
-			Command: []string{"/bin/bash"},
-			Args:    []string{"-c", `sleep $(params.sleep-time) && [[ "$(params.fail-on-item)" == "" || "$(params.current-item)" != "$(params.fail-on-item)" ]]`},
-		},
+		Name:    "passfail",
+		Image:   "ubuntu",
+		Command: []string{"/bin/bash"},
+		Args:    []string{"-c", `sleep $(params.sleep-time) && [[ "$(params.fail-on-item)" == "" || "$(params.current-item)" != "$(params.fail-on-item)" ]]`},
 	}},
 }
 
@@ -148,7 +148,7 @@ var aTaskLoopUsingAClusterTask = &taskloopv1alpha1.TaskLoop{
 	},
 }
 
-var runTaskLoopSuccess = &v1alpha1.Run{
+var runTaskLoopSuccess = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: "run-taskloop",
 		Labels: map[string]string{
@@ -158,12 +158,12 @@ var runTaskLoopSuccess = &v1alpha1.Run{
 			"myRunAnnotation": "myRunAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -171,7 +171,7 @@ var runTaskLoopSuccess = &v1alpha1.Run{
 	},
 }
 
-var runTaskLoopFailure = &v1alpha1.Run{
+var runTaskLoopFailure = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: "run-taskloop",
 		Labels: map[string]string{
@@ -181,7 +181,7 @@ var runTaskLoopFailure = &v1alpha1.Run{
 			"myRunAnnotation": "myRunAnnotationValue",
 		},
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
@@ -189,7 +189,7 @@ var runTaskLoopFailure = &v1alpha1.Run{
 			Name:  "fail-on-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "item1"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -197,17 +197,17 @@ var runTaskLoopFailure = &v1alpha1.Run{
 	},
 }
 
-var runTaskLoopUsingAnInlineTaskSuccess = &v1alpha1.Run{
+var runTaskLoopUsingAnInlineTaskSuccess = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: "run-taskloop",
 		// No labels or annotations in this one to test that case works
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop-using-an-inline-task",
@@ -215,17 +215,17 @@ var runTaskLoopUsingAnInlineTaskSuccess = &v1alpha1.Run{
 	},
 }
 
-var runTaskLoopUsingAClusterTaskSuccess = &v1alpha1.Run{
+var runTaskLoopUsingAClusterTaskSuccess = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: "run-taskloop",
 		// No labels or annotations in this one to test that case works
 	},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop-using-a-cluster-task",
@@ -236,9 +236,9 @@ var runTaskLoopUsingAClusterTaskSuccess = &v1alpha1.Run{
 // This Run is used in the concurrency tests.  The tasks need to execute a short amount of time to make it
 // unlikely that one could complete before the controller submits all of the TaskRuns.  If that happened, it would
 // appear as if the desired concurrency wasn't obtained.  See checkConcurrency() for details.
-var runTaskLoopWithShortSleep = &v1alpha1.Run{
+var runTaskLoopWithShortSleep = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{Name: "run-taskloop"},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
@@ -246,7 +246,7 @@ var runTaskLoopWithShortSleep = &v1alpha1.Run{
 			Name:  "sleep-time",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "10"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -255,9 +255,9 @@ var runTaskLoopWithShortSleep = &v1alpha1.Run{
 }
 
 // This Run is used in timeout and cancellation tests where we need the task to hang around a while.
-var runTaskLoopWithLongSleep = &v1alpha1.Run{
+var runTaskLoopWithLongSleep = &v1beta1.CustomRun{
 	ObjectMeta: metav1.ObjectMeta{Name: "run-taskloop"},
-	Spec: v1alpha1.RunSpec{
+	Spec: v1beta1.CustomRunSpec{
 		Params: []v1beta1.Param{{
 			Name:  "current-item",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"item1", "item2", "item3"}},
@@ -265,7 +265,7 @@ var runTaskLoopWithLongSleep = &v1alpha1.Run{
 			Name:  "sleep-time",
 			Value: v1beta1.ArrayOrString{Type: v1beta1.ParamTypeString, StringVal: "120"},
 		}},
-		Ref: &v1alpha1.TaskRef{
+		CustomRef: &v1beta1.TaskRef{
 			APIVersion: taskloopv1alpha1.SchemeGroupVersion.String(),
 			Kind:       taskloop.TaskLoopControllerName,
 			Name:       "a-taskloop",
@@ -273,7 +273,7 @@ var runTaskLoopWithLongSleep = &v1alpha1.Run{
 	},
 }
 
-var taskRunStatusSuccess = duckv1beta1.Status{
+var taskRunStatusSuccess = duckv1.Status{
 	Conditions: []apis.Condition{{
 		Type:   apis.ConditionSucceeded,
 		Status: corev1.ConditionTrue,
@@ -281,7 +281,7 @@ var taskRunStatusSuccess = duckv1beta1.Status{
 	}},
 }
 
-var taskRunStatusFailed = duckv1beta1.Status{
+var taskRunStatusFailed = duckv1.Status{
 	Conditions: []apis.Condition{{
 		Type:   apis.ConditionSucceeded,
 		Status: corev1.ConditionFalse,
@@ -289,7 +289,7 @@ var taskRunStatusFailed = duckv1beta1.Status{
 	}},
 }
 
-var taskRunStatusTimeout = duckv1beta1.Status{
+var taskRunStatusTimeout = duckv1.Status{
 	Conditions: []apis.Condition{{
 		Type:   apis.ConditionSucceeded,
 		Status: corev1.ConditionFalse,
@@ -473,7 +473,7 @@ func TestTaskLoopRun(t *testing.T) {
 		task        *v1beta1.Task
 		clustertask *v1beta1.ClusterTask
 		taskloop    *taskloopv1alpha1.TaskLoop
-		run         *v1alpha1.Run
+		run         *v1beta1.CustomRun
 		// The following set of fields describe the expected outcome.
 		expectedStatus      corev1.ConditionStatus
 		expectedReason      taskloopv1alpha1.TaskLoopRunReason
@@ -631,7 +631,7 @@ func TestTaskLoopRun(t *testing.T) {
 
 			run := tc.run.DeepCopy()
 			run.Namespace = namespace
-			run, err := c.RunClient.Create(ctx, run, metav1.CreateOptions{})
+			run, err := c.CustomRunClient.Create(ctx, run, metav1.CreateOptions{})
 			if err != nil {
 				t.Fatalf("Failed to create Run `%s`: %s", run.Name, err)
 			}
@@ -646,17 +646,17 @@ func TestTaskLoopRun(t *testing.T) {
 				inState = FailedWithReason(tc.expectedReason.String(), run.Name)
 				desc = "RunFailed"
 			}
-			if err := WaitForRunState(ctx, c, run.Name, runTimeout, inState, desc); err != nil {
+			if err := WaitForCustomRunState(ctx, c, run.Name, runTimeout, inState, desc); err != nil {
 				t.Fatalf("Error waiting for Run %s/%s to finish: %s", run.Namespace, run.Name, err)
 			}
 
-			run, err = c.RunClient.Get(ctx, run.Name, metav1.GetOptions{})
+			run, err = c.CustomRunClient.Get(ctx, run.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatalf("Couldn't get expected Run %s/%s: %s", run.Namespace, run.Name, err)
 			}
 
 			t.Logf("Making sure the expected TaskRuns were created")
-			actualTaskRunList, err := c.TaskRunClient.List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("tekton.dev/run=%s", run.Name)})
+			actualTaskRunList, err := c.TaskRunClient.List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("tekton.dev/customRun=%s", run.Name)})
 			if err != nil {
 				t.Fatalf("Error listing TaskRuns for Run %s/%s: %s", run.Namespace, run.Name, err)
 			}
@@ -759,16 +759,16 @@ func TestCancelTaskLoopRun(t *testing.T) {
 
 		run := runTaskLoopWithLongSleep
 		t.Logf("Creating Run %s in namespace %s", run.Name, namespace)
-		if _, err := c.RunClient.Create(ctx, run, metav1.CreateOptions{}); err != nil {
+		if _, err := c.CustomRunClient.Create(ctx, run, metav1.CreateOptions{}); err != nil {
 			t.Fatalf("Failed to create Run `%s`: %s", run.Name, err)
 		}
 
 		t.Logf("Waiting for Run %s in namespace %s to be started", run.Name, namespace)
-		if err := WaitForRunState(ctx, c, run.Name, runTimeout, Running(run.Name), "RunRunning"); err != nil {
+		if err := WaitForCustomRunState(ctx, c, run.Name, runTimeout, Running(run.Name), "RunRunning"); err != nil {
 			t.Fatalf("Error waiting for Run %s to be running: %s", run.Name, err)
 		}
 
-		taskrunList, err := c.TaskRunClient.List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/run=" + run.Name})
+		taskrunList, err := c.TaskRunClient.List(ctx, metav1.ListOptions{LabelSelector: "tekton.dev/customRun=" + run.Name})
 		if err != nil {
 			t.Fatalf("Error listing TaskRuns for Run %s: %s", run.Name, err)
 		}
@@ -787,7 +787,7 @@ func TestCancelTaskLoopRun(t *testing.T) {
 		}
 		wg.Wait()
 
-		pr, err := c.RunClient.Get(ctx, run.Name, metav1.GetOptions{})
+		pr, err := c.CustomRunClient.Get(ctx, run.Name, metav1.GetOptions{})
 		if err != nil {
 			t.Fatalf("Failed to get Run `%s`: %s", run.Name, err)
 		}
@@ -795,19 +795,19 @@ func TestCancelTaskLoopRun(t *testing.T) {
 		patches := []jsonpatch.JsonPatchOperation{{
 			Operation: "add",
 			Path:      "/spec/status",
-			Value:     v1alpha1.RunSpecStatusCancelled,
+			Value:     v1beta1.CustomRunSpecStatusCancelled,
 		}}
 		patchBytes, err := json.Marshal(patches)
 		if err != nil {
 			t.Fatalf("failed to marshal patch bytes in order to cancel")
 		}
-		if _, err := c.RunClient.Patch(ctx, pr.Name, types.JSONPatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		if _, err := c.CustomRunClient.Patch(ctx, pr.Name, types.JSONPatchType, patchBytes, metav1.PatchOptions{}); err != nil {
 			t.Fatalf("Failed to patch Run `%s` with cancellation: %s", run.Name, err)
 		}
 
 		t.Logf("Waiting for Run %s in namespace %s to be cancelled", run.Name, namespace)
-		if err := WaitForRunState(ctx, c, run.Name, runTimeout,
-			FailedWithReason(v1alpha1.RunReasonCancelled, run.Name), "RunCancelled"); err != nil {
+		if err := WaitForCustomRunState(ctx, c, run.Name, runTimeout,
+			FailedWithReason(v1beta1.CustomRunReasonCancelled.String(), run.Name), "RunCancelled"); err != nil {
 			t.Errorf("Error waiting for Run %q to finished: %s", run.Name, err)
 		}
 
@@ -855,7 +855,7 @@ func getExpectedTaskRunForClusterTask(expectedTaskRun *v1beta1.TaskRun) *v1beta1
 	return expectedTaskRun
 }
 
-func getExpectedTaskRunAnnotations(taskloop *taskloopv1alpha1.TaskLoop, run *v1alpha1.Run) map[string]string {
+func getExpectedTaskRunAnnotations(taskloop *taskloopv1alpha1.TaskLoop, run *v1beta1.CustomRun) map[string]string {
 	annotations := make(map[string]string, len(taskloop.ObjectMeta.Annotations)+len(run.ObjectMeta.Annotations))
 	for key, value := range taskloop.ObjectMeta.Labels {
 		run.ObjectMeta.Labels[key] = value
@@ -866,10 +866,10 @@ func getExpectedTaskRunAnnotations(taskloop *taskloopv1alpha1.TaskLoop, run *v1a
 	return annotations
 }
 
-func getExpectedTaskRunLabels(task *v1beta1.Task, clustertask *v1beta1.ClusterTask, taskloop *taskloopv1alpha1.TaskLoop, run *v1alpha1.Run, iteration int) map[string]string {
+func getExpectedTaskRunLabels(task *v1beta1.Task, clustertask *v1beta1.ClusterTask, taskloop *taskloopv1alpha1.TaskLoop, run *v1beta1.CustomRun, iteration int) map[string]string {
 	labels := map[string]string{
 		"app.kubernetes.io/managed-by":        "tekton-pipelines",
-		"tekton.dev/run":                      run.Name,
+		"tekton.dev/customRun":                run.Name,
 		"custom.tekton.dev/taskLoop":          taskloop.Name,
 		"custom.tekton.dev/taskLoopIteration": strconv.Itoa(iteration),
 	}
@@ -915,7 +915,7 @@ func checkTaskRunRetries(t *testing.T, expectedTaskRun *v1beta1.TaskRun, actualT
 
 // checkConcurrency collects and sorts the creation and completion times of each TaskRun.
 // It then processes the times in sequence to determine the maximum number of TaskRuns were alive at a time.
-func checkConcurrency(t *testing.T, expectedConcurrency *int, run *v1alpha1.Run,
+func checkConcurrency(t *testing.T, expectedConcurrency *int, run *v1beta1.CustomRun,
 	expectedTaskRuns []*v1beta1.TaskRun, actualTaskRunList *v1beta1.TaskRunList) {
 
 	type taskRunEventType int
@@ -1039,8 +1039,8 @@ func checkConcurrency(t *testing.T, expectedConcurrency *int, run *v1alpha1.Run,
 	}
 }
 
-func checkEvents(ctx context.Context, t *testing.T, c *clients, run *v1alpha1.Run, namespace string, expectedEvents []ev) {
-	matchKinds := map[string][]string{"Run": {run.Name}}
+func checkEvents(ctx context.Context, t *testing.T, c *clients, run *v1beta1.CustomRun, namespace string, expectedEvents []ev) {
+	matchKinds := map[string][]string{"CustomRun": {run.Name}}
 	events, err := collectMatchingEvents(ctx, c.KubeClient, namespace, matchKinds)
 	if err != nil {
 		t.Fatalf("Failed to collect matching events: %q", err)
@@ -1073,7 +1073,7 @@ func checkEvents(ctx context.Context, t *testing.T, c *clients, run *v1alpha1.Ru
 
 // collectMatchingEvents collects a list of events under 5 seconds that match certain objects by kind and name.
 // This is copied from pipelinerun_test and modified to drop the reason parameter.
-func collectMatchingEvents(ctx context.Context, kubeClient *knativetest.KubeClient, namespace string, kinds map[string][]string) ([]*corev1.Event, error) {
+func collectMatchingEvents(ctx context.Context, kubeClient kubernetes.Interface, namespace string, kinds map[string][]string) ([]*corev1.Event, error) {
 	var events []*corev1.Event
 
 	watchEvents, err := kubeClient.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{})
@@ -18,35 +18,74 @@ package main // import "github.com/tektoncd/experimental/wait-task/cmd/controlle
 
 import (
 	"context"
+	"os"
+	"strings"
+	// Embed the timezone database, for the timezone param of nextCron.
+	_ "time/tzdata"
 
+	"github.com/tektoncd/experimental/wait-task/pkg/approval"
 	"github.com/tektoncd/experimental/wait-task/pkg/reconciler"
-	runinformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1alpha1/run"
-	runreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1alpha1/run"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	tkncontroller "github.com/tektoncd/pipeline/pkg/controller"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection/clients/dynamicclient"
 	"knative.dev/pkg/injection/sharedmain"
 )
 
-const controllerName = "wait-task-controller"
+const (
+	controllerName = "wait-task-controller"
+
+	// conditionNamespacesEnv is the environment variable holding the comma-separated namespaces, besides its own,
+	// whose objects a CustomRun can wait for with untilCondition.
+	conditionNamespacesEnv = "UNTIL_CONDITION_NAMESPACES"
+
+	// conditionClusterKindsEnv is the environment variable holding the comma-separated cluster-scoped kinds, as
+	// kind.group, whose objects a CustomRun can wait for with untilCondition.
+	conditionClusterKindsEnv = "UNTIL_CONDITION_CLUSTER_KINDS"
+)
 
 func main() {
-	sharedmain.Main(controllerName, newController)
+	sharedmain.Main(controllerName, newController, approval.NewController)
 }
 
 func newController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-	c := &reconciler.Reconciler{}
-	impl := runreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
+	c := &reconciler.Reconciler{
+		DynamicClient: dynamicclient.Get(ctx),
+		// Discover the resources of the objects waited for with untilCondition the first time they're read.
+		RESTMapper:            restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeclient.Get(ctx).Discovery())),
+		ConditionNamespaces:   listEnv(conditionNamespacesEnv),
+		ConditionClusterKinds: listEnv(conditionClusterKindsEnv),
+		Clock:                 clock.RealClock{},
+	}
+	impl := customrunreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
 		return controller.Options{
 			AgentName: controllerName,
 		}
 	})
 
-	runinformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
-		FilterFunc: tkncontroller.FilterRunRef("example.dev/v0", "Wait"),
+	customruninformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: tkncontroller.FilterCustomRunRef("example.dev/v0", "Wait"),
 		Handler:    controller.HandleAll(impl.Enqueue),
 	})
 
 	return impl
 }
+
+// listEnv returns the comma-separated values of an environment variable.
+func listEnv(name string) sets.String {
+	values := sets.NewString()
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values.Insert(v)
+		}
+	}
+	return values
+}
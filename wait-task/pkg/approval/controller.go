@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
+	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
+	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
+	tkncontroller "github.com/tektoncd/pipeline/pkg/controller"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	controllerName = "approval-controller"
+
+	// keyEnv is the environment variable holding the key the decisions are signed with.  It must be set, so that the
+	// decisions still verify after the controller restarts.
+	keyEnv = "APPROVAL_SIGNING_KEY"
+
+	// insecureHTTPEnv is the environment variable which, when true, lets the endpoint be served over plain HTTP when
+	// tlsDir holds no certificate.  It is only meant for when a proxy in front of the endpoint terminates TLS.
+	insecureHTTPEnv = "APPROVAL_INSECURE_HTTP"
+
+	// addr is the address the HTTP endpoint of the approvers listens on.
+	addr = ":8080"
+
+	// tlsDir is where the approval-tls Secret is mounted.  The endpoint is served with its tls.crt and tls.key.
+	tlsDir = "/etc/approval-tls"
+)
+
+// NewController returns the controller of the approvals, and serves the HTTP endpoint of the approvers until the
+// context is done.
+func NewController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	logger := logging.FromContext(ctx)
+
+	key, err := signingKey()
+	if err != nil {
+		logger.Fatalw("Failed to read the signing key of the decisions", "error", err)
+	}
+
+	clk := clock.RealClock{}
+	c := &Reconciler{Key: key, Clock: clk}
+	impl := customrunreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
+		return controller.Options{
+			AgentName: controllerName,
+		}
+	})
+
+	customruninformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: tkncontroller.FilterCustomRunRef(apiVersion, kind),
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
+
+	srv := &http.Server{
+		Addr: addr,
+		Handler: &Server{
+			Key:            key,
+			KubeClient:     kubeclient.Get(ctx),
+			PipelineClient: pipelineclient.Get(ctx),
+			Clock:          clk,
+		},
+	}
+	serve, secure, err := listenAndServe(srv, tlsDir, os.Getenv(insecureHTTPEnv))
+	if err != nil {
+		logger.Fatalw("Failed to set up the approval endpoint", "error", err)
+	}
+	if !secure {
+		logger.Warnf("Serving the approval endpoint over plain HTTP since %s is true; "+
+			"it must only be exposed through a proxy terminating TLS, since the approvers send their tokens", insecureHTTPEnv)
+	}
+	go func() {
+		if err := serve(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalw("Failed to serve the approval endpoint", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	return impl
+}
+
+// signingKey returns the key the decisions are signed with.
+func signingKey() ([]byte, error) {
+	key := []byte(os.Getenv(keyEnv))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s must be set, e.g. from the approval-signing-key Secret", keyEnv)
+	}
+	return key, nil
+}
+
+// listenAndServe returns the function serving the endpoint with the certificate in dir, and whether it is served over
+// TLS.  Without a certificate, the endpoint is only served over plain HTTP if insecureHTTP is true, since the approvers
+// send their tokens to it.
+func listenAndServe(srv *http.Server, dir, insecureHTTP string) (func() error, bool, error) {
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if fileExists(certFile) && fileExists(keyFile) {
+		return func() error { return srv.ListenAndServeTLS(certFile, keyFile) }, true, nil
+	}
+	allowed := false
+	if insecureHTTP != "" {
+		var err error
+		if allowed, err = strconv.ParseBool(insecureHTTP); err != nil {
+			return nil, false, fmt.Errorf("%s must be true or false: %w", insecureHTTPEnv, err)
+		}
+	}
+	if !allowed {
+		return nil, false, fmt.Errorf("no certificate found in %s to serve the approval endpoint over TLS; mount the approval-tls Secret, "+
+			"or set %s to true if a proxy in front of the endpoint terminates TLS", dir, insecureHTTPEnv)
+	}
+	return srv.ListenAndServe, false, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenAndServe(t *testing.T) {
+	withCert := t.TempDir()
+	for _, f := range []string{"tls.crt", "tls.key"} {
+		if err := os.WriteFile(filepath.Join(withCert, f), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withoutCert := t.TempDir()
+
+	for _, c := range []struct {
+		desc         string
+		dir          string
+		insecureHTTP string
+		wantSecure   bool
+		wantErr      bool
+	}{{
+		desc:       "certificate",
+		dir:        withCert,
+		wantSecure: true,
+	}, {
+		desc:         "certificate with plain HTTP allowed",
+		dir:          withCert,
+		insecureHTTP: "true",
+		wantSecure:   true,
+	}, {
+		desc:    "no certificate",
+		dir:     withoutCert,
+		wantErr: true,
+	}, {
+		desc:         "no certificate with plain HTTP not allowed",
+		dir:          withoutCert,
+		insecureHTTP: "false",
+		wantErr:      true,
+	}, {
+		desc:         "no certificate with plain HTTP allowed",
+		dir:          withoutCert,
+		insecureHTTP: "true",
+	}, {
+		desc:         "invalid opt-out",
+		dir:          withoutCert,
+		insecureHTTP: "yes please",
+		wantErr:      true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			serve, secure, err := listenAndServe(&http.Server{}, c.dir, c.insecureHTTP)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("wanted an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("listenAndServe() = %v", err)
+			}
+			if serve == nil {
+				t.Errorf("wanted a function serving the endpoint, got nil")
+			}
+			if secure != c.wantSecure {
+				t.Errorf("wanted the endpoint served over TLS to be %t, got %t", c.wantSecure, secure)
+			}
+		})
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	t.Setenv(keyEnv, "")
+	if _, err := signingKey(); err == nil {
+		t.Errorf("wanted an error without a signing key, got none")
+	}
+
+	t.Setenv(keyEnv, "secret")
+	key, err := signingKey()
+	if err != nil {
+		t.Fatalf("signingKey() = %v", err)
+	}
+	if string(key) != "secret" {
+		t.Errorf("wanted the signing key of %s, got %q", keyEnv, key)
+	}
+}
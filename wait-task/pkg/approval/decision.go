@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+)
+
+// decisionsAnnotation is the annotation of the CustomRun holding the decisions of its approvers.  It is only written
+// by the HTTP endpoint of the controller, which the reconciler doesn't race with since it only updates the status.
+const decisionsAnnotation = "approval.example.dev/decisions"
+
+// Decision is the approval or rejection of a CustomRun by one of its approvers.
+type Decision struct {
+	User     string    `json:"user"`
+	Approved bool      `json:"approved"`
+	Comment  string    `json:"comment,omitempty"`
+	Time     time.Time `json:"time"`
+	// Signature is the HMAC of the decision and the UID of its CustomRun with the key of the controller.  Anyone
+	// allowed to update the CustomRun can write the annotation, but only the HTTP endpoint, which authenticates
+	// the approvers, can sign the decisions.
+	Signature string `json:"signature"`
+}
+
+// signature returns the signature of the decision of a CustomRun.
+func (d *Decision) signature(key []byte, r *v1beta1.CustomRun) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%t\n%s\n%s", r.UID, d.User, d.Approved, d.Time.UTC().Format(time.RFC3339), d.Comment)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getDecisions returns the decisions of the CustomRun which are signed with the key.  The other ones are ignored.
+func getDecisions(key []byte, r *v1beta1.CustomRun) ([]Decision, error) {
+	value, ok := r.Annotations[decisionsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var decisions []Decision
+	if err := json.Unmarshal([]byte(value), &decisions); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", decisionsAnnotation, err)
+	}
+	var signed []Decision
+	for _, d := range decisions {
+		if hmac.Equal([]byte(d.Signature), []byte(d.signature(key, r))) {
+			signed = append(signed, d)
+		}
+	}
+	return signed, nil
+}
+
+// setDecision signs the decision and records it in the annotation of the CustomRun, replacing an earlier decision of
+// the same approver.
+func setDecision(key []byte, r *v1beta1.CustomRun, d Decision) error {
+	decisions, err := getDecisions(key, r)
+	if err != nil {
+		return err
+	}
+	d.Time = d.Time.UTC().Truncate(time.Second)
+	d.Signature = d.signature(key, r)
+	updated := []Decision{}
+	for _, existing := range decisions {
+		if existing.User != d.User {
+			updated = append(updated, existing)
+		}
+	}
+	updated = append(updated, d)
+	b, err := json.Marshal(updated)
+	if err != nil {
+		return err
+	}
+	if r.Annotations == nil {
+		r.Annotations = map[string]string{}
+	}
+	r.Annotations[decisionsAnnotation] = string(b)
+	return nil
+}
+
+// approvers are the users and groups allowed to approve or reject a CustomRun.  A group is prefixed with "group:".
+type approvers []string
+
+// hasGroups returns true if one of the approvers is a group, which can hold any number of users.
+func (a approvers) hasGroups() bool {
+	for _, approver := range a {
+		if strings.HasPrefix(approver, "group:") {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the user or one of its groups is one of the approvers.
+func (a approvers) allows(user string, groups []string) bool {
+	for _, approver := range a {
+		if group := strings.TrimPrefix(approver, "group:"); group != approver {
+			for _, g := range groups {
+				if g == group {
+					return true
+				}
+			}
+		} else if approver == user {
+			return true
+		}
+	}
+	return false
+}
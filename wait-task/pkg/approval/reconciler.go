@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	kreconciler "knative.dev/pkg/reconciler"
+)
+
+const (
+	apiVersion = "example.dev/v0"
+	kind       = "Approval"
+)
+
+// spec is what a CustomRun waits for, parsed from its params.
+type spec struct {
+	approvers         approvers
+	approvalsRequired int
+	timeout           time.Duration
+}
+
+type Reconciler struct {
+	// Key verifies the signatures of the decisions of the approvers.
+	Key   []byte
+	Clock clock.PassiveClock
+}
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1beta1.CustomRun) kreconciler.Event {
+	logger := logging.FromContext(ctx)
+	logger.Infof("Reconciling %s/%s", r.Namespace, r.Name)
+
+	// Ignore completed approvals.
+	if r.IsDone() {
+		logger.Info("CustomRun is finished, done reconciling")
+		return nil
+	}
+
+	if !isApproval(r) {
+		// This is not a CustomRun we should have been notified about; do nothing.
+		return nil
+	}
+	if r.Spec.CustomRef.Name != "" {
+		r.Status.MarkCustomRunFailed("UnexpectedName", "Found unexpected ref name: %s", r.Spec.CustomRef.Name)
+		return nil
+	}
+
+	s, ok := getSpec(r)
+	if !ok {
+		return nil
+	}
+
+	if r.Status.StartTime == nil {
+		now := metav1.NewTime(c.Clock.Now())
+		r.Status.StartTime = &now
+	}
+
+	if r.IsCancelled() {
+		r.Status.MarkCustomRunFailed(v1beta1.CustomRunReasonCancelled.String(), "The approval was cancelled")
+		c.setCompletionTime(r)
+		return nil
+	}
+
+	decisions, err := getDecisions(c.Key, r)
+	if err != nil {
+		r.Status.MarkCustomRunFailed("InvalidDecisions", "The decisions could not be read: %v", err)
+		return nil
+	}
+	var approvedBy []string
+	comments := map[string]string{}
+	for _, d := range decisions {
+		if d.Comment != "" {
+			comments[d.User] = d.Comment
+		}
+		if !d.Approved {
+			r.Status.Results = []v1beta1.CustomRunResult{
+				{Name: "rejectedBy", Value: d.User},
+				{Name: "comments", Value: commentsResult(comments)},
+			}
+			r.Status.MarkCustomRunFailed("Rejected", "The approval was rejected by %s", d.User)
+			c.setCompletionTime(r)
+			return nil
+		}
+		approvedBy = append(approvedBy, d.User)
+	}
+	sort.Strings(approvedBy)
+
+	if len(approvedBy) >= s.approvalsRequired {
+		r.Status.Results = []v1beta1.CustomRunResult{
+			{Name: "approvedBy", Value: strings.Join(approvedBy, ",")},
+			{Name: "comments", Value: commentsResult(comments)},
+		}
+		r.Status.MarkCustomRunSucceeded("Approved", "The approval was approved by %s", strings.Join(approvedBy, ", "))
+		c.setCompletionTime(r)
+		return nil
+	}
+
+	// The approval expires after the timeout param, and times out with the CustomRun, e.g. with its PipelineRun.
+	var next time.Time
+	if s.timeout > 0 {
+		expiry := r.Status.StartTime.Time.Add(s.timeout)
+		if !c.Clock.Now().Before(expiry) {
+			r.Status.MarkCustomRunFailed("ApprovalExpired", "The approval expired after %s with %d of %d approvals", s.timeout, len(approvedBy), s.approvalsRequired)
+			c.setCompletionTime(r)
+			return nil
+		}
+		next = expiry
+	}
+	if r.Spec.Timeout != nil && r.Spec.Timeout.Duration > 0 {
+		deadline := r.Status.StartTime.Time.Add(r.Spec.Timeout.Duration)
+		if !c.Clock.Now().Before(deadline) {
+			r.Status.MarkCustomRunFailed(v1beta1.CustomRunReasonTimedOut.String(), "The approval timed out after %s", r.Spec.Timeout.Duration)
+			c.setCompletionTime(r)
+			return nil
+		}
+		if next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+
+	r.Status.MarkCustomRunRunning("WaitingForApproval", "Waiting for %d more approvals from %s", s.approvalsRequired-len(approvedBy), strings.Join(s.approvers, ", "))
+	if !next.IsZero() {
+		// Enqueue another check when the approval should expire.  The decisions enqueue a check when they
+		// update the CustomRun.
+		return controller.NewRequeueAfter(next.Sub(c.Clock.Now()))
+	}
+
+	// Don't emit events on nop-reconciliations, it causes scale problems.
+	return nil
+}
+
+// setCompletionTime sets the completion time of a CustomRun which is done from the clock, instead of from when
+// its condition was set.
+func (c *Reconciler) setCompletionTime(r *v1beta1.CustomRun) {
+	now := metav1.NewTime(c.Clock.Now())
+	r.Status.CompletionTime = &now
+}
+
+// isApproval returns true if the CustomRun references the approval custom task.
+func isApproval(r *v1beta1.CustomRun) bool {
+	return r.Spec.CustomRef != nil && r.Spec.CustomRef.APIVersion == apiVersion && r.Spec.CustomRef.Kind == kind
+}
+
+// getApprovers returns the approvers of a CustomRun, which are passed as an array or a comma-separated string.
+func getApprovers(r *v1beta1.CustomRun) approvers {
+	p := r.Spec.GetParam("approvers")
+	if p == nil {
+		return nil
+	}
+	values := p.Value.ArrayVal
+	if p.Value.Type == v1beta1.ParamTypeString {
+		values = strings.Split(p.Value.StringVal, ",")
+	}
+	var a approvers
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			a = append(a, v)
+		}
+	}
+	return a
+}
+
+// getSpec parses the params of the CustomRun, or marks the CustomRun failed if they are invalid.
+func getSpec(r *v1beta1.CustomRun) (*spec, bool) {
+	var found []string
+	for _, p := range r.Spec.Params {
+		switch p.Name {
+		case "approvers", "approvalsRequired", "timeout":
+		default:
+			found = append(found, p.Name)
+		}
+	}
+	if len(found) > 0 {
+		r.Status.MarkCustomRunFailed("UnexpectedParams", "Found unexpected params: %v", found)
+		return nil, false
+	}
+
+	s := &spec{approvers: getApprovers(r), approvalsRequired: 1}
+	if len(s.approvers) == 0 {
+		r.Status.MarkCustomRunFailed("MissingApprovers", "The approvers param was not passed")
+		return nil, false
+	}
+	if p := r.Spec.GetParam("approvalsRequired"); p != nil {
+		n, err := strconv.Atoi(p.Value.StringVal)
+		if err != nil || n < 1 {
+			r.Status.MarkCustomRunFailed("InvalidApprovalsRequired", "The approvalsRequired param must be a positive number: %q", p.Value.StringVal)
+			return nil, false
+		}
+		if n > len(s.approvers) && !s.approvers.hasGroups() {
+			r.Status.MarkCustomRunFailed("InvalidApprovalsRequired", "The approvalsRequired param %d is more than the %d approvers", n, len(s.approvers))
+			return nil, false
+		}
+		s.approvalsRequired = n
+	}
+	if p := r.Spec.GetParam("timeout"); p != nil {
+		d, err := time.ParseDuration(p.Value.StringVal)
+		if err != nil || d <= 0 {
+			r.Status.MarkCustomRunFailed("InvalidTimeout", "The timeout param must be a positive duration: %q", p.Value.StringVal)
+			return nil, false
+		}
+		s.timeout = d
+	}
+	return s, true
+}
+
+// commentsResult returns the comments of the approvers as a JSON object, e.g. {"alice":"LGTM"}.
+func commentsResult(comments map[string]string) string {
+	b, _ := json.Marshal(comments)
+	return string(b)
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+)
+
+var (
+	key = []byte("key")
+	// now is the time of the fake clocks of the tests.
+	now      = time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	validRef = &v1beta1.TaskRef{
+		APIVersion: "example.dev/v0",
+		Kind:       "Approval",
+	}
+)
+
+func param(name string, value ...string) v1beta1.Param {
+	if len(value) == 1 {
+		return v1beta1.Param{Name: name, Value: *v1beta1.NewStructuredValues(value[0])}
+	}
+	return v1beta1.Param{Name: name, Value: *v1beta1.NewStructuredValues(value[0], value[1:]...)}
+}
+
+func newCustomRun(params ...v1beta1.Param) *v1beta1.CustomRun {
+	return &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "run",
+			Namespace: "ns",
+			UID:       "uid",
+		},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: validRef,
+			Params:    params,
+		},
+	}
+}
+
+func decide(t *testing.T, r *v1beta1.CustomRun, user string, approved bool, comment string) {
+	t.Helper()
+	if err := setDecision(key, r, Decision{User: user, Approved: approved, Comment: comment, Time: time.Now()}); err != nil {
+		t.Fatalf("setDecision() = %v", err)
+	}
+}
+
+func results(r *v1beta1.CustomRun) map[string]string {
+	m := map[string]string{}
+	for _, res := range r.Status.Results {
+		m[res.Name] = res.Value
+	}
+	return m
+}
+
+func TestReconcile_Approved(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := newCustomRun(param("approvers", "alice", "group:admins"), param("approvalsRequired", "2"))
+	rec := &Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}
+
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsUnknown() || c.Reason != "WaitingForApproval" {
+		t.Fatalf("CustomRun is not waiting for approval: %v", c)
+	}
+
+	decide(t, r, "alice", true, "LGTM")
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if r.IsDone() {
+		t.Fatalf("CustomRun is done after one of two approvals: %v", r.Status.GetCondition(apis.ConditionSucceeded))
+	}
+
+	// Approving twice doesn't count twice.
+	decide(t, r, "alice", true, "Still LGTM")
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if r.IsDone() {
+		t.Fatalf("CustomRun is done after the same approval twice: %v", r.Status.GetCondition(apis.ConditionSucceeded))
+	}
+
+	decide(t, r, "bob", true, "")
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsTrue() || c.Reason != "Approved" {
+		t.Fatalf("CustomRun was not approved: %v", c)
+	}
+	want := map[string]string{"approvedBy": "alice,bob", "comments": `{"alice":"Still LGTM"}`}
+	if got := results(r); got["approvedBy"] != want["approvedBy"] || got["comments"] != want["comments"] {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_Rejected(t *testing.T) {
+	t.Parallel()
+	r := newCustomRun(param("approvers", "alice,bob"), param("approvalsRequired", "2"))
+	decide(t, r, "alice", true, "")
+	decide(t, r, "bob", false, "Not today")
+
+	if err := (&Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}).ReconcileKind(context.Background(), r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsFalse() || c.Reason != "Rejected" {
+		t.Fatalf("CustomRun was not rejected: %v", c)
+	}
+	if got := results(r); got["rejectedBy"] != "bob" || got["comments"] != `{"bob":"Not today"}` {
+		t.Errorf("results = %v", got)
+	}
+}
+
+func TestReconcile_UnsignedDecision(t *testing.T) {
+	t.Parallel()
+	r := newCustomRun(param("approvers", "alice"))
+	// The decision is signed with another key, e.g. forged by someone allowed to update the CustomRun.
+	if err := setDecision([]byte("forged"), r, Decision{User: "alice", Approved: true, Time: time.Now()}); err != nil {
+		t.Fatalf("setDecision() = %v", err)
+	}
+
+	if err := (&Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}).ReconcileKind(context.Background(), r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if r.IsDone() {
+		t.Fatalf("CustomRun is done with an unsigned decision: %v", r.Status.GetCondition(apis.ConditionSucceeded))
+	}
+}
+
+func TestReconcile_Expired(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := newCustomRun(param("approvers", "alice"), param("timeout", "1h"))
+	clk := testclock.NewFakeClock(now)
+	rec := &Reconciler{Key: key, Clock: clk}
+
+	if err := rec.ReconcileKind(ctx, r); err == nil {
+		t.Fatal("wanted error, got nil")
+	} else if ok, dur := controller.IsRequeueKey(err); !ok || dur != time.Hour {
+		t.Fatalf("wanted requeue error in 1h, got %v", err)
+	} else {
+		// Simulate EnqueueAfter
+		clk.Step(dur)
+	}
+
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsFalse() || c.Reason != "ApprovalExpired" {
+		t.Fatalf("CustomRun did not expire: %v", c)
+	}
+}
+
+func TestReconcile_Cancelled(t *testing.T) {
+	t.Parallel()
+	r := newCustomRun(param("approvers", "alice"))
+	r.Spec.Status = v1beta1.CustomRunSpecStatusCancelled
+
+	if err := (&Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}).ReconcileKind(context.Background(), r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsFalse() || c.Reason != v1beta1.CustomRunReasonCancelled.String() {
+		t.Fatalf("CustomRun was not cancelled: %v", c)
+	}
+}
+
+func TestReconcile_Failure(t *testing.T) {
+	t.Parallel()
+	for _, c := range []struct {
+		desc   string
+		r      *v1beta1.CustomRun
+		reason string
+	}{{
+		desc:   "no approvers",
+		r:      newCustomRun(param("approvalsRequired", "1")),
+		reason: "MissingApprovers",
+	}, {
+		desc:   "extra params",
+		r:      newCustomRun(param("approvers", "alice"), param("extra", "blah")),
+		reason: "UnexpectedParams",
+	}, {
+		desc:   "invalid approvalsRequired",
+		r:      newCustomRun(param("approvers", "alice"), param("approvalsRequired", "0")),
+		reason: "InvalidApprovalsRequired",
+	}, {
+		desc:   "more approvalsRequired than approvers",
+		r:      newCustomRun(param("approvers", "alice,bob"), param("approvalsRequired", "3")),
+		reason: "InvalidApprovalsRequired",
+	}, {
+		desc:   "invalid timeout",
+		r:      newCustomRun(param("approvers", "alice"), param("timeout", "soon")),
+		reason: "InvalidTimeout",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := (&Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}).ReconcileKind(context.Background(), c.r); err != nil {
+				t.Fatalf("ReconcileKind() = %v", err)
+			}
+			if cond := c.r.Status.GetCondition(apis.ConditionSucceeded); !cond.IsFalse() || cond.Reason != c.reason {
+				t.Errorf("condition = %v, want reason %s", cond, c.reason)
+			}
+		})
+	}
+}
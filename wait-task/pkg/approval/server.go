@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+)
+
+// maxBodySize is the maximum size of the body of a request to the Server.
+const maxBodySize = 64 * 1024
+
+// Server is the HTTP endpoint the approvers approve or reject CustomRuns with:
+//
+//	POST /approve/<namespace>/<name>
+//	POST /reject/<namespace>/<name>
+//
+// The approvers authenticate with a bearer token, which is reviewed by the API server, and can pass a comment
+// in a JSON body, e.g. {"comment": "LGTM"}.
+type Server struct {
+	// Key signs the decisions of the approvers.
+	Key            []byte
+	KubeClient     kubernetes.Interface
+	PipelineClient versioned.Interface
+	// Clock timestamps the decisions, like the reconciler of the approvals.
+	Clock clock.PassiveClock
+}
+
+// request is the body of a request to the Server.
+type request struct {
+	Comment string `json:"comment"`
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 3 || (parts[0] != "approve" && parts[0] != "reject") || parts[1] == "" || parts[2] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	approved, namespace, name := parts[0] == "approve", parts[1], parts[2]
+	verb := "rejected"
+	if approved {
+		verb = "approved"
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+	review, err := s.KubeClient.AuthenticationV1().TokenReviews().Create(req.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("the token could not be reviewed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !review.Status.Authenticated {
+		http.Error(w, "the token is invalid", http.StatusUnauthorized)
+		return
+	}
+	user := review.Status.User
+
+	var body request
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxBodySize)).Decode(&body); err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	status, msg := http.StatusOK, ""
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		r, err := s.PipelineClient.TektonV1beta1().CustomRuns(namespace).Get(req.Context(), name, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err) || (err == nil && !isApproval(r)):
+			status, msg = http.StatusNotFound, fmt.Sprintf("approval %s/%s not found", namespace, name)
+			return nil
+		case err != nil:
+			return err
+		case r.IsDone():
+			status, msg = http.StatusConflict, fmt.Sprintf("approval %s/%s is already done", namespace, name)
+			return nil
+		case !getApprovers(r).allows(user.Username, user.Groups):
+			status, msg = http.StatusForbidden, fmt.Sprintf("%s is not an approver of %s/%s", user.Username, namespace, name)
+			return nil
+		}
+		if err := setDecision(s.Key, r, Decision{User: user.Username, Approved: approved, Comment: body.Comment, Time: s.Clock.Now()}); err != nil {
+			return err
+		}
+		_, err = s.PipelineClient.TektonV1beta1().CustomRuns(namespace).Update(req.Context(), r, metav1.UpdateOptions{})
+		status, msg = http.StatusOK, fmt.Sprintf("approval %s/%s %s by %s", namespace, name, verb, user.Username)
+		return err
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("the decision could not be recorded: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+	fmt.Fprintln(w, msg)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	pipelinefake "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/fake"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testclock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+)
+
+// users are the users of the tokens reviewed by the fake API server.
+var users = map[string]authenticationv1.UserInfo{
+	"alice-token": {Username: "alice"},
+	"bob-token":   {Username: "bob", Groups: []string{"admins"}},
+	"eve-token":   {Username: "eve"},
+}
+
+func newServer(runs ...runtime.Object) *Server {
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		user, ok := users[review.Spec.Token]
+		review.Status = authenticationv1.TokenReviewStatus{Authenticated: ok, User: user}
+		return true, review, nil
+	})
+	return &Server{
+		Key:            key,
+		KubeClient:     kubeClient,
+		PipelineClient: pipelinefake.NewSimpleClientset(runs...),
+		Clock:          testclock.NewFakeClock(now),
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	done := newCustomRun(param("approvers", "alice"))
+	done.Name = "done"
+	done.Status.MarkCustomRunSucceeded("Approved", "")
+	other := newCustomRun()
+	other.Name = "other"
+	other.Spec.CustomRef = &v1beta1.TaskRef{APIVersion: "example.dev/v0", Kind: "Wait"}
+
+	for _, c := range []struct {
+		desc, method, path, token, body string
+		want                            int
+	}{{
+		desc:   "approve",
+		method: http.MethodPost,
+		path:   "/approve/ns/run",
+		token:  "alice-token",
+		body:   `{"comment": "LGTM"}`,
+		want:   http.StatusOK,
+	}, {
+		desc:   "reject as a member of a group",
+		method: http.MethodPost,
+		path:   "/reject/ns/run",
+		token:  "bob-token",
+		want:   http.StatusOK,
+	}, {
+		desc:   "not an approver",
+		method: http.MethodPost,
+		path:   "/approve/ns/run",
+		token:  "eve-token",
+		want:   http.StatusForbidden,
+	}, {
+		desc:   "no token",
+		method: http.MethodPost,
+		path:   "/approve/ns/run",
+		want:   http.StatusUnauthorized,
+	}, {
+		desc:   "invalid token",
+		method: http.MethodPost,
+		path:   "/approve/ns/run",
+		token:  "mallory-token",
+		want:   http.StatusUnauthorized,
+	}, {
+		desc:   "GET",
+		method: http.MethodGet,
+		path:   "/approve/ns/run",
+		token:  "alice-token",
+		want:   http.StatusMethodNotAllowed,
+	}, {
+		desc:   "unknown action",
+		method: http.MethodPost,
+		path:   "/delete/ns/run",
+		token:  "alice-token",
+		want:   http.StatusNotFound,
+	}, {
+		desc:   "missing CustomRun",
+		method: http.MethodPost,
+		path:   "/approve/ns/missing",
+		token:  "alice-token",
+		want:   http.StatusNotFound,
+	}, {
+		desc:   "not an approval",
+		method: http.MethodPost,
+		path:   "/approve/ns/other",
+		token:  "alice-token",
+		want:   http.StatusNotFound,
+	}, {
+		desc:   "done",
+		method: http.MethodPost,
+		path:   "/approve/ns/done",
+		token:  "alice-token",
+		want:   http.StatusConflict,
+	}, {
+		desc:   "invalid body",
+		method: http.MethodPost,
+		path:   "/approve/ns/run",
+		token:  "alice-token",
+		body:   "LGTM",
+		want:   http.StatusBadRequest,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			s := newServer(newCustomRun(param("approvers", "alice", "group:admins")), done, other)
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if c.token != "" {
+				req.Header.Set("Authorization", "Bearer "+c.token)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != c.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, c.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestServer_RecordsDecision(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := newServer(newCustomRun(param("approvers", "alice")))
+
+	req := httptest.NewRequest(http.MethodPost, "/approve/ns/run", strings.NewReader(`{"comment": "LGTM"}`))
+	req.Header.Set("Authorization", "Bearer alice-token")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// The reconciler approves the updated CustomRun.
+	r, err := s.PipelineClient.TektonV1beta1().CustomRuns("ns").Get(ctx, "run", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	if decisions, err := getDecisions(key, r); err != nil || len(decisions) != 1 || !decisions[0].Time.Equal(now) {
+		t.Errorf("decisions = %v, %v, want one decision made at %s", decisions, err, now)
+	}
+	if err := (&Reconciler{Key: key, Clock: testclock.NewFakeClock(now)}).ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if c := r.Status.GetCondition(apis.ConditionSucceeded); !c.IsTrue() {
+		t.Fatalf("CustomRun was not approved: %v", c)
+	}
+	if got := results(r); got["approvedBy"] != "alice" || got["comments"] != `{"alice":"LGTM"}` {
+		t.Errorf("results = %v", got)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	kreconciler "knative.dev/pkg/reconciler"
+)
+
+// defaultPollInterval is how often the object is read when the pollInterval param isn't passed.
+const defaultPollInterval = 10 * time.Second
+
+// condition is what a CustomRun waits for with the untilCondition param.  It is either a condition of the object,
+// e.g. `Ready` or `Ready=False`, or a field of the object selected with JSONPath, e.g. `{.status.phase}=Succeeded`.
+type condition struct {
+	conditionType string
+	jsonPath      *jsonpath.JSONPath
+	value         string
+}
+
+// parseCondition parses the untilCondition param.  A condition without a status waits for the status `True`.
+func parseCondition(s string) (*condition, error) {
+	if strings.HasPrefix(s, "{") {
+		i := strings.LastIndex(s, "}=")
+		if i < 0 {
+			return nil, fmt.Errorf("%q must be a JSONPath followed by the expected value, e.g. {.status.phase}=Succeeded", s)
+		}
+		jp := jsonpath.New(untilConditionParam).AllowMissingKeys(true)
+		if err := jp.Parse(s[:i+1]); err != nil {
+			return nil, err
+		}
+		return &condition{jsonPath: jp, value: s[i+2:]}, nil
+	}
+	conditionType, status := s, "True"
+	if i := strings.Index(s, "="); i >= 0 {
+		conditionType, status = s[:i], s[i+1:]
+	}
+	if conditionType == "" {
+		return nil, fmt.Errorf("%q must be a condition type, e.g. Ready or Ready=False", s)
+	}
+	return &condition{conditionType: conditionType, value: status}, nil
+}
+
+// isMet returns true if the object meets the condition.
+func (c *condition) isMet(obj *unstructured.Unstructured) (bool, error) {
+	if c.jsonPath != nil {
+		var buf bytes.Buffer
+		if err := c.jsonPath.Execute(&buf, obj.Object); err != nil {
+			return false, err
+		}
+		return buf.String() == c.value, nil
+	}
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, cond := range conditions {
+		if m, ok := cond.(map[string]interface{}); ok && m["type"] == c.conditionType {
+			return m["status"] == c.value, nil
+		}
+	}
+	return false, nil
+}
+
+// reconcileCondition reads the object of a CustomRun waiting with the untilCondition param and marks the CustomRun
+// succeeded when the object meets the condition, or failed if the timeout elapses first.  Until then, the object is
+// read again every poll interval.  An object which doesn't exist yet doesn't meet the condition.
+func (c *Reconciler) reconcileCondition(ctx context.Context, r *v1beta1.CustomRun) kreconciler.Event {
+	logger := logging.FromContext(ctx)
+
+	cond, err := parseCondition(r.Spec.GetParam(untilConditionParam).Value.StringVal)
+	if err != nil {
+		r.Status.MarkCustomRunFailed("InvalidUntilCondition", "The untilCondition param was invalid: %v", err)
+		return nil
+	}
+	pollInterval, ok := getDurationParam(r, "pollInterval", defaultPollInterval)
+	if !ok {
+		return nil
+	}
+	timeout, ok := getDurationParam(r, "timeout", 0)
+	if !ok {
+		return nil
+	}
+
+	var missing []string
+	for _, name := range []string{"apiVersion", "kind", "name"} {
+		if p := r.Spec.GetParam(name); p == nil || p.Value.StringVal == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		r.Status.MarkCustomRunFailed("MissingObject", "The untilCondition param requires the params: %v", missing)
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(r.Spec.GetParam("apiVersion").Value.StringVal)
+	if err != nil {
+		r.Status.MarkCustomRunFailed("InvalidObject", "The apiVersion param was invalid: %v", err)
+		return nil
+	}
+	gvk := gv.WithKind(r.Spec.GetParam("kind").Value.StringVal)
+	name := r.Spec.GetParam("name").Value.StringVal
+	namespace := r.Namespace
+	if p := r.Spec.GetParam("namespace"); p != nil && p.Value.StringVal != "" {
+		namespace = p.Value.StringVal
+	}
+
+	mapping, err := c.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		r.Status.MarkCustomRunFailed("InvalidObject", "The kind %s could not be found: %v", gvk, err)
+		return nil
+	}
+	var resource dynamic.ResourceInterface = c.DynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		// Keep a CustomRun from reading the objects of the namespaces the administrator didn't allow.
+		if namespace != r.Namespace && !c.ConditionNamespaces.Has(namespace) {
+			r.Status.MarkCustomRunFailed("NamespaceNotAllowed", "The objects of namespace %s can't be waited for from namespace %s", namespace, r.Namespace)
+			return nil
+		}
+		resource = c.DynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	} else if !c.ConditionClusterKinds.Has(gvk.GroupKind().String()) {
+		// Keep a CustomRun from probing the fields of the cluster-scoped objects the administrator didn't allow.
+		r.Status.MarkCustomRunFailed("KindNotAllowed", "The cluster-scoped objects of kind %s can't be waited for", gvk.GroupKind())
+		return nil
+	}
+	obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+
+	met := false
+	switch {
+	case errors.IsNotFound(err):
+		logger.Infof("%s %s/%s of CustomRun %s/%s doesn't exist yet", gvk.Kind, namespace, name, r.Namespace, r.Name)
+	case err != nil:
+		// Let the reconciler retry with backoff, e.g. if the controller isn't allowed to read the object yet.
+		return fmt.Errorf("failed to get %s %s/%s: %w", gvk.Kind, namespace, name, err)
+	default:
+		if met, err = cond.isMet(obj); err != nil {
+			r.Status.MarkCustomRunFailed("InvalidUntilCondition", "The untilCondition param could not be evaluated: %v", err)
+			return nil
+		}
+	}
+
+	if met {
+		r.Status.MarkCustomRunSucceeded("ConditionMet", "%s %s met the condition %s", gvk.Kind, name, r.Spec.GetParam(untilConditionParam).Value.StringVal)
+		c.setCompletionTime(r)
+		return nil
+	}
+
+	next := c.Clock.Now().Add(pollInterval)
+	if timeout > 0 {
+		expiry := r.Status.StartTime.Time.Add(timeout)
+		if !c.Clock.Now().Before(expiry) {
+			r.Status.MarkCustomRunFailed("ConditionTimedOut", "%s %s did not meet the condition %s within %s", gvk.Kind, name, r.Spec.GetParam(untilConditionParam).Value.StringVal, timeout)
+			c.setCompletionTime(r)
+			return nil
+		}
+		if expiry.Before(next) {
+			next = expiry
+		}
+	}
+	// Enqueue another check after the poll interval.
+	return controller.NewRequeueAfter(c.requeueAfter(r, next))
+}
+
+// getDurationParam returns the value of an optional duration param, or marks the CustomRun failed if it's invalid.
+func getDurationParam(r *v1beta1.CustomRun, name string, defaultValue time.Duration) (time.Duration, bool) {
+	p := r.Spec.GetParam(name)
+	if p == nil || p.Value.StringVal == "" {
+		return defaultValue, true
+	}
+	d, err := time.ParseDuration(p.Value.StringVal)
+	if err != nil || d <= 0 {
+		if err == nil {
+			err = fmt.Errorf("%s must be positive", d)
+		}
+		r.Status.MarkCustomRunFailed("Invalid"+strings.ToUpper(name[:1])+name[1:], "The %s param was invalid: %v", name, err)
+		return 0, false
+	}
+	return d, true
+}
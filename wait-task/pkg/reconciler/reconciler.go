@@ -18,78 +18,265 @@ package reconciler
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"github.com/robfig/cron/v3"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	kreconciler "knative.dev/pkg/reconciler"
 )
 
-type Reconciler struct{}
+// The params selecting what the CustomRun waits for.  Exactly one of them must be passed.
+const (
+	durationParam       = "duration"
+	untilParam          = "until"
+	nextCronParam       = "nextCron"
+	untilConditionParam = "untilCondition"
+)
+
+// modeParams are the params selecting what the CustomRun waits for, and the optional params of each of them.
+var modeParams = map[string][]string{
+	durationParam:       nil,
+	untilParam:          nil,
+	nextCronParam:       {"timezone"},
+	untilConditionParam: {"apiVersion", "kind", "name", "namespace", "pollInterval", "timeout"},
+}
+
+type Reconciler struct {
+	// DynamicClient and RESTMapper read the objects whose condition is waited for with untilCondition.
+	DynamicClient dynamic.Interface
+	RESTMapper    meta.RESTMapper
+	// ConditionNamespaces are the namespaces, besides its own, whose objects a CustomRun can wait for with
+	// untilCondition.
+	ConditionNamespaces sets.String
+	// ConditionClusterKinds are the cluster-scoped kinds, as kind.group e.g. Node or StorageClass.storage.k8s.io,
+	// whose objects a CustomRun can wait for with untilCondition.
+	ConditionClusterKinds sets.String
+	Clock                 clock.PassiveClock
+}
 
 // ReconcileKind implements Interface.ReconcileKind.
-func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1alpha1.Run) kreconciler.Event {
+func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1beta1.CustomRun) kreconciler.Event {
 	logger := logging.FromContext(ctx)
 	logger.Infof("Reconciling %s/%s", r.Namespace, r.Name)
 
 	// Ignore completed waits.
 	if r.IsDone() {
-		logger.Info("Run is finished, done reconciling")
+		logger.Info("CustomRun is finished, done reconciling")
 		return nil
 	}
 
-	if r.Spec.Ref == nil ||
-		r.Spec.Ref.APIVersion != "example.dev/v0" || r.Spec.Ref.Kind != "Wait" {
-		// This is not a Run we should have been notified about; do nothing.
+	if r.Spec.CustomRef == nil ||
+		r.Spec.CustomRef.APIVersion != "example.dev/v0" || r.Spec.CustomRef.Kind != "Wait" {
+		if r.Spec.CustomSpec != nil && r.Spec.CustomSpec.APIVersion == "example.dev/v0" && r.Spec.CustomSpec.Kind == "Wait" {
+			r.Status.MarkCustomRunFailed("UnexpectedSpec", "Wait doesn't take a spec, its params must be passed instead")
+			return nil
+		}
+		// This is not a CustomRun we should have been notified about; do nothing.
 		return nil
 	}
-	if r.Spec.Ref.Name != "" {
-		r.Status.MarkRunFailed("UnexpectedName", "Found unexpected ref name: %s", r.Spec.Ref.Name)
+	if r.Spec.CustomRef.Name != "" {
+		r.Status.MarkCustomRunFailed("UnexpectedName", "Found unexpected ref name: %s", r.Spec.CustomRef.Name)
 		return nil
 	}
 
-	expr := r.Spec.GetParam("duration")
-	if expr == nil || expr.Value.StringVal == "" {
-		r.Status.MarkRunFailed("MissingDuration", "The duration param was not passed")
+	if r.Status.StartTime == nil {
+		now := metav1.NewTime(c.Clock.Now())
+		r.Status.StartTime = &now
+	}
+
+	// Stop waiting as soon as the CustomRun is cancelled, e.g. with its PipelineRun.
+	if r.IsCancelled() {
+		c.markCancelled(r)
 		return nil
 	}
-	if len(r.Spec.Params) != 1 {
-		var found []string
-		for _, p := range r.Spec.Params {
-			if p.Name == "duration" {
-				continue
-			}
-			found = append(found, p.Name)
+
+	for _, p := range r.Spec.Params {
+		if p.Value.Type != v1beta1.ParamTypeString {
+			r.Status.MarkCustomRunFailed("InvalidParam", "The %s param must be a string", p.Name)
+			return nil
 		}
-		r.Status.MarkRunFailed("UnexpectedParams", "Found unexpected params: %v", found)
+	}
+	mode, ok := getMode(r)
+	if !ok {
 		return nil
 	}
 
-	dur, err := time.ParseDuration(expr.Value.StringVal)
-	if err != nil {
-		r.Status.MarkRunFailed("InvalidDuration", "The duration param was invalid: %v", err)
+	if c.hasTimedOut(r) {
+		c.markTimedOut(r)
 		return nil
 	}
+	if r.Status.GetCondition(apis.ConditionSucceeded) == nil {
+		r.Status.MarkCustomRunRunning("Waiting", waitingMessage(mode, r))
+	}
 
-	if r.Status.StartTime == nil {
-		now := metav1.Now()
-		r.Status.StartTime = &now
-		r.Status.MarkRunRunning("Waiting", "Waiting for duration to elapse")
+	if mode == untilConditionParam {
+		return c.reconcileCondition(ctx, r)
 	}
 
-	done := r.Status.StartTime.Time.Add(dur)
+	done, reason, message, ok := getDeadline(mode, r)
+	if !ok {
+		return nil
+	}
 
-	if time.Now().After(done) {
-		now := metav1.Now()
-		r.Status.CompletionTime = &now
-		r.Status.MarkRunSucceeded("DurationElapsed", "The wait duration has elapsed")
+	if !c.Clock.Now().Before(done) {
+		r.Status.MarkCustomRunSucceeded(reason, message)
+		c.setCompletionTime(r)
 	} else {
-		// Enqueue another check when the timeout should be elapsed.
-		return controller.NewRequeueAfter(time.Until(r.Status.StartTime.Time.Add(dur)))
+		// Enqueue another check when the wait should be elapsed, or when the CustomRun times out if that is sooner.
+		return controller.NewRequeueAfter(c.requeueAfter(r, done))
 	}
 
 	// Don't emit events on nop-reconciliations, it causes scale problems.
 	return nil
 }
+
+// markCancelled marks the CustomRun failed because it was cancelled, with the message of the cancellation if any.
+func (c *Reconciler) markCancelled(r *v1beta1.CustomRun) {
+	message := "The wait was cancelled"
+	if r.Spec.StatusMessage != "" {
+		message = string(r.Spec.StatusMessage)
+	}
+	r.Status.MarkCustomRunFailed(v1beta1.CustomRunReasonCancelled.String(), message)
+	c.setCompletionTime(r)
+}
+
+// setCompletionTime sets the completion time of a CustomRun which is done from the clock, instead of from when
+// its condition was set.
+func (c *Reconciler) setCompletionTime(r *v1beta1.CustomRun) {
+	now := metav1.NewTime(c.Clock.Now())
+	r.Status.CompletionTime = &now
+}
+
+// timeoutDeadline returns when the CustomRun times out.  A CustomRun without a timeout, e.g. one created outside
+// of a PipelineRun, doesn't time out.
+func timeoutDeadline(r *v1beta1.CustomRun) (time.Time, bool) {
+	if r.Spec.Timeout == nil || r.Spec.Timeout.Duration <= 0 {
+		return time.Time{}, false
+	}
+	return r.Status.StartTime.Time.Add(r.Spec.Timeout.Duration), true
+}
+
+// hasTimedOut returns true if the timeout of the CustomRun has elapsed.
+func (c *Reconciler) hasTimedOut(r *v1beta1.CustomRun) bool {
+	deadline, ok := timeoutDeadline(r)
+	return ok && !c.Clock.Now().Before(deadline)
+}
+
+// markTimedOut marks the CustomRun failed because its timeout elapsed.
+func (c *Reconciler) markTimedOut(r *v1beta1.CustomRun) {
+	r.Status.MarkCustomRunFailed(v1beta1.CustomRunReasonTimedOut.String(), "The wait timed out after %s", r.Spec.Timeout.Duration)
+	c.setCompletionTime(r)
+}
+
+// requeueAfter returns how long until the next check of the CustomRun, which is at the given time or when the
+// CustomRun times out if that is sooner.
+func (c *Reconciler) requeueAfter(r *v1beta1.CustomRun, next time.Time) time.Duration {
+	if deadline, ok := timeoutDeadline(r); ok && deadline.Before(next) {
+		next = deadline
+	}
+	return next.Sub(c.Clock.Now())
+}
+
+// getMode returns the param selecting what the CustomRun waits for, or marks the CustomRun failed if there isn't
+// exactly one of them or if there are params that the mode doesn't support.
+func getMode(r *v1beta1.CustomRun) (string, bool) {
+	var modes []string
+	for _, p := range r.Spec.Params {
+		if _, ok := modeParams[p.Name]; ok {
+			modes = append(modes, p.Name)
+		}
+	}
+	switch len(modes) {
+	case 0:
+		r.Status.MarkCustomRunFailed("MissingParam", "One of the duration, until, nextCron or untilCondition params must be passed")
+		return "", false
+	case 1:
+	default:
+		r.Status.MarkCustomRunFailed("ConflictingParams", "Only one of the duration, until, nextCron or untilCondition params can be passed, found: %v", modes)
+		return "", false
+	}
+
+	mode := modes[0]
+	allowed := map[string]bool{mode: true}
+	for _, name := range modeParams[mode] {
+		allowed[name] = true
+	}
+	var found []string
+	for _, p := range r.Spec.Params {
+		if !allowed[p.Name] {
+			found = append(found, p.Name)
+		}
+	}
+	if len(found) > 0 {
+		sort.Strings(found)
+		r.Status.MarkCustomRunFailed("UnexpectedParams", "Found unexpected params: %v", found)
+		return "", false
+	}
+	if expr := r.Spec.GetParam(mode); expr.Value.StringVal == "" {
+		r.Status.MarkCustomRunFailed("Missing"+strings.ToUpper(mode[:1])+mode[1:], "The %s param was not passed", mode)
+		return "", false
+	}
+	return mode, true
+}
+
+// getDeadline returns when a CustomRun waiting for a duration, a time or a cron slot is done, and the reason and
+// message of its success, or marks the CustomRun failed if its params are invalid.  The next cron slot is computed
+// from the start time of the CustomRun, so that it doesn't move on later reconciles.
+func getDeadline(mode string, r *v1beta1.CustomRun) (time.Time, string, string, bool) {
+	value := r.Spec.GetParam(mode).Value.StringVal
+	switch mode {
+	case untilParam:
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			r.Status.MarkCustomRunFailed("InvalidUntil", "The until param was invalid: %v", err)
+			return time.Time{}, "", "", false
+		}
+		return until, "TimeReached", "The wait time has been reached", true
+	case nextCronParam:
+		schedule, err := cron.ParseStandard(value)
+		if err != nil {
+			r.Status.MarkCustomRunFailed("InvalidNextCron", "The nextCron param was invalid: %v", err)
+			return time.Time{}, "", "", false
+		}
+		location := time.UTC
+		if tz := r.Spec.GetParam("timezone"); tz != nil {
+			if location, err = time.LoadLocation(tz.Value.StringVal); err != nil {
+				r.Status.MarkCustomRunFailed("InvalidTimezone", "The timezone param was invalid: %v", err)
+				return time.Time{}, "", "", false
+			}
+		}
+		return schedule.Next(r.Status.StartTime.Time.In(location)), "CronSlotReached", "The next cron slot has been reached", true
+	default:
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			r.Status.MarkCustomRunFailed("InvalidDuration", "The duration param was invalid: %v", err)
+			return time.Time{}, "", "", false
+		}
+		return r.Status.StartTime.Time.Add(dur), "DurationElapsed", "The wait duration has elapsed", true
+	}
+}
+
+// waitingMessage describes what a running CustomRun waits for.
+func waitingMessage(mode string, r *v1beta1.CustomRun) string {
+	value := r.Spec.GetParam(mode).Value.StringVal
+	switch mode {
+	case untilParam:
+		return "Waiting until " + value
+	case nextCronParam:
+		return "Waiting for the next slot of " + value
+	case untilConditionParam:
+		return "Waiting until " + value
+	default:
+		return "Waiting for duration to elapse"
+	}
+}
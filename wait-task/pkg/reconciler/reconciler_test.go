@@ -21,145 +21,511 @@ import (
 	"testing"
 	"time"
 
-	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	testclock "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
 )
 
-var validRef = &v1alpha1.TaskRef{
+var validRef = &v1beta1.TaskRef{
 	APIVersion: "example.dev/v0",
 	Kind:       "Wait",
 }
 
+// now is the time of the fake clocks of the tests.
+var now = time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+
 func TestReconcile(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	r := &v1alpha1.Run{
+	r := &v1beta1.CustomRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "run",
 		},
-		Spec: v1alpha1.RunSpec{
-			Ref: validRef,
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: validRef,
 			Params: []v1beta1.Param{{
 				Name:  "duration",
-				Value: *v1beta1.NewArrayOrString("1s"),
+				Value: *v1beta1.NewStructuredValues("1s"),
 			}},
 		},
 	}
-	rec := &Reconciler{}
+	clk := testclock.NewFakeClock(now)
+	rec := &Reconciler{Clock: clk}
 
-	// Start reconciling the Run.
+	// Start reconciling the CustomRun.
 	if err := rec.ReconcileKind(ctx, r); err == nil {
 		t.Fatal("wanted error, got nil")
 	} else if ok, dur := controller.IsRequeueKey(err); !ok {
 		t.Fatalf("wanted requeue error, got %v", err)
+	} else if dur != time.Second {
+		t.Fatalf("wanted a requeue after 1s, got %s", dur)
 	} else {
 		// Simulate EnqueueAfter
-		time.Sleep(dur)
+		clk.Step(dur)
 	}
 
-	// Reconcile again after sleeping.
+	// Reconcile again after the requeue.
 	if err := rec.ReconcileKind(ctx, r); err != nil {
 		t.Fatalf("ReconcileKind() = %v", err)
 	}
 
 	// At this point it should have been successful.
 	if !r.IsSuccessful() {
-		t.Errorf("Run was not successful after second reconcile: %v", r.Status.GetCondition(apis.ConditionSucceeded).Status)
+		t.Errorf("CustomRun was not successful after second reconcile: %v", r.Status.GetCondition(apis.ConditionSucceeded).Status)
+	}
+
+	// Reconciling into the final completed state should take exactly the duration.
+	if dur := r.Status.CompletionTime.Time.Sub(r.Status.StartTime.Time); dur != time.Second {
+		t.Fatalf("completion_time - start_time != 1s: %s", dur)
 	}
+}
 
-	// Reconciling into the final completed state should take <2s.
-	dur := r.Status.CompletionTime.Time.Sub(r.Status.StartTime.Time)
-	if dur > 2*time.Second {
-		t.Fatalf("completion_time - start_time > 2s: %s", dur)
+func TestReconcile_Cancelled(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{
+		CustomRef: validRef,
+		Params:    []v1beta1.Param{param("duration", "1h")},
+	}}
+	clk := testclock.NewFakeClock(now)
+	rec := &Reconciler{Clock: clk}
+
+	if err := rec.ReconcileKind(ctx, r); err == nil {
+		t.Fatal("wanted requeue error, got nil")
 	}
 
+	// Cancel the CustomRun long before the duration elapses, as its PipelineRun does.
+	clk.Step(time.Minute)
+	r.Spec.Status = v1beta1.CustomRunSpecStatusCancelled
+	r.Spec.StatusMessage = v1beta1.CustomRunCancelledByPipelineMsg
+	if err := rec.ReconcileKind(ctx, r); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	c := r.Status.GetCondition(apis.ConditionSucceeded)
+	if !c.IsFalse() || c.Reason != v1beta1.CustomRunReasonCancelled.String() {
+		t.Fatalf("CustomRun was not cancelled: %v", c)
+	}
+	if c.Message != string(v1beta1.CustomRunCancelledByPipelineMsg) {
+		t.Errorf("wanted the cancellation message, got %q", c.Message)
+	}
+	if !r.Status.CompletionTime.Time.Equal(now.Add(time.Minute)) {
+		t.Errorf("completion_time = %s, want %s", r.Status.CompletionTime.Time, now.Add(time.Minute))
+	}
+}
+
+func TestReconcile_Timeout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	for _, c := range []struct {
+		desc   string
+		params []v1beta1.Param
+	}{{
+		desc:   "duration",
+		params: []v1beta1.Param{param("duration", "1h")},
+	}, {
+		desc: "untilCondition",
+		params: []v1beta1.Param{param("untilCondition", "Ready"), param("pollInterval", "1h"),
+			param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod")},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper(nil)
+			mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+			clk := testclock.NewFakeClock(now)
+			rec := &Reconciler{
+				DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+				RESTMapper:    mapper,
+				Clock:         clk,
+			}
+			r := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    c.params,
+				Timeout:   &metav1.Duration{Duration: 10 * time.Minute},
+			}}
+
+			// The CustomRun is checked again when it times out, before it is done waiting.
+			err := rec.ReconcileKind(ctx, r)
+			if ok, dur := controller.IsRequeueKey(err); !ok {
+				t.Fatalf("wanted requeue error, got %v", err)
+			} else if dur != 10*time.Minute {
+				t.Fatalf("wanted a requeue after 10m, got %s", dur)
+			} else {
+				clk.Step(dur)
+			}
+
+			if err := rec.ReconcileKind(ctx, r); err != nil {
+				t.Fatalf("ReconcileKind() = %v", err)
+			}
+			if cond := r.Status.GetCondition(apis.ConditionSucceeded); !cond.IsFalse() || cond.Reason != v1beta1.CustomRunReasonTimedOut.String() {
+				t.Fatalf("CustomRun did not time out: %v", cond)
+			}
+		})
+	}
 }
 
 func TestReconcile_Failure(t *testing.T) {
 	t.Parallel()
 	for _, c := range []struct {
 		desc string
-		r    *v1alpha1.Run
+		r    *v1beta1.CustomRun
 	}{{
 		desc: "unexpected ref name",
-		r: &v1alpha1.Run{
-			Spec: v1alpha1.RunSpec{
-				Ref: &v1alpha1.TaskRef{
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: &v1beta1.TaskRef{
 					APIVersion: "example.dev/v0",
 					Kind:       "Wait",
 					Name:       "wtf",
 				},
 				Params: []v1beta1.Param{{
 					Name:  "duration",
-					Value: *v1beta1.NewArrayOrString("1h"),
+					Value: *v1beta1.NewStructuredValues("1h"),
 				}},
 			},
 		},
 	}, {
 		desc: "no duration param",
-		r: &v1alpha1.Run{
-			Spec: v1alpha1.RunSpec{
-				Ref: validRef,
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
 				Params: []v1beta1.Param{{
 					Name:  "not-duration",
-					Value: *v1beta1.NewArrayOrString("blah"),
+					Value: *v1beta1.NewStructuredValues("blah"),
 				}},
 			},
 		},
 	}, {
 		desc: "extra params",
-		r: &v1alpha1.Run{
-			Spec: v1alpha1.RunSpec{
-				Ref: validRef,
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
 				Params: []v1beta1.Param{{
 					Name:  "not-duration",
-					Value: *v1beta1.NewArrayOrString("blah"),
+					Value: *v1beta1.NewStructuredValues("blah"),
 				}, {
 					Name:  "duration",
-					Value: *v1beta1.NewArrayOrString("1h"),
+					Value: *v1beta1.NewStructuredValues("1h"),
 				}},
 			},
 		},
 	}, {
 		desc: "duration param not a string",
-		r: &v1alpha1.Run{
-			Spec: v1alpha1.RunSpec{
-				Ref: validRef,
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
 				Params: []v1beta1.Param{{
 					Name:  "duration",
-					Value: *v1beta1.NewArrayOrString("blah", "blah", "blah"),
+					Value: *v1beta1.NewStructuredValues("blah", "blah", "blah"),
 				}},
 			},
 		},
+	}, {
+		desc: "conflicting params",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("duration", "1h"), param("until", "2021-01-01T00:00:00Z")},
+			},
+		},
+	}, {
+		desc: "param of another mode",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("duration", "1h"), param("timezone", "UTC")},
+			},
+		},
+	}, {
+		desc: "invalid until value",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("until", "tomorrow")},
+			},
+		},
+	}, {
+		desc: "invalid nextCron value",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("nextCron", "every day")},
+			},
+		},
+	}, {
+		desc: "invalid timezone value",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("nextCron", "0 12 * * *"), param("timezone", "Mars/Olympus_Mons")},
+			},
+		},
+	}, {
+		desc: "invalid untilCondition value",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params: []v1beta1.Param{param("untilCondition", "{.status.phase"),
+					param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod")},
+			},
+		},
+	}, {
+		desc: "untilCondition without an object",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params:    []v1beta1.Param{param("untilCondition", "Ready")},
+			},
+		},
+	}, {
+		desc: "invalid pollInterval value",
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
+				Params: []v1beta1.Param{param("untilCondition", "Ready"), param("pollInterval", "-1s"),
+					param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod")},
+			},
+		},
 	}, {
 		desc: "invalid duration value",
-		r: &v1alpha1.Run{
-			Spec: v1alpha1.RunSpec{
-				Ref: validRef,
+		r: &v1beta1.CustomRun{
+			Spec: v1beta1.CustomRunSpec{
+				CustomRef: validRef,
 				Params: []v1beta1.Param{{
 					Name:  "duration",
-					Value: *v1beta1.NewArrayOrString("blah"),
+					Value: *v1beta1.NewStructuredValues("blah"),
 				}},
 			},
 		},
 	}} {
 		t.Run(c.desc, func(t *testing.T) {
 			ctx := context.Background()
-			rec := &Reconciler{}
+			rec := &Reconciler{Clock: testclock.NewFakeClock(now)}
 
-			// Start reconciling the Run.
-			// This will not return until the second Reconcile is done.
 			if err := rec.ReconcileKind(ctx, c.r); err != nil {
 				t.Fatalf("ReconcileKind() = %v", err)
 			}
 
 			if !c.r.IsDone() {
-				t.Fatal("Run was not done")
+				t.Fatal("CustomRun was not done")
 			} else if c.r.IsSuccessful() {
-				t.Fatal("Run was successful")
+				t.Fatal("CustomRun was successful")
+			}
+		})
+	}
+}
+
+func param(name, value string) v1beta1.Param {
+	return v1beta1.Param{Name: name, Value: *v1beta1.NewStructuredValues(value)}
+}
+
+func TestReconcile_Until(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rec := &Reconciler{Clock: testclock.NewFakeClock(now)}
+
+	past := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{
+		CustomRef: validRef,
+		Params:    []v1beta1.Param{param("until", "2021-01-01T00:00:00Z")},
+	}}
+	if err := rec.ReconcileKind(ctx, past); err != nil {
+		t.Fatalf("ReconcileKind() = %v", err)
+	}
+	if !past.IsSuccessful() || past.Status.GetCondition(apis.ConditionSucceeded).Reason != "TimeReached" {
+		t.Errorf("CustomRun waiting until a past time was not successful: %v", past.Status.GetCondition(apis.ConditionSucceeded))
+	}
+
+	until := now.Add(time.Hour).Format(time.RFC3339)
+	future := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{
+		CustomRef: validRef,
+		Params:    []v1beta1.Param{param("until", until)},
+	}}
+	err := rec.ReconcileKind(ctx, future)
+	if ok, dur := controller.IsRequeueKey(err); !ok {
+		t.Fatalf("wanted requeue error, got %v", err)
+	} else if dur != time.Hour {
+		t.Errorf("wanted a requeue in an hour, got %s", dur)
+	}
+	if future.IsDone() {
+		t.Error("CustomRun waiting until a future time was done")
+	}
+}
+
+func TestGetDeadline_NextCron(t *testing.T) {
+	t.Parallel()
+	start := metav1.NewTime(time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC))
+	for _, c := range []struct {
+		desc   string
+		params []v1beta1.Param
+		want   time.Time
+	}{{
+		desc:   "UTC by default",
+		params: []v1beta1.Param{param("nextCron", "0 12 * * *")},
+		want:   time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+	}, {
+		desc:   "in a timezone",
+		params: []v1beta1.Param{param("nextCron", "0 12 * * *"), param("timezone", "Europe/Paris")},
+		want:   time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC),
+	}, {
+		desc:   "on the next weekday",
+		params: []v1beta1.Param{param("nextCron", "0 9 * * MON-FRI")},
+		want:   time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			r := &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{CustomRef: validRef, Params: c.params}}
+			r.Status.StartTime = &start
+			got, reason, _, ok := getDeadline("nextCron", r)
+			if !ok {
+				t.Fatalf("getDeadline() failed: %v", r.Status.GetCondition(apis.ConditionSucceeded))
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("getDeadline() = %s, want %s", got, c.want)
+			}
+			if reason != "CronSlotReached" {
+				t.Errorf("getDeadline() reason = %s", reason)
+			}
+		})
+	}
+}
+
+func TestReconcile_UntilCondition(t *testing.T) {
+	t.Parallel()
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	namespaceGVK := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(namespaceGVK, meta.RESTScopeRoot)
+
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod", "namespace": "ns"},
+		"status": map[string]interface{}{
+			"phase": "Running",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+				map[string]interface{}{"type": "Initialized", "status": "False"},
+			},
+		},
+	}}
+	namespace := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": "ns"},
+		"status":     map[string]interface{}{"phase": "Active"},
+	}}
+	podParams := func(params ...v1beta1.Param) []v1beta1.Param {
+		return append(params, param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod"))
+	}
+
+	for _, c := range []struct {
+		desc         string
+		params       []v1beta1.Param
+		namespaces   []string
+		clusterKinds []string
+		startedAgo   time.Duration
+		wantReason   string
+		wantRequeue  time.Duration
+	}{{
+		desc:       "condition is met",
+		params:     podParams(param("untilCondition", "Ready")),
+		wantReason: "ConditionMet",
+	}, {
+		desc:       "condition with a status is met",
+		params:     podParams(param("untilCondition", "Initialized=False")),
+		wantReason: "ConditionMet",
+	}, {
+		desc:        "condition is not met",
+		params:      podParams(param("untilCondition", "Initialized"), param("pollInterval", "30s")),
+		wantReason:  "Waiting",
+		wantRequeue: 30 * time.Second,
+	}, {
+		desc:       "field is met",
+		params:     podParams(param("untilCondition", "{.status.phase}=Running")),
+		wantReason: "ConditionMet",
+	}, {
+		desc:        "field is not met",
+		params:      podParams(param("untilCondition", "{.status.phase}=Succeeded")),
+		wantReason:  "Waiting",
+		wantRequeue: defaultPollInterval,
+	}, {
+		desc: "object in another namespace doesn't exist yet",
+		params: []v1beta1.Param{param("untilCondition", "Ready"),
+			param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod"), param("namespace", "other")},
+		namespaces:  []string{"other"},
+		wantReason:  "Waiting",
+		wantRequeue: defaultPollInterval,
+	}, {
+		desc: "object in a namespace that is not allowed",
+		params: []v1beta1.Param{param("untilCondition", "Ready"),
+			param("apiVersion", "v1"), param("kind", "Pod"), param("name", "pod"), param("namespace", "other")},
+		wantReason: "NamespaceNotAllowed",
+	}, {
+		desc: "cluster-scoped object",
+		params: []v1beta1.Param{param("untilCondition", "{.status.phase}=Active"),
+			param("apiVersion", "v1"), param("kind", "Namespace"), param("name", "ns")},
+		clusterKinds: []string{"Namespace"},
+		wantReason:   "ConditionMet",
+	}, {
+		desc: "cluster-scoped object of a kind that is not allowed",
+		params: []v1beta1.Param{param("untilCondition", "{.status.phase}=Active"),
+			param("apiVersion", "v1"), param("kind", "Namespace"), param("name", "ns")},
+		clusterKinds: []string{"Node"},
+		wantReason:   "KindNotAllowed",
+	}, {
+		desc:         "namespaced kind isn't restricted by the allowed cluster-scoped kinds",
+		params:       podParams(param("untilCondition", "Ready")),
+		clusterKinds: []string{"Namespace"},
+		wantReason:   "ConditionMet",
+	}, {
+		desc:        "poll until the timeout",
+		params:      podParams(param("untilCondition", "Initialized"), param("timeout", "1m")),
+		startedAgo:  55 * time.Second,
+		wantReason:  "Waiting",
+		wantRequeue: 5 * time.Second,
+	}, {
+		desc:       "timeout elapsed",
+		params:     podParams(param("untilCondition", "Initialized"), param("timeout", "1m")),
+		startedAgo: 2 * time.Minute,
+		wantReason: "ConditionTimedOut",
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			ctx := context.Background()
+			rec := &Reconciler{
+				DynamicClient:         dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), pod.DeepCopy(), namespace.DeepCopy()),
+				RESTMapper:            mapper,
+				ConditionNamespaces:   sets.NewString(c.namespaces...),
+				ConditionClusterKinds: sets.NewString(c.clusterKinds...),
+				Clock:                 testclock.NewFakeClock(now),
+			}
+			r := &v1beta1.CustomRun{
+				ObjectMeta: metav1.ObjectMeta{Name: "run", Namespace: "ns"},
+				Spec:       v1beta1.CustomRunSpec{CustomRef: validRef, Params: c.params},
+			}
+			if c.startedAgo > 0 {
+				start := metav1.NewTime(now.Add(-c.startedAgo))
+				r.Status.StartTime = &start
+				r.Status.MarkCustomRunRunning("Waiting", "Waiting")
+			}
+
+			err := rec.ReconcileKind(ctx, r)
+			if c.wantRequeue > 0 {
+				if ok, dur := controller.IsRequeueKey(err); !ok {
+					t.Fatalf("wanted requeue error, got %v", err)
+				} else if dur != c.wantRequeue {
+					t.Errorf("wanted a requeue after %s, got %s", c.wantRequeue, dur)
+				}
+			} else if err != nil {
+				t.Fatalf("ReconcileKind() = %v", err)
+			}
+			if got := r.Status.GetCondition(apis.ConditionSucceeded).Reason; got != c.wantReason {
+				t.Errorf("wanted reason %s, got %s: %s", c.wantReason, got, r.Status.GetCondition(apis.ConditionSucceeded).Message)
 			}
 		})
 	}